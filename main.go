@@ -1,14 +1,17 @@
 package main
 
 import (
-	"crypto/tls"
+	"bytes"
 	"flag"
 	"fmt"
 	"io"
+	"io/ioutil"
 	"log"
 	"net/http"
 	"net/url"
 	"os"
+	"path/filepath"
+	"strings"
 
 	"github.com/seamuncle/wsdl2go/wsdl"
 	"github.com/seamuncle/wsdl2go/wsdlgo"
@@ -16,27 +19,103 @@ import (
 
 var version = "tip"
 
+// options are the command line options.
+type options struct {
+	Src       string
+	Dst       string
+	Insecure  bool
+	User      string
+	Digest    bool
+	Header    headerFlag
+	AuthHosts string
+	Cert      string
+	Key       string
+	CACert    string
+	Cache     string
+	Refresh   bool
+	Binding   string
+	Service   string
+	Port      string
+	Transport string
+	OmitEmpty bool
+	BigInt    bool
+	Decimal   decimalFlag
+	Types     typeFlag
+	Naming    string
+	Acronyms  string
+	Tags      string
+	TagNaming string
+	Getters   bool
+	Stringers bool
+	Clone     bool
+	Validate  bool
+	Construct bool
+	Mocks     bool
+	Servers   bool
+	NoContext bool
+	Tests     bool
+	Samples   bool
+	Split     bool
+	Packages  string
+	Generate  string
+	Version   bool
+}
+
 func main() {
-	opts := struct {
-		Src      string
-		Dst      string
-		Insecure bool
-		Generate string
-		Version  bool
-	}{}
+	var opts options
 	flag.StringVar(&opts.Src, "i", opts.Src, "input file, url, or '-' for stdin")
 	flag.StringVar(&opts.Dst, "o", opts.Dst, "output file, or '-' for stdout")
 	flag.BoolVar(&opts.Insecure, "yolo", opts.Insecure, "accept invalid https certificates")
-	flag.StringVar(&opts.Generate, "gen", opts.Generate, "[go|mock|both] create go interace and implementation or mock or both in output")
+	flag.StringVar(&opts.User, "user", opts.User, "user:password for HTTP basic authentication when downloading the input and its imports")
+	flag.BoolVar(&opts.Digest, "digest", opts.Digest, "use HTTP digest rather than basic authentication for -user")
+	flag.Var(&opts.Header, "header", "'Name: value' header to send when downloading the input and its imports (repeatable)")
+	flag.StringVar(&opts.AuthHosts, "authhosts", opts.AuthHosts, "comma separated hosts, besides that of -i, to send -user and -header to when downloading imports")
+	flag.StringVar(&opts.Cert, "cert", opts.Cert, "PEM client certificate file for downloading the input and its imports")
+	flag.StringVar(&opts.Key, "key", opts.Key, "PEM client key file for -cert")
+	flag.StringVar(&opts.CACert, "cacert", opts.CACert, "PEM file of additional certificate authorities to trust")
+	flag.StringVar(&opts.Cache, "cache", opts.Cache, "directory to cache downloaded imports in, and reuse them from")
+	flag.BoolVar(&opts.Refresh, "refresh", opts.Refresh, "download imports again and update the -cache directory")
+	flag.StringVar(&opts.Binding, "binding", opts.Binding, "name of the binding to generate code for (default: the first SOAP binding of each port type)")
+	flag.StringVar(&opts.Service, "service", opts.Service, "name of the service whose bindings to consider (default: all bindings)")
+	flag.StringVar(&opts.Port, "port", opts.Port, "name of the port whose binding to generate code for (default: none)")
+	flag.StringVar(&opts.Transport, "transport", opts.Transport, "transport URI of the bindings to consider (default: any)")
+	flag.BoolVar(&opts.OmitEmpty, "omitempty", opts.OmitEmpty, "declare optional elements as values tagged omitempty rather than pointers")
+	flag.BoolVar(&opts.BigInt, "bigint", opts.BigInt, "declare xsd:integer and its unbounded derived types as big.Int")
+	flag.Var(&opts.Decimal, "decimal", "Go type of xsd:decimal: big.Rat, shopspring, string or float64 (default big.Rat); 'Type=mapping' maps a simple type restricting xsd:decimal instead (repeatable)")
+	flag.Var(&opts.Types, "type", "'Type=go type' maps a schema type, by local name or as {namespace}name, to a Go type such as github.com/google/uuid.UUID (repeatable)")
+	flag.StringVar(&opts.Naming, "naming", wsdlgo.NamingCamelCase, "naming of Go identifiers after schema names: camel (first-name becomes FirstName) or underscore (First_name)")
+	flag.StringVar(&opts.Acronyms, "acronyms", strings.Join(wsdlgo.DefaultAcronyms, ","), "comma separated words written in upper case in Go identifiers, e.g. ID,URL,HTTP")
+	flag.StringVar(&opts.Tags, "tags", strings.Join(wsdlgo.DefaultTags, ","), "comma separated struct tags of fields besides xml: json, yaml or bson")
+	flag.StringVar(&opts.TagNaming, "tagnaming", wsdlgo.TagNamingSchema, "naming of the values of -tags: schema (as in the schema), camel (firstName) or snake (first_name)")
+	flag.BoolVar(&opts.Getters, "getters", opts.Getters, "generate a GetX method for each pointer field X, returning the zero value when nil")
+	flag.BoolVar(&opts.Stringers, "stringers", opts.Stringers, "generate String methods for enumerations and the structs of messages, for logging")
+	flag.BoolVar(&opts.Clone, "clone", opts.Clone, "generate Clone methods returning deep copies of the structs")
+	flag.BoolVar(&opts.Validate, "validate", opts.Validate, "generate Validate methods checking the facets of simple types and the fields of structs")
+	flag.BoolVar(&opts.Construct, "constructors", opts.Construct, "generate NewX constructors of structs X taking their required fields")
+	flag.BoolVar(&opts.Mocks, "mocks", opts.Mocks, "generate MockX implementations of the interfaces X of port types, recording calls and returning results set by tests, without a mocking package; preferred to -gen mock, with which it conflicts")
+	flag.BoolVar(&opts.Servers, "servers", opts.Servers, "generate NewXServer functions returning SOAP servers, http.Handlers, of implementations of the interfaces X of port types")
+	flag.BoolVar(&opts.NoContext, "nocontext", opts.NoContext, "generate the methods of port types without their first context.Context parameter, as in earlier versions")
+	flag.BoolVar(&opts.Tests, "tests", opts.Tests, "generate a test marshaling a value of each struct to XML and back, to the _test.go file of -o")
+	flag.BoolVar(&opts.Samples, "samples", opts.Samples, "write a sample request of each SOAP operation, filled with placeholder values, to Operation.xml in the -o directory instead of code")
+	flag.BoolVar(&opts.Split, "split", opts.Split, "split the code into types.go, enums.go, messages.go, client.go and a file per additional namespace, in the -o directory")
+	flag.StringVar(&opts.Packages, "packages", opts.Packages, "import path of the -o directory, to split the code into a package per namespace in it, with files as by -split")
+	flag.StringVar(&opts.Generate, "gen", opts.Generate, "[go|mock|both] create go interace and implementation or mock or both in output; mock generates mocks for github.com/maraino/go-mock, kept for existing users: use -mocks otherwise")
 	flag.BoolVar(&opts.Version, "version", opts.Version, "show version and exit")
 	flag.Parse()
 	if opts.Version {
 		fmt.Printf("wsdl2go %s\n", version)
 		return
 	}
+	if opts.Tests && (opts.Dst == "" || opts.Dst == "-") {
+		log.Fatal("-tests requires -o to name a file or directory")
+	}
 	var w io.Writer
-	switch opts.Dst {
-	case "", "-":
+	switch {
+	case opts.Split || opts.Packages != "" || opts.Samples:
+		if opts.Dst == "" || opts.Dst == "-" {
+			log.Fatal("-split, -packages and -samples require -o to name a directory")
+		}
+	case opts.Dst == "" || opts.Dst == "-":
 		w = os.Stdout
 	default:
 		f, err := os.OpenFile(opts.Dst, os.O_CREATE|os.O_WRONLY, 0644)
@@ -46,46 +125,225 @@ func main() {
 		defer f.Close()
 		w = f
 	}
-	cli := http.DefaultClient
-	if opts.Insecure {
-		cli.Transport = &http.Transport{
-			TLSClientConfig: &tls.Config{InsecureSkipVerify: true},
+	fc := &wsdlgo.FetchConfig{
+		Header:   http.Header(opts.Header),
+		CertFile: opts.Cert,
+		KeyFile:  opts.Key,
+		CAFile:   opts.CACert,
+		Insecure: opts.Insecure,
+	}
+	if u, err := url.Parse(opts.Src); err == nil && u.Host != "" {
+		fc.Hosts = append(fc.Hosts, u.Host)
+	}
+	for _, h := range strings.Split(opts.AuthHosts, ",") {
+		if h = strings.TrimSpace(h); h != "" {
+			fc.Hosts = append(fc.Hosts, h)
+		}
+	}
+	if opts.User != "" {
+		fc.Username = opts.User
+		if i := strings.Index(opts.User, ":"); i >= 0 {
+			fc.Username, fc.Password = opts.User[:i], opts.User[i+1:]
 		}
+		fc.Digest = opts.Digest
 	}
-	err := decode(w, opts.Src, cli, opts.Generate)
+	cli, err := wsdlgo.NewFetchClient(fc)
+	if err != nil {
+		log.Fatal(err)
+	}
+	err = decode(w, cli, &opts)
 	if err != nil {
 		log.Fatal(err)
 	}
 }
 
-func decode(w io.Writer, src string, cli *http.Client, gen string) error {
+func decode(w io.Writer, cli *http.Client, opts *options) error {
 	var err error
 	var f io.ReadCloser
+	src := opts.Src
 	if src == "" || src == "-" {
 		f = os.Stdin
+		src = ""
 	} else if f, err = open(src, cli); err != nil {
 		return err
 	}
-	d, err := wsdl.Unmarshal(f)
+	doc, err := ioutil.ReadAll(f)
+	f.Close()
+	if err != nil {
+		return err
+	}
+	d, err := wsdl.Unmarshal(bytes.NewReader(doc))
 	if err != nil {
 		return err
 	}
-	f.Close()
 
 	genMock := false
 	genGo := true
 
-	if gen == "mock" {
+	if opts.Generate == "mock" {
 		genMock = true
 		genGo = false
 	}
-	if gen == "both" {
+	if opts.Generate == "both" {
 		genMock = true
 	}
 
 	enc := wsdlgo.NewEncoder(w, genGo, genMock)
 	enc.SetClient(cli)
-	return enc.Encode(d)
+	enc.SetBinding(opts.Binding)
+	enc.SetService(opts.Service)
+	enc.SetPort(opts.Port)
+	enc.SetTransport(opts.Transport)
+	enc.SetOmitEmpty(opts.OmitEmpty)
+	enc.SetBigIntegers(opts.BigInt)
+	enc.SetDecimal(opts.Decimal.Mapping, opts.Decimal.Types)
+	enc.SetTypeMap(opts.Types)
+	enc.SetNaming(opts.Naming, splitList(opts.Acronyms))
+	enc.SetTags(splitList(opts.Tags), opts.TagNaming)
+	enc.SetGetters(opts.Getters)
+	enc.SetStringers(opts.Stringers)
+	enc.SetClone(opts.Clone)
+	enc.SetValidate(opts.Validate)
+	enc.SetConstructors(opts.Construct)
+	enc.SetMocks(opts.Mocks)
+	enc.SetServers(opts.Servers)
+	enc.SetWSDL(doc)
+	enc.SetNoContext(opts.NoContext)
+	enc.SetTests(opts.Tests)
+	enc.SetLocation(src)
+	enc.SetCache(opts.Cache, opts.Refresh)
+	enc.SetPackages(opts.Packages)
+	if opts.Samples {
+		files, err := enc.EncodeSamples(d)
+		if err != nil {
+			return err
+		}
+		return writeFiles(files, opts.Dst)
+	}
+	if opts.Split || opts.Packages != "" {
+		err = encodeFiles(enc, d, opts.Dst)
+	} else {
+		err = enc.Encode(d)
+	}
+	if err != nil {
+		return err
+	}
+	if code := enc.Tests(); code != nil && !opts.Split && opts.Packages == "" {
+		name := strings.TrimSuffix(opts.Dst, ".go") + "_test.go"
+		err = ioutil.WriteFile(name, code, 0644)
+		if err != nil {
+			return err
+		}
+	}
+	for _, s := range enc.Renamed() {
+		log.Printf("renamed %s", s)
+	}
+	return nil
+}
+
+// encodeFiles generates the code of d split into files, written in dir
+// and the directories of their path.
+func encodeFiles(enc wsdlgo.Encoder, d *wsdl.Definitions, dir string) error {
+	files, err := enc.EncodeFiles(d)
+	if err != nil {
+		return err
+	}
+	return writeFiles(files, dir)
+}
+
+// writeFiles writes the given content of files, by slash separated
+// path, in dir and the directories of their path.
+func writeFiles(files map[string][]byte, dir string) error {
+	for name, code := range files {
+		name = filepath.Join(dir, filepath.FromSlash(name))
+		err := os.MkdirAll(filepath.Dir(name), 0755)
+		if err != nil {
+			return err
+		}
+		err = ioutil.WriteFile(name, code, 0644)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// splitList returns the non-empty elements of the comma separated list
+// s, never nil.
+func splitList(s string) []string {
+	v := []string{}
+	for _, e := range strings.Split(s, ",") {
+		if e = strings.TrimSpace(e); e != "" {
+			v = append(v, e)
+		}
+	}
+	return v
+}
+
+// headerFlag collects the values of a repeatable header flag.
+type headerFlag http.Header
+
+func (h *headerFlag) String() string {
+	return fmt.Sprint(http.Header(*h))
+}
+
+func (h *headerFlag) Set(v string) error {
+	i := strings.Index(v, ":")
+	if i < 1 {
+		return fmt.Errorf("invalid header %q, want 'Name: value'", v)
+	}
+	if *h == nil {
+		*h = make(headerFlag)
+	}
+	http.Header(*h).Add(strings.TrimSpace(v[:i]), strings.TrimSpace(v[i+1:]))
+	return nil
+}
+
+// decimalFlag collects the values of the repeatable decimal flag: the
+// mapping of xsd:decimal, and of simple types by name.
+type decimalFlag struct {
+	Mapping string
+	Types   map[string]string
+}
+
+func (f *decimalFlag) String() string {
+	return fmt.Sprint(f.Mapping, f.Types)
+}
+
+func (f *decimalFlag) Set(v string) error {
+	i := strings.Index(v, "=")
+	if i < 0 {
+		f.Mapping = v
+		return nil
+	}
+	if i == 0 {
+		return fmt.Errorf("invalid decimal mapping %q, want 'Type=mapping'", v)
+	}
+	if f.Types == nil {
+		f.Types = make(map[string]string)
+	}
+	f.Types[v[:i]] = v[i+1:]
+	return nil
+}
+
+// typeFlag collects the values of the repeatable type flag: the Go
+// types of schema types, by name.
+type typeFlag map[string]string
+
+func (f *typeFlag) String() string {
+	return fmt.Sprint(map[string]string(*f))
+}
+
+func (f *typeFlag) Set(v string) error {
+	i := strings.LastIndex(v, "=")
+	if i < 1 || i == len(v)-1 {
+		return fmt.Errorf("invalid type mapping %q, want 'Type=go type'", v)
+	}
+	if *f == nil {
+		*f = make(typeFlag)
+	}
+	(*f)[v[:i]] = v[i+1:]
+	return nil
 }
 
 func open(name string, cli *http.Client) (io.ReadCloser, error) {
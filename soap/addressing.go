@@ -0,0 +1,107 @@
+package soap
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/xml"
+	"fmt"
+)
+
+// AddressingNamespace is the namespace of WS-Addressing 1.0.
+const AddressingNamespace = "http://www.w3.org/2005/08/addressing"
+
+// Addressing configures the WS-Addressing header blocks a Client adds
+// to its requests: To, Action, MessageID and ReplyTo.
+type Addressing struct {
+	Namespace string // Optional WS-Addressing namespace (default AddressingNamespace)
+	To        string // Optional destination (default URL of the client)
+	ReplyTo   string // Optional reply endpoint (default anonymous)
+}
+
+type actionKey struct{}
+
+// WithAction returns a copy of ctx carrying the WS-Addressing action of
+// requests made with it. The action defaults to the SOAPAction of the
+// request.
+func WithAction(ctx context.Context, action string) context.Context {
+	return context.WithValue(ctx, actionKey{}, action)
+}
+
+// header returns the WS-Addressing header blocks of a request of c.
+func (a *Addressing) header(ctx context.Context, c *Client) (Header, error) {
+	h := &addressingHeader{
+		Namespace: a.Namespace,
+		To:        a.To,
+		ReplyTo:   a.ReplyTo,
+	}
+	if h.Namespace == "" {
+		h.Namespace = AddressingNamespace
+	}
+	if h.To == "" {
+		h.To = c.URL
+	}
+	if h.ReplyTo == "" {
+		h.ReplyTo = h.Namespace + "/anonymous"
+	}
+	if ctx != nil {
+		if action, ok := ctx.Value(actionKey{}).(string); ok {
+			h.Action = action
+		} else if action, ok := soapAction(ctx); ok {
+			h.Action = action
+		}
+	}
+	var err error
+	h.MessageID, err = newMessageID()
+	return h, err
+}
+
+// newMessageID returns a random UUID URN.
+func newMessageID() (string, error) {
+	var b [16]byte
+	_, err := rand.Read(b[:])
+	if err != nil {
+		return "", err
+	}
+	b[6] = b[6]&0x0f | 0x40 // version 4
+	b[8] = b[8]&0x3f | 0x80 // RFC 4122 variant
+	return fmt.Sprintf("urn:uuid:%x-%x-%x-%x-%x", b[0:4], b[4:6], b[6:8], b[8:10], b[10:]), nil
+}
+
+// addressingHeader are the WS-Addressing header blocks of a request.
+type addressingHeader struct {
+	Namespace string
+	To        string
+	Action    string
+	MessageID string
+	ReplyTo   string
+}
+
+// MarshalXML implements the xml.Marshaler interface.
+func (h *addressingHeader) MarshalXML(e *xml.Encoder, start xml.StartElement) error {
+	name := func(local string) xml.StartElement {
+		return xml.StartElement{Name: xml.Name{Space: h.Namespace, Local: local}}
+	}
+	for _, v := range []struct{ Local, Value string }{
+		{"To", h.To},
+		{"Action", h.Action},
+		{"MessageID", h.MessageID},
+	} {
+		if v.Value == "" {
+			continue
+		}
+		err := e.EncodeElement(v.Value, name(v.Local))
+		if err != nil {
+			return err
+		}
+	}
+	replyTo := name("ReplyTo")
+	err := e.EncodeToken(replyTo)
+	if err != nil {
+		return err
+	}
+	err = e.EncodeElement(h.ReplyTo, name("Address"))
+	if err != nil {
+		return err
+	}
+	return e.EncodeToken(replyTo.End())
+}
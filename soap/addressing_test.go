@@ -0,0 +1,56 @@
+package soap
+
+import (
+	"context"
+	"encoding/xml"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestAddressing(t *testing.T) {
+	var body []byte
+	echo := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ = ioutil.ReadAll(r.Body)
+		w.Write(body)
+	})
+	s := httptest.NewServer(echo)
+	defer s.Close()
+	type headerT struct {
+		To        string `xml:"http://www.w3.org/2005/08/addressing To"`
+		Action    string `xml:"http://www.w3.org/2005/08/addressing Action"`
+		MessageID string `xml:"http://www.w3.org/2005/08/addressing MessageID"`
+		ReplyTo   string `xml:"http://www.w3.org/2005/08/addressing ReplyTo>Address"`
+	}
+	cases := []struct {
+		Ctx    context.Context
+		Action string
+	}{
+		{WithSOAPAction(context.Background(), "urn:soap"), "urn:soap"},
+		{WithAction(WithSOAPAction(context.Background(), "urn:soap"), "urn:wsa"), "urn:wsa"},
+	}
+	c := &Client{URL: s.URL, Addressing: &Addressing{}}
+	ids := make(map[string]bool)
+	for i, tc := range cases {
+		err := c.RoundTrip(tc.Ctx, &struct{ A string }{"a"}, &struct{}{})
+		if err != nil {
+			t.Fatal(err)
+		}
+		var env struct {
+			Header headerT
+		}
+		if err = xml.Unmarshal(body, &env); err != nil {
+			t.Fatal(err)
+		}
+		h := env.Header
+		if h.To != s.URL || h.Action != tc.Action || h.ReplyTo != AddressingNamespace+"/anonymous" {
+			t.Errorf("test %d: unexpected header: %#v", i, h)
+		}
+		if !strings.HasPrefix(h.MessageID, "urn:uuid:") || len(h.MessageID) != 45 || ids[h.MessageID] {
+			t.Errorf("test %d: unexpected message id: %q", i, h.MessageID)
+		}
+		ids[h.MessageID] = true
+	}
+}
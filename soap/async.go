@@ -0,0 +1,62 @@
+package soap
+
+import (
+	"context"
+	"sync"
+)
+
+// Call is an asynchronous call made by the Go method of a Client.
+type Call struct {
+	Err  error      // error of the call, once done
+	Done chan *Call // receives the call once done
+}
+
+// Go runs f asynchronously, such as a closure calling methods of the
+// port types of c, once fewer than the MaxConcurrent of c, if any, of
+// the functions run by Go are running, and returns its Call. The call
+// is sent on done once f returns, or once ctx is done while waiting to
+// run. If done is nil, a new channel is allocated; otherwise it must be
+// buffered, as by net/rpc, so that Go can fan out many calls and
+// collect them on done.
+func (c *Client) Go(ctx context.Context, f func(ctx context.Context) error, done chan *Call) *Call {
+	if done == nil {
+		done = make(chan *Call, 1)
+	} else if cap(done) == 0 {
+		panic("soap: unbuffered done channel of Go")
+	}
+	call := &Call{Done: done}
+	go func() {
+		sem := c.semaphore()
+		if sem != nil {
+			select {
+			case sem <- struct{}{}:
+				defer func() { <-sem }()
+			case <-ctx.Done():
+				call.Err = ctx.Err()
+				call.Done <- call
+				return
+			}
+		}
+		call.Err = f(ctx)
+		call.Done <- call
+	}()
+	return call
+}
+
+// semMu guards the allocation of the semaphores of clients, so that a
+// Client holds no lock and can be copied.
+var semMu sync.Mutex
+
+// semaphore returns the channel bounding the functions run by Go, or
+// nil if c has no MaxConcurrent.
+func (c *Client) semaphore() chan struct{} {
+	if c.MaxConcurrent <= 0 {
+		return nil
+	}
+	semMu.Lock()
+	defer semMu.Unlock()
+	if c.sem == nil {
+		c.sem = make(chan struct{}, c.MaxConcurrent)
+	}
+	return c.sem
+}
@@ -0,0 +1,74 @@
+package soap
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestGo(t *testing.T) {
+	var mu sync.Mutex
+	running, max := 0, 0
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		running++
+		if running > max {
+			max = running
+		}
+		mu.Unlock()
+		time.Sleep(20 * time.Millisecond)
+		mu.Lock()
+		running--
+		mu.Unlock()
+		io.Copy(w, r.Body)
+	}))
+	defer s.Close()
+
+	c := &Client{URL: s.URL, MaxConcurrent: 2}
+	ctx := context.Background()
+	done := make(chan *Call, 6)
+	for i := 0; i < 6; i++ {
+		c.Go(ctx, func(ctx context.Context) error {
+			return c.RoundTrip(ctx, &struct{}{}, &struct{}{})
+		}, done)
+	}
+	for i := 0; i < 6; i++ {
+		if call := <-done; call.Err != nil {
+			t.Fatal(call.Err)
+		}
+	}
+	mu.Lock()
+	n := max
+	mu.Unlock()
+	if n != 2 {
+		t.Errorf("want 2 concurrent calls, have %d", n)
+	}
+
+	// calls waiting for a slot give up with their context
+	block, started := make(chan struct{}), make(chan bool)
+	blocked := func(context.Context) error {
+		started <- true
+		<-block
+		return nil
+	}
+	first := c.Go(ctx, blocked, nil)
+	c.Go(ctx, blocked, nil)
+	<-started
+	<-started
+	// copies of the client share its slots
+	cp := *c
+	cctx, cancel := context.WithCancel(ctx)
+	waiting := cp.Go(cctx, func(context.Context) error { return nil }, nil)
+	cancel()
+	if call := <-waiting.Done; call.Err != context.Canceled {
+		t.Errorf("want canceled call, have %v", call.Err)
+	}
+	close(block)
+	if call := <-first.Done; call.Err != nil {
+		t.Error(call.Err)
+	}
+}
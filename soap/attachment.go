@@ -0,0 +1,182 @@
+package soap
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"mime"
+	"mime/multipart"
+	"net/http"
+	"net/textproto"
+	"net/url"
+	"strings"
+)
+
+// Attachment is a MIME part of a SOAP with Attachments message, sent
+// or received alongside the envelope in a multipart/related request or
+// response, and referred to by the envelope with its cid: URL.
+type Attachment struct {
+	ContentID   string // Content-ID of the part, without angle brackets
+	ContentType string // Optional Content-Type (default application/octet-stream)
+	Data        []byte
+}
+
+// NewAttachment returns an attachment of data of the given content
+// type, with a new random content ID.
+func NewAttachment(contentType string, data []byte) *Attachment {
+	return &Attachment{
+		ContentID:   newID("") + "@wsdl2go",
+		ContentType: contentType,
+		Data:        data,
+	}
+}
+
+// Href returns the cid: URL of a, for references to it in envelopes.
+func (a *Attachment) Href() string {
+	return "cid:" + url.PathEscape(a.ContentID)
+}
+
+// Attachments are the attachments of a call: those of its request,
+// sent by the Client, and those of its response, set by the Client
+// once received.
+type Attachments struct {
+	Request  []*Attachment
+	Response []*Attachment
+}
+
+// Get returns the response attachment of the given cid: URL or content
+// ID, or nil if there is none.
+func (a *Attachments) Get(href string) *Attachment {
+	id := href
+	if strings.HasPrefix(href, "cid:") {
+		var err error
+		id, err = url.PathUnescape(strings.TrimPrefix(href, "cid:"))
+		if err != nil {
+			return nil
+		}
+	}
+	for _, att := range a.Response {
+		if att.ContentID == id {
+			return att
+		}
+	}
+	return nil
+}
+
+type attachmentsKey struct{}
+
+// WithAttachments returns a copy of ctx carrying a, so that calls made
+// with the context send the request attachments of a with their
+// envelope, as a multipart/related message, and store the attachments
+// of their response in a.
+func WithAttachments(ctx context.Context, a *Attachments) context.Context {
+	return context.WithValue(ctx, attachmentsKey{}, a)
+}
+
+// attachments returns the attachments of ctx, if any.
+func attachments(ctx context.Context) *Attachments {
+	if ctx == nil {
+		return nil
+	}
+	a, _ := ctx.Value(attachmentsKey{}).(*Attachments)
+	return a
+}
+
+// rootID is the content ID of the envelope of multipart requests.
+const rootID = "soap-envelope@wsdl2go"
+
+// multipartBody returns the multipart/related body of a request of the
+// given envelope of content type ct, and attachments, and its content
+// type.
+func multipartBody(envelope []byte, ct string, attachments []*Attachment) ([]byte, string, error) {
+	var b bytes.Buffer
+	w := multipart.NewWriter(&b)
+	root := textproto.MIMEHeader{}
+	root.Set("Content-Type", ct)
+	root.Set("Content-Transfer-Encoding", "8bit")
+	root.Set("Content-ID", "<"+rootID+">")
+	part, err := w.CreatePart(root)
+	if err != nil {
+		return nil, "", err
+	}
+	part.Write(envelope)
+	for _, a := range attachments {
+		if a.ContentID == "" {
+			return nil, "", errors.New("soap: attachment without content ID")
+		}
+		h := textproto.MIMEHeader{}
+		act := a.ContentType
+		if act == "" {
+			act = "application/octet-stream"
+		}
+		h.Set("Content-Type", act)
+		h.Set("Content-Transfer-Encoding", "binary")
+		h.Set("Content-ID", "<"+a.ContentID+">")
+		part, err = w.CreatePart(h)
+		if err != nil {
+			return nil, "", err
+		}
+		part.Write(a.Data)
+	}
+	if err = w.Close(); err != nil {
+		return nil, "", err
+	}
+	mt, _, _ := mime.ParseMediaType(ct)
+	return b.Bytes(), mime.FormatMediaType("multipart/related", map[string]string{
+		"type":     mt,
+		"start":    "<" + rootID + ">",
+		"boundary": w.Boundary(),
+	}), nil
+}
+
+// responseBody returns the SOAP envelope of resp: its body, or the root
+// part of its body if multipart/related, whose other parts are stored
+// as the response attachments of a, if not nil.
+func responseBody(resp *http.Response, a *Attachments) (io.Reader, error) {
+	mt, params, err := mime.ParseMediaType(resp.Header.Get("Content-Type"))
+	if err != nil || mt != "multipart/related" {
+		return resp.Body, nil
+	}
+	start := strings.Trim(params["start"], "<>")
+	mr := multipart.NewReader(resp.Body, params["boundary"])
+	var root []byte
+	var parts []*Attachment
+	for {
+		p, err := mr.NextPart()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("soap: invalid multipart response: %v", err)
+		}
+		var r io.Reader = p
+		if strings.EqualFold(p.Header.Get("Content-Transfer-Encoding"), "base64") {
+			r = base64.NewDecoder(base64.StdEncoding, p)
+		}
+		data, err := ioutil.ReadAll(r)
+		if err != nil {
+			return nil, err
+		}
+		id := strings.Trim(p.Header.Get("Content-ID"), "<>")
+		if root == nil && (start == "" || id == start) {
+			root = data
+			continue
+		}
+		parts = append(parts, &Attachment{
+			ContentID:   id,
+			ContentType: p.Header.Get("Content-Type"),
+			Data:        data,
+		})
+	}
+	if root == nil {
+		return nil, errors.New("soap: multipart response without envelope")
+	}
+	if a != nil {
+		a.Response = parts
+	}
+	return bytes.NewReader(root), nil
+}
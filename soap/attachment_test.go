@@ -0,0 +1,74 @@
+package soap
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/xml"
+	"fmt"
+	"io/ioutil"
+	"mime"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestAttachments(t *testing.T) {
+	type msg struct {
+		XMLName xml.Name `xml:"msg"`
+		Href    string   `xml:"href,attr"`
+	}
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mt, params, err := mime.ParseMediaType(r.Header.Get("Content-Type"))
+		if err != nil || mt != "multipart/related" || params["type"] != "text/xml" {
+			http.Error(w, "unexpected content type "+r.Header.Get("Content-Type"), http.StatusBadRequest)
+			return
+		}
+		mr := multipart.NewReader(r.Body, params["boundary"])
+		root, err := mr.NextPart()
+		if err != nil || root.Header.Get("Content-ID") != params["start"] {
+			http.Error(w, "missing root part", http.StatusBadRequest)
+			return
+		}
+		envelope, _ := ioutil.ReadAll(root)
+		part, err := mr.NextPart()
+		if err != nil {
+			http.Error(w, "missing attachment", http.StatusBadRequest)
+			return
+		}
+		data, _ := ioutil.ReadAll(part)
+		if !bytes.Contains(envelope, []byte(`href="cid:`)) || string(data) != "request data" ||
+			part.Header.Get("Content-Type") != "text/plain" {
+			http.Error(w, "unexpected attachment", http.StatusBadRequest)
+			return
+		}
+		w.Header().Set("Content-Type", `multipart/related; type="text/xml"; start="<root>"; boundary=b`)
+		fmt.Fprintf(w, "--b\r\nContent-Type: image/png\r\nContent-ID: <img>\r\n"+
+			"Content-Transfer-Encoding: base64\r\n\r\n%s\r\n"+
+			"--b\r\nContent-Type: text/xml\r\nContent-ID: <root>\r\n\r\n"+
+			`<Envelope xmlns="http://schemas.xmlsoap.org/soap/envelope/"><Body><msg href="cid:img"/></Body></Envelope>`+
+			"\r\n--b--\r\n", base64.StdEncoding.EncodeToString([]byte("response data")))
+	}))
+	defer s.Close()
+
+	c := &Client{URL: s.URL}
+	a := &Attachments{Request: []*Attachment{NewAttachment("text/plain", []byte("request data"))}}
+	in := &msg{Href: a.Request[0].Href()}
+	out := &struct{ Body struct{ Msg msg } }{}
+	err := c.RoundTrip(WithAttachments(context.Background(), a), in, out)
+	if err != nil {
+		t.Fatal(err)
+	}
+	href := out.Body.Msg.Href
+	if href != "cid:img" {
+		t.Fatalf("unexpected response %+v", out)
+	}
+	att := a.Get(href)
+	if att == nil || string(att.Data) != "response data" || att.ContentType != "image/png" {
+		t.Errorf("unexpected attachment %+v of %+v", att, a.Response)
+	}
+	if a.Get("cid:none") != nil {
+		t.Error("unexpected attachment of cid:none")
+	}
+}
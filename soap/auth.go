@@ -0,0 +1,248 @@
+package soap
+
+import (
+	"crypto/md5"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"hash"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"sync"
+)
+
+// Auth is the HTTP authentication of the requests of a Client, Basic
+// unless Digest is set. Digest authentication answers the challenge of
+// the first request, and reuses it for the next ones while the server
+// accepts it. An Auth must not be copied after first use.
+type Auth struct {
+	Username string
+	Password string
+	Digest   bool
+
+	mu        sync.Mutex
+	challenge *digestChallenge
+	count     int
+}
+
+// Transport returns an http.RoundTripper authenticating the requests
+// it makes with next, or with http.DefaultTransport if nil. Requests
+// redirected to another host than the one first requested are not
+// authenticated, so that the credentials are not sent to it.
+func (a *Auth) Transport(next http.RoundTripper) http.RoundTripper {
+	if next == nil {
+		next = http.DefaultTransport
+	}
+	return &authTransport{auth: a, next: next}
+}
+
+// setToken sets the Authorization header of r to the bearer token of
+// c, if any.
+func (c *Client) setToken(r *http.Request) error {
+	if c.Token == nil {
+		return nil
+	}
+	token, err := c.Token(r.Context())
+	if err != nil {
+		return err
+	}
+	r.Header.Set("Authorization", "Bearer "+token)
+	return nil
+}
+
+// authTransport is the http.RoundTripper of an Auth.
+type authTransport struct {
+	auth *Auth
+	next http.RoundTripper
+	host string // Optional host of the authenticated requests
+}
+
+// authenticates reports whether the credentials of t are sent with r:
+// whether r goes to the host of t, or else to the host first requested
+// before the redirects leading to r, if any.
+func (t *authTransport) authenticates(r *http.Request) bool {
+	host := t.host
+	if host == "" {
+		first := r
+		for first.Response != nil && first.Response.Request != nil {
+			first = first.Response.Request
+		}
+		host = first.URL.Host
+	}
+	return r.URL.Host == host
+}
+
+func (t *authTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if !t.authenticates(req) {
+		return t.next.RoundTrip(req)
+	}
+	a := t.auth
+	// the request must not be modified, as per http.RoundTripper
+	r := req.Clone(req.Context())
+	if !a.Digest {
+		r.SetBasicAuth(a.Username, a.Password)
+		return t.next.RoundTrip(r)
+	}
+	sent := a.authorize(r)
+	resp, err := t.next.RoundTrip(r)
+	if err != nil || resp.StatusCode != http.StatusUnauthorized {
+		return resp, err
+	}
+	c := parseDigestChallenge(resp.Header.Get("WWW-Authenticate"))
+	if c == nil || sent && !c.stale || req.Body != nil && req.GetBody == nil {
+		return resp, nil
+	}
+	ioutil.ReadAll(resp.Body)
+	resp.Body.Close()
+	a.mu.Lock()
+	a.challenge, a.count = c, 0
+	a.mu.Unlock()
+	r = req.Clone(req.Context())
+	if req.Body != nil {
+		r.Body, err = req.GetBody()
+		if err != nil {
+			return nil, err
+		}
+	}
+	a.authorize(r)
+	return t.next.RoundTrip(r)
+}
+
+// authorize sets the Digest Authorization header of r, answering the
+// last challenge of a, if any, and reports whether it did.
+func (a *Auth) authorize(r *http.Request) bool {
+	a.mu.Lock()
+	c := a.challenge
+	a.count++
+	nc := fmt.Sprintf("%08x", a.count)
+	a.mu.Unlock()
+	if c == nil {
+		return false
+	}
+	h := c.hash()
+	if h == nil {
+		return false
+	}
+	b := make([]byte, 8)
+	rand.Read(b)
+	cnonce := hex.EncodeToString(b)
+	uri := r.URL.RequestURI()
+	ha1 := digest(h, a.Username+":"+c.realm+":"+a.Password)
+	if strings.HasSuffix(strings.ToUpper(c.algorithm), "-SESS") {
+		ha1 = digest(h, ha1+":"+c.nonce+":"+cnonce)
+	}
+	ha2 := digest(h, r.Method+":"+uri)
+	v := fmt.Sprintf(`Digest username=%q, realm=%q, nonce=%q, uri=%q`, a.Username, c.realm, c.nonce, uri)
+	if c.qop {
+		v += fmt.Sprintf(`, qop=auth, nc=%s, cnonce=%q, response=%q`,
+			nc, cnonce, digest(h, ha1+":"+c.nonce+":"+nc+":"+cnonce+":auth:"+ha2))
+	} else {
+		v += fmt.Sprintf(`, response=%q`, digest(h, ha1+":"+c.nonce+":"+ha2))
+	}
+	if c.algorithm != "" {
+		v += ", algorithm=" + c.algorithm
+	}
+	if c.opaque != "" {
+		v += fmt.Sprintf(`, opaque=%q`, c.opaque)
+	}
+	r.Header.Set("Authorization", v)
+	return true
+}
+
+// digestChallenge is a challenge of HTTP Digest authentication.
+type digestChallenge struct {
+	realm, nonce, opaque, algorithm string
+	qop, stale                      bool // qop: whether auth is offered
+}
+
+// parseDigestChallenge returns the Digest challenge of the given value
+// of a WWW-Authenticate header, or nil if it has none.
+func parseDigestChallenge(s string) *digestChallenge {
+	s = strings.TrimSpace(s)
+	if len(s) < 7 || !strings.EqualFold(s[:7], "Digest ") {
+		return nil
+	}
+	c := &digestChallenge{}
+	for _, p := range splitParams(s[7:]) {
+		k, v := p[0], p[1]
+		switch strings.ToLower(k) {
+		case "realm":
+			c.realm = v
+		case "nonce":
+			c.nonce = v
+		case "opaque":
+			c.opaque = v
+		case "algorithm":
+			c.algorithm = v
+		case "stale":
+			c.stale = strings.EqualFold(v, "true")
+		case "qop":
+			for _, q := range strings.Split(v, ",") {
+				if strings.TrimSpace(q) == "auth" {
+					c.qop = true
+				}
+			}
+		}
+	}
+	if c.nonce == "" {
+		return nil
+	}
+	return c
+}
+
+// splitParams splits the comma separated name=value parameters of s,
+// whose values may be quoted strings.
+func splitParams(s string) [][2]string {
+	var params [][2]string
+	for {
+		s = strings.TrimLeft(s, ", \t")
+		i := strings.Index(s, "=")
+		if i < 0 {
+			return params
+		}
+		k := strings.TrimSpace(s[:i])
+		s = strings.TrimLeft(s[i+1:], " \t")
+		var v string
+		if strings.HasPrefix(s, `"`) {
+			var b strings.Builder
+			i = 1
+			for ; i < len(s) && s[i] != '"'; i++ {
+				if s[i] == '\\' && i+1 < len(s) {
+					i++
+				}
+				b.WriteByte(s[i])
+			}
+			if i < len(s) {
+				i++
+			}
+			v, s = b.String(), s[i:]
+		} else {
+			i = strings.IndexAny(s, ",")
+			if i < 0 {
+				i = len(s)
+			}
+			v, s = strings.TrimSpace(s[:i]), s[i:]
+		}
+		params = append(params, [2]string{k, v})
+	}
+}
+
+// hash returns the hash of the algorithm of c, or nil if not supported.
+func (c *digestChallenge) hash() hash.Hash {
+	switch strings.TrimSuffix(strings.ToUpper(c.algorithm), "-SESS") {
+	case "", "MD5":
+		return md5.New()
+	case "SHA-256":
+		return sha256.New()
+	}
+	return nil
+}
+
+// digest returns the hex encoded hash of s with h.
+func digest(h hash.Hash, s string) string {
+	h.Reset()
+	h.Write([]byte(s))
+	return hex.EncodeToString(h.Sum(nil))
+}
@@ -0,0 +1,166 @@
+package soap
+
+import (
+	"context"
+	"crypto/md5"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestBasicAuth(t *testing.T) {
+	echo := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if u, p, ok := r.BasicAuth(); !ok || u != "u" || p != "p" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		io.Copy(w, r.Body)
+	})
+	s := httptest.NewServer(echo)
+	defer s.Close()
+	for _, tc := range []struct {
+		Auth *Auth
+		Err  bool
+	}{{&Auth{Username: "u", Password: "p"}, false}, {&Auth{Username: "u"}, true}, {nil, true}} {
+		c := &Client{URL: s.URL, Auth: tc.Auth}
+		err := c.RoundTrip(context.Background(), &struct{}{}, &struct{}{})
+		if (err != nil) != tc.Err {
+			t.Errorf("auth %+v: want error %v, have %v", tc.Auth, tc.Err, err)
+		}
+	}
+}
+
+func TestBasicAuthRedirect(t *testing.T) {
+	var leaked bool
+	other := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _, leaked = r.BasicAuth()
+		io.Copy(w, r.Body)
+	}))
+	defer other.Close()
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if _, _, ok := r.BasicAuth(); !ok {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		http.Redirect(w, r, other.URL, http.StatusTemporaryRedirect)
+	}))
+	defer s.Close()
+
+	c := &Client{URL: s.URL, Auth: &Auth{Username: "u", Password: "p"}}
+	if err := c.RoundTrip(context.Background(), &struct{}{}, &struct{}{}); err != nil {
+		t.Fatal(err)
+	}
+	if leaked {
+		t.Error("credentials sent to the host redirected to")
+	}
+
+	leaked = false
+	cli := &http.Client{Transport: c.Auth.Transport(nil)}
+	resp, err := cli.Post(s.URL, "text/xml", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK || leaked {
+		t.Errorf("want the redirect followed without credentials, have %s, leaked %v", resp.Status, leaked)
+	}
+}
+
+func TestToken(t *testing.T) {
+	var auth string
+	echo := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		auth = r.Header.Get("Authorization")
+		io.Copy(w, r.Body)
+	})
+	s := httptest.NewServer(echo)
+	defer s.Close()
+	var n int
+	c := &Client{URL: s.URL, Token: func(ctx context.Context) (string, error) {
+		n++
+		if n > 2 {
+			return "", errors.New("expired")
+		}
+		return fmt.Sprintf("t%d", n), nil
+	}}
+	for i, want := range []string{"Bearer t1", "Bearer t2"} {
+		err := c.RoundTrip(context.Background(), &struct{}{}, &struct{}{})
+		if err != nil || auth != want {
+			t.Errorf("call %d: want %q, have %q, %v", i, want, auth, err)
+		}
+	}
+	err := c.RoundTripHTTP(context.Background(), "GET", "", nil, nil)
+	if err == nil || err.Error() != "expired" {
+		t.Errorf("want error of the token, have %v", err)
+	}
+}
+
+func TestDigestAuth(t *testing.T) {
+	md5hex := func(s string) string {
+		h := md5.Sum([]byte(s))
+		return hex.EncodeToString(h[:])
+	}
+	nonce, stale := "n1", false
+	var requests int
+	echo := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		challenge := func() {
+			w.Header().Set("WWW-Authenticate", fmt.Sprintf(
+				`Digest realm="soap", qop="auth,auth-int", nonce=%q, opaque="o", stale=%v`, nonce, stale))
+			w.WriteHeader(http.StatusUnauthorized)
+		}
+		h := r.Header.Get("Authorization")
+		if h == "" {
+			challenge()
+			return
+		}
+		p := make(map[string]string)
+		for _, kv := range splitParams(h[len("Digest "):]) {
+			p[kv[0]] = kv[1]
+		}
+		ha1 := md5hex("u:soap:p")
+		ha2 := md5hex(r.Method + ":" + p["uri"])
+		want := md5hex(ha1 + ":" + nonce + ":" + p["nc"] + ":" + p["cnonce"] + ":auth:" + ha2)
+		if p["nonce"] != nonce || p["opaque"] != "o" || p["response"] != want {
+			challenge()
+			return
+		}
+		io.Copy(w, r.Body)
+	})
+	s := httptest.NewServer(echo)
+	defer s.Close()
+
+	c := &Client{URL: s.URL, Auth: &Auth{Username: "u", Password: "p", Digest: true}}
+	call := func() error {
+		return c.RoundTrip(context.Background(), &struct{ A string }{"a"}, &struct{}{})
+	}
+	if err := call(); err != nil || requests != 2 {
+		t.Fatalf("first call: want 2 requests, have %d, %v", requests, err)
+	}
+	requests = 0
+	if err := call(); err != nil || requests != 1 {
+		t.Errorf("second call: want 1 request, have %d, %v", requests, err)
+	}
+	requests, nonce, stale = 0, "n2", true
+	if err := call(); err != nil || requests != 2 {
+		t.Errorf("stale nonce: want 2 requests, have %d, %v", requests, err)
+	}
+	requests, stale = 0, false
+	c.Auth = &Auth{Username: "u", Password: "wrong", Digest: true}
+	if err := call(); err == nil || requests != 2 {
+		t.Errorf("wrong password: want an error after 2 requests, have %d, %v", requests, err)
+	}
+}
+
+func TestParseDigestChallenge(t *testing.T) {
+	c := parseDigestChallenge(`Digest realm="a \"b\", c", nonce=xyz, algorithm=SHA-256, qop="auth-int, auth"`)
+	if c == nil || c.realm != `a "b", c` || c.nonce != "xyz" || c.algorithm != "SHA-256" || !c.qop {
+		t.Errorf("unexpected challenge %+v", c)
+	}
+	if c := parseDigestChallenge(`Basic realm="a"`); c != nil {
+		t.Errorf("unexpected challenge %+v of Basic", c)
+	}
+}
@@ -0,0 +1,131 @@
+package soap
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"sync"
+	"time"
+)
+
+// ErrCircuitOpen is the error of the calls of a Client failed fast by
+// its open circuit breaker.
+var ErrCircuitOpen = errors.New("soap: circuit breaker open")
+
+// CircuitBreaker stops the calls of a Client to a failing server. The
+// circuit opens once the rate of failed calls within a Window, counting
+// those slower than SlowCall, reaches ErrorRate, after at least MinCalls.
+// Calls then fail fast with ErrCircuitOpen until OpenTimeout has passed,
+// when a single trial call closes the circuit again if it succeeds, or
+// reopens it. Faults caused by the request, such as FaultClient, and
+// calls canceled by the caller are not failures of the server.
+type CircuitBreaker struct {
+	ErrorRate   float64       // rate of failures opening the circuit (default 0.5)
+	MinCalls    int           // minimum calls within a window to open it (default 10)
+	Window      time.Duration // period of the rate of failures (default 1m)
+	SlowCall    time.Duration // Optional latency of calls counted as failures
+	OpenTimeout time.Duration // time open before a trial call (default 30s)
+
+	mu       sync.Mutex
+	start    time.Time // of the current window
+	calls    int       // within the window
+	failures int       // within the window
+	openedAt time.Time // zero if closed
+	trial    bool      // whether a trial call is running
+}
+
+// wrap returns call failing fast while b is open, or call itself if b
+// is nil.
+func (b *CircuitBreaker) wrap(call CallFunc) CallFunc {
+	if b == nil {
+		return call
+	}
+	return func(ctx context.Context, in, out Message) error {
+		trial, err := b.allow(time.Now())
+		if err != nil {
+			return err
+		}
+		start := time.Now()
+		err = call(ctx, in, out)
+		b.record(time.Now(), trial, b.failed(time.Since(start), err))
+		return err
+	}
+}
+
+// allow reports whether a call can be made at now, and whether it is
+// the trial call of an open circuit, or returns ErrCircuitOpen.
+func (b *CircuitBreaker) allow(now time.Time) (bool, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.openedAt.IsZero() {
+		return false, nil
+	}
+	timeout := b.OpenTimeout
+	if timeout <= 0 {
+		timeout = 30 * time.Second
+	}
+	if b.trial || now.Sub(b.openedAt) < timeout {
+		return false, ErrCircuitOpen
+	}
+	b.trial = true
+	return true, nil
+}
+
+// record records the end at now of a call, the trial call of an open
+// circuit or not, which failed or not.
+func (b *CircuitBreaker) record(now time.Time, trial, failed bool) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if trial {
+		b.trial = false
+		b.openedAt = time.Time{}
+		if failed {
+			b.openedAt = now
+		}
+		b.start, b.calls, b.failures = now, 0, 0
+		return
+	}
+	if !b.openedAt.IsZero() {
+		// a call made before the circuit opened
+		return
+	}
+	window := b.Window
+	if window <= 0 {
+		window = time.Minute
+	}
+	if now.Sub(b.start) >= window {
+		b.start, b.calls, b.failures = now, 0, 0
+	}
+	b.calls++
+	if failed {
+		b.failures++
+	}
+	rate, min := b.ErrorRate, b.MinCalls
+	if rate <= 0 {
+		rate = 0.5
+	}
+	if min <= 0 {
+		min = 10
+	}
+	if b.calls >= min && float64(b.failures) >= rate*float64(b.calls) {
+		b.openedAt = now
+	}
+}
+
+// failed reports whether a call that took d and failed with err, if
+// not nil, is a failure of the server.
+func (b *CircuitBreaker) failed(d time.Duration, err error) bool {
+	if b.SlowCall > 0 && d > b.SlowCall {
+		return true
+	}
+	if err == nil || errors.Is(err, context.Canceled) {
+		return false
+	}
+	var f *Fault
+	if errors.As(err, &f) {
+		// codes of faults of the request, possibly qualified
+		code := f.Code[strings.LastIndex(f.Code, ":")+1:]
+		return code != FaultClient && code != "Sender"
+	}
+	return true
+}
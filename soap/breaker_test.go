@@ -0,0 +1,87 @@
+package soap
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestCircuitBreaker(t *testing.T) {
+	var mu sync.Mutex
+	status, hits := http.StatusServiceUnavailable, 0
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+		hits++
+		if r.Header.Get("SOAPAction") == `"urn:Bad"` {
+			w.WriteHeader(http.StatusInternalServerError)
+			io.WriteString(w, `<Envelope xmlns="http://schemas.xmlsoap.org/soap/envelope/"><Body><Fault>`+
+				`<faultcode>soap:Client</faultcode><faultstring>bad request</faultstring></Fault></Body></Envelope>`)
+			return
+		}
+		w.WriteHeader(status)
+		io.Copy(w, r.Body)
+	}))
+	defer s.Close()
+
+	b := &CircuitBreaker{MinCalls: 4, OpenTimeout: 50 * time.Millisecond}
+	c := &Client{URL: s.URL, Breaker: b}
+	call := func(action string) error {
+		return c.RoundTrip(WithSOAPAction(context.Background(), action), &struct{}{}, &struct{}{})
+	}
+
+	// faults of requests are not failures of the server
+	for i := 0; i < 4; i++ {
+		if err := call("urn:Bad"); err == ErrCircuitOpen {
+			t.Fatal("circuit opened by faults of requests")
+		}
+	}
+	for i := 0; i < 4; i++ {
+		if err := call("urn:A"); err == nil || err == ErrCircuitOpen {
+			t.Fatalf("want error of server, have %v", err)
+		}
+	}
+	hitsOpen := hits
+	if err := call("urn:A"); err != ErrCircuitOpen {
+		t.Fatalf("want open circuit, have %v", err)
+	}
+	if hits != hitsOpen {
+		t.Error("call made while the circuit is open")
+	}
+
+	// the trial call reopens the circuit, then closes it once healthy
+	time.Sleep(60 * time.Millisecond)
+	if err := call("urn:A"); err == nil || err == ErrCircuitOpen {
+		t.Fatalf("want error of trial call, have %v", err)
+	}
+	if err := call("urn:A"); err != ErrCircuitOpen {
+		t.Fatalf("want reopened circuit, have %v", err)
+	}
+	mu.Lock()
+	status = http.StatusOK
+	mu.Unlock()
+	time.Sleep(60 * time.Millisecond)
+	for i := 0; i < 2; i++ {
+		if err := call("urn:A"); err != nil {
+			t.Fatalf("want closed circuit, have %v", err)
+		}
+	}
+}
+
+func TestCircuitBreakerSlowCalls(t *testing.T) {
+	b := &CircuitBreaker{MinCalls: 2, SlowCall: time.Second}
+	now := time.Now()
+	for i := 0; i < 2; i++ {
+		b.record(now, false, b.failed(2*time.Second, nil))
+	}
+	if _, err := b.allow(now); err != ErrCircuitOpen {
+		t.Errorf("want circuit opened by slow calls, have %v", err)
+	}
+	if _, err := b.allow(now.Add(31 * time.Second)); err != nil {
+		t.Errorf("want trial call after the default timeout, have %v", err)
+	}
+}
@@ -9,6 +9,9 @@ import (
 	"io"
 	"io/ioutil"
 	"net/http"
+	"net/url"
+	"strings"
+	"time"
 )
 
 // A RoundTripper executes a request passing the given req as the SOAP
@@ -40,64 +43,426 @@ type Client struct {
 	Namespace   string              // SOAP Namespace
 	Envelope    string              // Optional SOAP Envelope
 	Header      Header              // Optional SOAP Header
-	ContentType string              // Optional Content-Type (default text/xml)
+	ContentType string              // Optional Content-Type (default text/xml or application/soap+xml)
 	Config      *http.Client        // Optional HTTP client
+	Transport   http.RoundTripper   // Optional HTTP transport of Config
+	Auth        *Auth               // Optional HTTP Basic or Digest authentication
+	Jar         http.CookieJar      // Optional cookie jar of sessions
 	Pre         func(*http.Request) // Optional hook to modify outbound requests
+	Addressing  *Addressing         // Optional WS-Addressing header blocks
+	Hooks       *Hooks              // Optional hooks of envelopes, for logging
+	Retry       *RetryPolicy        // Optional retries of failed calls
+	Timeout     time.Duration       // Optional timeout of each attempt of a call
+	Encryption  *Encryption         // Optional XML Encryption of bodies
+	Dump        *Dump               // Optional dumps of requests, for debugging
+	Tracer      Tracer              // Optional tracing of calls, e.g. by OpenTelemetry
+	Metrics     Metrics             // Optional metrics of calls, e.g. PrometheusMetrics
+	Breaker     *CircuitBreaker     // Optional circuit breaker of calls to a failing server
+
+	// Token, if set, returns the bearer token of the Authorization
+	// header of each request, such as the AccessToken of the tokens of
+	// an oauth2.TokenSource, which refreshes them once expired.
+	Token func(ctx context.Context) (string, error)
+
+	// UsernameToken, if set, adds a WS-Security header block with the
+	// credentials of the token to requests.
+	UsernameToken *UsernameToken
+
+	// Timestamp, if set, adds a wsu:Timestamp to the WS-Security
+	// header block of requests, which many gateways require.
+	Timestamp *Timestamp
+
+	// Assertion, if set, is the XML of a SAML assertion, such as one
+	// issued by an identity provider, added as is as the security token
+	// of the WS-Security header block of requests.
+	Assertion []byte
+
+	// Interceptors wrap each call of RoundTrip, the first outermost.
+	Interceptors []Interceptor
+
+	// ValidateRequests makes RoundTrip validate the messages of
+	// requests that implement Validator before sending them, so that
+	// invalid ones fail without a call to the server.
+	ValidateRequests bool
+
+	// MaxConcurrent, if positive, bounds the functions run by Go at
+	// once, so that calls can be fanned out to a bounded pool.
+	MaxConcurrent int
+
+	// semaphore of MaxConcurrent, shared by the copies of the client
+	// made once allocated
+	sem chan struct{}
+}
+
+// Validator is implemented by messages that can check themselves
+// against the constraints of their schema, such as the structs
+// generated by wsdl2go -validate.
+type Validator interface {
+	Validate() error
+}
+
+type envelopeKey struct{}
+
+// WithEnvelope returns a copy of ctx carrying the envelope namespace of
+// requests made with it, EnvelopeNamespace or Envelope12Namespace,
+// overriding the Envelope of the Client. Operations generated from SOAP
+// 1.2 bindings call it.
+func WithEnvelope(ctx context.Context, envelope string) context.Context {
+	return context.WithValue(ctx, envelopeKey{}, envelope)
 }
 
-// RoundTrip implements the RoundTripper interface.
+// version returns the envelope namespace of the requests of c made
+// with ctx: that of the context, the Envelope of c, or else SOAP 1.1.
+func (c *Client) version(ctx context.Context) string {
+	if ctx != nil {
+		if v, ok := ctx.Value(envelopeKey{}).(string); ok && v != "" {
+			return v
+		}
+	}
+	if c.Envelope != "" {
+		return c.Envelope
+	}
+	return EnvelopeNamespace
+}
+
+type soapActionKey struct{}
+
+// WithSOAPAction returns a copy of ctx carrying the SOAP action of
+// requests made with it.
+func WithSOAPAction(ctx context.Context, action string) context.Context {
+	return context.WithValue(ctx, soapActionKey{}, action)
+}
+
+// soapAction returns the SOAP action carried by ctx, if any. Contexts
+// carrying it as a "SOAPAction" string value are still supported.
+func soapAction(ctx context.Context) (string, bool) {
+	if ctx == nil {
+		return "", false
+	}
+	if action, ok := ctx.Value(soapActionKey{}).(string); ok {
+		return action, true
+	}
+	action, ok := ctx.Value("SOAPAction").(string)
+	return action, ok
+}
+
+type timeoutKey struct{}
+
+// WithTimeout returns a copy of ctx carrying the timeout of each attempt
+// of the calls made with it, instead of the Timeout of the Client, so
+// that slow operations need no client of their own. A timeout of zero
+// disables the one of the Client. The deadline of ctx, if any, still
+// bounds all the attempts of a call.
+func WithTimeout(ctx context.Context, d time.Duration) context.Context {
+	return context.WithValue(ctx, timeoutKey{}, d)
+}
+
+// withTimeout returns a copy of ctx bounded by the timeout of a call of
+// c, carried by ctx or else the Timeout of c, if any.
+func (c *Client) withTimeout(ctx context.Context) (context.Context, context.CancelFunc) {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	d, ok := ctx.Value(timeoutKey{}).(time.Duration)
+	if !ok {
+		d = c.Timeout
+	}
+	if d <= 0 {
+		return ctx, func() {}
+	}
+	return context.WithTimeout(ctx, d)
+}
+
+type headerKey struct{}
+
+// WithHeader returns a copy of ctx carrying the header block h, to be
+// encoded in the SOAP Header element of requests made with the context.
+//
+// Requests made with such a context, or by a Client with Addressing or
+// WS-Security tokens or timestamps, encode the Header of the Client, if
+// any, as a header block of its own rather than as the content of the
+// SOAP Header element.
+func WithHeader(ctx context.Context, h Header) context.Context {
+	hs, _ := ctx.Value(headerKey{}).([]Header)
+	return context.WithValue(ctx, headerKey{}, append(hs[:len(hs):len(hs)], h))
+}
+
+// headerBlocks is the content of a SOAP Header element with several
+// header blocks.
+type headerBlocks []Header
+
+// MarshalXML implements the xml.Marshaler interface.
+func (hb headerBlocks) MarshalXML(e *xml.Encoder, start xml.StartElement) error {
+	err := e.EncodeToken(start)
+	if err != nil {
+		return err
+	}
+	for _, h := range hb {
+		err = e.Encode(h)
+		if err != nil {
+			return err
+		}
+	}
+	return e.EncodeToken(start.End())
+}
+
+// Cookies returns the cookies of the Jar of c that requests to its URL
+// send, those set by the responses of previous calls such as the
+// session of a login, or nil if c has no Jar.
+func (c *Client) Cookies() []*http.Cookie {
+	if c.Jar == nil {
+		return nil
+	}
+	u, err := url.Parse(c.URL)
+	if err != nil {
+		return nil
+	}
+	return c.Jar.Cookies(u)
+}
+
+// httpClient returns the HTTP client of c, its Config or else the
+// default client, with the Transport of c instead of its own if set,
+// authenticating requests to the host of its URL with the Auth of c,
+// keeping cookies in its Jar and dumping requests to its Dump, if any.
+func (c *Client) httpClient() *http.Client {
+	cli := c.Config
+	if cli == nil {
+		cli = http.DefaultClient
+	}
+	if c.Transport == nil && c.Auth == nil && c.Jar == nil && c.Dump == nil {
+		return cli
+	}
+	cp := *cli
+	if c.Transport != nil {
+		cp.Transport = c.Transport
+	}
+	if c.Dump != nil {
+		cp.Transport = c.Dump.Transport(cp.Transport)
+	}
+	if c.Jar != nil {
+		cp.Jar = c.Jar
+	}
+	if c.Auth != nil {
+		t := c.Auth.Transport(cp.Transport).(*authTransport)
+		if u, err := url.Parse(c.URL); err == nil {
+			t.host = u.Host
+		}
+		cp.Transport = t
+	}
+	return &cp
+}
+
+// RoundTrip implements the RoundTripper interface. The call goes through
+// the Interceptors of c, then those carried by ctx, in the span of its
+// Tracer, if any, and is recorded by its Metrics, if any. Calls fail
+// fast with ErrCircuitOpen while its Breaker is open. Calls of one-way
+// operations pass a nil out, and return once the request is accepted.
 func (c *Client) RoundTrip(ctx context.Context, in, out Message) error {
+	return c.measure(c.trace(c.Breaker.wrap(c.intercept(ctx, c.roundTrip))))(ctx, in, out)
+}
+
+// roundTrip makes the call of RoundTrip.
+func (c *Client) roundTrip(ctx context.Context, in, out Message) error {
+	if v, ok := in.(Validator); ok && c.ValidateRequests {
+		if err := v.Validate(); err != nil {
+			return err
+		}
+	}
+	for attempt := 1; ; attempt++ {
+		envelope, ok := in.(RawEnvelope)
+		if !ok {
+			var err error
+			envelope, err = c.envelope(ctx, in)
+			if err != nil {
+				return err
+			}
+		}
+		status, err := c.send(ctx, envelope, out)
+		if err == nil || !c.Retry.retry(ctx, attempt, status, err) {
+			return err
+		}
+	}
+}
+
+// envelope returns the envelope of a request of c whose body is in,
+// with the header blocks of c and those carried by ctx. Each attempt
+// of a call has its own, with a new WS-Addressing message ID and
+// WS-Security nonce.
+func (c *Client) envelope(ctx context.Context, in Message) ([]byte, error) {
 	req := &Envelope{
 		EnvelopeAttr: c.Envelope,
 		NSAttr:       c.Namespace,
 		Header:       c.Header,
 		Body:         Body{Message: in},
 	}
-
-	if req.EnvelopeAttr == "" {
-		req.EnvelopeAttr = "http://schemas.xmlsoap.org/soap/envelope/"
+	var hs []Header
+	if ctx != nil {
+		hs, _ = ctx.Value(headerKey{}).([]Header)
+	}
+	if c.Addressing != nil {
+		h, err := c.Addressing.header(ctx, c)
+		if err != nil {
+			return nil, err
+		}
+		hs = append([]Header{h}, hs...)
+	}
+	version := c.version(ctx)
+	h, err := c.securityHeader(version, time.Now())
+	if err != nil {
+		return nil, err
 	}
+	if h != nil {
+		hs = append([]Header{h}, hs...)
+	}
+	if len(hs) > 0 {
+		var hb headerBlocks
+		if c.Header != nil {
+			hb = append(hb, c.Header)
+		}
+		req.Header = append(hb, hs...)
+	}
+
+	req.EnvelopeAttr = version
 	if req.NSAttr == "" {
 		req.NSAttr = c.URL
 	}
 	var b bytes.Buffer
-	err := xml.NewEncoder(&b).Encode(req)
+	err = xml.NewEncoder(&b).Encode(req)
 	if err != nil {
-		return err
+		return nil, err
+	}
+	if c.Encryption != nil {
+		return c.Encryption.encrypt(b.Bytes(), version)
 	}
+	return b.Bytes(), nil
+}
+
+// send makes one attempt of a call of c, posting the given envelope
+// and decoding the response onto out, and returns the HTTP status of
+// the response, if any.
+func (c *Client) send(ctx context.Context, envelope []byte, out Message) (int, error) {
 	ct := c.ContentType
 	if ct == "" {
 		ct = "text/xml"
+		if c.version(ctx) == Envelope12Namespace {
+			ct = "application/soap+xml; charset=utf-8"
+		}
 	}
-	cli := c.Config
-	if cli == nil {
-		cli = http.DefaultClient
+	// SOAP 1.2 conveys the action as a parameter of the content type,
+	// SOAP 1.1 in its own header, which is required even if empty.
+	action, _ := soapAction(ctx)
+	soap12 := strings.HasPrefix(ct, "application/soap+xml")
+	if soap12 && action != "" {
+		ct += `; action="` + action + `"`
 	}
-	r, err := http.NewRequest("POST", c.URL, &b)
+	body := envelope
+	if a := attachments(ctx); a != nil && len(a.Request) > 0 {
+		var err error
+		body, ct, err = multipartBody(envelope, ct, a.Request)
+		if err != nil {
+			return 0, err
+		}
+	}
+	r, err := http.NewRequest("POST", c.URL, bytes.NewReader(body))
 	if err != nil {
-		return err
+		return 0, err
+	}
+	rctx, cancel := c.withTimeout(ctx)
+	defer cancel()
+	r = r.WithContext(rctx)
+	if !soap12 {
+		r.Header.Set("SOAPAction", `"`+action+`"`)
 	}
 	r.Header.Set("Content-Type", ct)
+	if err = c.setToken(r); err != nil {
+		return 0, err
+	}
+	if c.Tracer != nil {
+		c.Tracer.Inject(ctx, r.Header)
+	}
 	if c.Pre != nil {
 		c.Pre(r)
 	}
-
-	if ctx != nil {
-		r.Header.Set("SOAPAction", ctx.Value("SOAPAction").(string))
+	var x *Exchange
+	if c.Hooks != nil {
+		x = c.Hooks.before(ctx, envelope)
+	}
+	status, err := c.do(r, out, x)
+	if x != nil {
+		x.Status = status
+		c.Hooks.after(ctx, x, err)
+	}
+	if s := callSpan(ctx); s != nil && status != 0 {
+		s.SetAttribute(AttrStatus, status)
 	}
+	return status, err
+}
 
-	resp, err := cli.Do(r)
+// do sends the request r and decodes the response onto out, decrypted
+// if encrypted, recording its body in x, if not nil. Responses of
+// one-way calls, whose out is nil, and 202 Accepted responses are not
+// decoded. It returns the HTTP status of the response, if any.
+func (c *Client) do(r *http.Request, out Message, x *Exchange) (int, error) {
+	resp, err := c.httpClient().Do(r)
 	if err != nil {
-		return err
+		return 0, err
 	}
 	defer resp.Body.Close()
-	if resp.StatusCode != http.StatusOK {
+	rb, err := responseBody(resp, attachments(r.Context()))
+	if err != nil {
+		return resp.StatusCode, err
+	}
+	rh := responseHeader(r.Context())
+	// one-way operations get 202 Accepted, or 200 OK, without a message
+	oneWay := out == nil || resp.StatusCode == http.StatusAccepted
+	if resp.StatusCode != http.StatusOK && resp.StatusCode != http.StatusAccepted {
 		// read only the first Mb of the body in error case
-		limReader := io.LimitReader(resp.Body, 1024*1024)
+		limReader := io.LimitReader(rb, 1024*1024)
 		body, _ := ioutil.ReadAll(limReader)
-		return fmt.Errorf("%q: %q", resp.Status, body)
+		if x != nil {
+			x.Response = body
+		}
+		if c.Encryption != nil {
+			if plain, err := c.Encryption.decrypt(body); err == nil {
+				body = plain
+			}
+		}
+		if rh != nil {
+			rh.set(body)
+		}
+		if f := parseFault(body); f != nil {
+			return resp.StatusCode, f
+		}
+		return resp.StatusCode, fmt.Errorf("%q: %q", resp.Status, body)
+	}
+	raw, _ := out.(*RawEnvelope)
+	if x == nil && c.Encryption == nil && rh == nil && raw == nil && !oneWay {
+		return resp.StatusCode, xml.NewDecoder(rb).Decode(out)
+	}
+	body, err := ioutil.ReadAll(rb)
+	if err != nil {
+		return resp.StatusCode, err
+	}
+	if x != nil {
+		x.Response = body
+	}
+	if c.Encryption != nil {
+		body, err = c.Encryption.decrypt(body)
+		if err != nil {
+			return resp.StatusCode, err
+		}
+	}
+	if rh != nil {
+		rh.set(body)
+	}
+	if oneWay {
+		return resp.StatusCode, nil
+	}
+	if raw != nil {
+		*raw = body
+		return resp.StatusCode, nil
 	}
-	return xml.NewDecoder(resp.Body).Decode(out)
+	return resp.StatusCode, xml.Unmarshal(body, out)
 }
 
 // Envelope is a SOAP envelope.
@@ -1,11 +1,18 @@
 package soap
 
 import (
+	"context"
+	"encoding/xml"
+	"errors"
 	"io"
+	"io/ioutil"
 	"net/http"
+	"net/http/cookiejar"
 	"net/http/httptest"
 	"reflect"
+	"strings"
 	"testing"
+	"time"
 )
 
 func TestRoundTrip(t *testing.T) {
@@ -47,7 +54,7 @@ func TestRoundTrip(t *testing.T) {
 		},
 	}
 	for i, tc := range cases {
-		err := tc.C.RoundTrip(tc.In, tc.Out)
+		err := tc.C.RoundTrip(nil, tc.In, tc.Out)
 		if err != nil && !tc.Fail {
 			t.Errorf("test %d: %v", i, err)
 			continue
@@ -67,3 +74,307 @@ func TestRoundTrip(t *testing.T) {
 		}
 	}
 }
+
+// validatedMsg is a message whose Validate method fails if A is empty.
+type validatedMsg struct{ A string }
+
+func (m *validatedMsg) Validate() error {
+	if m.A == "" {
+		return errors.New("A is empty")
+	}
+	return nil
+}
+
+func TestValidateRequests(t *testing.T) {
+	calls := 0
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		io.Copy(w, r.Body)
+	}))
+	defer s.Close()
+	var out struct {
+		Body struct{ Message validatedMsg }
+	}
+	c := &Client{URL: s.URL, ValidateRequests: true}
+	err := c.RoundTrip(nil, &validatedMsg{}, &out)
+	if err == nil || err.Error() != "A is empty" {
+		t.Fatalf("invalid request: got error %v, want A is empty", err)
+	}
+	if calls != 0 {
+		t.Fatalf("invalid request sent to the server")
+	}
+	err = c.RoundTrip(nil, &validatedMsg{A: "a"}, &out)
+	if err != nil {
+		t.Fatal(err)
+	}
+	c.ValidateRequests = false
+	err = c.RoundTrip(nil, &validatedMsg{}, &out)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if calls != 2 {
+		t.Fatalf("%d requests sent, want 2", calls)
+	}
+}
+
+func TestWithHeader(t *testing.T) {
+	type blockT struct {
+		XMLName xml.Name `xml:"urn:test Session"`
+		ID      string   `xml:"id"`
+	}
+	var body []byte
+	echo := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ = ioutil.ReadAll(r.Body)
+		w.Write(body)
+	})
+	s := httptest.NewServer(echo)
+	defer s.Close()
+	c := &Client{URL: s.URL, Header: &AuthHeader{Username: "u", Password: "p"}}
+	ctx := WithHeader(context.Background(), &blockT{ID: "1"})
+	ctx = WithHeader(ctx, &blockT{ID: "2"})
+	err := c.RoundTrip(ctx, &struct{ A string }{"a"}, &struct{}{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var env struct {
+		Header struct {
+			Auth     *AuthHeader `xml:"AuthHeader"`
+			Sessions []blockT    `xml:"urn:test Session"`
+		}
+	}
+	if err = xml.Unmarshal(body, &env); err != nil {
+		t.Fatal(err)
+	}
+	if env.Header.Auth == nil {
+		t.Errorf("missing client header block in %s", body)
+	}
+	if n := len(env.Header.Sessions); n != 2 || env.Header.Sessions[1].ID != "2" {
+		t.Errorf("unexpected header blocks in %s", body)
+	}
+}
+
+func TestRoundTripContext(t *testing.T) {
+	done := make(chan struct{})
+	block := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-done
+	})
+	s := httptest.NewServer(block)
+	defer s.Close()
+	defer close(done)
+	c := &Client{URL: s.URL}
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	err := c.RoundTrip(ctx, &struct{}{}, &struct{}{})
+	if err == nil || !strings.Contains(err.Error(), context.DeadlineExceeded.Error()) {
+		t.Fatalf("want %v, have %v", context.DeadlineExceeded, err)
+	}
+}
+
+// transportFunc is an http.RoundTripper of a function.
+type transportFunc func(*http.Request) (*http.Response, error)
+
+func (f transportFunc) RoundTrip(r *http.Request) (*http.Response, error) {
+	return f(r)
+}
+
+func TestTimeout(t *testing.T) {
+	done := make(chan struct{})
+	block := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case <-done:
+		case <-time.After(50 * time.Millisecond):
+		}
+		io.Copy(w, r.Body)
+	})
+	s := httptest.NewServer(block)
+	defer s.Close()
+	defer close(done)
+	cases := []struct {
+		Timeout time.Duration
+		Ctx     context.Context
+		Err     bool
+	}{
+		{Timeout: 10 * time.Millisecond, Ctx: context.Background(), Err: true},
+		{Timeout: 10 * time.Millisecond, Ctx: WithTimeout(context.Background(), time.Minute)},
+		{Timeout: 10 * time.Millisecond, Ctx: WithTimeout(context.Background(), 0)},
+		{Ctx: WithTimeout(context.Background(), 10*time.Millisecond), Err: true},
+		{Ctx: context.Background()},
+	}
+	for i, tc := range cases {
+		c := &Client{URL: s.URL, Timeout: tc.Timeout}
+		err := c.RoundTrip(tc.Ctx, &struct{}{}, &struct{}{})
+		if (err != nil) != tc.Err {
+			t.Errorf("test %d: want error %v, have %v", i, tc.Err, err)
+		}
+		err = c.RoundTripHTTP(tc.Ctx, "GET", "", nil, nil)
+		if (err != nil) != tc.Err {
+			t.Errorf("test %d: want HTTP error %v, have %v", i, tc.Err, err)
+		}
+	}
+}
+
+func TestTransport(t *testing.T) {
+	echo := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		io.Copy(w, r.Body)
+	})
+	s := httptest.NewServer(echo)
+	defer s.Close()
+	var n int
+	tr := transportFunc(func(r *http.Request) (*http.Response, error) {
+		n++
+		return http.DefaultTransport.RoundTrip(r)
+	})
+	config := &http.Client{Timeout: time.Minute}
+	for _, c := range []*Client{
+		{URL: s.URL, Transport: tr},
+		{URL: s.URL, Transport: tr, Config: config},
+		{URL: s.URL, Config: &http.Client{Transport: tr}},
+	} {
+		err := c.RoundTrip(context.Background(), &struct{}{}, &struct{}{})
+		if err != nil {
+			t.Fatal(err)
+		}
+		err = c.RoundTripHTTP(context.Background(), "GET", "", nil, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	if n != 6 {
+		t.Errorf("want 6 requests through the transport, have %d", n)
+	}
+	if config.Transport != nil {
+		t.Errorf("transport of Config changed")
+	}
+}
+
+func TestSOAPAction(t *testing.T) {
+	var header http.Header
+	echo := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		header = r.Header
+		io.Copy(w, r.Body)
+	})
+	s := httptest.NewServer(echo)
+	defer s.Close()
+	cases := []struct {
+		Ctx         context.Context
+		ContentType string
+		Action      []string
+		WantType    string
+	}{
+		{WithSOAPAction(context.Background(), "urn:a"), "", []string{`"urn:a"`}, "text/xml"},
+		{context.WithValue(context.Background(), "SOAPAction", "urn:b"), "", []string{`"urn:b"`}, "text/xml"},
+		{nil, "", []string{`""`}, "text/xml"},
+		{
+			WithSOAPAction(context.Background(), "urn:c"),
+			"application/soap+xml; charset=utf-8",
+			nil,
+			`application/soap+xml; charset=utf-8; action="urn:c"`,
+		},
+	}
+	for i, tc := range cases {
+		c := &Client{URL: s.URL, ContentType: tc.ContentType}
+		err := c.RoundTrip(tc.Ctx, &struct{ A string }{"a"}, &struct{}{})
+		if err != nil {
+			t.Fatalf("test %d: %v", i, err)
+		}
+		if have := header["Soapaction"]; !reflect.DeepEqual(have, tc.Action) {
+			t.Errorf("test %d: want SOAPAction %q, have %q", i, tc.Action, have)
+		}
+		if have := header.Get("Content-Type"); have != tc.WantType {
+			t.Errorf("test %d: want Content-Type %q, have %q", i, tc.WantType, have)
+		}
+	}
+}
+
+func TestJar(t *testing.T) {
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("SOAPAction") == `"login"` {
+			http.SetCookie(w, &http.Cookie{Name: "session", Value: "s1"})
+		} else if c, err := r.Cookie("session"); err != nil || c.Value != "s1" {
+			http.Error(w, "no session", http.StatusForbidden)
+			return
+		}
+		io.Copy(w, r.Body)
+	}))
+	defer s.Close()
+
+	c := &Client{URL: s.URL}
+	if c.Cookies() != nil {
+		t.Fatal("unexpected cookies without a jar")
+	}
+	c.Jar, _ = cookiejar.New(nil)
+	err := c.RoundTrip(WithSOAPAction(context.Background(), "login"), &struct{}{}, &struct{}{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cs := c.Cookies(); len(cs) != 1 || cs[0].Name != "session" || cs[0].Value != "s1" {
+		t.Fatalf("unexpected cookies %v", cs)
+	}
+	err = c.RoundTrip(context.Background(), &struct{}{}, &struct{}{})
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestWithEnvelope(t *testing.T) {
+	var ct string
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ct = r.Header.Get("Content-Type")
+		body, _ := ioutil.ReadAll(r.Body)
+		if !strings.Contains(string(body), Envelope12Namespace) || r.Header.Get("SOAPAction") != "" {
+			http.Error(w, "not a SOAP 1.2 request", http.StatusBadRequest)
+			return
+		}
+		w.Header().Set("Content-Type", "application/soap+xml; charset=utf-8")
+		w.WriteHeader(http.StatusInternalServerError)
+		io.WriteString(w, `<env:Envelope xmlns:env="http://www.w3.org/2003/05/soap-envelope"><env:Body><env:Fault>`+
+			`<env:Code><env:Value>env:Sender</env:Value></env:Code>`+
+			`<env:Reason><env:Text xml:lang="en">bad symbol</env:Text></env:Reason>`+
+			`</env:Fault></env:Body></env:Envelope>`)
+	}))
+	defer s.Close()
+
+	c := &Client{URL: s.URL}
+	ctx := WithEnvelope(WithSOAPAction(context.Background(), "urn:GetQuote"), Envelope12Namespace)
+	err := c.RoundTrip(ctx, &struct{}{}, &struct{}{})
+	f, ok := err.(*Fault)
+	if !ok {
+		t.Fatalf("want fault, have %v", err)
+	}
+	if f.Code != FaultClient || f.String != "bad symbol" {
+		t.Errorf("unexpected fault %+v", f)
+	}
+	if want := `application/soap+xml; charset=utf-8; action="urn:GetQuote"`; ct != want {
+		t.Errorf("want content type %q, have %q", want, ct)
+	}
+}
+
+func TestOneWay(t *testing.T) {
+	var calls int
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		io.Copy(ioutil.Discard, r.Body)
+		if r.URL.Query().Get("ok") != "" {
+			io.WriteString(w, "not a message")
+			return
+		}
+		w.WriteHeader(http.StatusAccepted)
+	}))
+	defer s.Close()
+
+	c := &Client{URL: s.URL}
+	if err := c.RoundTrip(context.Background(), &struct{}{}, nil); err != nil {
+		t.Fatal(err)
+	}
+	var out struct{ Body struct{ A string } }
+	if err := c.RoundTrip(context.Background(), &struct{}{}, &out); err != nil {
+		t.Fatalf("unexpected error of 202 Accepted: %v", err)
+	}
+	c.URL = s.URL + "?ok=1"
+	if err := c.RoundTrip(context.Background(), &struct{}{}, nil); err != nil {
+		t.Fatalf("unexpected error of 200 OK: %v", err)
+	}
+	if calls != 3 {
+		t.Errorf("want 3 calls, have %d", calls)
+	}
+}
@@ -0,0 +1,154 @@
+package soap
+
+import (
+	"bytes"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/http/httputil"
+	"strings"
+	"sync"
+)
+
+// Dump writes the HTTP requests and responses of a Client, with their
+// headers and envelopes, for debugging interoperability problems with
+// servers. Dumps include credentials sent in headers, such as those of
+// Auth and Token.
+type Dump struct {
+	Writer io.Writer
+	Pretty bool // Optional indentation of XML bodies
+
+	mu sync.Mutex
+}
+
+// DumpTo returns a Dump writing to w, to be set as the Dump of a
+// Client.
+func DumpTo(w io.Writer) *Dump {
+	return &Dump{Writer: w}
+}
+
+// Transport returns an HTTP transport dumping the requests of next, or
+// of http.DefaultTransport if nil, and their responses.
+func (d *Dump) Transport(next http.RoundTripper) http.RoundTripper {
+	if next == nil {
+		next = http.DefaultTransport
+	}
+	return &dumpTransport{dump: d, next: next}
+}
+
+// dumpTransport is the transport of a Dump.
+type dumpTransport struct {
+	dump *Dump
+	next http.RoundTripper
+}
+
+// RoundTrip implements the http.RoundTripper interface.
+func (t *dumpTransport) RoundTrip(r *http.Request) (*http.Response, error) {
+	var body []byte
+	if r.Body != nil {
+		var err error
+		body, err = ioutil.ReadAll(r.Body)
+		r.Body.Close()
+		if err != nil {
+			return nil, err
+		}
+		r = r.Clone(r.Context())
+	}
+	head, err := httputil.DumpRequestOut(r, false)
+	if err != nil {
+		return nil, err
+	}
+	if r.Body != nil {
+		r.Body = ioutil.NopCloser(bytes.NewReader(body))
+	}
+	t.dump.write("-->", head, body)
+	resp, err := t.next.RoundTrip(r)
+	if err != nil {
+		t.dump.write("<--", []byte(err.Error()+"\r\n"), nil)
+		return nil, err
+	}
+	body, err = ioutil.ReadAll(resp.Body)
+	resp.Body.Close()
+	if err != nil {
+		t.dump.write("<--", []byte(err.Error()+"\r\n"), nil)
+		return nil, err
+	}
+	resp.Body = ioutil.NopCloser(bytes.NewReader(body))
+	head, _ = httputil.DumpResponse(resp, false)
+	t.dump.write("<--", head, body)
+	return resp, nil
+}
+
+// write writes the dump of a request or response, given by direction,
+// of the given head and body, at once.
+func (d *Dump) write(direction string, head, body []byte) {
+	if d.Pretty {
+		body = indentXML(body)
+	}
+	var b bytes.Buffer
+	b.WriteString(direction + " ")
+	b.WriteString(strings.TrimRight(string(head), "\r\n"))
+	b.WriteString("\n\n")
+	if len(body) > 0 {
+		b.Write(body)
+		b.WriteString("\n\n")
+	}
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.Writer.Write(b.Bytes())
+}
+
+// indentXML returns the XML document doc indented, with its elements
+// and their namespace prefixes as is, or doc itself if it is not XML.
+func indentXML(doc []byte) []byte {
+	d := xml.NewDecoder(bytes.NewReader(doc))
+	var b bytes.Buffer
+	depth := 0
+	inline := false // the last element written is still open, or has text
+	newline := func(depth int) {
+		if b.Len() > 0 {
+			fmt.Fprintf(&b, "\n%s", strings.Repeat("  ", depth))
+		}
+	}
+	for {
+		off := d.InputOffset()
+		t, err := d.RawToken()
+		if err == io.EOF {
+			return b.Bytes()
+		}
+		if err != nil {
+			return doc
+		}
+		raw := doc[off:d.InputOffset()]
+		switch t.(type) {
+		case xml.StartElement:
+			newline(depth)
+			b.Write(raw)
+			depth++
+			inline = true
+		case xml.EndElement:
+			depth--
+			if len(raw) == 0 {
+				// end of an empty element, written with its start
+				inline = false
+				continue
+			}
+			if !inline {
+				newline(depth)
+			}
+			b.Write(raw)
+			inline = false
+		case xml.CharData:
+			if len(bytes.TrimSpace(raw)) == 0 {
+				continue
+			}
+			b.Write(raw)
+		default:
+			newline(depth)
+			b.Write(raw)
+			inline = false
+		}
+	}
+}
@@ -0,0 +1,82 @@
+package soap
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestDump(t *testing.T) {
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("X-Server", "test")
+		io.Copy(w, r.Body)
+	}))
+	defer s.Close()
+
+	var b bytes.Buffer
+	c := &Client{URL: s.URL, Dump: DumpTo(&b)}
+	in := &struct{ A string }{"a"}
+	err := c.RoundTrip(WithSOAPAction(context.Background(), "urn:A"), in, &struct{}{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	dump := b.String()
+	for _, want := range []string{"--> POST / HTTP/1.1\r\n", `Soapaction: "urn:A"`, "<A>a</A>",
+		"<-- HTTP/1.1 200 OK\r\n", "X-Server: test"} {
+		if !strings.Contains(dump, want) {
+			t.Errorf("missing %q in dump %s", want, dump)
+		}
+	}
+}
+
+// failingBody is a response body failing to be read.
+type failingBody struct{ closed bool }
+
+func (b *failingBody) Read([]byte) (int, error) { return 0, errors.New("connection reset") }
+func (b *failingBody) Close() error             { b.closed = true; return nil }
+
+func TestDumpReadError(t *testing.T) {
+	body := &failingBody{}
+	next := transportFunc(func(r *http.Request) (*http.Response, error) {
+		return &http.Response{StatusCode: 200, Proto: "HTTP/1.1", ProtoMajor: 1, ProtoMinor: 1,
+			Header: http.Header{}, Body: body, Request: r}, nil
+	})
+	var b bytes.Buffer
+	r, _ := http.NewRequest("POST", "http://example.com/", strings.NewReader("<A/>"))
+	resp, err := DumpTo(&b).Transport(next).RoundTrip(r)
+	if resp != nil || err == nil {
+		t.Fatalf("want no response and an error, have %v, %v", resp, err)
+	}
+	if !body.closed {
+		t.Error("response body not closed")
+	}
+	if !strings.Contains(b.String(), "<-- connection reset") {
+		t.Errorf("missing error in dump %s", b.String())
+	}
+}
+
+func TestIndentXML(t *testing.T) {
+	doc := `<?xml version="1.0"?><s:Envelope xmlns:s="urn:s"><s:Body><a x="1">text</a><b/>` +
+		`<c><d>1</d></c></s:Body></s:Envelope>`
+	want := `<?xml version="1.0"?>
+<s:Envelope xmlns:s="urn:s">
+  <s:Body>
+    <a x="1">text</a>
+    <b/>
+    <c>
+      <d>1</d>
+    </c>
+  </s:Body>
+</s:Envelope>`
+	if have := string(indentXML([]byte(doc))); have != want {
+		t.Errorf("want\n%s\nhave\n%s", want, have)
+	}
+	if have := string(indentXML([]byte("not <xml"))); have != "not <xml" {
+		t.Errorf("unexpected indentation of text: %s", have)
+	}
+}
@@ -0,0 +1,196 @@
+package soap
+
+import (
+	"encoding/xml"
+	"reflect"
+	"strconv"
+)
+
+// Namespaces of the SOAP encoding, of XML Schema and of XML Schema
+// instances, as used by messages of operations of the rpc style and
+// encoded use.
+const (
+	EncodingNamespace = "http://schemas.xmlsoap.org/soap/encoding/"
+	XSDNamespace      = "http://www.w3.org/2001/XMLSchema"
+	XSINamespace      = "http://www.w3.org/2001/XMLSchema-instance"
+)
+
+// RPCEncoded is embedded in the request messages of operations of the
+// rpc style and encoded use. It binds the namespace of the operation
+// to the rpc prefix, and declares the encoding style of the message
+// and the namespaces used by its Encoded values.
+type RPCEncoded struct {
+	Namespace     string `xml:"xmlns:rpc,attr,omitempty"`
+	EncodingStyle string `xml:"SOAP-ENV:encodingStyle,attr"`
+	Enc           string `xml:"xmlns:SOAP-ENC,attr"`
+	XSD           string `xml:"xmlns:xsd,attr"`
+	XSI           string `xml:"xmlns:xsi,attr"`
+}
+
+// RPCLiteral is embedded in the request messages of operations of the
+// rpc style and literal use. It binds the namespace of the operation to
+// the rpc prefix.
+type RPCLiteral struct {
+	Namespace string `xml:"xmlns:rpc,attr,omitempty"`
+}
+
+// NewRPCEncoded returns the RPCEncoded of operations of the given
+// namespace and encoding style. The encoding style defaults to the
+// SOAP encoding.
+func NewRPCEncoded(namespace, encodingStyle string) RPCEncoded {
+	if encodingStyle == "" {
+		encodingStyle = EncodingNamespace
+	}
+	return RPCEncoded{
+		Namespace:     namespace,
+		EncodingStyle: encodingStyle,
+		Enc:           EncodingNamespace,
+		XSD:           XSDNamespace,
+		XSI:           XSINamespace,
+	}
+}
+
+// Encoded is a part of a message of the encoded use: its value is
+// encoded with an xsi:type attribute of the given Type.
+//
+// When ItemType is set, the value must be a slice, or a pointer to a
+// slice, and is encoded as a SOAP array of items of that type.
+type Encoded struct {
+	Type     xml.Name
+	ItemType xml.Name
+	Value    interface{}
+}
+
+// MarshalXML implements the xml.Marshaler interface.
+func (v Encoded) MarshalXML(e *xml.Encoder, start xml.StartElement) error {
+	rv := reflect.ValueOf(v.Value)
+	for rv.Kind() == reflect.Ptr || rv.Kind() == reflect.Interface {
+		if rv.IsNil() {
+			return nil
+		}
+		rv = rv.Elem()
+	}
+	if !rv.IsValid() {
+		return nil
+	}
+	if v.ItemType.Local == "" {
+		start.Attr = append(start.Attr, typeAttrs("xsi:type", v.Type)...)
+		return e.EncodeElement(v.Value, start)
+	}
+	start.Attr = append(start.Attr, xml.Attr{
+		Name:  xml.Name{Local: "xsi:type"},
+		Value: "SOAP-ENC:Array",
+	})
+	itemType := v.ItemType
+	itemType.Local += "[" + strconv.Itoa(rv.Len()) + "]"
+	start.Attr = append(start.Attr, typeAttrs("SOAP-ENC:arrayType", itemType)...)
+	err := e.EncodeToken(start)
+	if err != nil {
+		return err
+	}
+	item := xml.StartElement{Name: xml.Name{Local: "item"}}
+	for i := 0; i < rv.Len(); i++ {
+		err = e.EncodeElement(rv.Index(i).Interface(), item)
+		if err != nil {
+			return err
+		}
+	}
+	return e.EncodeToken(start.End())
+}
+
+// typeAttrs returns the attribute name referring to the given type,
+// preceded by the declaration of the namespace of the type if it is
+// neither XML Schema nor the SOAP encoding.
+func typeAttrs(name string, typ xml.Name) []xml.Attr {
+	switch typ.Space {
+	case "":
+		return []xml.Attr{{Name: xml.Name{Local: name}, Value: typ.Local}}
+	case XSDNamespace:
+		return []xml.Attr{{Name: xml.Name{Local: name}, Value: "xsd:" + typ.Local}}
+	case EncodingNamespace:
+		return []xml.Attr{{Name: xml.Name{Local: name}, Value: "SOAP-ENC:" + typ.Local}}
+	}
+	return []xml.Attr{
+		{Name: xml.Name{Local: "xmlns:types"}, Value: typ.Space},
+		{Name: xml.Name{Local: name}, Value: "types:" + typ.Local},
+	}
+}
+
+// ForeignAttrs returns attrs, the attributes captured by a field tagged
+// ",any,attr", without the namespace declarations encoding/xml decodes
+// along with them. Encoded as attributes, these would declare a second
+// default namespace, or the prefixes of the namespace "xmlns".
+func ForeignAttrs(attrs []xml.Attr) []xml.Attr {
+	var v []xml.Attr
+	for _, a := range attrs {
+		if a.Name.Space == "xmlns" || a.Name.Space == "" && a.Name.Local == "xmlns" {
+			continue
+		}
+		v = append(v, a)
+	}
+	return v
+}
+
+// nilElement is the content of a nil nillable element.
+type nilElement struct {
+	XSI string `xml:"xmlns:xsi,attr"`
+	Nil string `xml:"xsi:nil,attr"`
+}
+
+var nilElementType = reflect.TypeOf(nilElement{})
+
+// MarshalNillable encodes the struct v as the element start, like
+// e.EncodeElement, except that its nil pointer fields of the given
+// names are encoded as empty elements with xsi:nil="true" instead of
+// being omitted. It is meant to implement the MarshalXML method of v:
+// the methods of v are not used to encode it.
+func MarshalNillable(e *xml.Encoder, start xml.StartElement, v interface{}, fields ...string) error {
+	rv := reflect.ValueOf(v)
+	for rv.Kind() == reflect.Ptr {
+		if rv.IsNil() {
+			return nil
+		}
+		rv = rv.Elem()
+	}
+	if rv.Kind() != reflect.Struct {
+		return e.EncodeElement(v, start)
+	}
+	nils := make(map[string]bool)
+	for _, name := range fields {
+		f := rv.FieldByName(name)
+		if f.IsValid() && f.Kind() == reflect.Ptr && f.IsNil() {
+			nils[name] = true
+		}
+	}
+	// copy v to a struct of the same exported fields, but no methods,
+	// where those that are nil are replaced by nil elements
+	rt := rv.Type()
+	var sf []reflect.StructField
+	var index []int
+	for i := 0; i < rt.NumField(); i++ {
+		f := rt.Field(i)
+		if f.PkgPath != "" {
+			continue
+		}
+		if nils[f.Name] {
+			f.Type = nilElementType
+		}
+		sf = append(sf, f)
+		index = append(index, i)
+	}
+	nv := reflect.New(reflect.StructOf(sf)).Elem()
+	for i, j := range index {
+		if nils[sf[i].Name] {
+			nv.Field(i).Set(reflect.ValueOf(nilElement{XSINamespace, "true"}))
+			continue
+		}
+		nv.Field(i).Set(rv.Field(j))
+	}
+	// encoding v itself, rather than one of its fields, starts an
+	// element named after its type: prefer the name of its XMLName
+	if f, ok := rt.FieldByName("XMLName"); ok && f.Tag.Get("xml") != "" &&
+		start.Name.Space == "" && start.Name.Local == rt.Name() {
+		return e.Encode(nv.Interface())
+	}
+	return e.EncodeElement(nv.Interface(), start)
+}
@@ -0,0 +1,86 @@
+package soap
+
+import (
+	"encoding/xml"
+	"testing"
+)
+
+func TestEncoded(t *testing.T) {
+	type stockT struct {
+		XMLName xml.Name `xml:"urn:stock Stock"`
+		SKU     string   `xml:"sku"`
+	}
+	skus := []string{"a", "b"}
+	msg := struct {
+		XMLName xml.Name `xml:"rpc:Count"`
+		RPCEncoded
+		Skus  Encoded `xml:"skus"`
+		Stock Encoded `xml:"stock"`
+		Count Encoded `xml:"count"`
+		None  Encoded `xml:"none"`
+	}{
+		RPCEncoded: NewRPCEncoded("urn:inventory", ""),
+		Skus: Encoded{
+			ItemType: xml.Name{Space: XSDNamespace, Local: "string"},
+			Value:    &skus,
+		},
+		Stock: Encoded{Type: xml.Name{Space: "urn:stock", Local: "Stock"}, Value: &stockT{SKU: "a"}},
+		Count: Encoded{Type: xml.Name{Space: XSDNamespace, Local: "int"}, Value: 2},
+		None:  Encoded{Type: xml.Name{Space: "urn:stock", Local: "Stock"}, Value: (*stockT)(nil)},
+	}
+	b, err := xml.Marshal(msg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := `<rpc:Count xmlns:rpc="urn:inventory" SOAP-ENV:encodingStyle="` + EncodingNamespace + `"` +
+		` xmlns:SOAP-ENC="` + EncodingNamespace + `" xmlns:xsd="` + XSDNamespace + `" xmlns:xsi="` + XSINamespace + `">` +
+		`<skus xsi:type="SOAP-ENC:Array" SOAP-ENC:arrayType="xsd:string[2]"><item>a</item><item>b</item></skus>` +
+		`<stock xmlns:types="urn:stock" xsi:type="types:Stock"><sku>a</sku></stock>` +
+		`<count xsi:type="xsd:int">2</count>` +
+		`</rpc:Count>`
+	if have := string(b); have != want {
+		t.Errorf("unexpected encoding\nwant: %s\nhave: %s", want, have)
+	}
+}
+
+type nillableT struct {
+	XMLName xml.Name `xml:"urn:shop Item"`
+	Name    *string  `xml:"name,omitempty"`
+	Price   *int     `xml:"price,omitempty"`
+	Note    *string  `xml:"note,omitempty"`
+	SKU     string   `xml:"sku,attr"`
+}
+
+func (v nillableT) MarshalXML(e *xml.Encoder, start xml.StartElement) error {
+	return MarshalNillable(e, start, v, "Name", "Price")
+}
+
+func TestMarshalNillable(t *testing.T) {
+	price := 3
+	b, err := xml.Marshal(nillableT{Price: &price, SKU: "a"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := `<Item xmlns="urn:shop" sku="a">` +
+		`<name xmlns:xsi="` + XSINamespace + `" xsi:nil="true"></name>` +
+		`<price>3</price>` +
+		`</Item>`
+	if string(b) != want {
+		t.Errorf("unexpected xml:\nwant %s\nhave %s", want, b)
+	}
+	name := "b"
+	b, err = xml.Marshal(struct {
+		XMLName xml.Name    `xml:"urn:shop Order"`
+		Items   []nillableT `xml:"urn:shop Item"`
+	}{Items: []nillableT{{Name: &name, SKU: "b"}}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want = `<Order xmlns="urn:shop"><Item xmlns="urn:shop" sku="b">` +
+		`<name>b</name>` +
+		`<price xmlns:xsi="` + XSINamespace + `" xsi:nil="true"></price>` +
+		`</Item></Order>`
+	if string(b) != want {
+		t.Errorf("unexpected xml:\nwant %s\nhave %s", want, b)
+	}
+}
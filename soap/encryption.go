@@ -0,0 +1,444 @@
+package soap
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha1"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"hash"
+	"strings"
+)
+
+// Namespaces of XML Encryption, its 1.1 algorithms, and XML Signature.
+const (
+	XMLEncNamespace   = "http://www.w3.org/2001/04/xmlenc#"
+	XMLEnc11Namespace = "http://www.w3.org/2009/xmlenc11#"
+	XMLDSigNamespace  = "http://www.w3.org/2000/09/xmldsig#"
+)
+
+// Algorithms of the encryption of bodies and of their keys. Keys
+// encrypted by RSAPKCS1v15 are not decrypted, as its padding is open to
+// oracle attacks.
+const (
+	AES128CBC   = XMLEncNamespace + "aes128-cbc"
+	AES256CBC   = XMLEncNamespace + "aes256-cbc"
+	AES128GCM   = XMLEnc11Namespace + "aes128-gcm"
+	AES256GCM   = XMLEnc11Namespace + "aes256-gcm"
+	RSAOAEP     = XMLEncNamespace + "rsa-oaep-mgf1p"
+	RSAPKCS1v15 = XMLEncNamespace + "rsa-1_5"
+)
+
+// ErrDecryption is the error of encrypted data of responses, or of
+// their keys, that fail to decrypt, whatever the cause, such as an
+// unsupported algorithm, a missing key or tampered data, so that errors
+// tell attackers nothing of the failures.
+var ErrDecryption = errors.New("soap: decryption failed")
+
+// Encryption configures the XML Encryption of the bodies of the requests
+// of a Client, as by WS-Security: the content of each body is encrypted
+// with a new key, itself encrypted with the public key of the server
+// and sent in the WS-Security header block of the request. Encrypted
+// data of responses, those of the body and of faults, is decrypted with
+// the private key of the client, if any.
+type Encryption struct {
+	// Certificate is the certificate of the server, whose RSA public
+	// key encrypts the keys of requests. Requests are not encrypted
+	// without one.
+	Certificate *x509.Certificate
+
+	// Key is the RSA private key of the client, which decrypts the keys
+	// of encrypted responses.
+	Key *rsa.PrivateKey
+
+	// Algorithm is the algorithm of the encryption of bodies: AES128CBC,
+	// AES256CBC, AES128GCM or AES256GCM (default).
+	Algorithm string
+
+	// AllowCBC, if set, lets data of responses encrypted by AES-CBC be
+	// decrypted, such as those of servers that support no other
+	// algorithm. AES-CBC has no integrity check, so that tampered data
+	// is only rejected by chance: only AES-GCM is decrypted otherwise.
+	AllowCBC bool
+}
+
+// encryptedKey is an xenc:EncryptedKey.
+type encryptedKey struct {
+	XMLName    xml.Name          `xml:"http://www.w3.org/2001/04/xmlenc# EncryptedKey"`
+	ID         string            `xml:"Id,attr,omitempty"`
+	Method     encryptionMethod  `xml:"http://www.w3.org/2001/04/xmlenc# EncryptionMethod"`
+	KeyInfo    *keyInfo          `xml:"http://www.w3.org/2000/09/xmldsig# KeyInfo,omitempty"`
+	CipherData cipherData        `xml:"http://www.w3.org/2001/04/xmlenc# CipherData"`
+	References *dataReferenceSet `xml:"http://www.w3.org/2001/04/xmlenc# ReferenceList,omitempty"`
+}
+
+// encryptedData is an xenc:EncryptedData.
+type encryptedData struct {
+	XMLName    xml.Name         `xml:"http://www.w3.org/2001/04/xmlenc# EncryptedData"`
+	ID         string           `xml:"Id,attr,omitempty"`
+	Type       string           `xml:"Type,attr,omitempty"`
+	Method     encryptionMethod `xml:"http://www.w3.org/2001/04/xmlenc# EncryptionMethod"`
+	KeyInfo    *keyInfo         `xml:"http://www.w3.org/2000/09/xmldsig# KeyInfo,omitempty"`
+	CipherData cipherData       `xml:"http://www.w3.org/2001/04/xmlenc# CipherData"`
+}
+
+type encryptionMethod struct {
+	Algorithm string        `xml:"Algorithm,attr"`
+	Digest    *digestMethod `xml:"http://www.w3.org/2000/09/xmldsig# DigestMethod,omitempty"`
+}
+
+type digestMethod struct {
+	Algorithm string `xml:"Algorithm,attr"`
+}
+
+type cipherData struct {
+	Value string `xml:"http://www.w3.org/2001/04/xmlenc# CipherValue"`
+}
+
+type dataReferenceSet struct {
+	References []dataReference `xml:"http://www.w3.org/2001/04/xmlenc# DataReference"`
+}
+
+type dataReference struct {
+	URI string `xml:"URI,attr"`
+}
+
+// keyInfo is a ds:KeyInfo, identifying the certificate of an encrypted
+// key by its issuer and serial number, or holding the encrypted key of
+// encrypted data.
+type keyInfo struct {
+	Reference *tokenReference `xml:"http://docs.oasis-open.org/wss/2004/01/oasis-200401-wss-wssecurity-secext-1.0.xsd SecurityTokenReference,omitempty"`
+	Key       *encryptedKey   `xml:"http://www.w3.org/2001/04/xmlenc# EncryptedKey,omitempty"`
+}
+
+type tokenReference struct {
+	IssuerName   string `xml:"http://www.w3.org/2000/09/xmldsig# X509Data>X509IssuerSerial>X509IssuerName"`
+	SerialNumber string `xml:"http://www.w3.org/2000/09/xmldsig# X509Data>X509IssuerSerial>X509SerialNumber"`
+}
+
+// encryptedContent is the type of encrypted data of element content.
+const encryptedContent = XMLEncNamespace + "Content"
+
+// encrypt returns the SOAP envelope doc, of the given envelope
+// namespace, whose body content is encrypted, and whose WS-Security
+// header block, added if missing, has the encrypted key. It returns doc
+// itself if e has no certificate.
+func (e *Encryption) encrypt(doc []byte, envelope string) ([]byte, error) {
+	if e.Certificate == nil {
+		return doc, nil
+	}
+	pub, ok := e.Certificate.PublicKey.(*rsa.PublicKey)
+	if !ok {
+		return nil, errors.New("soap: encryption requires a certificate of an RSA key")
+	}
+	s, err := scanEnvelope(doc, envelope)
+	if err != nil {
+		return nil, err
+	}
+	if s.body[0] < 0 {
+		return nil, errors.New("soap: no body to encrypt")
+	}
+	alg := e.Algorithm
+	if alg == "" {
+		alg = AES256GCM
+	}
+	key := make([]byte, keySize(alg))
+	if len(key) == 0 {
+		return nil, fmt.Errorf("soap: unsupported encryption algorithm %q", alg)
+	}
+	if _, err = rand.Read(key); err != nil {
+		return nil, err
+	}
+	value, err := encryptData(alg, key, doc[s.body[0]:s.body[1]])
+	if err != nil {
+		return nil, err
+	}
+	wrapped, err := rsa.EncryptOAEP(sha1.New(), rand.Reader, pub, key, nil)
+	if err != nil {
+		return nil, err
+	}
+	id := newID("ED-")
+	data, err := xml.Marshal(&encryptedData{
+		ID:         id,
+		Type:       encryptedContent,
+		Method:     encryptionMethod{Algorithm: alg},
+		CipherData: cipherData{base64.StdEncoding.EncodeToString(value)},
+	})
+	if err != nil {
+		return nil, err
+	}
+	ek, err := xml.Marshal(&encryptedKey{
+		ID:     newID("EK-"),
+		Method: encryptionMethod{Algorithm: RSAOAEP, Digest: &digestMethod{XMLDSigNamespace + "sha1"}},
+		KeyInfo: &keyInfo{Reference: &tokenReference{
+			IssuerName:   e.Certificate.Issuer.String(),
+			SerialNumber: e.Certificate.SerialNumber.String(),
+		}},
+		CipherData: cipherData{base64.StdEncoding.EncodeToString(wrapped)},
+		References: &dataReferenceSet{[]dataReference{{"#" + id}}},
+	})
+	if err != nil {
+		return nil, err
+	}
+	// Splice the body before the header, which precedes it.
+	var b bytes.Buffer
+	switch {
+	case s.security >= 0:
+		b.Write(doc[:s.security])
+		b.Write(ek)
+		b.Write(doc[s.security:s.body[0]])
+	case s.header >= 0:
+		b.Write(doc[:s.header])
+		fmt.Fprintf(&b, `<Security xmlns="%s">%s</Security>`, SecurityNamespace, ek)
+		b.Write(doc[s.header:s.body[0]])
+	default:
+		b.Write(doc[:s.bodyStart])
+		fmt.Fprintf(&b, `<Header xmlns="%s"><Security xmlns="%s">%s</Security></Header>`, envelope, SecurityNamespace, ek)
+		b.Write(doc[s.bodyStart:s.body[0]])
+	}
+	b.Write(data)
+	b.Write(doc[s.body[1]:])
+	return b.Bytes(), nil
+}
+
+// envelopeOffsets are the offsets of the parts of a SOAP envelope that
+// encrypt changes, or -1 if missing.
+type envelopeOffsets struct {
+	header    int64    // start of the content of the Header element
+	security  int64    // end of the content of the WS-Security header block
+	bodyStart int64    // start of the Body element
+	body      [2]int64 // content of the Body element
+}
+
+// scanEnvelope returns the offsets of the SOAP envelope doc of the
+// given envelope namespace.
+func scanEnvelope(doc []byte, envelope string) (*envelopeOffsets, error) {
+	s := &envelopeOffsets{header: -1, security: -1, bodyStart: -1, body: [2]int64{-1, -1}}
+	var path []xml.Name
+	d := xml.NewDecoder(bytes.NewReader(doc))
+	for {
+		off := d.InputOffset()
+		t, err := d.Token()
+		if err != nil {
+			if s.body[1] < 0 {
+				return nil, fmt.Errorf("soap: no envelope: %v", err)
+			}
+			return s, nil
+		}
+		switch t := t.(type) {
+		case xml.StartElement:
+			path = append(path, t.Name)
+			switch {
+			case len(path) == 2 && t.Name == xml.Name{Space: envelope, Local: "Header"}:
+				s.header = d.InputOffset()
+			case len(path) == 2 && t.Name == xml.Name{Space: envelope, Local: "Body"}:
+				s.bodyStart, s.body[0] = off, d.InputOffset()
+			}
+		case xml.EndElement:
+			switch {
+			case len(path) == 3 && s.security < 0 && path[1].Local == "Header" &&
+				t.Name == xml.Name{Space: SecurityNamespace, Local: "Security"}:
+				s.security = off
+			case len(path) == 2 && t.Name == xml.Name{Space: envelope, Local: "Body"}:
+				s.body[1] = off
+			}
+			path = path[:len(path)-1]
+		}
+	}
+}
+
+// decrypt returns the document doc whose encrypted data is replaced by
+// its decryption with the keys of its encrypted keys, or doc itself if
+// it has none or e has no key. Encrypted keys are those referring to
+// the data, or else those in its key info. Keys that the key of e does
+// not decrypt, such as those of other recipients, are ignored.
+func (e *Encryption) decrypt(doc []byte) ([]byte, error) {
+	if e.Key == nil || !bytes.Contains(doc, []byte("EncryptedData")) {
+		return doc, nil
+	}
+	type span struct {
+		start, end int64
+		data       *encryptedData
+	}
+	keys := make(map[string][]byte)
+	var spans []span
+	d := xml.NewDecoder(bytes.NewReader(doc))
+	for {
+		off := d.InputOffset()
+		t, err := d.Token()
+		if err != nil {
+			break
+		}
+		start, ok := t.(xml.StartElement)
+		if !ok || start.Name.Space != XMLEncNamespace {
+			continue
+		}
+		switch start.Name.Local {
+		case "EncryptedKey":
+			var ek encryptedKey
+			if err = d.DecodeElement(&ek, &start); err != nil {
+				return nil, ErrDecryption
+			}
+			key, err := e.decryptKey(&ek)
+			if err != nil {
+				continue
+			}
+			if ek.References != nil {
+				for _, r := range ek.References.References {
+					keys[strings.TrimPrefix(r.URI, "#")] = key
+				}
+			}
+		case "EncryptedData":
+			data := &encryptedData{}
+			if err = d.DecodeElement(data, &start); err != nil {
+				return nil, ErrDecryption
+			}
+			spans = append(spans, span{off, d.InputOffset(), data})
+		}
+	}
+	if len(spans) == 0 {
+		return doc, nil
+	}
+	var b bytes.Buffer
+	var last int64
+	for _, s := range spans {
+		key, ok := keys[s.data.ID]
+		if !ok && s.data.KeyInfo != nil && s.data.KeyInfo.Key != nil {
+			var err error
+			if key, err = e.decryptKey(s.data.KeyInfo.Key); err != nil {
+				return nil, err
+			}
+		}
+		if key == nil {
+			return nil, ErrDecryption
+		}
+		value, err := base64.StdEncoding.DecodeString(strings.TrimSpace(s.data.CipherData.Value))
+		if err != nil {
+			return nil, ErrDecryption
+		}
+		plain, err := decryptData(s.data.Method.Algorithm, key, value, e.AllowCBC)
+		if err != nil {
+			return nil, err
+		}
+		b.Write(doc[last:s.start])
+		b.Write(plain)
+		last = s.end
+	}
+	b.Write(doc[last:])
+	return b.Bytes(), nil
+}
+
+// decryptKey returns the key of ek, decrypted with the key of e, or
+// ErrDecryption.
+func (e *Encryption) decryptKey(ek *encryptedKey) ([]byte, error) {
+	if ek.Method.Algorithm != RSAOAEP && ek.Method.Algorithm != XMLEnc11Namespace+"rsa-oaep" {
+		return nil, ErrDecryption
+	}
+	value, err := base64.StdEncoding.DecodeString(strings.TrimSpace(ek.CipherData.Value))
+	if err != nil {
+		return nil, ErrDecryption
+	}
+	var h hash.Hash = sha1.New()
+	if ek.Method.Digest != nil && ek.Method.Digest.Algorithm == XMLEncNamespace+"sha256" {
+		h = sha256.New()
+	}
+	key, err := rsa.DecryptOAEP(h, rand.Reader, e.Key, value, nil)
+	if err != nil {
+		return nil, ErrDecryption
+	}
+	return key, nil
+}
+
+// keySize returns the size of the keys of the given algorithm of data
+// encryption, or 0 if not supported.
+func keySize(alg string) int {
+	switch alg {
+	case AES128CBC, AES128GCM:
+		return 16
+	case XMLEncNamespace + "aes192-cbc":
+		return 24
+	case AES256CBC, AES256GCM:
+		return 32
+	}
+	return 0
+}
+
+// encryptData returns the cipher value of plain encrypted with key by
+// the given algorithm: its IV followed by the cipher text.
+func encryptData(alg string, key, plain []byte) ([]byte, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	if strings.HasSuffix(alg, "-gcm") {
+		gcm, err := cipher.NewGCM(block)
+		if err != nil {
+			return nil, err
+		}
+		nonce := make([]byte, gcm.NonceSize())
+		if _, err = rand.Read(nonce); err != nil {
+			return nil, err
+		}
+		return gcm.Seal(nonce, nonce, plain, nil), nil
+	}
+	// XML Encryption pads to the block size with bytes whose last is
+	// the length of the padding.
+	n := aes.BlockSize - len(plain)%aes.BlockSize
+	padded := append(append([]byte{}, plain...), bytes.Repeat([]byte{byte(n)}, n)...)
+	value := make([]byte, aes.BlockSize+len(padded))
+	if _, err = rand.Read(value[:aes.BlockSize]); err != nil {
+		return nil, err
+	}
+	cipher.NewCBCEncrypter(block, value[:aes.BlockSize]).CryptBlocks(value[aes.BlockSize:], padded)
+	return value, nil
+}
+
+// decryptData returns the cipher value of encryptData decrypted with
+// key by the given algorithm, which may be AES-CBC only if allowCBC is
+// set, or ErrDecryption.
+func decryptData(alg string, key, value []byte, allowCBC bool) ([]byte, error) {
+	gcm := strings.HasSuffix(alg, "-gcm")
+	if n := keySize(alg); n == 0 || n != len(key) || !gcm && !allowCBC {
+		return nil, ErrDecryption
+	}
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, ErrDecryption
+	}
+	if gcm {
+		aead, err := cipher.NewGCM(block)
+		if err != nil || len(value) < aead.NonceSize() {
+			return nil, ErrDecryption
+		}
+		plain, err := aead.Open(nil, value[:aead.NonceSize()], value[aead.NonceSize():], nil)
+		if err != nil {
+			return nil, ErrDecryption
+		}
+		return plain, nil
+	}
+	if len(value) < 2*aes.BlockSize || len(value)%aes.BlockSize != 0 {
+		return nil, ErrDecryption
+	}
+	plain := make([]byte, len(value)-aes.BlockSize)
+	cipher.NewCBCDecrypter(block, value[:aes.BlockSize]).CryptBlocks(plain, value[aes.BlockSize:])
+	pad := int(plain[len(plain)-1])
+	if pad == 0 || pad > aes.BlockSize {
+		return nil, ErrDecryption
+	}
+	return plain[:len(plain)-pad], nil
+}
+
+// newID returns a random XML ID of the given prefix.
+func newID(prefix string) string {
+	b := make([]byte, 16)
+	rand.Read(b)
+	return prefix + hex.EncodeToString(b)
+}
@@ -0,0 +1,175 @@
+package soap
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/xml"
+	"io/ioutil"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+// newTestCertificate returns a new RSA key and a self-signed
+// certificate of it.
+func newTestCertificate(t *testing.T, name string) (*rsa.PrivateKey, *x509.Certificate) {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tmpl := &x509.Certificate{
+		SerialNumber: big.NewInt(42),
+		Subject:      pkix.Name{CommonName: name},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, tmpl, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cert, err := x509.ParseCertificate(der)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return key, cert
+}
+
+func TestEncryption(t *testing.T) {
+	serverKey, serverCert := newTestCertificate(t, "server")
+	clientKey, clientCert := newTestCertificate(t, "client")
+	type echoT struct {
+		XMLName xml.Name `xml:"urn:test Echo"`
+		Text    string   `xml:"urn:test text"`
+	}
+	var raw, plain []byte
+	srv := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		raw, _ = ioutil.ReadAll(r.Body)
+		var err error
+		plain, err = (&Encryption{Key: serverKey}).decrypt(raw)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		resp := strings.Replace(string(plain), "hello", "hello back", 1)
+		b, err := (&Encryption{Certificate: clientCert, Algorithm: AES128GCM}).encrypt([]byte(resp), EnvelopeNamespace)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		w.Write(b)
+	})
+	s := httptest.NewServer(srv)
+	defer s.Close()
+
+	c := &Client{
+		URL:           s.URL,
+		Encryption:    &Encryption{Certificate: serverCert, Key: clientKey},
+		UsernameToken: &UsernameToken{Username: "u", Password: "p"},
+	}
+	var out struct {
+		Body struct{ Message echoT }
+	}
+	err := c.RoundTrip(context.Background(), &echoT{Text: "hello"}, &out)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if out.Body.Message.Text != "hello back" {
+		t.Errorf("unexpected response %+v", out.Body.Message)
+	}
+	if bytes.Contains(raw, []byte("hello")) || !bytes.Contains(plain, []byte("<text xmlns=\"urn:test\">hello</text>")) {
+		t.Errorf("body not encrypted as expected:\n%s\n%s", raw, plain)
+	}
+	if n := bytes.Count(raw, []byte("<Security ")); n != 1 {
+		t.Errorf("want 1 security header block, have %d in %s", n, raw)
+	}
+	var env struct {
+		Header struct {
+			Security struct {
+				Token *struct{} `xml:"UsernameToken"`
+				Key   *struct{} `xml:"http://www.w3.org/2001/04/xmlenc# EncryptedKey"`
+			} `xml:"http://docs.oasis-open.org/wss/2004/01/oasis-200401-wss-wssecurity-secext-1.0.xsd Security"`
+		}
+	}
+	if err = xml.Unmarshal(raw, &env); err != nil {
+		t.Fatal(err)
+	}
+	if env.Header.Security.Token == nil || env.Header.Security.Key == nil {
+		t.Errorf("want token and encrypted key in security header block of %s", raw)
+	}
+}
+
+func TestEncryptionAlgorithms(t *testing.T) {
+	key, cert := newTestCertificate(t, "server")
+	doc := `<Envelope xmlns="` + EnvelopeNamespace + `"><Body><Echo xmlns:p="urn:p"><p:text>hello</p:text></Echo></Body></Envelope>`
+	for _, alg := range []string{"", AES128CBC, AES256CBC, AES128GCM, AES256GCM} {
+		b, err := (&Encryption{Certificate: cert, Algorithm: alg}).encrypt([]byte(doc), EnvelopeNamespace)
+		if err != nil {
+			t.Fatalf("%s: %v", alg, err)
+		}
+		if bytes.Contains(b, []byte("hello")) || !bytes.Contains(b, []byte("<Header ")) {
+			t.Errorf("%s: unexpected encryption %s", alg, b)
+		}
+		b, err = (&Encryption{Key: key, AllowCBC: true}).decrypt(b)
+		if err != nil {
+			t.Fatalf("%s: %v", alg, err)
+		}
+		if !strings.Contains(string(b), `<Body><Echo xmlns:p="urn:p"><p:text>hello</p:text></Echo></Body>`) {
+			t.Errorf("%s: unexpected decryption %s", alg, b)
+		}
+	}
+	_, err := (&Encryption{Certificate: cert, Algorithm: "des"}).encrypt([]byte(doc), EnvelopeNamespace)
+	if err == nil {
+		t.Errorf("want error of an unsupported algorithm")
+	}
+}
+
+func TestDecryptionErrors(t *testing.T) {
+	key, cert := newTestCertificate(t, "server")
+	other, _ := newTestCertificate(t, "other")
+	doc := `<Envelope xmlns="` + EnvelopeNamespace + `"><Body><Echo>hello</Echo></Body></Envelope>`
+	b, err := (&Encryption{Certificate: cert}).encrypt([]byte(doc), EnvelopeNamespace)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Contains(b, []byte(AES256GCM)) {
+		t.Errorf("want %s by default in %s", AES256GCM, b)
+	}
+	if _, err = (&Encryption{Key: other}).decrypt(b); err != ErrDecryption {
+		t.Errorf("key of another certificate: want %v, have %v", ErrDecryption, err)
+	}
+	pkcs := bytes.Replace(b, []byte(RSAOAEP), []byte(RSAPKCS1v15), 1)
+	if _, err = (&Encryption{Key: key}).decrypt(pkcs); err != ErrDecryption {
+		t.Errorf("unsupported key transport: want %v, have %v", ErrDecryption, err)
+	}
+	unknown := bytes.Replace(b, []byte(AES256GCM), []byte(XMLEnc11Namespace+"camellia256-gcm"), 1)
+	if _, err = (&Encryption{Key: key}).decrypt(unknown); err != ErrDecryption {
+		t.Errorf("unsupported algorithm: want %v, have %v", ErrDecryption, err)
+	}
+	cbc, err := (&Encryption{Certificate: cert, Algorithm: AES256CBC}).encrypt([]byte(doc), EnvelopeNamespace)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err = (&Encryption{Key: key}).decrypt(cbc); err != ErrDecryption {
+		t.Errorf("CBC not allowed: want %v, have %v", ErrDecryption, err)
+	}
+	for _, alg := range []string{AES256CBC, AES256GCM} {
+		b, err = (&Encryption{Certificate: cert, Algorithm: alg}).encrypt([]byte(doc), EnvelopeNamespace)
+		if err != nil {
+			t.Fatal(err)
+		}
+		// replace the cipher value of the data, the last one
+		end := bytes.LastIndex(b, []byte("</CipherValue>"))
+		start := bytes.LastIndexByte(b[:end], '>') + 1
+		tampered := append(append(append([]byte{}, b[:start]...), []byte(strings.Repeat("A", 24))...), b[end:]...)
+		if _, err = (&Encryption{Key: key, AllowCBC: true}).decrypt(tampered); err != ErrDecryption {
+			t.Errorf("%s: want %v, have %v", alg, ErrDecryption, err)
+		}
+	}
+}
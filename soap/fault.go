@@ -0,0 +1,173 @@
+package soap
+
+import (
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+// Fault codes of the SOAP 1.1 faults caused by the request and by the
+// server. SOAP 1.2 faults name them Sender and Receiver.
+const (
+	FaultClient = "Client"
+	FaultServer = "Server"
+)
+
+// Fault is a SOAP fault. A Client returns one when the server responds
+// with a fault, and operations of a Server return one to fail with a
+// fault code and detail of their choice.
+//
+// The Detail of faults received by a Client is the first element of
+// their detail, as a RawElement, if any; DecodeDetail decodes it, or
+// another element of the detail, onto a value of its type.
+type Fault struct {
+	Code   string      // FaultClient, FaultServer or a qualified code
+	String string      // explanation of the fault
+	Detail interface{} // optional, encoded in the detail element
+
+	// version and envelope of a received fault
+	version  string
+	envelope []byte
+}
+
+// Error implements the error interface.
+func (f *Fault) Error() string {
+	return fmt.Sprintf("soap fault %s: %s", f.Code, f.String)
+}
+
+// DecodeDetail decodes the element of the given name of the detail of
+// f, a fault received by a Client, onto v, and reports whether it did.
+func (f *Fault) DecodeDetail(name xml.Name, v interface{}) bool {
+	detail := xml.Name{Local: "detail"}
+	if f.version == Envelope12Namespace {
+		detail = xml.Name{Space: f.version, Local: "Detail"}
+	}
+	path := []xml.Name{{Space: f.version, Local: "Body"}, {Space: f.version, Local: "Fault"}, detail}
+	d, start, err := findElement(f.envelope, path, func(n xml.Name) bool { return n == name })
+	if err != nil || start == nil {
+		return false
+	}
+	return d.DecodeElement(v, start) == nil
+}
+
+// faultElements is the detail of a received fault.
+type faultElements struct {
+	Elements []RawElement `xml:",any"`
+}
+
+// parseFault returns the fault of the SOAP 1.1 or SOAP 1.2 envelope b,
+// or nil if b is not an envelope of a fault.
+func parseFault(b []byte) *Fault {
+	var env struct {
+		XMLName xml.Name
+		Body    struct {
+			Fault *struct {
+				Code     string         `xml:"faultcode"`
+				String   string         `xml:"faultstring"`
+				Detail   *faultElements `xml:"detail"`
+				Value    string         `xml:"Code>Value"`
+				Reason   []string       `xml:"Reason>Text"`
+				Detail12 *faultElements `xml:"Detail"`
+			}
+		}
+	}
+	err := xml.Unmarshal(b, &env)
+	if err != nil || env.Body.Fault == nil || env.XMLName.Local != "Envelope" {
+		return nil
+	}
+	v := env.Body.Fault
+	f := &Fault{version: env.XMLName.Space, envelope: b}
+	detail := v.Detail
+	switch f.version {
+	case EnvelopeNamespace:
+		f.Code, f.String = faultCode(v.Code), strings.TrimSpace(v.String)
+	case Envelope12Namespace:
+		f.Code = faultCode(v.Value)
+		if len(v.Reason) > 0 {
+			f.String = strings.TrimSpace(v.Reason[0])
+		}
+		detail = v.Detail12
+	default:
+		return nil
+	}
+	if detail != nil && len(detail.Elements) > 0 {
+		f.Detail = detail.Elements[0]
+	}
+	return f
+}
+
+// faultCode returns the code of a received fault: FaultClient or
+// FaultServer for the codes of SOAP 1.1 and SOAP 1.2 meaning either,
+// the local name of the other codes of SOAP, or else code as is.
+func faultCode(code string) string {
+	code = strings.TrimSpace(code)
+	switch local := code[strings.LastIndex(code, ":")+1:]; local {
+	case "Client", "Sender":
+		return FaultClient
+	case "Server", "Receiver":
+		return FaultServer
+	case "VersionMismatch", "MustUnderstand", "DataEncodingUnknown":
+		return local
+	}
+	return code
+}
+
+// fault11 is a SOAP 1.1 fault.
+type fault11 struct {
+	XMLName xml.Name     `xml:"SOAP-ENV:Fault"`
+	Code    string       `xml:"faultcode"`
+	String  string       `xml:"faultstring"`
+	Detail  *faultDetail `xml:"detail,omitempty"`
+}
+
+// fault12 is a SOAP 1.2 fault.
+type fault12 struct {
+	XMLName xml.Name     `xml:"SOAP-ENV:Fault"`
+	Code    string       `xml:"SOAP-ENV:Code>SOAP-ENV:Value"`
+	Reason  faultReason  `xml:"SOAP-ENV:Reason>SOAP-ENV:Text"`
+	Detail  *faultDetail `xml:"SOAP-ENV:Detail,omitempty"`
+}
+
+// faultReason is the text of the reason of a SOAP 1.2 fault.
+type faultReason struct {
+	Lang string `xml:"xml:lang,attr"`
+	Text string `xml:",chardata"`
+}
+
+// faultDetail holds the detail of a fault, encoded as an element named
+// after its type.
+type faultDetail struct {
+	Value interface{} `xml:",any"`
+}
+
+// writeFault writes err as a fault in an envelope of the given
+// namespace. Errors other than a Fault are faults of the server.
+func writeFault(w http.ResponseWriter, version string, err error) {
+	var f *Fault
+	if !errors.As(err, &f) {
+		f = &Fault{Code: FaultServer, String: err.Error()}
+	}
+	code := f.Code
+	if version == Envelope12Namespace {
+		switch code {
+		case FaultClient:
+			code = "Sender"
+		case FaultServer:
+			code = "Receiver"
+		}
+	}
+	if !strings.Contains(code, ":") {
+		code = "SOAP-ENV:" + code
+	}
+	var detail *faultDetail
+	if f.Detail != nil {
+		detail = &faultDetail{f.Detail}
+	}
+	var m Message = &fault11{Code: code, String: f.String, Detail: detail}
+	if version == Envelope12Namespace {
+		m = &fault12{Code: code, Reason: faultReason{"en", f.String}, Detail: detail}
+	}
+	writeEnvelope(w, version, http.StatusInternalServerError, m)
+}
@@ -0,0 +1,88 @@
+package soap
+
+import (
+	"context"
+	"encoding/xml"
+	"errors"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestFault(t *testing.T) {
+	type limitT struct {
+		XMLName xml.Name `xml:"urn:test Limit"`
+		Max     int      `xml:"urn:test max"`
+	}
+	var srv Server
+	srv.Handle(&Operation{
+		Action: "urn:limit",
+		Serve: func(ctx context.Context, r *Request) (Message, error) {
+			return nil, &Fault{Code: FaultClient, String: "too many", Detail: &limitT{Max: 3}}
+		},
+	})
+	s := httptest.NewServer(&srv)
+	defer s.Close()
+	c := &Client{URL: s.URL}
+	err := c.RoundTrip(WithSOAPAction(context.Background(), "urn:limit"), &struct{}{}, &struct{}{})
+	var f *Fault
+	if !errors.As(err, &f) {
+		t.Fatalf("want a fault, have %v", err)
+	}
+	if f.Code != FaultClient || f.String != "too many" {
+		t.Errorf("unexpected fault %v", f)
+	}
+	if el, ok := f.Detail.(RawElement); !ok || el.XMLName.Local != "Limit" {
+		t.Errorf("unexpected detail %#v", f.Detail)
+	}
+	var limit limitT
+	if !f.DecodeDetail(xml.Name{Space: "urn:test", Local: "Limit"}, &limit) || limit.Max != 3 {
+		t.Errorf("detail decoded as %+v", limit)
+	}
+	if f.DecodeDetail(xml.Name{Space: "urn:test", Local: "Other"}, &limit) {
+		t.Errorf("decoded a missing detail")
+	}
+}
+
+func TestParseFault(t *testing.T) {
+	cases := []struct {
+		Body       string
+		Code, Text string
+	}{
+		{
+			`<s:Envelope xmlns:s="http://schemas.xmlsoap.org/soap/envelope/"><s:Body><s:Fault>` +
+				`<faultcode>s:Server</faultcode><faultstring> oops </faultstring></s:Fault></s:Body></s:Envelope>`,
+			FaultServer, "oops",
+		},
+		{
+			`<env:Envelope xmlns:env="http://www.w3.org/2003/05/soap-envelope"><env:Body><env:Fault>` +
+				`<env:Code><env:Value>env:Sender</env:Value></env:Code>` +
+				`<env:Reason><env:Text xml:lang="en">bad</env:Text></env:Reason>` +
+				`<env:Detail><t:Limit xmlns:t="urn:test"><t:max>3</t:max></t:Limit></env:Detail></env:Fault></env:Body></env:Envelope>`,
+			FaultClient, "bad",
+		},
+		{
+			`<s:Envelope xmlns:s="http://schemas.xmlsoap.org/soap/envelope/"><s:Body><s:Fault>` +
+				`<faultcode>x:Quota</faultcode><faultstring>over</faultstring></s:Fault></s:Body></s:Envelope>`,
+			"x:Quota", "over",
+		},
+	}
+	for i, tc := range cases {
+		f := parseFault([]byte(tc.Body))
+		if f == nil || f.Code != tc.Code || f.String != tc.Text {
+			t.Errorf("test %d: unexpected fault %#v", i, f)
+			continue
+		}
+		var max struct {
+			Max int `xml:"urn:test max"`
+		}
+		found := f.DecodeDetail(xml.Name{Space: "urn:test", Local: "Limit"}, &max)
+		if found != (f.Detail != nil) || found && max.Max != 3 {
+			t.Errorf("test %d: detail decoded as %+v", i, max)
+		}
+	}
+	for _, body := range []string{"", "<html>Bad Gateway</html>", `<Envelope xmlns="` + EnvelopeNamespace + `"><Body/></Envelope>`} {
+		if f := parseFault([]byte(body)); f != nil {
+			t.Errorf("%q: unexpected fault %v", body, f)
+		}
+	}
+}
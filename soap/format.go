@@ -0,0 +1,82 @@
+package soap
+
+import (
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+// Format returns a human readable representation of v, a value of a
+// generated type, for logging and debugging. Structs are written with
+// the names of their fields that are set, pointers as the value they
+// point to, strings quoted and byte slices by length. Values within v
+// that implement fmt.Stringer are written by their String method, but
+// v itself is not, so that String methods can return Format of their
+// receiver.
+func Format(v interface{}) string {
+	var b strings.Builder
+	formatValue(&b, reflect.ValueOf(v), true)
+	return b.String()
+}
+
+func formatValue(b *strings.Builder, v reflect.Value, top bool) {
+	if !v.IsValid() {
+		b.WriteString("nil")
+		return
+	}
+	if (v.Kind() == reflect.Ptr || v.Kind() == reflect.Interface) && v.IsNil() {
+		b.WriteString("nil")
+		return
+	}
+	if !top && v.CanInterface() {
+		if s, ok := v.Interface().(fmt.Stringer); ok {
+			b.WriteString(s.String())
+			return
+		}
+	}
+	switch v.Kind() {
+	case reflect.Ptr, reflect.Interface:
+		formatValue(b, v.Elem(), top)
+	case reflect.Struct:
+		t := v.Type()
+		b.WriteString(t.Name())
+		b.WriteString("{")
+		n := 0
+		for i := 0; i < t.NumField(); i++ {
+			f := t.Field(i)
+			if f.PkgPath != "" || f.Name == "XMLName" || v.Field(i).IsZero() {
+				continue
+			}
+			if n > 0 {
+				b.WriteString(", ")
+			}
+			n++
+			if !f.Anonymous {
+				b.WriteString(f.Name)
+				b.WriteString(": ")
+			}
+			formatValue(b, v.Field(i), false)
+		}
+		b.WriteString("}")
+	case reflect.Slice, reflect.Array:
+		if v.Type().Elem().Kind() == reflect.Uint8 {
+			fmt.Fprintf(b, "[%d bytes]", v.Len())
+			return
+		}
+		b.WriteString("[")
+		for i := 0; i < v.Len(); i++ {
+			if i > 0 {
+				b.WriteString(", ")
+			}
+			formatValue(b, v.Index(i), false)
+		}
+		b.WriteString("]")
+	case reflect.String:
+		b.WriteString(strconv.Quote(v.String()))
+	default:
+		if v.CanInterface() {
+			fmt.Fprint(b, v.Interface())
+		}
+	}
+}
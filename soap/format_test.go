@@ -0,0 +1,49 @@
+package soap
+
+import (
+	"encoding/xml"
+	"testing"
+)
+
+type formatStatus string
+
+func (v formatStatus) String() string {
+	return string(v)
+}
+
+type formatItem struct {
+	Sku   string
+	Price *float64
+}
+
+type formatOrder struct {
+	XMLName xml.Name `xml:"order"`
+	ID      string
+	Status  formatStatus
+	Items   []*formatItem
+	Note    *string
+	Data    []byte
+	count   int
+}
+
+func (o *formatOrder) String() string {
+	return Format(o)
+}
+
+func TestFormat(t *testing.T) {
+	price := 2.5
+	o := &formatOrder{
+		ID:     "A1",
+		Status: "open",
+		Items:  []*formatItem{{Sku: "x", Price: &price}, nil},
+		Data:   []byte("abc"),
+		count:  1,
+	}
+	want := `formatOrder{ID: "A1", Status: open, Items: [formatItem{Sku: "x", Price: 2.5}, nil], Data: [3 bytes]}`
+	if s := o.String(); s != want {
+		t.Fatalf("unexpected format:\nwant %s\nhave %s", want, s)
+	}
+	if s := Format(nil); s != "nil" {
+		t.Fatalf("unexpected format of nil: %s", s)
+	}
+}
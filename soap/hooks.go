@@ -0,0 +1,124 @@
+package soap
+
+import (
+	"bytes"
+	"context"
+	"encoding/xml"
+	"time"
+)
+
+// Redacted replaces the content of the elements named by the Redact
+// option of Hooks.
+const Redacted = "***"
+
+// Hooks are called with the envelopes of the calls of a Client, for
+// logging them.
+type Hooks struct {
+	// Before, if set, is called with the envelope of each request
+	// before it is sent.
+	Before func(ctx context.Context, envelope []byte)
+
+	// After, if set, is called with each exchange once its response
+	// is read, or the request failed.
+	After func(ctx context.Context, x *Exchange)
+
+	// Redact names the elements whose content is replaced by Redacted
+	// in the envelopes given to Before and After, such as passwords and
+	// tokens, by local name or as {namespace}local.
+	Redact []string
+}
+
+// Exchange is a request of a Client and its response.
+type Exchange struct {
+	Request  []byte        // envelope of the request
+	Response []byte        // body of the response, if any
+	Status   int           // HTTP status of the response, if any
+	Duration time.Duration // time from sending the request to reading the response
+	Err      error         // error of the call, if any
+	start    time.Time
+}
+
+// before calls the Before hook, if any, with the request envelope and
+// returns the exchange of the request.
+func (h *Hooks) before(ctx context.Context, envelope []byte) *Exchange {
+	x := &Exchange{Request: h.redact(envelope)}
+	if h.Before != nil {
+		h.Before(ctx, x.Request)
+	}
+	x.start = time.Now()
+	return x
+}
+
+// after completes x with its response and error, and calls the After
+// hook, if any.
+func (h *Hooks) after(ctx context.Context, x *Exchange, err error) {
+	x.Duration = time.Since(x.start)
+	x.Response = h.redact(x.Response)
+	x.Err = err
+	if h.After != nil {
+		h.After(ctx, x)
+	}
+}
+
+// redact returns a copy of the XML document doc whose elements named by
+// h.Redact have their content replaced by Redacted, or doc itself if
+// there are none. Documents that are not well formed are redacted up to
+// their error.
+func (h *Hooks) redact(doc []byte) []byte {
+	if len(h.Redact) == 0 || len(doc) == 0 {
+		return doc
+	}
+	names := make(map[string]bool)
+	for _, name := range h.Redact {
+		names[name] = true
+	}
+	var spans [][2]int64
+	var open []int64 // start of the content of redacted elements, -1 if not
+	d := xml.NewDecoder(bytes.NewReader(doc))
+	for {
+		off := d.InputOffset()
+		t, err := d.Token()
+		if err != nil {
+			break
+		}
+		switch t := t.(type) {
+		case xml.StartElement:
+			start := int64(-1)
+			if names[t.Name.Local] || names["{"+t.Name.Space+"}"+t.Name.Local] {
+				start = d.InputOffset()
+			}
+			open = append(open, start)
+		case xml.EndElement:
+			start := open[len(open)-1]
+			open = open[:len(open)-1]
+			// Elements within redacted ones go with them.
+			if start >= 0 && !redacting(open) {
+				spans = append(spans, [2]int64{start, off})
+			}
+		}
+	}
+	if len(spans) == 0 {
+		return doc
+	}
+	var b bytes.Buffer
+	var last int64
+	for _, s := range spans {
+		b.Write(doc[last:s[0]])
+		if s[1] > s[0] {
+			b.WriteString(Redacted)
+		}
+		last = s[1]
+	}
+	b.Write(doc[last:])
+	return b.Bytes()
+}
+
+// redacting returns whether any of the open elements is redacted.
+func redacting(open []int64) bool {
+	for _, start := range open {
+		if start >= 0 {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,78 @@
+package soap
+
+import (
+	"context"
+	"encoding/xml"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestHooks(t *testing.T) {
+	status := http.StatusOK
+	echo := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(status)
+		io.Copy(w, r.Body)
+	})
+	s := httptest.NewServer(echo)
+	defer s.Close()
+
+	type loginT struct {
+		XMLName  xml.Name `xml:"urn:test Login"`
+		User     string   `xml:"urn:test user"`
+		Password string   `xml:"urn:test password"`
+	}
+	var before []byte
+	var x *Exchange
+	c := &Client{URL: s.URL, Hooks: &Hooks{
+		Before: func(ctx context.Context, envelope []byte) { before = envelope },
+		After:  func(ctx context.Context, e *Exchange) { x = e },
+		Redact: []string{"{urn:test}password"},
+	}}
+	var out struct {
+		Body struct{ Message loginT }
+	}
+	err := c.RoundTrip(context.Background(), &loginT{User: "u", Password: "secret"}, &out)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if out.Body.Message.Password != "secret" {
+		t.Errorf("response decoded from redacted envelope: %+v", out.Body.Message)
+	}
+	if x == nil {
+		t.Fatal("After not called")
+	}
+	for _, b := range [][]byte{before, x.Request, x.Response} {
+		if strings.Contains(string(b), "secret") || !strings.Contains(string(b), ">"+Redacted+"<") {
+			t.Errorf("password not redacted in %s", b)
+		}
+	}
+	if x.Status != http.StatusOK || x.Err != nil || x.Duration <= 0 {
+		t.Errorf("unexpected exchange %+v", x)
+	}
+
+	status = http.StatusInternalServerError
+	err = c.RoundTrip(context.Background(), &loginT{}, &out)
+	if err == nil || x.Err != err || x.Status != status || len(x.Response) == 0 {
+		t.Errorf("unexpected exchange %+v of error %v", x, err)
+	}
+}
+
+func TestRedact(t *testing.T) {
+	h := &Hooks{Redact: []string{"password", "{urn:a}token"}}
+	cases := []struct{ In, Want string }{
+		{`<a><password>x</password></a>`, `<a><password>***</password></a>`},
+		{`<a><password/><token xmlns="urn:b">t</token></a>`, `<a><password/><token xmlns="urn:b">t</token></a>`},
+		{`<a xmlns:p="urn:a"><p:token>t</p:token></a>`, `<a xmlns:p="urn:a"><p:token>***</p:token></a>`},
+		{`<password><password>x</password>y</password>`, `<password>***</password>`},
+		{`<a><password>x</password><b>`, `<a><password>***</password><b>`},
+	}
+	for i, tc := range cases {
+		have := string(h.redact([]byte(tc.In)))
+		if have != tc.Want {
+			t.Errorf("test %d: want %s, have %s", i, tc.Want, have)
+		}
+	}
+}
@@ -0,0 +1,79 @@
+package soap
+
+import (
+	"context"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// RoundTripHTTP calls an operation of a WSDL HTTP binding, that is,
+// without a SOAP envelope. The params are sent in the query string of
+// GET requests, or as a form in the body of POST requests, except for
+// those replacing a (name) placeholder in location. The location is
+// appended to the URL of the client. The XML response is then
+// de-serialized onto out, unless out is nil.
+func (c *Client) RoundTripHTTP(ctx context.Context, verb, location string, params url.Values, out Message) error {
+	params = replaceLocation(&location, params)
+	u := strings.TrimSuffix(c.URL, "/") + "/" + strings.TrimPrefix(location, "/")
+	var body io.Reader
+	if verb == "POST" {
+		body = strings.NewReader(params.Encode())
+	} else if len(params) > 0 {
+		u += "?" + params.Encode()
+	}
+	r, err := http.NewRequest(verb, u, body)
+	if err != nil {
+		return err
+	}
+	ctx, cancel := c.withTimeout(ctx)
+	defer cancel()
+	r = r.WithContext(ctx)
+	if verb == "POST" {
+		r.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	}
+	if err = c.setToken(r); err != nil {
+		return err
+	}
+	if c.Pre != nil {
+		c.Pre(r)
+	}
+	resp, err := c.httpClient().Do(r)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		// read only the first Mb of the body in error case
+		limReader := io.LimitReader(resp.Body, 1024*1024)
+		body, _ := ioutil.ReadAll(limReader)
+		return fmt.Errorf("%q: %q", resp.Status, body)
+	}
+	if out == nil {
+		return nil
+	}
+	return xml.NewDecoder(resp.Body).Decode(out)
+}
+
+// replaceLocation replaces the (name) placeholders of location with the
+// escaped values of params, as for http:urlReplacement, and returns the
+// params left.
+func replaceLocation(location *string, params url.Values) url.Values {
+	if !strings.Contains(*location, "(") {
+		return params
+	}
+	left := make(url.Values, len(params))
+	for k, v := range params {
+		p := "(" + k + ")"
+		if len(v) > 0 && strings.Contains(*location, p) {
+			*location = strings.Replace(*location, p, url.PathEscape(v[0]), -1)
+			continue
+		}
+		left[k] = v
+	}
+	return left
+}
@@ -0,0 +1,53 @@
+package soap
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+)
+
+func TestRoundTripHTTP(t *testing.T) {
+	type outT struct {
+		Method, Path, Query, Form string
+	}
+	echo := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		r.ParseForm()
+		var form string
+		if r.Method == "POST" {
+			form = r.PostForm.Encode()
+		}
+		w.Write([]byte("<out><Method>" + r.Method + "</Method><Path>" + r.URL.Path +
+			"</Path><Query>" + r.URL.RawQuery + "</Query><Form>" + form + "</Form></out>"))
+	})
+	s := httptest.NewServer(echo)
+	defer s.Close()
+	c := &Client{URL: s.URL + "/svc.asmx"}
+	cases := []struct {
+		Verb, Location string
+		Params         url.Values
+		Want           outT
+	}{
+		{"GET", "/Echo", url.Values{"a": {"1"}},
+			outT{"GET", "/svc.asmx/Echo", "a=1", ""}},
+		{"POST", "/Echo", url.Values{"a": {"x y"}},
+			outT{"POST", "/svc.asmx/Echo", "", "a=x+y"}},
+		{"GET", "/items/(id)/(name)", url.Values{"id": {"7"}, "name": {"n"}, "q": {"z"}},
+			outT{"GET", "/svc.asmx/items/7/n", "q=z", ""}},
+	}
+	for i, tc := range cases {
+		var have outT
+		err := c.RoundTripHTTP(nil, tc.Verb, tc.Location, tc.Params, &have)
+		if err != nil {
+			t.Errorf("test %d: %v", i, err)
+			continue
+		}
+		if have != tc.Want {
+			t.Errorf("test %d: want %#v, have %#v", i, tc.Want, have)
+		}
+	}
+	err := (&Client{URL: s.URL + "/nope\x7f"}).RoundTripHTTP(nil, "GET", "x", nil, nil)
+	if err == nil {
+		t.Error("expected error for invalid URL")
+	}
+}
@@ -0,0 +1,44 @@
+package soap
+
+import "context"
+
+// CallFunc makes a SOAP call of the Client, sending in as the body of
+// the request and decoding the response onto out.
+type CallFunc func(ctx context.Context, in, out Message) error
+
+// Interceptor wraps the calls of a Client with cross-cutting concerns,
+// such as logging, metrics, or adding header blocks with WithHeader.
+// It returns a CallFunc that may change the context and messages of a
+// call, or its error, and calls next to make it.
+type Interceptor func(next CallFunc) CallFunc
+
+type interceptorKey struct{}
+
+// WithInterceptor returns a copy of ctx carrying the interceptor i of
+// calls made with it, which runs after those of the Client and those
+// carried by ctx already.
+func WithInterceptor(ctx context.Context, i Interceptor) context.Context {
+	is, _ := ctx.Value(interceptorKey{}).([]Interceptor)
+	return context.WithValue(ctx, interceptorKey{}, append(is[:len(is):len(is)], i))
+}
+
+// SOAPAction returns the SOAP action carried by ctx, as set by
+// WithSOAPAction, for interceptors.
+func SOAPAction(ctx context.Context) string {
+	action, _ := soapAction(ctx)
+	return action
+}
+
+// intercept returns call wrapped by the interceptors of c and those
+// carried by ctx, the first of them outermost.
+func (c *Client) intercept(ctx context.Context, call CallFunc) CallFunc {
+	is := c.Interceptors
+	if ctx != nil {
+		more, _ := ctx.Value(interceptorKey{}).([]Interceptor)
+		is = append(is[:len(is):len(is)], more...)
+	}
+	for i := len(is) - 1; i >= 0; i-- {
+		call = is[i](call)
+	}
+	return call
+}
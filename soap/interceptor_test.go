@@ -0,0 +1,67 @@
+package soap
+
+import (
+	"context"
+	"encoding/xml"
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestInterceptors(t *testing.T) {
+	var body []byte
+	echo := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ = ioutil.ReadAll(r.Body)
+		w.Write(body)
+	})
+	s := httptest.NewServer(echo)
+	defer s.Close()
+
+	var calls []string
+	record := func(name string) Interceptor {
+		return func(next CallFunc) CallFunc {
+			return func(ctx context.Context, in, out Message) error {
+				calls = append(calls, name+" "+SOAPAction(ctx))
+				err := next(ctx, in, out)
+				calls = append(calls, name+" done")
+				return err
+			}
+		}
+	}
+	type blockT struct {
+		XMLName xml.Name `xml:"urn:test Session"`
+		ID      string   `xml:"id"`
+	}
+	session := func(next CallFunc) CallFunc {
+		return func(ctx context.Context, in, out Message) error {
+			return next(WithHeader(ctx, &blockT{ID: "s1"}), in, out)
+		}
+	}
+	c := &Client{URL: s.URL, Interceptors: []Interceptor{record("a"), session}}
+	ctx := WithInterceptor(WithSOAPAction(context.Background(), "urn:echo"), record("b"))
+	err := c.RoundTrip(ctx, &struct{ A string }{"a"}, &struct{}{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []string{"a urn:echo", "b urn:echo", "b done", "a done"}
+	if !reflect.DeepEqual(calls, want) {
+		t.Errorf("want calls %q, have %q", want, calls)
+	}
+	if !strings.Contains(string(body), "<id>s1</id>") {
+		t.Errorf("missing header block of interceptor in %s", body)
+	}
+
+	fail := errors.New("short-circuited")
+	c.Interceptors = []Interceptor{func(CallFunc) CallFunc {
+		return func(context.Context, Message, Message) error { return fail }
+	}}
+	body = nil
+	err = c.RoundTrip(context.Background(), &struct{}{}, &struct{}{})
+	if err != fail || body != nil {
+		t.Errorf("want %v without a request, have %v", fail, err)
+	}
+}
@@ -0,0 +1,139 @@
+package soap
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Metrics records the calls of a Client, such as for dashboards of the
+// SOAP services a program depends on.
+type Metrics interface {
+	// Observe records a call of the operation of the given SOAP
+	// action, that took d and failed with err, if not nil, such as a
+	// *Fault.
+	Observe(action string, d time.Duration, err error)
+}
+
+// measure returns call recorded by the Metrics of c, if any.
+func (c *Client) measure(call CallFunc) CallFunc {
+	if c.Metrics == nil {
+		return call
+	}
+	return func(ctx context.Context, in, out Message) error {
+		start := time.Now()
+		err := call(ctx, in, out)
+		c.Metrics.Observe(SOAPAction(ctx), time.Since(start), err)
+		return err
+	}
+}
+
+// DefBuckets are the default buckets of the latencies of
+// PrometheusMetrics, in seconds.
+var DefBuckets = []float64{.005, .01, .025, .05, .1, .25, .5, 1, 2.5, 5, 10}
+
+// PrometheusMetrics are Metrics served in the text format of Prometheus
+// by its ServeHTTP method, e.g. on /metrics: the counters of calls,
+// errors and faults, and the histogram of the latency of calls, of
+// each operation, labeled by its SOAP action. The zero value is ready
+// to use; its settings must not change once used.
+type PrometheusMetrics struct {
+	Namespace string    // Optional prefix of the metric names (default soap_client)
+	Buckets   []float64 // Optional latency buckets, in seconds (default DefBuckets)
+
+	mu  sync.Mutex
+	ops map[string]*operationMetrics
+}
+
+// operationMetrics are the metrics of an operation.
+type operationMetrics struct {
+	requests, errors, faults uint64
+	buckets                  []uint64 // cumulative counts of Buckets
+	sum                      float64  // of the latencies, in seconds
+}
+
+// Observe implements the Metrics interface.
+func (m *PrometheusMetrics) Observe(action string, d time.Duration, err error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.ops == nil {
+		m.ops = make(map[string]*operationMetrics)
+	}
+	op, ok := m.ops[action]
+	if !ok {
+		op = &operationMetrics{buckets: make([]uint64, len(m.buckets()))}
+		m.ops[action] = op
+	}
+	op.requests++
+	if err != nil {
+		op.errors++
+		var f *Fault
+		if errors.As(err, &f) {
+			op.faults++
+		}
+	}
+	s := d.Seconds()
+	op.sum += s
+	for i, le := range m.buckets() {
+		if s <= le {
+			op.buckets[i]++
+		}
+	}
+}
+
+// buckets returns the latency buckets of m.
+func (m *PrometheusMetrics) buckets() []float64 {
+	if len(m.Buckets) > 0 {
+		return m.Buckets
+	}
+	return DefBuckets
+}
+
+// ServeHTTP writes the metrics of m in the text format of Prometheus.
+func (m *PrometheusMetrics) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	ns := m.Namespace
+	if ns == "" {
+		ns = "soap_client"
+	}
+	actions := make([]string, 0, len(m.ops))
+	for action := range m.ops {
+		actions = append(actions, action)
+	}
+	sort.Strings(actions)
+	counter := func(name, help string, value func(*operationMetrics) uint64) {
+		fmt.Fprintf(w, "# HELP %s_%s %s\n# TYPE %s_%s counter\n", ns, name, help, ns, name)
+		for _, action := range actions {
+			fmt.Fprintf(w, "%s_%s{action=%s} %d\n", ns, name, quoteLabel(action), value(m.ops[action]))
+		}
+	}
+	counter("requests_total", "SOAP calls made.", func(op *operationMetrics) uint64 { return op.requests })
+	counter("errors_total", "SOAP calls failed, faults included.", func(op *operationMetrics) uint64 { return op.errors })
+	counter("faults_total", "SOAP calls failed with a fault.", func(op *operationMetrics) uint64 { return op.faults })
+	name := ns + "_request_duration_seconds"
+	fmt.Fprintf(w, "# HELP %s Latency of SOAP calls.\n# TYPE %s histogram\n", name, name)
+	for _, action := range actions {
+		op, label := m.ops[action], quoteLabel(action)
+		for i, le := range m.buckets() {
+			fmt.Fprintf(w, "%s_bucket{action=%s,le=\"%s\"} %d\n", name, label,
+				strconv.FormatFloat(le, 'g', -1, 64), op.buckets[i])
+		}
+		fmt.Fprintf(w, "%s_bucket{action=%s,le=\"+Inf\"} %d\n", name, label, op.requests)
+		fmt.Fprintf(w, "%s_sum{action=%s} %s\n", name, label, strconv.FormatFloat(op.sum, 'g', -1, 64))
+		fmt.Fprintf(w, "%s_count{action=%s} %d\n", name, label, op.requests)
+	}
+}
+
+// quoteLabel returns the label value v quoted as by Prometheus.
+func quoteLabel(v string) string {
+	r := strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+	return `"` + r.Replace(v) + `"`
+}
@@ -0,0 +1,56 @@
+package soap
+
+import (
+	"context"
+	"errors"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestMetrics(t *testing.T) {
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("SOAPAction") == `"urn:Fail"` {
+			w.WriteHeader(http.StatusInternalServerError)
+			io.WriteString(w, `<Envelope xmlns="http://schemas.xmlsoap.org/soap/envelope/"><Body><Fault>`+
+				`<faultcode>Server</faultcode><faultstring>failed</faultstring></Fault></Body></Envelope>`)
+			return
+		}
+		io.Copy(w, r.Body)
+	}))
+	defer s.Close()
+
+	m := &PrometheusMetrics{Buckets: []float64{0.5, 10}}
+	c := &Client{URL: s.URL, Metrics: m}
+	for _, action := range []string{"urn:A", "urn:A", "urn:Fail"} {
+		c.RoundTrip(WithSOAPAction(context.Background(), action), &struct{}{}, &struct{}{})
+	}
+	m.Observe(`urn:"B"`, 2*time.Second, errors.New("timeout"))
+
+	w := httptest.NewRecorder()
+	m.ServeHTTP(w, httptest.NewRequest("GET", "/metrics", nil))
+	body, _ := ioutil.ReadAll(w.Body)
+	for _, want := range []string{
+		"# TYPE soap_client_requests_total counter\n",
+		`soap_client_requests_total{action="urn:A"} 2`,
+		`soap_client_errors_total{action="urn:A"} 0`,
+		`soap_client_errors_total{action="urn:Fail"} 1`,
+		`soap_client_faults_total{action="urn:Fail"} 1`,
+		`soap_client_faults_total{action="urn:\"B\""} 0`,
+		"# TYPE soap_client_request_duration_seconds histogram\n",
+		`soap_client_request_duration_seconds_bucket{action="urn:A",le="0.5"} 2`,
+		`soap_client_request_duration_seconds_bucket{action="urn:\"B\"",le="0.5"} 0`,
+		`soap_client_request_duration_seconds_bucket{action="urn:\"B\"",le="10"} 1`,
+		`soap_client_request_duration_seconds_bucket{action="urn:\"B\"",le="+Inf"} 1`,
+		`soap_client_request_duration_seconds_sum{action="urn:\"B\""} 2`,
+		`soap_client_request_duration_seconds_count{action="urn:A"} 2`,
+	} {
+		if !strings.Contains(string(body), want) {
+			t.Errorf("missing %s in\n%s", want, body)
+		}
+	}
+}
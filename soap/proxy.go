@@ -0,0 +1,59 @@
+package soap
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// ProxyConfig configures the proxy of the transport of a Client, rather
+// than that of the HTTP_PROXY, HTTPS_PROXY and NO_PROXY environment
+// variables.
+type ProxyConfig struct {
+	// URL is the URL of the proxy, of the http, https or socks5
+	// scheme, e.g. "socks5://proxy:1080", or "http" if it has none.
+	URL string
+
+	// Username and Password are the credentials of proxies that
+	// require authentication, instead of the user info of URL.
+	Username string
+	Password string
+}
+
+// proxy returns the Proxy func of the transports configured by c.
+func (c *ProxyConfig) proxy() (func(*http.Request) (*url.URL, error), error) {
+	raw := c.URL
+	if !strings.Contains(raw, "://") {
+		raw = "http://" + raw
+	}
+	u, err := url.Parse(raw)
+	if err != nil {
+		return nil, fmt.Errorf("soap: invalid proxy URL: %v", err)
+	}
+	switch u.Scheme {
+	case "http", "https", "socks5", "socks5h":
+	default:
+		return nil, fmt.Errorf("soap: unsupported proxy scheme %q", u.Scheme)
+	}
+	if u.Host == "" {
+		return nil, fmt.Errorf("soap: proxy URL %q without host", c.URL)
+	}
+	if c.Username != "" || c.Password != "" {
+		u.User = url.UserPassword(c.Username, c.Password)
+	}
+	return http.ProxyURL(u), nil
+}
+
+// NewProxyTransport returns an HTTP transport using the proxy of c, to
+// be set as the Transport of a Client. Its other settings are those of
+// http.DefaultTransport.
+func NewProxyTransport(c *ProxyConfig) (*http.Transport, error) {
+	proxy, err := c.proxy()
+	if err != nil {
+		return nil, err
+	}
+	t := http.DefaultTransport.(*http.Transport).Clone()
+	t.Proxy = proxy
+	return t, nil
+}
@@ -0,0 +1,53 @@
+package soap
+
+import (
+	"context"
+	"encoding/base64"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestProxyTransport(t *testing.T) {
+	auth := "Basic " + base64.StdEncoding.EncodeToString([]byte("u:p"))
+	proxy := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.String() != "http://soap.example/svc" {
+			http.Error(w, "unexpected URL "+r.URL.String(), http.StatusBadGateway)
+			return
+		}
+		if r.Header.Get("Proxy-Authorization") != auth {
+			http.Error(w, "unauthorized", http.StatusProxyAuthRequired)
+			return
+		}
+		io.Copy(w, r.Body)
+	}))
+	defer proxy.Close()
+
+	tr, err := NewProxyTransport(&ProxyConfig{URL: proxy.URL, Username: "u", Password: "p"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	c := &Client{URL: "http://soap.example/svc", Transport: tr}
+	err = c.RoundTrip(context.Background(), &struct{}{}, &struct{}{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tr, err = NewProxyTransport(&ProxyConfig{URL: proxy.URL})
+	if err != nil {
+		t.Fatal(err)
+	}
+	c.Transport = tr
+	err = c.RoundTrip(context.Background(), &struct{}{}, &struct{}{})
+	if err == nil {
+		t.Fatal("want error of proxy without credentials")
+	}
+
+	for _, u := range []string{"ftp://proxy:21", "http://", "socks5://proxy:1080"} {
+		_, err = NewProxyTransport(&ProxyConfig{URL: u})
+		if (err == nil) != (u == "socks5://proxy:1080") {
+			t.Errorf("unexpected error of %q: %v", u, err)
+		}
+	}
+}
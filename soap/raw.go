@@ -0,0 +1,23 @@
+package soap
+
+import "context"
+
+// RawEnvelope is a SOAP envelope as is. A Client sends a RawEnvelope
+// request message as the envelope of the request, without the header
+// blocks or encryption of the Client, and stores the envelope of the
+// response in a *RawEnvelope response message rather than decoding it.
+type RawEnvelope []byte
+
+// CallRaw sends the hand-crafted SOAP envelope with the given SOAP
+// action, through the transport, authentication, interceptors, hooks
+// and retries of c, and returns the envelope of the response, for
+// calls that the generated types fall short of. Faults are returned as
+// a *Fault, as by RoundTrip.
+func (c *Client) CallRaw(ctx context.Context, soapAction string, envelope []byte) ([]byte, error) {
+	var out RawEnvelope
+	err := c.RoundTrip(WithSOAPAction(ctx, soapAction), RawEnvelope(envelope), &out)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
@@ -0,0 +1,55 @@
+package soap
+
+import (
+	"context"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestCallRaw(t *testing.T) {
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("SOAPAction") != `"urn:Echo"` || r.Header.Get("X-Test") != "true" {
+			http.Error(w, "unexpected request", http.StatusBadRequest)
+			return
+		}
+		body, _ := ioutil.ReadAll(r.Body)
+		if string(body) == "fault" {
+			w.WriteHeader(http.StatusInternalServerError)
+			io.WriteString(w, `<Envelope xmlns="http://schemas.xmlsoap.org/soap/envelope/"><Body><Fault>`+
+				`<faultcode>Client</faultcode><faultstring>bad</faultstring></Fault></Body></Envelope>`)
+			return
+		}
+		w.Write(body)
+	}))
+	defer s.Close()
+
+	var intercepted int
+	c := &Client{
+		URL: s.URL,
+		Pre: func(r *http.Request) { r.Header.Set("X-Test", "true") },
+		Interceptors: []Interceptor{func(next CallFunc) CallFunc {
+			return func(ctx context.Context, in, out Message) error {
+				intercepted++
+				return next(ctx, in, out)
+			}
+		}},
+	}
+	envelope := `<Envelope xmlns="http://schemas.xmlsoap.org/soap/envelope/"><Body><x:Echo xmlns:x="urn:x"/></Body></Envelope>`
+	resp, err := c.CallRaw(context.Background(), "urn:Echo", []byte(envelope))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(resp) != envelope {
+		t.Errorf("want envelope sent as is, have %s", resp)
+	}
+	if intercepted != 1 {
+		t.Errorf("want one intercepted call, have %d", intercepted)
+	}
+	_, err = c.CallRaw(context.Background(), "urn:Echo", []byte("fault"))
+	if f, ok := err.(*Fault); !ok || f.String != "bad" {
+		t.Errorf("want fault, have %v", err)
+	}
+}
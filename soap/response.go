@@ -0,0 +1,65 @@
+package soap
+
+import (
+	"context"
+	"encoding/xml"
+)
+
+// ResponseHeader is the SOAP Header of the response of a call, such as
+// the session IDs, quotas or pagination cursors some services return in
+// header blocks. A Client sets it once the response is received, for
+// calls made with a context returned by WithResponseHeader.
+type ResponseHeader struct {
+	Elements []RawElement // header blocks of the response, as is
+
+	// version and envelope of the response
+	version  string
+	envelope []byte
+}
+
+type responseHeaderKey struct{}
+
+// WithResponseHeader returns a copy of ctx carrying h, so that calls
+// made with the context set h to the SOAP Header of their response.
+func WithResponseHeader(ctx context.Context, h *ResponseHeader) context.Context {
+	return context.WithValue(ctx, responseHeaderKey{}, h)
+}
+
+// responseHeader returns the response header of ctx, if any.
+func responseHeader(ctx context.Context) *ResponseHeader {
+	if ctx == nil {
+		return nil
+	}
+	h, _ := ctx.Value(responseHeaderKey{}).(*ResponseHeader)
+	return h
+}
+
+// Decode decodes the header block of the given name onto v, such as a
+// struct of a header type of the WSDL. It leaves v unchanged if h has
+// no such block.
+func (h *ResponseHeader) Decode(name xml.Name, v interface{}) error {
+	path := []xml.Name{{Space: h.version, Local: "Header"}}
+	d, start, err := findElement(h.envelope, path, func(n xml.Name) bool { return n == name })
+	if err != nil || start == nil {
+		return err
+	}
+	return d.DecodeElement(v, start)
+}
+
+// set sets h to the header of the SOAP envelope doc, or resets it if
+// doc is not an envelope.
+func (h *ResponseHeader) set(doc []byte) {
+	var env struct {
+		XMLName xml.Name
+		Header  struct {
+			Elements []RawElement `xml:",any"`
+		}
+	}
+	*h = ResponseHeader{}
+	if err := xml.Unmarshal(doc, &env); err != nil || env.XMLName.Local != "Envelope" {
+		return
+	}
+	h.Elements = env.Header.Elements
+	h.version = env.XMLName.Space
+	h.envelope = doc
+}
@@ -0,0 +1,47 @@
+package soap
+
+import (
+	"context"
+	"encoding/xml"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestResponseHeader(t *testing.T) {
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		io.WriteString(w, `<Envelope xmlns="http://schemas.xmlsoap.org/soap/envelope/"><Header>`+
+			`<q:Quota xmlns:q="urn:quota"><q:remaining>41</q:remaining></q:Quota>`+
+			`<Session xmlns="urn:session">s1</Session>`+
+			`</Header><Body><A>a</A></Body></Envelope>`)
+	}))
+	defer s.Close()
+
+	var h ResponseHeader
+	var out struct{ Body struct{ A string } }
+	c := &Client{URL: s.URL}
+	err := c.RoundTrip(WithResponseHeader(context.Background(), &h), &struct{}{}, &out)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if out.Body.A != "a" {
+		t.Errorf("unexpected body %+v", out)
+	}
+	if len(h.Elements) != 2 || h.Elements[1].XMLName != (xml.Name{Space: "urn:session", Local: "Session"}) ||
+		h.Elements[1].Content != "s1" {
+		t.Fatalf("unexpected header blocks %+v", h.Elements)
+	}
+	var quota struct {
+		Remaining int `xml:"urn:quota remaining"`
+	}
+	if err = h.Decode(xml.Name{Space: "urn:quota", Local: "Quota"}, &quota); err != nil {
+		t.Fatal(err)
+	}
+	if quota.Remaining != 41 {
+		t.Errorf("unexpected quota %+v", quota)
+	}
+	if err = h.Decode(xml.Name{Space: "urn:quota", Local: "Other"}, &quota); err != nil || quota.Remaining != 41 {
+		t.Errorf("unexpected decoding of missing block: %v, %+v", err, quota)
+	}
+}
@@ -0,0 +1,89 @@
+package soap
+
+import (
+	"context"
+	"errors"
+	"math/rand"
+	"net"
+	"time"
+)
+
+// RetryPolicy configures the retries of the failed calls of a Client:
+// those whose response has a 5xx HTTP status but no fault, those that
+// timed out, and those failing with a fault of one of FaultCodes.
+// Calls are retried after a delay that doubles with each attempt, up to
+// MaxBackoff, from a random duration between half and all of it.
+type RetryPolicy struct {
+	MaxAttempts int           // attempts of a call, including the first (default 3)
+	Backoff     time.Duration // delay of the first retry (default 100ms)
+	MaxBackoff  time.Duration // maximum delay of retries (default 10s)
+	FaultCodes  []string      // codes of faults to retry, such as FaultServer
+}
+
+// retry reports whether the call of the given attempt, that failed
+// with the given HTTP status, if any, and error, is to be attempted
+// again, after waiting for its delay. It does not retry once ctx is
+// done, nor if p is nil.
+func (p *RetryPolicy) retry(ctx context.Context, attempt, status int, err error) bool {
+	if p == nil || !p.retryable(status, err) {
+		return false
+	}
+	max := p.MaxAttempts
+	if max == 0 {
+		max = 3
+	}
+	if attempt >= max {
+		return false
+	}
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	if ctx.Err() != nil {
+		return false
+	}
+	t := time.NewTimer(p.backoff(attempt))
+	defer t.Stop()
+	select {
+	case <-t.C:
+		return true
+	case <-ctx.Done():
+		return false
+	}
+}
+
+// retryable reports whether p retries calls that failed with the given
+// HTTP status, if any, and error.
+func (p *RetryPolicy) retryable(status int, err error) bool {
+	var f *Fault
+	if errors.As(err, &f) {
+		for _, code := range p.FaultCodes {
+			if f.Code == code {
+				return true
+			}
+		}
+		return false
+	}
+	if status >= 500 {
+		return true
+	}
+	var ne net.Error
+	return errors.As(err, &ne) && ne.Timeout()
+}
+
+// backoff returns the delay before retrying the given attempt.
+func (p *RetryPolicy) backoff(attempt int) time.Duration {
+	d, max := p.Backoff, p.MaxBackoff
+	if d <= 0 {
+		d = 100 * time.Millisecond
+	}
+	if max <= 0 {
+		max = 10 * time.Second
+	}
+	for i := 1; i < attempt && d < max; i++ {
+		d *= 2
+	}
+	if d > max {
+		d = max
+	}
+	return d/2 + time.Duration(rand.Int63n(int64(d/2)+1))
+}
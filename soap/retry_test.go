@@ -0,0 +1,83 @@
+package soap
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestRetry(t *testing.T) {
+	var n int
+	var fail func(w http.ResponseWriter) bool
+	srv := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		n++
+		if fail(w) {
+			return
+		}
+		w.Write([]byte(`<Envelope xmlns="` + EnvelopeNamespace + `"><Body/></Envelope>`))
+	})
+	s := httptest.NewServer(srv)
+	defer s.Close()
+	unavailable := func(w http.ResponseWriter) bool {
+		if n < 3 {
+			http.Error(w, "unavailable", http.StatusServiceUnavailable)
+			return true
+		}
+		return false
+	}
+	fault := func(w http.ResponseWriter) bool {
+		writeFault(w, EnvelopeNamespace, &Fault{Code: FaultServer, String: "busy"})
+		return true
+	}
+	c := &Client{URL: s.URL, Retry: &RetryPolicy{Backoff: time.Millisecond, FaultCodes: []string{"Throttled"}}}
+	cases := []struct {
+		Fail  func(w http.ResponseWriter) bool
+		Codes []string
+		Calls int
+		Err   bool
+	}{
+		{Fail: unavailable, Calls: 3},
+		{Fail: fault, Calls: 1, Err: true},
+		{Fail: fault, Codes: []string{FaultServer}, Calls: 3, Err: true},
+	}
+	for i, tc := range cases {
+		n, fail = 0, tc.Fail
+		c.Retry.FaultCodes = tc.Codes
+		err := c.RoundTrip(context.Background(), &struct{}{}, &struct{}{})
+		if (err != nil) != tc.Err || n != tc.Calls {
+			t.Errorf("test %d: want %d calls, error %v; have %d, %v", i, tc.Calls, tc.Err, n, err)
+		}
+	}
+
+	n, fail = 0, fault
+	c.Retry = &RetryPolicy{Backoff: time.Hour, FaultCodes: []string{FaultServer}}
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	start := time.Now()
+	err := c.RoundTrip(ctx, &struct{}{}, &struct{}{})
+	if err == nil || n != 1 || time.Since(start) > time.Minute {
+		t.Errorf("want one call until the context is done, have %d, %v", n, err)
+	}
+}
+
+func TestRetryBackoff(t *testing.T) {
+	p := &RetryPolicy{Backoff: 100 * time.Millisecond, MaxBackoff: time.Second}
+	cases := []struct {
+		Attempt int
+		Max     time.Duration
+	}{
+		{1, 100 * time.Millisecond},
+		{2, 200 * time.Millisecond},
+		{4, 800 * time.Millisecond},
+		{5, time.Second},
+		{50, time.Second},
+	}
+	for _, tc := range cases {
+		d := p.backoff(tc.Attempt)
+		if d < tc.Max/2 || d > tc.Max {
+			t.Errorf("attempt %d: want a delay between %v and %v, have %v", tc.Attempt, tc.Max/2, tc.Max, d)
+		}
+	}
+}
@@ -0,0 +1,270 @@
+package soap
+
+import (
+	"bytes"
+	"crypto/rand"
+	"crypto/sha1"
+	"encoding/base64"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"time"
+)
+
+// Namespaces of WS-Security 1.0 and its utility schema.
+const (
+	SecurityNamespace        = "http://docs.oasis-open.org/wss/2004/01/oasis-200401-wss-wssecurity-secext-1.0.xsd"
+	SecurityUtilityNamespace = "http://docs.oasis-open.org/wss/2004/01/oasis-200401-wss-wssecurity-utility-1.0.xsd"
+)
+
+// Types of the passwords of UsernameToken, and encoding of its nonce.
+const (
+	PasswordText   = "http://docs.oasis-open.org/wss/2004/01/oasis-200401-wss-username-token-profile-1.0#PasswordText"
+	PasswordDigest = "http://docs.oasis-open.org/wss/2004/01/oasis-200401-wss-username-token-profile-1.0#PasswordDigest"
+	Base64Binary   = "http://docs.oasis-open.org/wss/2004/01/oasis-200401-wss-soap-message-security-1.0#Base64Binary"
+)
+
+// UsernameToken configures the WS-Security header block a Client adds
+// to its requests, with a UsernameToken of its credentials. The
+// password is sent as text, or as a digest of a random nonce, the time
+// of creation and itself if Digest is set, which a new nonce protects
+// from replays.
+type UsernameToken struct {
+	Username       string
+	Password       string
+	Digest         bool // Optional PasswordDigest rather than PasswordText
+	MustUnderstand bool // Optional mustUnderstand attribute of the header block
+}
+
+// Timestamp configures the wsu:Timestamp a Client adds to the
+// WS-Security header block of its requests, with the time of their
+// creation and of their expiry.
+type Timestamp struct {
+	TTL            time.Duration // Optional time to expiry (default 5m)
+	MustUnderstand bool          // Optional mustUnderstand attribute of the header block
+}
+
+// timeFormat is the format of the times of WS-Security, in UTC.
+const timeFormat = "2006-01-02T15:04:05.000Z"
+
+// securityHeader returns the WS-Security header block of a request of
+// the given envelope namespace, created at now, with the tokens and
+// timestamp of c, or nil if it has none.
+func (c *Client) securityHeader(envelope string, now time.Time) (Header, error) {
+	if c.UsernameToken == nil && c.Timestamp == nil && c.Assertion == nil {
+		return nil, nil
+	}
+	h := &securityHeader{}
+	if c.Assertion != nil {
+		var err error
+		h.Assertion, err = xmlElement(c.Assertion)
+		if err != nil {
+			return nil, fmt.Errorf("soap: invalid SAML assertion: %v", err)
+		}
+	}
+	mustUnderstand := false
+	if ts := c.Timestamp; ts != nil {
+		ttl := ts.TTL
+		if ttl <= 0 {
+			ttl = 5 * time.Minute
+		}
+		h.Timestamp = &timestampHeader{
+			Created: now.UTC().Format(timeFormat),
+			Expires: now.Add(ttl).UTC().Format(timeFormat),
+		}
+		mustUnderstand = ts.MustUnderstand
+	}
+	if t := c.UsernameToken; t != nil {
+		var err error
+		h.Token, err = t.header(now)
+		if err != nil {
+			return nil, err
+		}
+		mustUnderstand = mustUnderstand || t.MustUnderstand
+	}
+	if mustUnderstand {
+		h.MustUnderstand = "1"
+		if envelope == Envelope12Namespace {
+			h.MustUnderstand = "true"
+		}
+		h.Envelope = envelope
+	}
+	return h, nil
+}
+
+// header returns the UsernameToken of t in a request created at now.
+func (t *UsernameToken) header(now time.Time) (*tokenHeader, error) {
+	h := &tokenHeader{
+		Username: t.Username,
+		Password: t.Password,
+		Type:     PasswordText,
+	}
+	if t.Digest {
+		nonce := make([]byte, 16)
+		_, err := rand.Read(nonce)
+		if err != nil {
+			return nil, err
+		}
+		h.Type = PasswordDigest
+		h.Nonce = base64.StdEncoding.EncodeToString(nonce)
+		h.Created = now.UTC().Format(timeFormat)
+		h.Password = passwordDigest(nonce, h.Created, t.Password)
+	}
+	return h, nil
+}
+
+// passwordDigest returns the PasswordDigest of the given nonce, time of
+// creation and password.
+func passwordDigest(nonce []byte, created, password string) string {
+	h := sha1.New()
+	h.Write(nonce)
+	h.Write([]byte(created))
+	h.Write([]byte(password))
+	return base64.StdEncoding.EncodeToString(h.Sum(nil))
+}
+
+// securityHeader is the WS-Security header block of a request.
+type securityHeader struct {
+	Envelope       string
+	MustUnderstand string
+	Timestamp      *timestampHeader
+	Assertion      []byte
+	Token          *tokenHeader
+}
+
+// securityElement is the content of the WS-Security header block of a
+// request, with its assertion, if any, as is.
+type securityElement struct {
+	Timestamp *timestampHeader
+	Assertion []byte `xml:",innerxml"`
+	Token     *tokenHeader
+}
+
+// timestampHeader is the wsu:Timestamp of a WS-Security header block.
+type timestampHeader struct {
+	Created string
+	Expires string
+}
+
+// tokenHeader is the UsernameToken of a WS-Security header block.
+type tokenHeader struct {
+	Username string
+	Password string
+	Type     string
+	Nonce    string
+	Created  string
+}
+
+// MarshalXML implements the xml.Marshaler interface.
+func (h *securityHeader) MarshalXML(e *xml.Encoder, start xml.StartElement) error {
+	security := xml.StartElement{Name: xml.Name{Space: SecurityNamespace, Local: "Security"}}
+	if h.MustUnderstand != "" {
+		security.Attr = append(security.Attr, xml.Attr{
+			Name:  xml.Name{Space: h.Envelope, Local: "mustUnderstand"},
+			Value: h.MustUnderstand,
+		})
+	}
+	return e.EncodeElement(&securityElement{
+		Timestamp: h.Timestamp,
+		Assertion: h.Assertion,
+		Token:     h.Token,
+	}, security)
+}
+
+// xmlElement returns the single element of the XML document doc, without
+// any declaration, comments or space around it.
+func xmlElement(doc []byte) ([]byte, error) {
+	d := xml.NewDecoder(bytes.NewReader(doc))
+	start, end, depth := int64(-1), int64(-1), 0
+	for {
+		off := d.InputOffset()
+		t, err := d.Token()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		switch t := t.(type) {
+		case xml.StartElement:
+			if depth == 0 && start >= 0 {
+				return nil, errors.New("more than one element")
+			}
+			if depth == 0 {
+				start = off
+			}
+			depth++
+		case xml.EndElement:
+			depth--
+			if depth == 0 {
+				end = d.InputOffset()
+			}
+		case xml.CharData:
+			if depth == 0 && len(bytes.TrimSpace(t)) > 0 {
+				return nil, errors.New("text outside of the element")
+			}
+		}
+	}
+	if start < 0 {
+		return nil, errors.New("no element")
+	}
+	return doc[start:end], nil
+}
+
+// MarshalXML writes the wsu:Timestamp element of h to e.
+func (h *timestampHeader) MarshalXML(e *xml.Encoder, start xml.StartElement) error {
+	name := func(local string) xml.StartElement {
+		return xml.StartElement{Name: xml.Name{Space: SecurityUtilityNamespace, Local: local}}
+	}
+	timestamp := name("Timestamp")
+	err := e.EncodeToken(timestamp)
+	if err != nil {
+		return err
+	}
+	err = e.EncodeElement(h.Created, name("Created"))
+	if err != nil {
+		return err
+	}
+	err = e.EncodeElement(h.Expires, name("Expires"))
+	if err != nil {
+		return err
+	}
+	return e.EncodeToken(timestamp.End())
+}
+
+// MarshalXML writes the wsse:UsernameToken element of h to e.
+func (h *tokenHeader) MarshalXML(e *xml.Encoder, start xml.StartElement) error {
+	name := func(local string) xml.StartElement {
+		return xml.StartElement{Name: xml.Name{Space: SecurityNamespace, Local: local}}
+	}
+	token := name("UsernameToken")
+	err := e.EncodeToken(token)
+	if err != nil {
+		return err
+	}
+	err = e.EncodeElement(h.Username, name("Username"))
+	if err != nil {
+		return err
+	}
+	password := name("Password")
+	password.Attr = []xml.Attr{{Name: xml.Name{Local: "Type"}, Value: h.Type}}
+	err = e.EncodeElement(h.Password, password)
+	if err != nil {
+		return err
+	}
+	if h.Nonce != "" {
+		nonce := name("Nonce")
+		nonce.Attr = []xml.Attr{{Name: xml.Name{Local: "EncodingType"}, Value: Base64Binary}}
+		err = e.EncodeElement(h.Nonce, nonce)
+		if err != nil {
+			return err
+		}
+		created := xml.StartElement{Name: xml.Name{Space: SecurityUtilityNamespace, Local: "Created"}}
+		err = e.EncodeElement(h.Created, created)
+		if err != nil {
+			return err
+		}
+	}
+	return e.EncodeToken(token.End())
+}
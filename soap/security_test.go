@@ -0,0 +1,201 @@
+package soap
+
+import (
+	"context"
+	"crypto/sha1"
+	"encoding/base64"
+	"encoding/xml"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestUsernameToken(t *testing.T) {
+	var body []byte
+	echo := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ = ioutil.ReadAll(r.Body)
+		w.Write(body)
+	})
+	s := httptest.NewServer(echo)
+	defer s.Close()
+
+	type passwordT struct {
+		Type  string `xml:"Type,attr"`
+		Value string `xml:",chardata"`
+	}
+	var env struct {
+		Header struct {
+			Security struct {
+				MustUnderstand string `xml:"http://schemas.xmlsoap.org/soap/envelope/ mustUnderstand,attr"`
+				Token          struct {
+					Username string    `xml:"Username"`
+					Password passwordT `xml:"Password"`
+					Nonce    string    `xml:"Nonce"`
+					Created  string    `xml:"http://docs.oasis-open.org/wss/2004/01/oasis-200401-wss-wssecurity-utility-1.0.xsd Created"`
+				} `xml:"UsernameToken"`
+			} `xml:"http://docs.oasis-open.org/wss/2004/01/oasis-200401-wss-wssecurity-secext-1.0.xsd Security"`
+		}
+	}
+	call := func(ut *UsernameToken) {
+		c := &Client{URL: s.URL, UsernameToken: ut}
+		err := c.RoundTrip(context.Background(), &struct{}{}, &struct{}{})
+		if err != nil {
+			t.Fatal(err)
+		}
+		env.Header.Security.MustUnderstand = ""
+		if err = xml.Unmarshal(body, &env); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	call(&UsernameToken{Username: "u", Password: "p"})
+	tok := env.Header.Security.Token
+	if tok.Username != "u" || tok.Password != (passwordT{PasswordText, "p"}) || tok.Nonce != "" {
+		t.Errorf("unexpected token %+v in %s", tok, body)
+	}
+	if env.Header.Security.MustUnderstand != "" {
+		t.Errorf("unexpected mustUnderstand in %s", body)
+	}
+
+	call(&UsernameToken{Username: "u", Password: "p", Digest: true, MustUnderstand: true})
+	tok = env.Header.Security.Token
+	nonce, err := base64.StdEncoding.DecodeString(tok.Nonce)
+	if err != nil || len(nonce) != 16 || tok.Created == "" {
+		t.Fatalf("unexpected nonce and creation of token %+v", tok)
+	}
+	h := sha1.Sum([]byte(string(nonce) + tok.Created + "p"))
+	if want := base64.StdEncoding.EncodeToString(h[:]); tok.Password != (passwordT{PasswordDigest, want}) {
+		t.Errorf("want digest %s, have %+v", want, tok.Password)
+	}
+	if env.Header.Security.MustUnderstand != "1" {
+		t.Errorf("missing mustUnderstand in %s", body)
+	}
+	if strings.Contains(string(body), ">p<") {
+		t.Errorf("password sent as text in %s", body)
+	}
+}
+
+func TestTimestamp(t *testing.T) {
+	var body []byte
+	echo := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ = ioutil.ReadAll(r.Body)
+		w.Write(body)
+	})
+	s := httptest.NewServer(echo)
+	defer s.Close()
+
+	var env struct {
+		Header struct {
+			Security []struct {
+				Timestamp struct {
+					Created string `xml:"Created"`
+					Expires string `xml:"Expires"`
+				} `xml:"http://docs.oasis-open.org/wss/2004/01/oasis-200401-wss-wssecurity-utility-1.0.xsd Timestamp"`
+				Token struct {
+					Username string `xml:"Username"`
+				} `xml:"UsernameToken"`
+			} `xml:"http://docs.oasis-open.org/wss/2004/01/oasis-200401-wss-wssecurity-secext-1.0.xsd Security"`
+		}
+	}
+	call := func(c *Client) {
+		c.URL = s.URL
+		err := c.RoundTrip(context.Background(), &struct{}{}, &struct{}{})
+		if err != nil {
+			t.Fatal(err)
+		}
+		env.Header.Security = nil
+		if err = xml.Unmarshal(body, &env); err != nil {
+			t.Fatal(err)
+		}
+		if len(env.Header.Security) != 1 {
+			t.Fatalf("want one security header block, have %s", body)
+		}
+	}
+	ttl := func() time.Duration {
+		ts := env.Header.Security[0].Timestamp
+		created, err := time.Parse(time.RFC3339, ts.Created)
+		if err != nil {
+			t.Fatal(err)
+		}
+		expires, err := time.Parse(time.RFC3339, ts.Expires)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return expires.Sub(created)
+	}
+
+	call(&Client{Timestamp: &Timestamp{}})
+	if d := ttl(); d != 5*time.Minute {
+		t.Errorf("want default TTL of 5m, have %v", d)
+	}
+	if strings.Contains(string(body), "UsernameToken") {
+		t.Errorf("unexpected token in %s", body)
+	}
+
+	call(&Client{
+		Timestamp:     &Timestamp{TTL: time.Minute},
+		UsernameToken: &UsernameToken{Username: "u", Password: "p"},
+	})
+	if d := ttl(); d != time.Minute {
+		t.Errorf("want TTL of 1m, have %v", d)
+	}
+	if u := env.Header.Security[0].Token.Username; u != "u" {
+		t.Errorf("want token of u, have %q in %s", u, body)
+	}
+}
+
+func TestAssertion(t *testing.T) {
+	var body []byte
+	echo := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ = ioutil.ReadAll(r.Body)
+		w.Write(body)
+	})
+	s := httptest.NewServer(echo)
+	defer s.Close()
+
+	assertion := `<saml:Assertion xmlns:saml="urn:oasis:names:tc:SAML:2.0:assertion" ID="a1">` +
+		`<saml:Issuer>idp</saml:Issuer></saml:Assertion>`
+	var env struct {
+		Header struct {
+			Security struct {
+				Timestamp struct {
+					Created string `xml:"Created"`
+				} `xml:"http://docs.oasis-open.org/wss/2004/01/oasis-200401-wss-wssecurity-utility-1.0.xsd Timestamp"`
+				Assertion struct {
+					ID     string `xml:"ID,attr"`
+					Issuer string `xml:"urn:oasis:names:tc:SAML:2.0:assertion Issuer"`
+				} `xml:"urn:oasis:names:tc:SAML:2.0:assertion Assertion"`
+			} `xml:"http://docs.oasis-open.org/wss/2004/01/oasis-200401-wss-wssecurity-secext-1.0.xsd Security"`
+		}
+	}
+	c := &Client{
+		URL:       s.URL,
+		Timestamp: &Timestamp{},
+		Assertion: []byte("<?xml version=\"1.0\"?>\n" + assertion + "\n"),
+	}
+	err := c.RoundTrip(context.Background(), &struct{}{}, &struct{}{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(body), assertion) {
+		t.Errorf("assertion not sent as is in %s", body)
+	}
+	if err = xml.Unmarshal(body, &env); err != nil {
+		t.Fatal(err)
+	}
+	sec := env.Header.Security
+	if sec.Assertion.ID != "a1" || sec.Assertion.Issuer != "idp" || sec.Timestamp.Created == "" {
+		t.Errorf("unexpected security header block %+v in %s", sec, body)
+	}
+
+	for _, a := range []string{"", "<a>", "<a/><b/>", "text<a/>"} {
+		c.Assertion = []byte(a)
+		err = c.RoundTrip(context.Background(), &struct{}{}, &struct{}{})
+		if err == nil || !strings.Contains(err.Error(), "SAML") {
+			t.Errorf("want error of assertion %q, have %v", a, err)
+		}
+	}
+}
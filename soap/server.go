@@ -0,0 +1,347 @@
+package soap
+
+import (
+	"bytes"
+	"context"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"mime"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strings"
+)
+
+// Namespaces of the envelopes of SOAP 1.1 and SOAP 1.2.
+const (
+	EnvelopeNamespace   = "http://schemas.xmlsoap.org/soap/envelope/"
+	Envelope12Namespace = "http://www.w3.org/2003/05/soap-envelope"
+)
+
+// DefaultMaxRequestBytes is the default MaxRequestBytes of a Server.
+const DefaultMaxRequestBytes = 10 << 20
+
+// Server is an http.Handler serving the SOAP operations given to its
+// Handle method. Each request is dispatched to the operation of the
+// element of its body, or of its SOAP action, and answered with an
+// envelope of the version of the request.
+type Server struct {
+	// WSDL, if set, is the document describing the service, served to
+	// GET requests with a wsdl query, such as /service?wsdl, with the
+	// location of the addresses of its ports set to URL. Its imports
+	// are served as is.
+	WSDL []byte
+
+	// URL, if set, is the address of the service, such as
+	// https://example.com/service, set as the location of the ports of
+	// the WSDL served. Otherwise the URL of the request is, whose host
+	// is that of the Host header sent by the client, and whose scheme
+	// is https only if the request came over TLS to s: a proxy in front
+	// of s should have it set.
+	URL string
+
+	// MaxRequestBytes, if positive, is the maximum size of the body
+	// of requests, rather than DefaultMaxRequestBytes. Larger requests
+	// are refused with status 413.
+	MaxRequestBytes int64
+
+	ops []*Operation
+}
+
+// Operation is an operation served by a Server.
+type Operation struct {
+	// Action is the SOAP action of the operation, if any.
+	Action string
+
+	// Element is the name of the element of the body of requests.
+	Element xml.Name
+
+	// Serve returns the message of the body of the response, or nil
+	// if there is none. Errors are returned to the client as faults;
+	// those other than a Fault as faults of the server.
+	Serve func(ctx context.Context, r *Request) (Message, error)
+}
+
+// Handle adds op to the operations of s. It must not be called while
+// s is serving requests.
+func (s *Server) Handle(op *Operation) {
+	s.ops = append(s.ops, op)
+}
+
+// operation returns the operation of the given body element and SOAP
+// action: the first of both, or else of the element, or else of the
+// action. It returns nil if there is none.
+func (s *Server) operation(el xml.Name, action string) *Operation {
+	var found *Operation
+	rank := 0
+	for _, op := range s.ops {
+		r := 0
+		switch {
+		case op.Element == el && op.Action == action:
+			return op
+		case op.Element == el:
+			r = 2
+		case action != "" && op.Action == action:
+			r = 1
+		}
+		if r > rank {
+			found, rank = op, r
+		}
+	}
+	return found
+}
+
+// ServeHTTP implements the http.Handler interface.
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method == "GET" && s.WSDL != nil && wsdlQuery(r.URL.Query()) {
+		location := s.URL
+		if location == "" {
+			scheme := "http"
+			if r.TLS != nil {
+				scheme = "https"
+			}
+			location = scheme + "://" + r.Host + r.URL.Path
+		}
+		w.Header().Set("Content-Type", "text/xml; charset=utf-8")
+		w.Write(setAddresses(s.WSDL, location))
+		return
+	}
+	if r.Method != "POST" {
+		w.Header().Set("Allow", "POST")
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	max := s.MaxRequestBytes
+	if max <= 0 {
+		max = DefaultMaxRequestBytes
+	}
+	b, err := ioutil.ReadAll(http.MaxBytesReader(w, r.Body, max))
+	if err != nil {
+		var tooLarge *http.MaxBytesError
+		if errors.As(err, &tooLarge) {
+			http.Error(w, err.Error(), http.StatusRequestEntityTooLarge)
+			return
+		}
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	req := &Request{HTTP: r, Version: EnvelopeNamespace, envelope: b}
+	err = req.parse()
+	if err != nil {
+		writeFault(w, req.Version, err)
+		return
+	}
+	op := s.operation(req.Element, req.Action)
+	if op == nil {
+		writeFault(w, req.Version, &Fault{
+			Code:   FaultClient,
+			String: fmt.Sprintf("no operation of element %q and action %q", req.Element.Local, req.Action),
+		})
+		return
+	}
+	out, err := op.Serve(r.Context(), req)
+	if err != nil {
+		writeFault(w, req.Version, err)
+		return
+	}
+	if out == nil {
+		w.WriteHeader(http.StatusAccepted)
+		return
+	}
+	writeEnvelope(w, req.Version, http.StatusOK, out)
+}
+
+// wsdlQuery returns whether q asks for the WSDL of a service: whether
+// it has a wsdl parameter, in any case, as in ?wsdl or ?WSDL.
+func wsdlQuery(q url.Values) bool {
+	for k := range q {
+		if strings.EqualFold(k, "wsdl") {
+			return true
+		}
+	}
+	return false
+}
+
+// locationAttr matches the location attribute of an address element.
+var locationAttr = regexp.MustCompile(`\slocation\s*=\s*("[^"]*"|'[^']*')`)
+
+// setAddresses returns the WSDL document doc whose address elements of
+// ports, of any binding, have the given location, or doc itself if it
+// is not XML.
+func setAddresses(doc []byte, location string) []byte {
+	var loc bytes.Buffer
+	xml.EscapeText(&loc, []byte(location))
+	var b bytes.Buffer
+	var last int64
+	d := xml.NewDecoder(bytes.NewReader(doc))
+	for {
+		off := d.InputOffset()
+		t, err := d.RawToken()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return doc
+		}
+		if el, ok := t.(xml.StartElement); !ok || el.Name.Local != "address" {
+			continue
+		}
+		m := locationAttr.FindSubmatchIndex(doc[off:d.InputOffset()])
+		if m == nil {
+			continue
+		}
+		// the value, within its quotes
+		start, end := off+int64(m[2])+1, off+int64(m[3])-1
+		b.Write(doc[last:start])
+		b.Write(loc.Bytes())
+		last = end
+	}
+	b.Write(doc[last:])
+	return b.Bytes()
+}
+
+// responseEnvelope is the envelope of a response of a Server.
+type responseEnvelope struct {
+	XMLName      xml.Name `xml:"SOAP-ENV:Envelope"`
+	EnvelopeAttr string   `xml:"xmlns:SOAP-ENV,attr"`
+	Body         Body
+}
+
+// writeEnvelope writes a response of the given status whose envelope,
+// of the given namespace, holds the message m.
+func writeEnvelope(w http.ResponseWriter, version string, status int, m Message) {
+	var b bytes.Buffer
+	b.WriteString(xml.Header)
+	err := xml.NewEncoder(&b).Encode(&responseEnvelope{
+		EnvelopeAttr: version,
+		Body:         Body{Message: m},
+	})
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	ct := "text/xml; charset=utf-8"
+	if version == Envelope12Namespace {
+		ct = "application/soap+xml; charset=utf-8"
+	}
+	w.Header().Set("Content-Type", ct)
+	w.WriteHeader(status)
+	w.Write(b.Bytes())
+}
+
+// Request is a SOAP request to a Server.
+type Request struct {
+	HTTP    *http.Request
+	Version string   // namespace of the envelope
+	Action  string   // SOAP action, if any
+	Element xml.Name // name of the element of the body
+
+	envelope []byte
+}
+
+// parse sets the version, action and body element of r from its
+// envelope and HTTP request.
+func (r *Request) parse() error {
+	d := xml.NewDecoder(bytes.NewReader(r.envelope))
+	for {
+		t, err := d.Token()
+		if err != nil {
+			return &Fault{Code: FaultClient, String: "no envelope: " + err.Error()}
+		}
+		if start, ok := t.(xml.StartElement); ok {
+			if start.Name.Local != "Envelope" ||
+				start.Name.Space != EnvelopeNamespace && start.Name.Space != Envelope12Namespace {
+				return &Fault{Code: FaultClient, String: fmt.Sprintf("no envelope: found %s", start.Name.Local)}
+			}
+			r.Version = start.Name.Space
+			break
+		}
+	}
+	if r.Version == Envelope12Namespace {
+		_, params, _ := mime.ParseMediaType(r.HTTP.Header.Get("Content-Type"))
+		r.Action = params["action"]
+	} else {
+		r.Action = strings.Trim(r.HTTP.Header.Get("SOAPAction"), `"`)
+	}
+	_, start, err := r.child("Body", func(xml.Name) bool { return true })
+	if err != nil {
+		return &Fault{Code: FaultClient, String: err.Error()}
+	}
+	if start == nil {
+		return &Fault{Code: FaultClient, String: "no body element"}
+	}
+	r.Element = start.Name
+	return nil
+}
+
+// child decodes the envelope of r up to the start of the first child
+// of its Header or Body element, as given by parent, whose name
+// satisfies match, and returns the decoder and start element. The start
+// element is nil if there is no such child.
+func (r *Request) child(parent string, match func(xml.Name) bool) (*xml.Decoder, *xml.StartElement, error) {
+	return findElement(r.envelope, []xml.Name{{Space: r.Version, Local: parent}}, match)
+}
+
+// findElement decodes the XML document doc up to the start of the
+// first element within the elements of path, given by name from the
+// child of the root on, whose name satisfies match, and returns the
+// decoder and start element. The start element is nil if there is no
+// such element.
+func findElement(doc []byte, path []xml.Name, match func(xml.Name) bool) (*xml.Decoder, *xml.StartElement, error) {
+	d := xml.NewDecoder(bytes.NewReader(doc))
+	depth := 0
+	for {
+		t, err := d.Token()
+		if err == io.EOF {
+			return nil, nil, nil
+		}
+		if err != nil {
+			return nil, nil, err
+		}
+		switch t := t.(type) {
+		case xml.StartElement:
+			depth++
+			switch n := depth - 2; {
+			case n >= 0 && n < len(path) && t.Name != path[n],
+				n == len(path) && !match(t.Name):
+				err = d.Skip()
+				if err != nil {
+					return nil, nil, err
+				}
+				depth--
+			case n == len(path):
+				return d, &t, nil
+			}
+		case xml.EndElement:
+			depth--
+		}
+	}
+}
+
+// DecodeBody decodes the element of the body of r onto v.
+func (r *Request) DecodeBody(v interface{}) error {
+	d, start, err := r.child("Body", func(xml.Name) bool { return true })
+	if err == nil && start != nil {
+		err = d.DecodeElement(v, start)
+	}
+	if err != nil {
+		return &Fault{Code: FaultClient, String: err.Error()}
+	}
+	return nil
+}
+
+// DecodeHeader decodes the header block of the given name onto v. It
+// leaves v unchanged if r has no such block.
+func (r *Request) DecodeHeader(name xml.Name, v interface{}) error {
+	d, start, err := r.child("Header", func(n xml.Name) bool { return n == name })
+	if err == nil && start != nil {
+		err = d.DecodeElement(v, start)
+	}
+	if err != nil {
+		return &Fault{Code: FaultClient, String: err.Error()}
+	}
+	return nil
+}
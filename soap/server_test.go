@@ -0,0 +1,212 @@
+package soap
+
+import (
+	"context"
+	"encoding/xml"
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestServer(t *testing.T) {
+	type echoT struct {
+		XMLName xml.Name `xml:"urn:test Echo"`
+		Text    string   `xml:"urn:test text"`
+	}
+	type sessionT struct {
+		ID string `xml:"urn:test id"`
+	}
+	type replyT struct {
+		XMLName xml.Name `xml:"urn:test EchoResponse"`
+		Text    string   `xml:"urn:test text"`
+		Session string   `xml:"urn:test session"`
+	}
+	var srv Server
+	srv.Handle(&Operation{
+		Action:  "urn:echo",
+		Element: xml.Name{Space: "urn:test", Local: "Echo"},
+		Serve: func(ctx context.Context, r *Request) (Message, error) {
+			var in echoT
+			if err := r.DecodeBody(&in); err != nil {
+				return nil, err
+			}
+			var session *sessionT
+			if err := r.DecodeHeader(xml.Name{Space: "urn:test", Local: "Session"}, &session); err != nil {
+				return nil, err
+			}
+			out := &replyT{Text: in.Text}
+			if session != nil {
+				out.Session = session.ID
+			}
+			return out, nil
+		},
+	})
+	srv.Handle(&Operation{
+		Action:  "urn:fail",
+		Element: xml.Name{Space: "urn:test", Local: "Echo"},
+		Serve: func(ctx context.Context, r *Request) (Message, error) {
+			return nil, &Fault{Code: FaultClient, String: "bad echo", Detail: &echoT{Text: "why"}}
+		},
+	})
+	srv.Handle(&Operation{
+		Action: "urn:crash",
+		Serve: func(ctx context.Context, r *Request) (Message, error) {
+			return nil, errors.New("crashed")
+		},
+	})
+	srv.WSDL = []byte("<definitions/>")
+	s := httptest.NewServer(&srv)
+	defer s.Close()
+
+	var out struct {
+		Body struct{ Message replyT }
+	}
+	c := &Client{URL: s.URL}
+	ctx := WithSOAPAction(context.Background(), "urn:echo")
+	ctx = WithHeader(ctx, &struct {
+		XMLName xml.Name `xml:"urn:test Session"`
+		sessionT
+	}{sessionT: sessionT{ID: "s1"}})
+	err := c.RoundTrip(ctx, &echoT{Text: "hello"}, &out)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if out.Body.Message.Text != "hello" || out.Body.Message.Session != "s1" {
+		t.Errorf("echo: unexpected response %+v", out.Body.Message)
+	}
+
+	cases := []struct {
+		Action, ContentType string
+		Body                string
+		Status              int
+		Want                []string
+	}{
+		{
+			Action: "urn:fail",
+			Body:   `<Echo xmlns="urn:test"/>`,
+			Status: http.StatusInternalServerError,
+			Want: []string{
+				`<faultcode>SOAP-ENV:Client</faultcode>`,
+				`<faultstring>bad echo</faultstring>`,
+				`<detail><Echo xmlns="urn:test"><text xmlns="urn:test">why</text></Echo></detail>`,
+			},
+		},
+		{
+			ContentType: `application/soap+xml; charset=utf-8; action="urn:crash"`,
+			Body:        `<Crash/>`,
+			Status:      http.StatusInternalServerError,
+			Want: []string{
+				`xmlns:SOAP-ENV="` + Envelope12Namespace + `"`,
+				`<SOAP-ENV:Code><SOAP-ENV:Value>SOAP-ENV:Receiver</SOAP-ENV:Value></SOAP-ENV:Code>`,
+				`<SOAP-ENV:Text xml:lang="en">crashed</SOAP-ENV:Text>`,
+			},
+		},
+		{
+			Action: "urn:unknown",
+			Body:   `<Unknown/>`,
+			Status: http.StatusInternalServerError,
+			Want:   []string{`<faultcode>SOAP-ENV:Client</faultcode>`},
+		},
+	}
+	for i, tc := range cases {
+		ns, ct := EnvelopeNamespace, "text/xml"
+		if tc.ContentType != "" {
+			ns, ct = Envelope12Namespace, tc.ContentType
+		}
+		env := `<Envelope xmlns="` + ns + `"><Body>` + tc.Body + `</Body></Envelope>`
+		r, err := http.NewRequest("POST", s.URL, strings.NewReader(env))
+		if err != nil {
+			t.Fatal(err)
+		}
+		r.Header.Set("Content-Type", ct)
+		if tc.Action != "" {
+			r.Header.Set("SOAPAction", `"`+tc.Action+`"`)
+		}
+		resp, err := http.DefaultClient.Do(r)
+		if err != nil {
+			t.Fatal(err)
+		}
+		b, _ := ioutil.ReadAll(resp.Body)
+		resp.Body.Close()
+		if resp.StatusCode != tc.Status {
+			t.Errorf("test %d: want status %d, have %d", i, tc.Status, resp.StatusCode)
+		}
+		for _, want := range tc.Want {
+			if !strings.Contains(string(b), want) {
+				t.Errorf("test %d: missing %s in\n%s", i, want, b)
+			}
+		}
+	}
+
+	resp, err := http.Get(s.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusMethodNotAllowed {
+		t.Errorf("GET: want status %d, have %d", http.StatusMethodNotAllowed, resp.StatusCode)
+	}
+	resp, err = http.Get(s.URL + "?WSDL")
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, _ := ioutil.ReadAll(resp.Body)
+	resp.Body.Close()
+	if string(b) != string(srv.WSDL) {
+		t.Errorf("GET ?WSDL: want %s, have %s", srv.WSDL, b)
+	}
+}
+
+func TestServerMaxRequestBytes(t *testing.T) {
+	srv := &Server{MaxRequestBytes: 128}
+	srv.Handle(&Operation{
+		Element: xml.Name{Space: "urn:test", Local: "Echo"},
+		Serve: func(ctx context.Context, r *Request) (Message, error) {
+			return nil, nil
+		},
+	})
+	for _, tc := range []struct {
+		Text   string
+		Status int
+	}{
+		{Text: "hi", Status: http.StatusAccepted},
+		{Text: strings.Repeat("x", 64), Status: http.StatusRequestEntityTooLarge},
+	} {
+		env := `<Envelope xmlns="` + EnvelopeNamespace + `"><Body><Echo xmlns="urn:test">` + tc.Text + `</Echo></Body></Envelope>`
+		w := httptest.NewRecorder()
+		srv.ServeHTTP(w, httptest.NewRequest("POST", "/", strings.NewReader(env)))
+		if w.Code != tc.Status {
+			t.Errorf("%d bytes: want status %d, have %d", len(env), tc.Status, w.Code)
+		}
+	}
+}
+
+func TestServerWSDLAddress(t *testing.T) {
+	srv := &Server{WSDL: []byte(`<definitions xmlns:soap="http://schemas.xmlsoap.org/wsdl/soap/">` +
+		`<service name="S"><port name="P" binding="B"><soap:address location="http://upstream:8080/svc"/></port>` +
+		`<port name="Q" binding="C"><address xmlns="http://schemas.xmlsoap.org/wsdl/http/" location='/q' /></port></service>` +
+		`<documentation>location="kept"</documentation></definitions>`)}
+	w := httptest.NewRecorder()
+	srv.ServeHTTP(w, httptest.NewRequest("GET", "http://svc.example.com/glossary?wsdl", nil))
+	want := `<definitions xmlns:soap="http://schemas.xmlsoap.org/wsdl/soap/">` +
+		`<service name="S"><port name="P" binding="B"><soap:address location="http://svc.example.com/glossary"/></port>` +
+		`<port name="Q" binding="C"><address xmlns="http://schemas.xmlsoap.org/wsdl/http/" location='http://svc.example.com/glossary' /></port></service>` +
+		`<documentation>location="kept"</documentation></definitions>`
+	if w.Body.String() != want {
+		t.Errorf("want %s\nhave %s", want, w.Body)
+	}
+
+	// behind a proxy, whatever the Host header
+	srv.URL = "https://example.com/svc"
+	w = httptest.NewRecorder()
+	r := httptest.NewRequest("GET", "http://svc.example.com/glossary?wsdl", nil)
+	r.Host = "attacker.example.net"
+	srv.ServeHTTP(w, r)
+	want = strings.Replace(want, "http://svc.example.com/glossary", srv.URL, -1)
+	if w.Body.String() != want {
+		t.Errorf("want %s\nhave %s", want, w.Body)
+	}
+}
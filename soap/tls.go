@@ -0,0 +1,69 @@
+package soap
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"io/ioutil"
+	"net/http"
+)
+
+// TLSConfig configures the TLS of the transport of a Client, such as the
+// client certificate of endpoints requiring mutual TLS.
+type TLSConfig struct {
+	// CertFile and KeyFile are the PEM encoded client certificate and
+	// key to present to servers that require one.
+	CertFile string
+	KeyFile  string
+
+	// CAFile is a PEM encoded bundle of certificate authorities to
+	// trust in addition to those of the system.
+	CAFile string
+
+	// Insecure accepts invalid server certificates.
+	Insecure bool
+
+	// Proxy, if set, is the proxy of the transport rather than that
+	// of the environment.
+	Proxy *ProxyConfig
+}
+
+// NewTLSTransport returns an HTTP transport configured by c, using the
+// proxy of the environment unless c has one, to be set as the Transport
+// of a Client. Its other settings are those of http.DefaultTransport.
+func NewTLSTransport(c *TLSConfig) (*http.Transport, error) {
+	proxy := http.ProxyFromEnvironment
+	if c.Proxy != nil {
+		var err error
+		proxy, err = c.Proxy.proxy()
+		if err != nil {
+			return nil, err
+		}
+	}
+	tc := &tls.Config{InsecureSkipVerify: c.Insecure}
+	if c.CertFile != "" || c.KeyFile != "" {
+		cert, err := tls.LoadX509KeyPair(c.CertFile, c.KeyFile)
+		if err != nil {
+			return nil, err
+		}
+		tc.Certificates = []tls.Certificate{cert}
+	}
+	if c.CAFile != "" {
+		pem, err := ioutil.ReadFile(c.CAFile)
+		if err != nil {
+			return nil, err
+		}
+		pool, err := x509.SystemCertPool()
+		if err != nil {
+			pool = x509.NewCertPool()
+		}
+		if !pool.AppendCertsFromPEM(pem) {
+			return nil, errors.New("no certificates found in " + c.CAFile)
+		}
+		tc.RootCAs = pool
+	}
+	t := http.DefaultTransport.(*http.Transport).Clone()
+	t.Proxy = proxy
+	t.TLSClientConfig = tc
+	return t, nil
+}
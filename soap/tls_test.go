@@ -0,0 +1,104 @@
+package soap
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"io"
+	"io/ioutil"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestTLSTransport(t *testing.T) {
+	dir, err := ioutil.TempDir("", "soap")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	// self-signed client certificate, trusted by the server
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tmpl := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: "client"},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, tmpl, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cert, err := x509.ParseCertificate(der)
+	if err != nil {
+		t.Fatal(err)
+	}
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	writePEM := func(name, typ string, b []byte) string {
+		path := filepath.Join(dir, name)
+		err := ioutil.WriteFile(path, pem.EncodeToMemory(&pem.Block{Type: typ, Bytes: b}), 0600)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return path
+	}
+	certFile := writePEM("cert.pem", "CERTIFICATE", der)
+	keyFile := writePEM("key.pem", "EC PRIVATE KEY", keyDER)
+
+	echo := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		io.Copy(w, r.Body)
+	})
+	s := httptest.NewUnstartedServer(echo)
+	clients := x509.NewCertPool()
+	clients.AddCert(cert)
+	s.TLS = &tls.Config{ClientAuth: tls.RequireAndVerifyClientCert, ClientCAs: clients}
+	s.StartTLS()
+	defer s.Close()
+	caFile := writePEM("ca.pem", "CERTIFICATE", s.Certificate().Raw)
+
+	cases := []struct {
+		Config *TLSConfig
+		Err    bool
+	}{
+		{Config: &TLSConfig{CertFile: certFile, KeyFile: keyFile, CAFile: caFile}},
+		{Config: &TLSConfig{CAFile: caFile}, Err: true},
+		{Config: &TLSConfig{CertFile: certFile, KeyFile: keyFile}, Err: true},
+		{Config: &TLSConfig{CertFile: certFile, KeyFile: keyFile, Insecure: true}},
+	}
+	for i, tc := range cases {
+		tr, err := NewTLSTransport(tc.Config)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if tr.IdleConnTimeout == 0 || tr.TLSHandshakeTimeout == 0 {
+			t.Errorf("test %d: want the timeouts of the default transport", i)
+		}
+		c := &Client{URL: s.URL, Transport: tr}
+		err = c.RoundTrip(context.Background(), &struct{}{}, &struct{}{})
+		if (err != nil) != tc.Err {
+			t.Errorf("test %d: want error %v, have %v", i, tc.Err, err)
+		}
+	}
+
+	_, err = NewTLSTransport(&TLSConfig{CAFile: keyFile})
+	if err == nil {
+		t.Errorf("want error of a CA file without certificates")
+	}
+}
@@ -0,0 +1,78 @@
+package soap
+
+import (
+	"context"
+	"errors"
+	"net/http"
+)
+
+// Attributes of the spans of calls traced by a Client.
+const (
+	AttrSOAPAction = "soap.action"               // SOAP action of the call
+	AttrEndpoint   = "url.full"                  // URL of the server
+	AttrStatus     = "http.response.status_code" // HTTP status of the last attempt
+	AttrFaultCode  = "soap.fault.code"           // code of the fault of the call
+)
+
+// Tracer adapts a tracing API, such as OpenTelemetry, to trace the calls
+// of a Client, each in a span of its own whose context is propagated in
+// the HTTP headers of its requests.
+type Tracer interface {
+	// Start starts the span of the call of the given name, the SOAP
+	// action of the call if any, in a child of ctx carrying it.
+	Start(ctx context.Context, name string) (context.Context, Span)
+
+	// Inject sets the headers propagating the span of ctx in h.
+	Inject(ctx context.Context, h http.Header)
+}
+
+// Span is the span of a call started by a Tracer.
+type Span interface {
+	// SetAttribute sets the attribute of the given key, one of the
+	// Attr constants, to a string or int value.
+	SetAttribute(key string, value interface{})
+
+	// End ends the span of a call that failed with err, if not nil.
+	End(err error)
+}
+
+type spanKey struct{}
+
+// callSpan returns the span of the call carried by ctx, if any.
+func callSpan(ctx context.Context) Span {
+	if ctx == nil {
+		return nil
+	}
+	s, _ := ctx.Value(spanKey{}).(Span)
+	return s
+}
+
+// trace returns call traced by the Tracer of c, if any: in a span with
+// the attributes of the call, ended once it returns.
+func (c *Client) trace(call CallFunc) CallFunc {
+	if c.Tracer == nil {
+		return call
+	}
+	return func(ctx context.Context, in, out Message) error {
+		if ctx == nil {
+			ctx = context.Background()
+		}
+		action := SOAPAction(ctx)
+		name := action
+		if name == "" {
+			name = "SOAP"
+		}
+		ctx, span := c.Tracer.Start(ctx, name)
+		if action != "" {
+			span.SetAttribute(AttrSOAPAction, action)
+		}
+		span.SetAttribute(AttrEndpoint, c.URL)
+		err := call(context.WithValue(ctx, spanKey{}, span), in, out)
+		var f *Fault
+		if errors.As(err, &f) {
+			span.SetAttribute(AttrFaultCode, f.Code)
+		}
+		span.End(err)
+		return err
+	}
+}
@@ -0,0 +1,69 @@
+package soap
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+type testSpan struct {
+	name  string
+	attrs map[string]interface{}
+	err   error
+	ended bool
+}
+
+func (s *testSpan) SetAttribute(key string, value interface{}) { s.attrs[key] = value }
+func (s *testSpan) End(err error)                              { s.err, s.ended = err, true }
+
+type testTracer struct{ spans []*testSpan }
+
+func (t *testTracer) Start(ctx context.Context, name string) (context.Context, Span) {
+	s := &testSpan{name: name, attrs: make(map[string]interface{})}
+	t.spans = append(t.spans, s)
+	return context.WithValue(ctx, t, s), s
+}
+
+func (t *testTracer) Inject(ctx context.Context, h http.Header) {
+	if s, ok := ctx.Value(t).(*testSpan); ok {
+		h.Set("Traceparent", s.name)
+	}
+}
+
+func TestTracer(t *testing.T) {
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Traceparent") != "urn:A" {
+			w.WriteHeader(http.StatusInternalServerError)
+			io.WriteString(w, `<Envelope xmlns="http://schemas.xmlsoap.org/soap/envelope/"><Body><Fault>`+
+				`<faultcode>Server</faultcode><faultstring>untraced</faultstring></Fault></Body></Envelope>`)
+			return
+		}
+		io.Copy(w, r.Body)
+	}))
+	defer s.Close()
+
+	tr := &testTracer{}
+	c := &Client{URL: s.URL, Tracer: tr}
+	err := c.RoundTrip(WithSOAPAction(context.Background(), "urn:A"), &struct{}{}, &struct{}{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = c.RoundTrip(WithSOAPAction(context.Background(), "urn:B"), &struct{}{}, &struct{}{})
+	if err == nil {
+		t.Fatal("want fault of untraced call")
+	}
+	if len(tr.spans) != 2 {
+		t.Fatalf("want 2 spans, have %d", len(tr.spans))
+	}
+	a, b := tr.spans[0], tr.spans[1]
+	if a.name != "urn:A" || !a.ended || a.err != nil || a.attrs[AttrSOAPAction] != "urn:A" ||
+		a.attrs[AttrEndpoint] != s.URL || a.attrs[AttrStatus] != http.StatusOK || a.attrs[AttrFaultCode] != nil {
+		t.Errorf("unexpected span %+v", a)
+	}
+	if !b.ended || b.err != err || b.attrs[AttrStatus] != http.StatusInternalServerError ||
+		b.attrs[AttrFaultCode] != FaultServer {
+		t.Errorf("unexpected span of fault %+v", b)
+	}
+}
@@ -0,0 +1,352 @@
+package soap
+
+import (
+	"encoding/xml"
+	"fmt"
+	"reflect"
+	"strings"
+	"sync"
+)
+
+// registry maps the names of XML Schema types to the Go types that
+// represent them, and back.
+var registry = struct {
+	sync.RWMutex
+	types map[xml.Name]reflect.Type
+	names map[reflect.Type]xml.Name
+}{
+	types: map[xml.Name]reflect.Type{
+		{Space: XSDNamespace, Local: "string"}:   reflect.TypeOf(""),
+		{Space: XSDNamespace, Local: "boolean"}:  reflect.TypeOf(false),
+		{Space: XSDNamespace, Local: "int"}:      reflect.TypeOf(int(0)),
+		{Space: XSDNamespace, Local: "integer"}:  reflect.TypeOf(int(0)),
+		{Space: XSDNamespace, Local: "long"}:     reflect.TypeOf(int64(0)),
+		{Space: XSDNamespace, Local: "float"}:    reflect.TypeOf(float64(0)),
+		{Space: XSDNamespace, Local: "double"}:   reflect.TypeOf(float64(0)),
+		{Space: XSDNamespace, Local: "dateTime"}: reflect.TypeOf(""),
+	},
+	names: map[reflect.Type]xml.Name{
+		reflect.TypeOf(""):         {Space: XSDNamespace, Local: "string"},
+		reflect.TypeOf(false):      {Space: XSDNamespace, Local: "boolean"},
+		reflect.TypeOf(int(0)):     {Space: XSDNamespace, Local: "int"},
+		reflect.TypeOf(int64(0)):   {Space: XSDNamespace, Local: "long"},
+		reflect.TypeOf(float64(0)): {Space: XSDNamespace, Local: "double"},
+	},
+}
+
+// RegisterType registers the Go type of v, or of the value v points
+// to, as the representation of the XML Schema type name. Generated
+// code registers its types so that AnyType values carrying an
+// xsi:type attribute decode into them.
+func RegisterType(name xml.Name, v interface{}) {
+	t := elemType(v)
+	registry.Lock()
+	registry.types[name] = t
+	registry.names[t] = name
+	registry.Unlock()
+}
+
+// lookupType returns the Go type registered for the XML Schema type
+// name. Names whose namespace is unknown are matched by local name,
+// if unambiguous.
+func lookupType(name xml.Name) (reflect.Type, bool) {
+	registry.RLock()
+	defer registry.RUnlock()
+	if t, ok := registry.types[name]; ok || name.Space != "" {
+		return t, ok
+	}
+	var found reflect.Type
+	for n, t := range registry.types {
+		if n.Local != name.Local {
+			continue
+		}
+		if found != nil && found != t {
+			return nil, false
+		}
+		found = t
+	}
+	return found, found != nil
+}
+
+// typeName returns the XML Schema type name registered for the Go
+// type t.
+func typeName(t reflect.Type) (xml.Name, bool) {
+	registry.RLock()
+	defer registry.RUnlock()
+	name, ok := registry.names[t]
+	return name, ok
+}
+
+// AnyType is the value of an element of type xsd:anyType. When the
+// element has an xsi:type attribute naming a registered type, Value
+// is a pointer to a value of the Go type registered for it. Otherwise
+// Value is the raw content of the element, as a string.
+//
+// When encoded, the xsi:type attribute names the type registered for
+// the type of Value, if any.
+type AnyType struct {
+	Type  xml.Name
+	Value interface{}
+}
+
+// MarshalXML implements the xml.Marshaler interface.
+func (v AnyType) MarshalXML(e *xml.Encoder, start xml.StartElement) error {
+	rv := indirect(v.Value)
+	if !rv.IsValid() {
+		return nil
+	}
+	name := v.Type
+	if n, ok := typeName(rv.Type()); ok && name.Local == "" {
+		name = n
+	}
+	return encodeTyped(e, start, v.Value, name)
+}
+
+// UnmarshalXML implements the xml.Unmarshaler interface.
+func (v *AnyType) UnmarshalXML(d *xml.Decoder, start xml.StartElement) error {
+	*v = AnyType{Type: xsiType(start)}
+	if t, ok := lookupType(v.Type); ok {
+		p := reflect.New(t)
+		err := d.DecodeElement(p.Interface(), &start)
+		if err != nil {
+			return err
+		}
+		v.Value = p.Interface()
+		return nil
+	}
+	var raw struct {
+		Content string `xml:",innerxml"`
+	}
+	err := d.DecodeElement(&raw, &start)
+	if err != nil {
+		return err
+	}
+	v.Value = raw.Content
+	return nil
+}
+
+// MarshalDerived encodes v, a value of the type of base or of a type
+// derived from it, as the element start. Values of derived types are
+// encoded with an xsi:type attribute naming the type registered for
+// them. A nil base stands for an abstract type.
+func MarshalDerived(e *xml.Encoder, start xml.StartElement, v, base interface{}) error {
+	rv := indirect(v)
+	if !rv.IsValid() {
+		return nil
+	}
+	var name xml.Name
+	if rv.Type() != elemType(base) {
+		n, ok := typeName(rv.Type())
+		if !ok {
+			return fmt.Errorf("soap: type %s is not registered", rv.Type())
+		}
+		name = n
+	}
+	return encodeTyped(e, start, v, name)
+}
+
+// UnmarshalDerived decodes the element start into a new value of the
+// type registered for its xsi:type attribute, or of the type of base
+// if it has none, and returns a pointer to it. A nil base stands for
+// an abstract type: the element must then have an xsi:type attribute.
+func UnmarshalDerived(d *xml.Decoder, start xml.StartElement, base interface{}) (interface{}, error) {
+	t := elemType(base)
+	if name := xsiType(start); name.Local != "" {
+		rt, ok := lookupType(name)
+		if !ok {
+			return nil, fmt.Errorf("soap: type %s of element %s is not registered",
+				name.Local, start.Name.Local)
+		}
+		t = rt
+	}
+	if t == nil {
+		return nil, fmt.Errorf("soap: element %s of an abstract type has no xsi:type", start.Name.Local)
+	}
+	p := reflect.New(t)
+	err := d.DecodeElement(p.Interface(), &start)
+	if err != nil {
+		return nil, err
+	}
+	return p.Interface(), nil
+}
+
+// elemType returns the type of v, or of the value v points to, or nil
+// if v is nil.
+func elemType(v interface{}) reflect.Type {
+	t := reflect.TypeOf(v)
+	if t != nil && t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	return t
+}
+
+// indirect returns the value v points to, through any number of
+// pointers, or the zero Value if v or any of the pointers is nil.
+func indirect(v interface{}) reflect.Value {
+	rv := reflect.ValueOf(v)
+	for rv.Kind() == reflect.Ptr {
+		if rv.IsNil() {
+			return reflect.Value{}
+		}
+		rv = rv.Elem()
+	}
+	return rv
+}
+
+// encodeTyped encodes v as the element start, with an xsi:type
+// attribute naming the type name unless it is empty.
+func encodeTyped(e *xml.Encoder, start xml.StartElement, v interface{}, name xml.Name) error {
+	if name.Local != "" {
+		start.Attr = append(start.Attr, xml.Attr{
+			Name:  xml.Name{Local: "xmlns:xsi"},
+			Value: XSINamespace,
+		})
+		if name.Space == XSDNamespace {
+			start.Attr = append(start.Attr, xml.Attr{
+				Name:  xml.Name{Local: "xmlns:xsd"},
+				Value: XSDNamespace,
+			})
+		}
+		start.Attr = append(start.Attr, typeAttrs("xsi:type", name)...)
+	}
+	return e.EncodeElement(v, start)
+}
+
+// xsiType returns the type named by the xsi:type attribute of start,
+// if any.
+func xsiType(start xml.StartElement) xml.Name {
+	for _, attr := range start.Attr {
+		if attr.Name.Space == XSINamespace && attr.Name.Local == "type" {
+			return resolveQName(attr.Value, start.Attr)
+		}
+	}
+	return xml.Name{}
+}
+
+// resolveQName resolves the qualified name qname, the value of an
+// attribute, using the namespace declarations in attrs. The namespace
+// is left empty if the prefix is not declared there.
+func resolveQName(qname string, attrs []xml.Attr) xml.Name {
+	prefix, local := "", qname
+	if i := strings.Index(qname, ":"); i >= 0 {
+		prefix, local = qname[:i], qname[i+1:]
+	}
+	for _, attr := range attrs {
+		if prefix == "" && attr.Name.Space == "" && attr.Name.Local == "xmlns" ||
+			prefix != "" && attr.Name.Space == "xmlns" && attr.Name.Local == prefix {
+			return xml.Name{Space: attr.Value, Local: local}
+		}
+	}
+	if prefix == "xsd" || prefix == "xs" {
+		return xml.Name{Space: XSDNamespace, Local: local}
+	}
+	return xml.Name{Local: local}
+}
+
+// RawElement is an element matched by an xsd:any wildcard. It keeps
+// the name, attributes and content of the element as they were
+// received, so that it can be inspected or encoded again unchanged.
+type RawElement struct {
+	XMLName xml.Name
+	Attrs   []xml.Attr `xml:",any,attr"`
+	Content string     `xml:",innerxml"`
+}
+
+// MarshalXML implements the xml.Marshaler interface. Namespace
+// declarations among the attributes are written as such, so that the
+// prefixes used by the content remain declared.
+func (r RawElement) MarshalXML(e *xml.Encoder, start xml.StartElement) error {
+	if r.XMLName.Local != "" {
+		start.Name = r.XMLName
+	}
+	for _, attr := range r.Attrs {
+		switch {
+		case attr.Name.Space == "xmlns":
+			attr.Name = xml.Name{Local: "xmlns:" + attr.Name.Local}
+		case attr.Name.Space == "" && attr.Name.Local == "xmlns":
+			// declared by the name of the element
+			continue
+		}
+		start.Attr = append(start.Attr, attr)
+	}
+	return e.EncodeElement(struct {
+		Content string `xml:",innerxml"`
+	}{r.Content}, start)
+}
+
+// QName is a value of xsd:QName: a name qualified by the URI of its
+// namespace. Prefix is the prefix the name was read with, if any, and
+// the one it is written with, if set.
+//
+// Prefixes of values read from elements are resolved using the
+// namespace declarations of the element itself, and values written
+// to elements declare the namespace of their prefix there. Values of
+// attributes can not declare namespaces: they are read and written
+// with their prefix as is, and only the prefixes xsd and xs are
+// resolved.
+type QName struct {
+	Space  string
+	Local  string
+	Prefix string
+}
+
+// String returns the qualified name of v, with its prefix.
+func (v QName) String() string {
+	if v.Prefix == "" {
+		return v.Local
+	}
+	return v.Prefix + ":" + v.Local
+}
+
+// MarshalXML implements the xml.Marshaler interface.
+func (v QName) MarshalXML(e *xml.Encoder, start xml.StartElement) error {
+	if v.Space != "" {
+		if v.Prefix == "" {
+			v.Prefix = "ns"
+			if v.Space == XSDNamespace {
+				v.Prefix = "xsd"
+			}
+		}
+		start.Attr = append(start.Attr, xml.Attr{
+			Name:  xml.Name{Local: "xmlns:" + v.Prefix},
+			Value: v.Space,
+		})
+	}
+	return e.EncodeElement(v.String(), start)
+}
+
+// UnmarshalXML implements the xml.Unmarshaler interface.
+func (v *QName) UnmarshalXML(d *xml.Decoder, start xml.StartElement) error {
+	var s string
+	err := d.DecodeElement(&s, &start)
+	if err != nil {
+		return err
+	}
+	*v = parseQName(strings.TrimSpace(s), start.Attr)
+	return nil
+}
+
+// MarshalXMLAttr implements the xml.MarshalerAttr interface.
+func (v QName) MarshalXMLAttr(name xml.Name) (xml.Attr, error) {
+	if v.Prefix == "" && v.Space == XSDNamespace {
+		v.Prefix = "xsd"
+	}
+	return xml.Attr{Name: name, Value: v.String()}, nil
+}
+
+// UnmarshalXMLAttr implements the xml.UnmarshalerAttr interface.
+func (v *QName) UnmarshalXMLAttr(attr xml.Attr) error {
+	*v = parseQName(strings.TrimSpace(attr.Value), nil)
+	return nil
+}
+
+// parseQName returns the QName of the qualified name qname, resolved
+// using the namespace declarations in attrs.
+func parseQName(qname string, attrs []xml.Attr) QName {
+	name := resolveQName(qname, attrs)
+	v := QName{Space: name.Space, Local: name.Local}
+	if i := strings.Index(qname, ":"); i >= 0 {
+		v.Prefix = qname[:i]
+	}
+	return v
+}
@@ -0,0 +1,178 @@
+package soap
+
+import (
+	"encoding/xml"
+	"reflect"
+	"testing"
+)
+
+type bookT struct {
+	Title string `xml:"title"`
+}
+
+func TestAnyType(t *testing.T) {
+	RegisterType(xml.Name{Space: "urn:shop", Local: "Book"}, (*bookT)(nil))
+	type itemsT struct {
+		XMLName xml.Name  `xml:"items"`
+		Items   []AnyType `xml:"item"`
+	}
+	b, err := xml.Marshal(itemsT{Items: []AnyType{
+		{Value: &bookT{Title: "Go"}},
+		{Value: 3},
+		{Value: "text"},
+		{},
+	}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := `<items>` +
+		`<item xmlns:xsi="` + XSINamespace + `" xmlns:types="urn:shop" xsi:type="types:Book"><title>Go</title></item>` +
+		`<item xmlns:xsi="` + XSINamespace + `" xmlns:xsd="` + XSDNamespace + `" xsi:type="xsd:int">3</item>` +
+		`<item xmlns:xsi="` + XSINamespace + `" xmlns:xsd="` + XSDNamespace + `" xsi:type="xsd:string">text</item>` +
+		`</items>`
+	if string(b) != want {
+		t.Fatalf("unexpected xml:\nwant %s\nhave %s", want, b)
+	}
+	var items itemsT
+	err = xml.Unmarshal([]byte(`<items xmlns:xsi="`+XSINamespace+`" xmlns:s="urn:shop">`+
+		`<item xsi:type="s:Book"><title>Go</title></item>`+
+		`<item xsi:type="xsd:int">3</item>`+
+		`<item xsi:type="Book"><title>Unqualified</title></item>`+
+		`<item><b>raw</b></item>`+
+		`</items>`), &items)
+	if err != nil {
+		t.Fatal(err)
+	}
+	values := []interface{}{&bookT{Title: "Go"}, intPtr(3), &bookT{Title: "Unqualified"}, "<b>raw</b>"}
+	if len(items.Items) != len(values) {
+		t.Fatalf("want %d items, have %d", len(values), len(items.Items))
+	}
+	for i, want := range values {
+		if have := items.Items[i].Value; !reflect.DeepEqual(have, want) {
+			t.Errorf("item %d: want %#v, have %#v", i, want, have)
+		}
+	}
+}
+
+func intPtr(n int) *int { return &n }
+
+func TestRawElement(t *testing.T) {
+	type extensibleT struct {
+		XMLName xml.Name     `xml:"ext"`
+		ID      string       `xml:"id"`
+		Any     []RawElement `xml:",any"`
+	}
+	var v extensibleT
+	err := xml.Unmarshal([]byte(`<ext><id>1</id><x:color xmlns:x="urn:x" shade="dark">red<x:b>!</x:b></x:color></ext>`), &v)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(v.Any) != 1 {
+		t.Fatalf("want 1 element, have %d", len(v.Any))
+	}
+	el := v.Any[0]
+	if el.XMLName != (xml.Name{Space: "urn:x", Local: "color"}) || el.Content != "red<x:b>!</x:b>" {
+		t.Fatalf("unexpected element: %#v", el)
+	}
+	b, err := xml.Marshal(&v)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := `<ext><id>1</id><color xmlns="urn:x" xmlns:x="urn:x" shade="dark">red<x:b>!</x:b></color></ext>`
+	if string(b) != want {
+		t.Errorf("unexpected xml:\nwant %s\nhave %s", want, b)
+	}
+}
+
+type shapeT struct {
+	Name string `xml:"name"`
+}
+
+type circleT struct {
+	Name   string  `xml:"name"`
+	Radius float64 `xml:"radius"`
+}
+
+type shapeHolder struct {
+	Value interface{}
+}
+
+func (h shapeHolder) MarshalXML(e *xml.Encoder, start xml.StartElement) error {
+	return MarshalDerived(e, start, h.Value, (*shapeT)(nil))
+}
+
+func (h *shapeHolder) UnmarshalXML(d *xml.Decoder, start xml.StartElement) error {
+	v, err := UnmarshalDerived(d, start, (*shapeT)(nil))
+	h.Value = v
+	return err
+}
+
+func TestDerived(t *testing.T) {
+	RegisterType(xml.Name{Space: "urn:draw", Local: "Shape"}, (*shapeT)(nil))
+	RegisterType(xml.Name{Space: "urn:draw", Local: "Circle"}, (*circleT)(nil))
+	type drawingT struct {
+		XMLName xml.Name      `xml:"drawing"`
+		Shapes  []shapeHolder `xml:"shape"`
+	}
+	in := drawingT{Shapes: []shapeHolder{
+		{&shapeT{Name: "a"}},
+		{&circleT{Name: "b", Radius: 1}},
+	}}
+	b, err := xml.Marshal(in)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := `<drawing>` +
+		`<shape><name>a</name></shape>` +
+		`<shape xmlns:xsi="` + XSINamespace + `" xmlns:types="urn:draw" xsi:type="types:Circle"><name>b</name><radius>1</radius></shape>` +
+		`</drawing>`
+	if string(b) != want {
+		t.Fatalf("unexpected xml:\nwant %s\nhave %s", want, b)
+	}
+	var out drawingT
+	err = xml.Unmarshal(b, &out)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(in.Shapes, out.Shapes) {
+		t.Errorf("want %#v, have %#v", in.Shapes, out.Shapes)
+	}
+	type squareT struct{}
+	_, err = xml.Marshal(shapeHolder{&squareT{}})
+	if err == nil {
+		t.Errorf("unregistered derived type encoded")
+	}
+}
+
+func TestQName(t *testing.T) {
+	type faultT struct {
+		XMLName xml.Name `xml:"fault"`
+		Code    QName    `xml:"code"`
+		Kind    QName    `xml:"kind,attr"`
+	}
+	var v faultT
+	err := xml.Unmarshal([]byte(`<fault xmlns:env="urn:env" kind="xsd:string">`+
+		`<code xmlns:c="urn:codes"> c:Server </code></fault>`), &v)
+	if err != nil {
+		t.Fatal(err)
+	}
+	code := QName{Space: "urn:codes", Local: "Server", Prefix: "c"}
+	if v.Code != code {
+		t.Errorf("unexpected code: %#v", v.Code)
+	}
+	kind := QName{Space: XSDNamespace, Local: "string", Prefix: "xsd"}
+	if v.Kind != kind {
+		t.Errorf("unexpected kind: %#v", v.Kind)
+	}
+	b, err := xml.Marshal(faultT{
+		Code: QName{Space: "urn:codes", Local: "Client"},
+		Kind: QName{Space: XSDNamespace, Local: "int"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := `<fault kind="xsd:int"><code xmlns:ns="urn:codes">ns:Client</code></fault>`
+	if string(b) != want {
+		t.Errorf("unexpected xml:\nwant %s\nhave %s", want, b)
+	}
+}
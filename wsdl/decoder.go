@@ -18,5 +18,8 @@ func Unmarshal(r io.Reader) (*Definitions, error) {
 	if err != nil {
 		return nil, err
 	}
+	for _, s := range d.Schemas {
+		s.Namespaces.inherit(d.Namespaces)
+	}
 	return &d, nil
 }
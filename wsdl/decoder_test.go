@@ -5,6 +5,7 @@ import (
 	"os"
 	"path/filepath"
 	"reflect"
+	"strings"
 	"testing"
 )
 
@@ -41,3 +42,346 @@ func TestUnmarshal(t *testing.T) {
 		}
 	}
 }
+
+func TestBindings(t *testing.T) {
+	f, err := os.Open(filepath.Join("testdata", "bindings.wsdl"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	d, err := Unmarshal(f)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n := len(d.Bindings); n != 3 {
+		t.Fatalf("want 3 bindings, have %d", n)
+	}
+	for _, name := range []string{"EchoSoap12", "tns:EchoSoap12"} {
+		b := d.BindingByName(name)
+		if b == nil || b.Name != "EchoSoap12" {
+			t.Errorf("binding %q: want EchoSoap12, have %#v", name, b)
+		}
+	}
+	if b := d.BindingByName("Nope"); b != nil {
+		t.Errorf("unexpected binding: %#v", b)
+	}
+	bb := d.BindingsByTransport("http://schemas.xmlsoap.org/soap/http")
+	if len(bb) != 2 || bb[0].Name != "EchoSoap" || bb[1].Name != "EchoSoap12" {
+		t.Errorf("unexpected bindings by transport: %#v", bb)
+	}
+	versions := map[string]string{
+		"EchoSoap":   "1.1",
+		"EchoSoap12": "1.2",
+		"EchoHttp":   "",
+	}
+	for name, want := range versions {
+		if have := d.BindingByName(name).SOAPVersion(); have != want {
+			t.Errorf("binding %q: want SOAP version %q, have %q", name, want, have)
+		}
+	}
+	op := d.BindingByName("EchoSoap12").Operations[0]
+	if op.Operation == nil || op.Operation.SoapAction != "urn:echo" || !op.Operation.SoapActionRequired {
+		t.Errorf("unexpected soap12 operation: %#v", op.Operation)
+	}
+	if op.Input == nil || op.Input.Use != "literal" || op.Output == nil || op.Output.Use != "literal" {
+		t.Errorf("unexpected soap12 body: %#v, %#v", op.Input, op.Output)
+	}
+	op = d.BindingByName("EchoSoap").Operations[0]
+	if len(op.InputHeaders) != 2 || len(op.OutputHeaders) != 1 {
+		t.Fatalf("unexpected soap headers: %#v, %#v", op.InputHeaders, op.OutputHeaders)
+	}
+	h := op.InputHeaders[1]
+	if h.Message != "tns:echoHeader" || h.Part != "trace" || h.Use != "encoded" || h.Namespace != "urn:trace" {
+		t.Errorf("unexpected soap header: %#v", h)
+	}
+	if f := op.FaultByName("EchoFault"); f == nil || f.SOAP == nil || f.SOAP.Name != "EchoFault" || f.SOAP.Use != "literal" {
+		t.Errorf("unexpected soap fault: %#v", f)
+	}
+	if f := op.FaultByName("Nope"); f != nil {
+		t.Errorf("unexpected soap fault: %#v", f)
+	}
+	if in := d.PortTypes[0].Operations[0].Input; in == nil || in.Action != "urn:echo:input" {
+		t.Errorf("unexpected operation input: %#v", in)
+	}
+	pf := d.PortTypes[0].Operations[0].Faults
+	if len(pf) != 1 || pf[0].Name != "EchoFault" || pf[0].Message != "tns:echoFault" {
+		t.Errorf("unexpected operation faults: %#v", pf)
+	}
+	hb := d.BindingByName("EchoHttp")
+	if hb.HTTPBinding == nil || hb.HTTPBinding.Verb != "GET" || hb.SOAPBinding != nil {
+		t.Errorf("unexpected http binding: %#v, %#v", hb.HTTPBinding, hb.SOAPBinding)
+	}
+	op = hb.Operations[0]
+	if op.HTTP == nil || op.HTTP.Location != "/echo/(text)" || op.Operation != nil {
+		t.Errorf("unexpected http operation: %#v, %#v", op.HTTP, op.Operation)
+	}
+	if op.URLReplacement == nil || op.URLEncoded != nil {
+		t.Errorf("unexpected http input: %#v, %#v", op.URLReplacement, op.URLEncoded)
+	}
+}
+
+func TestServices(t *testing.T) {
+	f, err := os.Open(filepath.Join("testdata", "bindings.wsdl"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	d, err := Unmarshal(f)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n := len(d.Services); n != 2 {
+		t.Fatalf("want 2 services, have %d", n)
+	}
+	s := d.ServiceByName("EchoHttpService")
+	if s == nil || len(s.Ports) != 1 || s.Ports[0].Binding != "tns:EchoHttp" {
+		t.Fatalf("unexpected service: %#v", s)
+	}
+	if loc := s.Ports[0].Address.Location; loc != "http://localhost:9999/echo" {
+		t.Errorf("unexpected address: %q", loc)
+	}
+	if s := d.ServiceByName("Nope"); s != nil {
+		t.Errorf("unexpected service: %#v", s)
+	}
+}
+
+func TestSchemas(t *testing.T) {
+	f, err := os.Open(filepath.Join("testdata", "schemas.wsdl"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	d, err := Unmarshal(f)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n := len(d.Schemas); n != 2 {
+		t.Fatalf("want 2 schemas, have %d", n)
+	}
+	a, b := d.Schemas[0], d.Schemas[1]
+	if a.TargetNamespace != "http://localhost:9999/a" || len(a.ComplexTypes) != 1 {
+		t.Errorf("unexpected schema: %#v", a)
+	}
+	if b.TargetNamespace != "http://localhost:9999/b" || len(b.Elements) != 1 {
+		t.Errorf("unexpected schema: %#v", b)
+	}
+}
+
+func TestFacets(t *testing.T) {
+	f, err := os.Open(filepath.Join("testdata", "facets.wsdl"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	d, err := Unmarshal(f)
+	if err != nil {
+		t.Fatal(err)
+	}
+	st := d.Schemas[0].SimpleTypes
+	if len(st) != 2 {
+		t.Fatalf("want 2 simple types, have %d", len(st))
+	}
+	code, amount := st[0].Restriction, st[1].Restriction
+	if len(code.Patterns) != 2 || code.Patterns[1].Value != "[0-9]{3}" {
+		t.Errorf("unexpected patterns: %#v", code.Patterns)
+	}
+	cases := []struct {
+		Facet *Facet
+		Value string
+	}{
+		{code.Length, "3"},
+		{code.MinLength, "3"},
+		{code.MaxLength, "3"},
+		{code.WhiteSpace, "collapse"},
+		{amount.MinInclusive, "0"},
+		{amount.MaxInclusive, "1000"},
+		{amount.MinExclusive, "-1"},
+		{amount.MaxExclusive, "1001"},
+		{amount.TotalDigits, "6"},
+		{amount.FractionDigits, "2"},
+	}
+	for i, tc := range cases {
+		if tc.Facet == nil || tc.Facet.Value != tc.Value {
+			t.Errorf("test %d: want %q, have %#v", i, tc.Value, tc.Facet)
+		}
+	}
+	if !code.Length.Fixed {
+		t.Errorf("length facet is not fixed")
+	}
+}
+
+func TestNamespaces(t *testing.T) {
+	f, err := os.Open(filepath.Join("testdata", "schemas.wsdl"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	d, err := Unmarshal(f)
+	if err != nil {
+		t.Fatal(err)
+	}
+	b := d.Schemas[1]
+	cases := []struct {
+		QName string
+		Want  xml.Name
+	}{
+		{"a:Person", xml.Name{Space: "http://localhost:9999/a", Local: "Person"}},
+		{"xsd:string", xml.Name{Space: XMLSchemaNamespace, Local: "string"}},
+		{"tns:Company", xml.Name{Space: "http://localhost:9999/b", Local: "Company"}},
+		{"Company", xml.Name{Space: "http://schemas.xmlsoap.org/wsdl/", Local: "Company"}},
+		{"x:Company", xml.Name{Local: "Company"}},
+	}
+	for i, tc := range cases {
+		if have := b.ResolveQName(tc.QName); have != tc.Want {
+			t.Errorf("test %d (%q) failed: want %v, have %v", i, tc.QName, tc.Want, have)
+		}
+	}
+	want := xml.Name{Space: "http://localhost:9999", Local: "Company"}
+	if have := d.ResolveQName("tns:Company"); have != want {
+		t.Errorf("want %v, have %v", want, have)
+	}
+}
+
+func TestMIME(t *testing.T) {
+	f, err := os.Open(filepath.Join("testdata", "mime.wsdl"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	d, err := Unmarshal(f)
+	if err != nil {
+		t.Fatal(err)
+	}
+	op := d.BindingByName("PhotoSoap").Operations[0]
+	in := op.InputMIME()
+	if in == nil || in.MultipartRelated == nil || len(in.MultipartRelated.Parts) != 2 {
+		t.Fatalf("unexpected input MIME: %#v", in)
+	}
+	body, image := in.MultipartRelated.Parts[0], in.MultipartRelated.Parts[1]
+	if body.Name != "body" || body.Body == nil || body.Body.Parts != "title" {
+		t.Errorf("unexpected body part: %#v", body)
+	}
+	if image.Name != "image" || len(image.Contents) != 2 || image.Contents[1].Type != "image/png" {
+		t.Errorf("unexpected image part: %#v", image)
+	}
+	if out := op.OutputMIME(); out != nil {
+		t.Errorf("unexpected output MIME: %#v", out)
+	}
+	op = d.BindingByName("PhotoHttp").Operations[0]
+	in = op.InputMIME()
+	if in == nil || len(in.Contents) != 1 || in.Contents[0].Type != "application/x-www-form-urlencoded" {
+		t.Errorf("unexpected input MIME: %#v", in)
+	}
+	out := op.OutputMIME()
+	if out == nil || out.MimeXML == nil || out.MimeXML.Part != "Body" {
+		t.Errorf("unexpected output MIME: %#v", out)
+	}
+}
+
+func TestPolicies(t *testing.T) {
+	f, err := os.Open(filepath.Join("testdata", "policy.wsdl"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	d, err := Unmarshal(f)
+	if err != nil {
+		t.Fatal(err)
+	}
+	p := d.PolicyByURI("#SecurePolicy")
+	if p == nil {
+		t.Fatal("policy SecurePolicy not found")
+	}
+	if !p.Contains("UsernameToken") || !p.Contains("Addressing") || p.Contains("TransportBinding") {
+		t.Errorf("unexpected assertions: %#v", p.Assertions)
+	}
+	a := p.Assertions[0].Assertions[0].Assertions[0]
+	if a.XMLName.Space != "http://www.w3.org/2007/05/addressing/metadata" || !a.Optional {
+		t.Errorf("unexpected addressing assertion: %#v", a)
+	}
+	if p := d.PolicyByURI("http://example.com/policy#SecurePolicy"); p != nil {
+		t.Errorf("unexpected external policy: %#v", p)
+	}
+	b := d.BindingByName("EchoSoap")
+	pp := d.ResolvePolicies(b.Policies, b.PolicyReferences)
+	if len(pp) != 1 || pp[0] != p {
+		t.Errorf("unexpected binding policies: %#v", pp)
+	}
+	op := b.Operations[0]
+	pp = d.ResolvePolicies(op.Policies, op.PolicyReferences)
+	if len(pp) != 1 || pp[0].Name != "EchoPolicy" || !pp[0].Contains("TransportBinding") {
+		t.Errorf("unexpected operation policies: %#v", pp)
+	}
+}
+
+func TestAppInfo(t *testing.T) {
+	f, err := os.Open(filepath.Join("testdata", "appinfo.wsdl"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	d, err := Unmarshal(f)
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := d.Schemas[0]
+	ct := s.ComplexTypes[0]
+	if ct.Doc != "An item." {
+		t.Errorf("unexpected documentation: %q", ct.Doc)
+	}
+	cases := []struct {
+		AppInfo []*AppInfo
+		Want    []AppInfo
+	}{
+		{s.AppInfo, []AppInfo{{Content: `<jaxb:schemaBindings><jaxb:package name="example"/></jaxb:schemaBindings>`}}},
+		{s.SimpleTypes[0].AppInfo, []AppInfo{{Source: "urn:hints", Content: "uppercase"}}},
+		{ct.AppInfo, []AppInfo{
+			{Content: `<jaxb:class name="ItemBean"/>`},
+			{Source: "urn:hints", Content: "cached"},
+		}},
+		{ct.Sequence.Elements[0].AppInfo, []AppInfo{{Content: `<jaxb:property name="itemCode"/>`}}},
+		{ct.Attributes[0].AppInfo, []AppInfo{{Source: "urn:hints", Content: "key"}}},
+	}
+	for i, tc := range cases {
+		if len(tc.AppInfo) != len(tc.Want) {
+			t.Errorf("test %d: want %d appinfo, have %d", i, len(tc.Want), len(tc.AppInfo))
+			continue
+		}
+		for j, want := range tc.Want {
+			if have := *tc.AppInfo[j]; have != want {
+				t.Errorf("test %d: want %#v, have %#v", i, want, have)
+			}
+		}
+	}
+}
+
+func TestSequenceParticles(t *testing.T) {
+	var s Schema
+	err := xml.Unmarshal([]byte(`<schema xmlns="http://www.w3.org/2001/XMLSchema"><complexType name="T"><sequence>`+
+		`<element name="a"/><group ref="g"/><element name="b"/><choice><element name="c"/></choice><any/><element name="d"/>`+
+		`</sequence></complexType></schema>`), &s)
+	if err != nil {
+		t.Fatal(err)
+	}
+	seq := s.ComplexTypes[0].Sequence
+	var have []string
+	for _, p := range seq.Particles() {
+		switch p := p.(type) {
+		case *Element:
+			have = append(have, p.Name)
+		case *Group:
+			have = append(have, "group "+p.Ref)
+		case *Choice:
+			have = append(have, "choice")
+		case *AnyElement:
+			have = append(have, "any")
+		}
+	}
+	want := "a, group g, b, choice, any, d"
+	if strings.Join(have, ", ") != want {
+		t.Errorf("want particles %s, have %s", want, strings.Join(have, ", "))
+	}
+	if len(seq.Elements) != 3 || seq.Elements[2].Min != 1 || len(seq.Groups) != 1 || len(seq.Choices) != 1 || len(seq.Any) != 1 {
+		t.Errorf("unexpected sequence %+v", seq)
+	}
+}
@@ -10,21 +10,206 @@ import (
 
 // Definitions is the root element of a WSDL document.
 type Definitions struct {
-	XMLName         xml.Name   `xml:"definitions"`
-	Name            string     `xml:"name,attr"`
-	TargetNamespace string     `xml:"targetNamespace,attr"`
-	SOAPEnv         string     `xml:"SOAP-ENV,attr"`
-	SOAPEnc         string     `xml:"SOAP-ENC,attr"`
-	Service         Service    `xml:"service"`
-	Imports         []*Import  `xml:"import"`
-	Schema          Schema     `xml:"types>schema"`
-	Messages        []*Message `xml:"message"`
-	PortType        PortType   `xml:"portType"` // TODO: PortType slice?
-	Binding         Binding    `xml:"binding"`
+	XMLName         xml.Name    `xml:"definitions"`
+	Name            string      `xml:"name,attr"`
+	TargetNamespace string      `xml:"targetNamespace,attr"`
+	SOAPEnv         string      `xml:"SOAP-ENV,attr"`
+	SOAPEnc         string      `xml:"SOAP-ENC,attr"`
+	Services        []*Service  `xml:"service"`
+	Imports         []*Import   `xml:"import"`
+	Schemas         []*Schema   `xml:"types>schema"`
+	Messages        []*Message  `xml:"message"`
+	PortTypes       []*PortType `xml:"portType"`
+	Bindings        []*Binding  `xml:"binding"`
+	Policies        []*Policy   `xml:"Policy"`
+	Namespaces      Namespaces  `xml:",any,attr"`
+}
+
+// Merge adds the definitions of the imported document imp to d. Messages,
+// port types, bindings and services already defined in d are kept.
+// Schemas of imp keep the namespace declarations in scope in imp.
+func (d *Definitions) Merge(imp *Definitions) {
+	for _, s := range imp.Schemas {
+		s.Namespaces.inherit(imp.Namespaces)
+		d.Schemas = append(d.Schemas, s)
+	}
+	for _, m := range imp.Messages {
+		if !d.hasMessage(m.Name) {
+			d.Messages = append(d.Messages, m)
+		}
+	}
+	for _, pt := range imp.PortTypes {
+		if !d.hasPortType(pt.Name) {
+			d.PortTypes = append(d.PortTypes, pt)
+		}
+	}
+	for _, b := range imp.Bindings {
+		if d.BindingByName(b.Name) == nil {
+			d.Bindings = append(d.Bindings, b)
+		}
+	}
+	for _, s := range imp.Services {
+		if d.ServiceByName(s.Name) == nil {
+			d.Services = append(d.Services, s)
+		}
+	}
+	for _, p := range imp.Policies {
+		if d.PolicyByURI("#"+p.ID) == nil {
+			d.Policies = append(d.Policies, p)
+		}
+	}
+}
+
+func (d *Definitions) hasMessage(name string) bool {
+	for _, m := range d.Messages {
+		if m.Name == name {
+			return true
+		}
+	}
+	return false
+}
+
+func (d *Definitions) hasPortType(name string) bool {
+	for _, pt := range d.PortTypes {
+		if pt.Name == name {
+			return true
+		}
+	}
+	return false
+}
+
+// PolicyByURI returns the policy of the document referred to by uri, in
+// the form #id where id is the wsu:Id or the Name of the policy, or nil
+// if not found. References to other documents are not resolved.
+func (d *Definitions) PolicyByURI(uri string) *Policy {
+	if !strings.HasPrefix(uri, "#") {
+		return nil
+	}
+	id := uri[1:]
+	for _, p := range d.Policies {
+		if id != "" && (p.ID == id || p.Name == id) {
+			return p
+		}
+	}
+	return nil
+}
+
+// ResolvePolicies returns the given inline policies followed by the
+// policies of the document referred to by refs.
+func (d *Definitions) ResolvePolicies(policies []*Policy, refs []*PolicyReference) []*Policy {
+	resolved := append([]*Policy(nil), policies...)
+	for _, ref := range refs {
+		if p := d.PolicyByURI(ref.URI); p != nil {
+			resolved = append(resolved, p)
+		}
+	}
+	return resolved
+}
+
+// XMLSchemaNamespace is the namespace of the XML Schema built-in types.
+const XMLSchemaNamespace = "http://www.w3.org/2001/XMLSchema"
+
+// Namespaces maps the prefixes declared on an element to namespace URIs.
+// The default namespace is mapped from the empty prefix.
+type Namespaces map[string]string
+
+// UnmarshalXMLAttr implements the xml.UnmarshalerAttr interface. It
+// records namespace declarations and ignores any other attribute.
+func (ns *Namespaces) UnmarshalXMLAttr(attr xml.Attr) error {
+	var prefix string
+	switch {
+	case attr.Name.Space == "xmlns":
+		prefix = attr.Name.Local
+	case attr.Name.Space == "" && attr.Name.Local == "xmlns":
+	default:
+		return nil
+	}
+	if *ns == nil {
+		*ns = make(Namespaces)
+	}
+	(*ns)[prefix] = attr.Value
+	return nil
+}
+
+// Resolve returns the namespace and local name of the qualified name
+// qname, such as tns:Foo. Names without a prefix are in the default
+// namespace. The namespace is empty if the prefix is not declared.
+func (ns Namespaces) Resolve(qname string) xml.Name {
+	var prefix string
+	local := qname
+	if n := strings.SplitN(qname, ":", 2); len(n) == 2 {
+		prefix, local = n[0], n[1]
+	}
+	return xml.Name{Space: ns[prefix], Local: local}
+}
+
+// inherit adds the declarations of parent that ns does not override.
+func (ns *Namespaces) inherit(parent Namespaces) {
+	for prefix, uri := range parent {
+		if _, exists := (*ns)[prefix]; exists {
+			continue
+		}
+		if *ns == nil {
+			*ns = make(Namespaces)
+		}
+		(*ns)[prefix] = uri
+	}
+}
+
+// ResolveQName resolves a qualified name, such as the type of a message
+// part, using the namespace declarations of the document.
+func (d *Definitions) ResolveQName(qname string) xml.Name {
+	return d.Namespaces.Resolve(qname)
+}
+
+// ServiceByName returns the service with the given name, or nil if
+// the document does not define it.
+func (d *Definitions) ServiceByName(name string) *Service {
+	for _, s := range d.Services {
+		if s.Name == name {
+			return s
+		}
+	}
+	return nil
+}
+
+// BindingByName returns the binding with the given name, or nil if
+// the document does not define it. The name may carry a namespace
+// prefix, as used by the type attribute of ports.
+func (d *Definitions) BindingByName(name string) *Binding {
+	name = localName(name)
+	for _, b := range d.Bindings {
+		if b.Name == name {
+			return b
+		}
+	}
+	return nil
+}
+
+// BindingsByTransport returns the bindings that use the given transport,
+// such as http://schemas.xmlsoap.org/soap/http, in document order.
+func (d *Definitions) BindingsByTransport(transport string) []*Binding {
+	var bindings []*Binding
+	for _, b := range d.Bindings {
+		if b.Transport() == transport {
+			bindings = append(bindings, b)
+		}
+	}
+	return bindings
+}
+
+// localName strips the namespace prefix of a qualified name.
+func localName(s string) string {
+	n := strings.SplitN(s, ":", 2)
+	if len(n) == 2 {
+		return n[1]
+	}
+	return s
 }
 
 // Service defines a WSDL service and with a location, like an HTTP server.
 type Service struct {
+	Name  string  `xml:"name,attr"`
 	Doc   string  `xml:"documentation"`
 	Ports []*Port `xml:"port"`
 }
@@ -45,25 +230,85 @@ type Address struct {
 
 // Schema of WSDL document.
 type Schema struct {
-	XMLName      xml.Name        `xml:"schema"`
-	Imports      []*ImportSchema `xml:"import"`
-	SimpleTypes  []*SimpleType   `xml:"simpleType"`
-	ComplexTypes []*ComplexType  `xml:"complexType"`
-	Elements     []*Element      `xml:"element"`
+	XMLName         xml.Name `xml:"schema"`
+	TargetNamespace string   `xml:"targetNamespace,attr"`
+
+	// ElementFormDefault and AttributeFormDefault tell whether local
+	// elements and attributes are in the target namespace: qualified
+	// or unqualified (the default).
+	ElementFormDefault   string `xml:"elementFormDefault,attr"`
+	AttributeFormDefault string `xml:"attributeFormDefault,attr"`
+
+	// AppInfo holds the application information annotating the
+	// schema, such as code generation hints.
+	AppInfo []*AppInfo `xml:"annotation>appinfo"`
+
+	Imports         []*ImportSchema   `xml:"import"`
+	Includes        []*IncludeSchema  `xml:"include"`
+	SimpleTypes     []*SimpleType     `xml:"simpleType"`
+	ComplexTypes    []*ComplexType    `xml:"complexType"`
+	Elements        []*Element        `xml:"element"`
+	Groups          []*Group          `xml:"group"`
+	Attributes      []*Attribute      `xml:"attribute"`
+	AttributeGroups []*AttributeGroup `xml:"attributeGroup"`
+
+	// Namespaces declared on the schema, including those declared on
+	// the definitions element when the schema is embedded in a WSDL.
+	Namespaces Namespaces `xml:",any,attr"`
+}
+
+// ResolveQName resolves a qualified name, such as the type of an element
+// or the base of a restriction, using the namespace declarations in
+// scope in the schema.
+func (s *Schema) ResolveQName(qname string) xml.Name {
+	return s.Namespaces.Resolve(qname)
+}
+
+// Include adds the components of the schema inc, included from another
+// document of the same target namespace, to s.
+func (s *Schema) Include(inc *Schema) {
+	s.AppInfo = append(s.AppInfo, inc.AppInfo...)
+	s.Imports = append(s.Imports, inc.Imports...)
+	s.SimpleTypes = append(s.SimpleTypes, inc.SimpleTypes...)
+	s.ComplexTypes = append(s.ComplexTypes, inc.ComplexTypes...)
+	s.Elements = append(s.Elements, inc.Elements...)
+	s.Groups = append(s.Groups, inc.Groups...)
+	s.Attributes = append(s.Attributes, inc.Attributes...)
+	s.AttributeGroups = append(s.AttributeGroups, inc.AttributeGroups...)
+	s.Namespaces.inherit(inc.Namespaces)
+}
+
+// AppInfo is the application information of an xsd:annotation, kept
+// as raw XML so that tools may interpret it.
+type AppInfo struct {
+	Source  string `xml:"source,attr"`
+	Content string `xml:",innerxml"`
 }
 
 // SimpleType describes a simple type, such as string.
 type SimpleType struct {
 	XMLName     xml.Name     `xml:"simpleType"`
 	Name        string       `xml:"name,attr"`
+	Doc         string       `xml:"annotation>documentation"`
+	AppInfo     []*AppInfo   `xml:"annotation>appinfo"`
 	Union       *Union       `xml:"union"`
 	Restriction *Restriction `xml:"restriction"`
+	List        *List        `xml:"list"`
 }
 
 // Union is a mix of multiple types in a union.
 type Union struct {
-	XMLName     xml.Name `xml:"union"`
-	MemberTypes string   `xml:"memberTypes,attr"`
+	XMLName     xml.Name      `xml:"union"`
+	MemberTypes string        `xml:"memberTypes,attr"`
+	SimpleTypes []*SimpleType `xml:"simpleType"` // anonymous member types
+}
+
+// List describes a simple type whose values are whitespace separated
+// lists of items of another simple type.
+type List struct {
+	XMLName    xml.Name    `xml:"list"`
+	ItemType   string      `xml:"itemType,attr"`
+	SimpleType *SimpleType `xml:"simpleType"`
 }
 
 // RestrictionAttr is a "meta" WSDL element it belongs to a Restriction and marks a an arbitrary value with its key "ref"
@@ -109,42 +354,83 @@ func (a *RestrictionAttr) UnmarshalXML(d *xml.Decoder, start xml.StartElement) e
 // Restriction describes the WSDL type of the simple or complex content type and
 // optionally its allowed values.
 type Restriction struct {
-	XMLName   xml.Name         `xml:"restriction"`
-	Base      string           `xml:"base,attr"`
-	Enum      []*Enum          `xml:"enumeration"`
-	Attribute *RestrictionAttr `xml:"attribute"`
+	XMLName        xml.Name         `xml:"restriction"`
+	Base           string           `xml:"base,attr"`
+	Enum           []*Enum          `xml:"enumeration"`
+	Attribute      *RestrictionAttr `xml:"attribute"`
+	Patterns       []*Facet         `xml:"pattern"`
+	Length         *Facet           `xml:"length"`
+	MinLength      *Facet           `xml:"minLength"`
+	MaxLength      *Facet           `xml:"maxLength"`
+	MinInclusive   *Facet           `xml:"minInclusive"`
+	MaxInclusive   *Facet           `xml:"maxInclusive"`
+	MinExclusive   *Facet           `xml:"minExclusive"`
+	MaxExclusive   *Facet           `xml:"maxExclusive"`
+	TotalDigits    *Facet           `xml:"totalDigits"`
+	FractionDigits *Facet           `xml:"fractionDigits"`
+	WhiteSpace     *Facet           `xml:"whiteSpace"` // preserve, replace or collapse
+}
+
+// Facet describes a constraining facet of a Restriction, such as its
+// maximum length or the pattern its values must match.
+type Facet struct {
+	Value string `xml:"value,attr"`
+	Fixed bool   `xml:"fixed,attr"`
 }
 
 // Enum describes one possible value for a Restriction.
 type Enum struct {
 	XMLName xml.Name `xml:"enumeration"`
 	Value   string   `xml:"value,attr"`
+	Doc     string   `xml:"annotation>documentation"`
 }
 
 // ComplexType describes a complex type, such as a struct.
 type ComplexType struct {
-	XMLName        xml.Name        `xml:"complexType"`
-	Name           string          `xml:"name,attr"`
-	Abstract       bool            `xml:"abstract,attr"`
-	Doc            string          `xml:"annotation>documentation"`
-	AllElements    []*Element      `xml:"all>element"`
-	ComplexContent *ComplexContent `xml:"complexContent"`
-	Sequence       *Sequence       `xml:"sequence"`
+	XMLName         xml.Name          `xml:"complexType"`
+	Name            string            `xml:"name,attr"`
+	Abstract        bool              `xml:"abstract,attr"`
+	Mixed           bool              `xml:"mixed,attr"`
+	Doc             string            `xml:"annotation>documentation"`
+	AppInfo         []*AppInfo        `xml:"annotation>appinfo"`
+	AllElements     []*Element        `xml:"all>element"`
+	ComplexContent  *ComplexContent   `xml:"complexContent"`
+	SimpleContent   *SimpleContent    `xml:"simpleContent"`
+	Sequence        *Sequence         `xml:"sequence"`
+	Choice          *Choice           `xml:"choice"`
+	Group           *Group            `xml:"group"`
+	Attributes      []*Attribute      `xml:"attribute"`
+	AttributeGroups []*AttributeGroup `xml:"attributeGroup"`
+	AnyAttribute    *AnyAttribute     `xml:"anyAttribute"`
 }
 
 // ComplexContent describes complex content within a complex type. Usually
 // for extending the complex type with fields from the complex content.
 type ComplexContent struct {
 	XMLName     xml.Name     `xml:"complexContent"`
+	Mixed       bool         `xml:"mixed,attr"`
 	Extension   *Extension   `xml:"extension"`
 	Restriction *Restriction `xml:"restriction"`
 }
 
-// Extension describes a complex content extension.
+// SimpleContent describes simple content within a complex type, that
+// is, a text body optionally carrying attributes.
+type SimpleContent struct {
+	XMLName     xml.Name     `xml:"simpleContent"`
+	Extension   *Extension   `xml:"extension"`
+	Restriction *Restriction `xml:"restriction"`
+}
+
+// Extension describes a complex or simple content extension.
 type Extension struct {
-	XMLName  xml.Name  `xml:"extension"`
-	Base     string    `xml:"base,attr"`
-	Sequence *Sequence `xml:"sequence"`
+	XMLName         xml.Name          `xml:"extension"`
+	Base            string            `xml:"base,attr"`
+	Sequence        *Sequence         `xml:"sequence"`
+	Choice          *Choice           `xml:"choice"`
+	Group           *Group            `xml:"group"`
+	Attributes      []*Attribute      `xml:"attribute"`
+	AttributeGroups []*AttributeGroup `xml:"attributeGroup"`
+	AnyAttribute    *AnyAttribute     `xml:"anyAttribute"`
 }
 
 // Sequence describes a list of elements (parameters) of a type.
@@ -152,7 +438,142 @@ type Sequence struct {
 	XMLName      xml.Name       `xml:"sequence"`
 	ComplexTypes []*ComplexType `xml:"complexType"`
 	Elements     []*Element     `xml:"element"`
+	Choices      []*Choice      `xml:"choice"`
+	Groups       []*Group       `xml:"group"`
 	Any          []*AnyElement  `xml:"any"`
+
+	particles []interface{} // in document order
+}
+
+// UnmarshalXML implements the xml.Unmarshaler interface. The order of
+// the particles of the sequence is kept, for Particles.
+func (s *Sequence) UnmarshalXML(d *xml.Decoder, start xml.StartElement) error {
+	*s = Sequence{XMLName: start.Name}
+	for {
+		t, err := d.Token()
+		if err != nil {
+			return err
+		}
+		switch t := t.(type) {
+		case xml.EndElement:
+			return nil
+		case xml.StartElement:
+			var p interface{}
+			switch t.Name.Local {
+			case "complexType":
+				ct := &ComplexType{}
+				err = d.DecodeElement(ct, &t)
+				s.ComplexTypes = append(s.ComplexTypes, ct)
+			case "element":
+				el := &Element{}
+				err = d.DecodeElement(el, &t)
+				s.Elements = append(s.Elements, el)
+				p = el
+			case "choice":
+				ch := &Choice{}
+				err = d.DecodeElement(ch, &t)
+				s.Choices = append(s.Choices, ch)
+				p = ch
+			case "group":
+				g := &Group{}
+				err = d.DecodeElement(g, &t)
+				s.Groups = append(s.Groups, g)
+				p = g
+			case "any":
+				a := &AnyElement{}
+				err = d.DecodeElement(a, &t)
+				s.Any = append(s.Any, a)
+				p = a
+			default:
+				err = d.Skip()
+			}
+			if err != nil {
+				return err
+			}
+			if p != nil {
+				s.particles = append(s.particles, p)
+			}
+		}
+	}
+}
+
+// Particles returns the elements, choices, group references and
+// wildcards of s, each an *Element, *Choice, *Group or *AnyElement, in
+// the order of the schema. Those of a sequence that was not read from
+// a schema are in the order of its fields.
+func (s *Sequence) Particles() []interface{} {
+	if s.particles != nil {
+		return s.particles
+	}
+	var particles []interface{}
+	for _, el := range s.Elements {
+		particles = append(particles, el)
+	}
+	for _, ch := range s.Choices {
+		particles = append(particles, ch)
+	}
+	for _, g := range s.Groups {
+		particles = append(particles, g)
+	}
+	for _, a := range s.Any {
+		particles = append(particles, a)
+	}
+	return particles
+}
+
+// Group describes a named group of elements at schema level, or a
+// reference to one within a complex type.
+type Group struct {
+	XMLName     xml.Name   `xml:"group"`
+	Name        string     `xml:"name,attr"`
+	Ref         string     `xml:"ref,attr"`
+	Min         int        `xml:"minOccurs,attr"`
+	Max         string     `xml:"maxOccurs,attr"` // can be # or unbounded
+	AllElements []*Element `xml:"all>element"`
+	Sequence    *Sequence  `xml:"sequence"`
+	Choice      *Choice    `xml:"choice"`
+}
+
+// Attribute describes an attribute of a complex type.
+type Attribute struct {
+	XMLName xml.Name   `xml:"attribute"`
+	Name    string     `xml:"name,attr"`
+	Ref     string     `xml:"ref,attr"`
+	Type    string     `xml:"type,attr"`
+	Use     string     `xml:"use,attr"` // optional, required or prohibited
+	Default string     `xml:"default,attr"`
+	Fixed   string     `xml:"fixed,attr"`
+	Form    string     `xml:"form,attr"` // qualified or unqualified
+	Doc     string     `xml:"annotation>documentation"`
+	AppInfo []*AppInfo `xml:"annotation>appinfo"`
+}
+
+// AnyAttribute describes a wildcard that allows attributes not declared
+// by the type.
+type AnyAttribute struct {
+	XMLName         xml.Name `xml:"anyAttribute"`
+	Namespace       string   `xml:"namespace,attr"`
+	ProcessContents string   `xml:"processContents,attr"`
+}
+
+// AttributeGroup describes a named group of attributes at schema level,
+// or a reference to one within a complex type.
+type AttributeGroup struct {
+	XMLName         xml.Name          `xml:"attributeGroup"`
+	Name            string            `xml:"name,attr"`
+	Ref             string            `xml:"ref,attr"`
+	Attributes      []*Attribute      `xml:"attribute"`
+	AttributeGroups []*AttributeGroup `xml:"attributeGroup"`
+	AnyAttribute    *AnyAttribute     `xml:"anyAttribute"`
+}
+
+// Choice describes a list of elements of which only one may be present.
+type Choice struct {
+	XMLName  xml.Name   `xml:"choice"`
+	Min      int        `xml:"minOccurs,attr"`
+	Max      string     `xml:"maxOccurs,attr"` // can be # or unbounded
+	Elements []*Element `xml:"element"`
+	Groups   []*Group   `xml:"group"`
 }
 
 // Element describes an element of a given type.
@@ -161,19 +582,55 @@ type Element struct {
 	Name        string       `xml:"name,attr"`
 	Ref         string       `xml:"ref,attr"`
 	Type        string       `xml:"type,attr"`
-	Min         int          `xml:"minOccurs,attr"`
+	Min         int          `xml:"minOccurs,attr"` // 1 if not set
 	Max         string       `xml:"maxOccurs,attr"` // can be # or unbounded
 	Nillable    bool         `xml:"nillable,attr"`
+	Abstract    bool         `xml:"abstract,attr"`
+	Default     string       `xml:"default,attr"`
+	Fixed       string       `xml:"fixed,attr"`
+	Form        string       `xml:"form,attr"` // qualified or unqualified
+	Doc         string       `xml:"annotation>documentation"`
+	AppInfo     []*AppInfo   `xml:"annotation>appinfo"`
 	ComplexType *ComplexType `xml:"complexType"`
+
+	// SubstitutionGroup is the head element this element can
+	// substitute for.
+	SubstitutionGroup string `xml:"substitutionGroup,attr"`
+}
+
+// UnmarshalXML implements the xml.Unmarshaler interface. The minimum
+// number of occurrences defaults to 1, as in XML Schema.
+func (el *Element) UnmarshalXML(d *xml.Decoder, start xml.StartElement) error {
+	type element Element
+	v := element{Min: 1}
+	err := d.DecodeElement(&v, &start)
+	if err != nil {
+		return err
+	}
+	*el = Element(v)
+	return nil
 }
 
 // AnyElement describes an element of an undefined type.
 type AnyElement struct {
 	XMLName xml.Name `xml:"any"`
-	Min     int      `xml:"minOccurs,attr"`
+	Min     int      `xml:"minOccurs,attr"` // 1 if not set
 	Max     string   `xml:"maxOccurs,attr"` // can be # or unbounded
 }
 
+// UnmarshalXML implements the xml.Unmarshaler interface. The minimum
+// number of occurrences defaults to 1, as in XML Schema.
+func (a *AnyElement) UnmarshalXML(d *xml.Decoder, start xml.StartElement) error {
+	type anyElement AnyElement
+	v := anyElement{Min: 1}
+	err := d.DecodeElement(&v, &start)
+	if err != nil {
+		return err
+	}
+	*a = AnyElement(v)
+	return nil
+}
+
 // Import points to another WSDL to be imported at root level.
 type Import struct {
 	XMLName   xml.Name `xml:"import"`
@@ -188,11 +645,19 @@ type ImportSchema struct {
 	Location  string   `xml:"schemaLocation,attr"`
 }
 
+// IncludeSchema points to a schema document of the same target
+// namespace to be included at schema level.
+type IncludeSchema struct {
+	XMLName  xml.Name `xml:"include"`
+	Location string   `xml:"schemaLocation,attr"`
+}
+
 // Message describes the data being communicated, such as functions
 // and their parameters.
 type Message struct {
 	XMLName xml.Name `xml:"message"`
 	Name    string   `xml:"name,attr"`
+	Doc     string   `xml:"documentation"`
 	Parts   []*Part  `xml:"part"`
 }
 
@@ -219,6 +684,14 @@ type Operation struct {
 	Doc            string   `xml:"documentation"`
 	Input          *IO      `xml:"input"`
 	Output         *IO      `xml:"output"`
+	Faults         []*Fault `xml:"fault"`
+}
+
+// Fault describes a fault message an operation may return instead of
+// its output.
+type Fault struct {
+	Name    string `xml:"name,attr"`
+	Message string `xml:"message,attr"`
 }
 
 // IO describes which message is linked to an operation, for input
@@ -226,14 +699,72 @@ type Operation struct {
 type IO struct {
 	XMLName xml.Name
 	Message string `xml:"message,attr"`
+
+	// Action is the WS-Addressing action of the message, given by the
+	// wsaw:Action or wsam:Action attribute.
+	Action string `xml:"Action,attr"`
 }
 
 // Binding describes SOAP to WSDL binding.
 type Binding struct {
-	XMLName    xml.Name            `xml:"binding"`
-	Name       string              `xml:"name,attr"`
-	Type       string              `xml:"type,attr"`
-	Operations []*BindingOperation `xml:"operation"`
+	XMLName     xml.Name            `xml:"binding"`
+	Name        string              `xml:"name,attr"`
+	Type        string              `xml:"type,attr"`
+	HTTPBinding *HTTPBinding        `xml:"http://schemas.xmlsoap.org/wsdl/http/ binding"`
+	SOAPBinding *SOAPBinding        `xml:"binding"`
+	Operations  []*BindingOperation `xml:"operation"`
+
+	// WS-Policy attached to the binding, inline or by reference.
+	Policies         []*Policy          `xml:"Policy"`
+	PolicyReferences []*PolicyReference `xml:"PolicyReference"`
+}
+
+// HTTPBinding describes the HTTP verb used by the operations of a
+// binding to plain HTTP, without SOAP.
+type HTTPBinding struct {
+	XMLName xml.Name `xml:"http://schemas.xmlsoap.org/wsdl/http/ binding"`
+	Verb    string   `xml:"verb,attr"` // GET or POST
+}
+
+// Transport returns the transport URI of the binding, or an empty
+// string if not defined.
+func (b *Binding) Transport() string {
+	if b.SOAPBinding == nil {
+		return ""
+	}
+	return b.SOAPBinding.Transport
+}
+
+// SOAPVersion returns the version of SOAP used by the binding, 1.1 or
+// 1.2, or an empty string if it is not a SOAP binding.
+func (b *Binding) SOAPVersion() string {
+	if b.SOAPBinding == nil {
+		return ""
+	}
+	switch b.SOAPBinding.XMLName.Space {
+	case HTTPBindingNamespace:
+		return ""
+	case SOAP12BindingNamespace, "soap12":
+		return "1.2"
+	}
+	// including documents that use the soap prefix without declaring it
+	return "1.1"
+}
+
+// Namespaces of the binding extensions of WSDL.
+const (
+	SOAP11BindingNamespace = "http://schemas.xmlsoap.org/wsdl/soap/"
+	SOAP12BindingNamespace = "http://schemas.xmlsoap.org/wsdl/soap12/"
+	HTTPBindingNamespace   = "http://schemas.xmlsoap.org/wsdl/http/"
+)
+
+// SOAPBinding describes the transport and default style of the
+// operations of a binding. Its XMLName tells whether it is a
+// soap:binding or a soap12:binding.
+type SOAPBinding struct {
+	XMLName   xml.Name `xml:"binding"`
+	Style     string   `xml:"style,attr"`
+	Transport string   `xml:"transport,attr"`
 }
 
 // BindingOperation describes the requirement for binding SOAP to WSDL
@@ -241,18 +772,194 @@ type Binding struct {
 type BindingOperation struct {
 	XMLName   xml.Name       `xml:"operation"`
 	Name      string         `xml:"name,attr"`
+	HTTP      *HTTPOperation `xml:"http://schemas.xmlsoap.org/wsdl/http/ operation"`
 	Operation *SoapOperation `xml:"operation"`
 	Input     *BindingIO     `xml:"input>body"`
 	Output    *BindingIO     `xml:"output>body"`
+
+	// SOAP header blocks of the input and output.
+	InputHeaders  []*SOAPHeader `xml:"input>header"`
+	OutputHeaders []*SOAPHeader `xml:"output>header"`
+
+	Faults []*BindingFault `xml:"fault"`
+
+	// WS-Policy attached to the operation, inline or by reference.
+	Policies         []*Policy          `xml:"Policy"`
+	PolicyReferences []*PolicyReference `xml:"PolicyReference"`
+
+	// URLEncoded and URLReplacement tell how the input parts of
+	// operations of HTTP bindings are sent: in the query string, or
+	// in place of (part) placeholders of the operation location.
+	URLEncoded     *struct{} `xml:"input>urlEncoded"`
+	URLReplacement *struct{} `xml:"input>urlReplacement"`
+
+	// MIME bindings of the input and output, as used by HTTP bindings
+	// and by SOAP messages with attachments. See InputMIME and
+	// OutputMIME.
+	InputMultipart  *MultipartRelated `xml:"input>multipartRelated"`
+	InputContents   []*MIMEContent    `xml:"input>content"`
+	InputMimeXML    *MIMEXML          `xml:"input>mimeXml"`
+	OutputMultipart *MultipartRelated `xml:"output>multipartRelated"`
+	OutputContents  []*MIMEContent    `xml:"output>content"`
+	OutputMimeXML   *MIMEXML          `xml:"output>mimeXml"`
+}
+
+// InputMIME returns the MIME binding of the input, or nil if the input
+// is not bound to MIME.
+func (bo *BindingOperation) InputMIME() *MIME {
+	return newMIME(bo.InputMultipart, bo.InputContents, bo.InputMimeXML)
+}
+
+// OutputMIME returns the MIME binding of the output, or nil if the
+// output is not bound to MIME.
+func (bo *BindingOperation) OutputMIME() *MIME {
+	return newMIME(bo.OutputMultipart, bo.OutputContents, bo.OutputMimeXML)
+}
+
+func newMIME(mp *MultipartRelated, contents []*MIMEContent, mx *MIMEXML) *MIME {
+	if mp == nil && len(contents) == 0 && mx == nil {
+		return nil
+	}
+	return &MIME{MultipartRelated: mp, Contents: contents, MimeXML: mx}
+}
+
+// MIME describes how the input or output of an operation is bound to
+// MIME: as a multipart/related message, as content of the given types,
+// or as XML without SOAP envelope.
+type MIME struct {
+	MultipartRelated *MultipartRelated
+	Contents         []*MIMEContent
+	MimeXML          *MIMEXML
+}
+
+// MultipartRelated describes a multipart/related MIME message, such as
+// a SOAP message with attachments.
+type MultipartRelated struct {
+	Parts []*MIMEPart `xml:"part"`
+}
+
+// MIMEPart describes a part of a multipart/related MIME message. It is
+// either the SOAP body, or content of the given types.
+type MIMEPart struct {
+	Name     string         `xml:"name,attr"`
+	Body     *BindingIO     `xml:"body"`
+	Contents []*MIMEContent `xml:"content"`
+}
+
+// MIMEContent describes the content type of a message part. An empty
+// Part refers to the whole message.
+type MIMEContent struct {
+	Part string `xml:"part,attr"`
+	Type string `xml:"type,attr"`
+}
+
+// MIMEXML describes a message part sent as XML without SOAP envelope.
+type MIMEXML struct {
+	Part string `xml:"part,attr"`
+}
+
+// HTTPOperation describes the location of an operation of an HTTP
+// binding, relative to the address of the port.
+type HTTPOperation struct {
+	Location string `xml:"location,attr"`
 }
 
 // A number of SOAP servers do additional routing via this header
 type SoapOperation struct {
 	SoapAction string `xml:"soapAction,attr"`
+
+	// Style overrides the style of the SOAP binding, if set.
+	Style string `xml:"style,attr"`
+
+	// SoapActionRequired is only defined by SOAP 1.2 bindings.
+	SoapActionRequired bool `xml:"soapActionRequired,attr"`
 }
 
 // BindingIO describes the IO binding of SOAP operations. See IO for details.
 type BindingIO struct {
-	Parts string `xml:"parts,attr"`
-	Use   string `xml:"use,attr"`
+	Parts         string `xml:"parts,attr"`
+	Use           string `xml:"use,attr"`
+	Namespace     string `xml:"namespace,attr"`
+	EncodingStyle string `xml:"encodingStyle,attr"`
+}
+
+// FaultByName returns the binding of the fault of the given name, or
+// nil if not found.
+func (bo *BindingOperation) FaultByName(name string) *BindingFault {
+	for _, f := range bo.Faults {
+		if f.Name == name {
+			return f
+		}
+	}
+	return nil
+}
+
+// BindingFault describes how a fault of an operation is bound to SOAP.
+// Its name matches the name of a fault of the port type operation.
+type BindingFault struct {
+	Name string     `xml:"name,attr"`
+	SOAP *SOAPFault `xml:"fault"`
+}
+
+// SOAPFault describes the content of the SOAP Fault detail element of
+// a fault.
+type SOAPFault struct {
+	Name          string `xml:"name,attr"`
+	Use           string `xml:"use,attr"`
+	Namespace     string `xml:"namespace,attr"`
+	EncodingStyle string `xml:"encodingStyle,attr"`
+}
+
+// SOAPHeader describes a SOAP header block of the input or output of a
+// binding operation: the message part to be sent as the header block.
+type SOAPHeader struct {
+	Message       string `xml:"message,attr"`
+	Part          string `xml:"part,attr"`
+	Use           string `xml:"use,attr"`
+	Namespace     string `xml:"namespace,attr"`
+	EncodingStyle string `xml:"encodingStyle,attr"`
+}
+
+// Namespaces of WS-Policy 1.2 and 1.5.
+const (
+	PolicyNamespace12 = "http://schemas.xmlsoap.org/ws/2004/09/policy"
+	PolicyNamespace15 = "http://www.w3.org/ns/ws-policy"
+)
+
+// Policy is a WS-Policy: a tree of policy operators, such as ExactlyOne
+// and All, and of assertions of domains such as WS-SecurityPolicy and
+// WS-Addressing.
+type Policy struct {
+	ID         string             `xml:"Id,attr"`
+	Name       string             `xml:"Name,attr"`
+	Assertions []*PolicyAssertion `xml:",any"`
+}
+
+// Contains returns whether the policy has an assertion of the given
+// local name, at any depth, regardless of its namespace.
+func (p *Policy) Contains(local string) bool {
+	return containsAssertion(p.Assertions, local)
+}
+
+func containsAssertion(assertions []*PolicyAssertion, local string) bool {
+	for _, a := range assertions {
+		if a.XMLName.Local == local || containsAssertion(a.Assertions, local) {
+			return true
+		}
+	}
+	return false
+}
+
+// PolicyAssertion is a policy operator or assertion, with the operators
+// and assertions nested in it.
+type PolicyAssertion struct {
+	XMLName    xml.Name
+	Optional   bool               `xml:"Optional,attr"`
+	Attrs      []xml.Attr         `xml:",any,attr"`
+	Assertions []*PolicyAssertion `xml:",any"`
+}
+
+// PolicyReference refers to a policy by URI.
+type PolicyReference struct {
+	URI string `xml:"URI,attr"`
 }
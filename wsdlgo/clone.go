@@ -0,0 +1,109 @@
+package wsdlgo
+
+import (
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+)
+
+func (ge *goEncoder) SetClone(clone bool) {
+	ge.clone = clone
+}
+
+// bigTypes are the types of math/big, whose values share their digits
+// when copied.
+var bigTypes = map[string]bool{
+	"big.Int":   true,
+	"big.Float": true,
+	"big.Rat":   true,
+}
+
+// cacheCloneTypes records the structs generated with a Clone method,
+// if enabled: those of the complex types but abstract ones, those
+// having a field named Clone, and arrays, which are recorded as slices.
+func (ge *goEncoder) cacheCloneTypes() {
+	if !ge.clone {
+		return
+	}
+	if ge.cloneTypes == nil {
+		ge.cloneTypes = make(map[string]bool)
+	}
+	ge.cloneSlices = map[string]bool{"Base64Binary": true, "HexBinary": true}
+	for name, ct := range ge.ctypes {
+		if isArray(ct) && !ge.isCachedTypeMapped(name) {
+			ge.cloneSlices[ge.exportedName(name)] = true
+		}
+	}
+	for name, fields := range ge.structFields {
+		if !fields["Clone"] {
+			ge.cloneTypes[name] = true
+		}
+	}
+}
+
+// genClone writes the Clone method of the struct being generated, if
+// it has one, which copies the struct and the values its fields refer
+// to, such as slices and the values of pointers, recursively.
+func (ge *goEncoder) genClone(w io.Writer, name string) {
+	if !ge.clone || !ge.cloneTypes[name] {
+		return
+	}
+	fmt.Fprintf(w, "// Clone returns a deep copy of t, or nil if t is nil. Values held by\n")
+	fmt.Fprintf(w, "// interfaces are shared with t.\n")
+	fmt.Fprintf(w, "func (t *%s) Clone() *%s {\n", name, name)
+	fmt.Fprintf(w, "if t == nil {\nreturn nil\n}\n")
+	fmt.Fprintf(w, "c := *t\n")
+	for _, f := range ge.fields {
+		io.WriteString(w, ge.cloneStmt("c."+f.Name, f.Type, 0))
+	}
+	fmt.Fprintf(w, "return &c\n}\n\n")
+}
+
+// cloneStmt returns the statements replacing x, an expression of Go type
+// typ, by a deep copy of its value, or "" if its value is copied by
+// assignment. Their variables are suffixed by depth, the number of
+// enclosing statements, but for the outermost ones.
+func (ge *goEncoder) cloneStmt(x, typ string, depth int) string {
+	suffix := ""
+	if depth > 0 {
+		suffix = strconv.Itoa(depth)
+	}
+	switch {
+	case strings.HasPrefix(typ, "[]") || ge.cloneSlices[typ]:
+		elem := strings.TrimPrefix(typ, "[]")
+		if elem == typ {
+			elem = ""
+		}
+		s := fmt.Sprintf("%s = append(%s{}, %s...)\n", x, typ, x)
+		i := "i" + suffix
+		if e := ge.cloneStmt(x+"["+i+"]", elem, depth+1); e != "" {
+			s += fmt.Sprintf("for %s := range %s {\n%s}\n", i, x, e)
+		}
+		return fmt.Sprintf("if %s != nil {\n%s}\n", x, s)
+	case strings.HasPrefix(typ, "*"):
+		elem := typ[1:]
+		if ge.cloneTypes[elem] {
+			return fmt.Sprintf("%s = %s.Clone()\n", x, x)
+		}
+		if bigTypes[elem] {
+			ge.needsStdPkg["math/big"] = true
+			return fmt.Sprintf("if %s != nil {\n%s = new(%s).Set(%s)\n}\n", x, x, elem, x)
+		}
+		v := "v" + suffix
+		return fmt.Sprintf("if %s != nil {\n%s := *%s\n%s%s = &%s\n}\n",
+			x, v, x, ge.cloneStmt(v, elem, depth+1), x, v)
+	case bigTypes[typ]:
+		ge.needsStdPkg["math/big"] = true
+		return fmt.Sprintf("%s = *new(%s).Set(&%s)\n", x, typ, x)
+	case typ == "soap.RawElement":
+		ge.needsStdPkg["encoding/xml"] = true
+		return ge.cloneStmt(x+".Attrs", "[]xml.Attr", depth)
+	case typ == "Decimal":
+		return ge.cloneStmt(x+".Rat", "big.Rat", depth)
+	case ge.embeddedType(typ) != "":
+		base := ge.embeddedType(typ)
+		return ge.cloneStmt(x+"."+base[strings.LastIndex(base, ".")+1:], base, depth)
+	}
+	return ""
+}
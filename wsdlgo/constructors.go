@@ -0,0 +1,92 @@
+package wsdlgo
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"text/template"
+
+	"github.com/seamuncle/wsdl2go/wsdl"
+)
+
+func (ge *goEncoder) SetConstructors(constructors bool) {
+	ge.constructors = constructors
+}
+
+var constructorT = template.Must(template.New("constructor").Parse(`
+{{.Doc}}func {{.Func}}({{range $i, $p := .Params}}{{if $i}}, {{end}}{{$p.Name}} {{$p.Type}}{{end}}) *{{.Name}} {
+{{- if .Defaults}}
+	t := &{{.Name}}{}
+	t.SetDefaults()
+	{{- range .Params}}
+	t.{{.Field}} = {{.Name}}
+	{{- end}}
+	return t
+{{- else}}
+	return &{{.Name}}{
+	{{- range .Params}}
+		{{.Field}}: {{.Name}},
+	{{- end}}
+	}
+{{- end}}
+}
+
+`))
+
+// constructorParam is a parameter of a constructor, setting a field.
+type constructorParam struct {
+	Name  string
+	Type  string
+	Field string
+}
+
+// genConstructor writes the constructor of the struct being generated,
+// if enabled, which takes the values of its required fields and sets
+// the defaults of the others. It is named after the struct prefixed by
+// New, followed by a number if that is taken by a type or by the
+// constructor of a client.
+func (ge *goEncoder) genConstructor(w io.Writer, d *wsdl.Definitions, name string) {
+	if !ge.constructors {
+		return
+	}
+	taken := func(fn string) bool {
+		if ge.typeExists(fn) {
+			return true
+		}
+		for _, pt := range d.PortTypes {
+			if fn == "New"+ge.interfaceName(pt) {
+				return true
+			}
+		}
+		return false
+	}
+	fn := uniqueName("New"+name, taken)
+	if fn != "New"+name {
+		ge.rename("constructor of %s declared as %s, as New%s is taken", name, fn, name)
+	}
+	names := map[string]bool{"t": true}
+	var params []*constructorParam
+	for _, f := range ge.fields {
+		if !f.Required {
+			continue
+		}
+		p := uniqueName(ge.unexportedName(f.Name), func(p string) bool {
+			return names[p]
+		})
+		names[p] = true
+		params = append(params, &constructorParam{Name: p, Type: f.Type, Field: f.Name})
+	}
+	doc := fmt.Sprintf("%s returns a new %s of the values of its required fields.", fn, name)
+	if len(ge.defaults) > 0 {
+		doc = fmt.Sprintf("%s returns a new %s of the values of its required fields, whose other fields that have a default value in the schema are set to it.", fn, name)
+	}
+	var b bytes.Buffer
+	writeComments(&b, "", doc)
+	constructorT.Execute(w, &struct {
+		Doc      string
+		Func     string
+		Name     string
+		Params   []*constructorParam
+		Defaults bool
+	}{b.String(), fn, name, params, len(ge.defaults) > 0})
+}
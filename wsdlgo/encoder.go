@@ -8,12 +8,18 @@ package wsdlgo
 import (
 	"bufio"
 	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
 	"encoding/xml"
+	"errors"
 	"fmt"
 	"go/parser"
 	"go/token"
 	"io"
+	"io/ioutil"
+	"math"
 	"net/http"
+	"net/url"
 	"os"
 	"os/exec"
 	"path/filepath"
@@ -22,6 +28,7 @@ import (
 	"strconv"
 	"strings"
 	"text/template"
+	"unicode"
 
 	"github.com/seamuncle/wsdl2go/wsdl"
 )
@@ -31,12 +38,197 @@ type Encoder interface {
 	// Encode generates Go code from d.
 	Encode(d *wsdl.Definitions) error
 
+	// EncodeFiles generates Go code from d like Encode, but split
+	// into files by kind of declaration, and returns the content of
+	// each by file name, rather than writing it. Types go in
+	// types.go, enumerated simple types in enums.go, the types of
+	// the elements and header blocks of messages in messages.go, and
+	// the service interfaces and their implementation in client.go.
+	// Types and enumerations of namespaces other than the main one
+	// go in a file named after their namespace.
+	EncodeFiles(d *wsdl.Definitions) (map[string][]byte, error)
+
+	// SetPackages makes EncodeFiles generate a package per target
+	// namespace of the schemas rather than a single one, for the
+	// directory of the given import path: the package of the main
+	// namespace, with the service interfaces, in the directory
+	// itself, and the others in directories named after their
+	// namespace below it, with file names preceded by the directory.
+	// Types refer to those of other namespaces through their
+	// package, so that types of the same name in different
+	// namespaces keep it. Encode can not generate packages.
+	SetPackages(importPath string)
+
+	// EncodeSamples returns a sample request of each operation of the
+	// SOAP bindings, by file name, rather than code: a SOAP envelope
+	// holding the header blocks and body parts of its input, whose
+	// elements and attributes have placeholder values satisfying the
+	// enumerations, lengths and ranges of their types, much like the
+	// requests of SoapUI. Files are named after the operation followed
+	// by .xml, prefixed by the port type if the name is taken.
+	EncodeSamples(d *wsdl.Definitions) (map[string][]byte, error)
+
 	// SetClient records the given http client that
 	// is used when fetching remote parts of WSDL
 	// and WSDL schemas.
 	SetClient(c *http.Client)
+
+	// SetBinding restricts code generation to the binding with
+	// the given name. By default the first SOAP binding of each
+	// port type is used, or its first binding if none is SOAP.
+	SetBinding(name string)
+
+	// SetService restricts code generation to the bindings used
+	// by the ports of the service with the given name.
+	SetService(name string)
+
+	// SetPort restricts code generation to the binding used by
+	// the port with the given name. If a service is set, the port
+	// is looked up in that service only.
+	SetPort(name string)
+
+	// SetTransport restricts code generation to the bindings that
+	// use the given transport URI, such as
+	// http://schemas.xmlsoap.org/soap/http.
+	SetTransport(uri string)
+
+	// SetLocation records the file path or URL the definitions
+	// were loaded from. Relative import locations are resolved
+	// against it, or against the working directory if not set.
+	SetLocation(location string)
+
+	// SetCache stores the documents downloaded for imports in dir,
+	// keyed by URL, and reuses them in later runs. If refresh is
+	// true, documents are downloaded again and the cache updated.
+	SetCache(dir string, refresh bool)
+
+	// SetOmitEmpty declares the fields of optional elements of
+	// basic types as values tagged omitempty, rather than as
+	// pointers. Zero values of such fields are not encoded.
+	SetOmitEmpty(omitEmpty bool)
+
+	// SetBigIntegers maps xsd:integer and the integer types derived
+	// from it that have no bounds, such as xsd:positiveInteger, to
+	// math/big.Int rather than to int or uint.
+	SetBigIntegers(big bool)
+
+	// SetTypeMap maps schema types to the given Go types, rather
+	// than to the types they would be mapped to or generated for.
+	// Types are named by their local name, or by their namespace in
+	// braces followed by it, e.g. {urn:vendor}GUID. Go types are
+	// named by the path of their package followed by their name,
+	// e.g. github.com/google/uuid.UUID; the package name must be the
+	// last element of its path.
+	SetTypeMap(types map[string]string)
+
+	// SetDecimal maps xsd:decimal to the Go type of the given
+	// mapping, one of the Decimal constants. The simple types named
+	// in types that restrict xsd:decimal are mapped to the mapping
+	// given for them instead. The default mapping is DecimalBigRat.
+	SetDecimal(mapping string, types map[string]string)
+
+	// SetNaming sets the strategy of naming Go identifiers after
+	// schema names, one of the Naming constants, and the acronyms
+	// written in upper case in them, or DefaultAcronyms if nil. The
+	// default strategy is NamingCamelCase.
+	SetNaming(strategy string, acronyms []string)
+
+	// SetTags sets the struct tags, other than xml, of the fields of
+	// generated types: json, yaml or bson, or DefaultTags if nil. Their
+	// values name elements and attributes following the given
+	// convention, one of the TagNaming constants. The default
+	// convention is TagNamingSchema.
+	SetTags(tags []string, naming string)
+
+	// SetGetters generates a GetX method for each pointer field X of
+	// the generated structs, which returns the value it points to, or
+	// the field itself if it points to a struct, and the zero value of
+	// that type if the field or the struct is nil.
+	SetGetters(getters bool)
+
+	// SetStringers generates String methods for enumerated simple
+	// types, returning their value as written in XML, and for the
+	// structs of message parts, returning their fields as formatted
+	// by soap.Format, for logging.
+	SetStringers(stringers bool)
+
+	// SetClone generates a Clone method for each generated struct,
+	// which returns a deep copy of it, so that a template of a request
+	// can be copied and changed without changing the template.
+	SetClone(clone bool)
+
+	// SetValidate generates a Validate method returning an error for
+	// each generated struct, and for each simple type restricted by
+	// facets, such as patterns, lengths and ranges, which checks them.
+	// Those of structs validate the values of their fields in turn.
+	// Enumerations have one too, rather than the one returning a bool.
+	SetValidate(validate bool)
+
+	// SetConstructors generates a NewX function for each generated
+	// struct X, which takes the values of its required fields, those
+	// of elements and attributes that must occur, and returns a new X
+	// of them whose other fields are set to their default, if any.
+	SetConstructors(constructors bool)
+
+	// SetMocks generates a mock of the interface of each port type,
+	// named after it prefixed by Mock, whose methods record their
+	// arguments and return the results of funcs set by tests. It
+	// conflicts with the mocks of go-mock, generated by NewEncoder.
+	SetMocks(mocks bool)
+
+	// SetServers generates a NewXServer function for the interface X of
+	// each port type bound to SOAP, which returns a soap.Server of an
+	// implementation of X: an http.Handler dispatching requests to its
+	// methods and encoding their results, or their errors as faults.
+	SetServers(servers bool)
+
+	// SetWSDL sets the WSDL document the definitions were decoded from,
+	// which the servers of SetServers serve to GET requests with a wsdl
+	// query, such as /service?wsdl, for clients to introspect them.
+	SetWSDL(doc []byte)
+
+	// SetNoContext generates the methods of port types without the
+	// context.Context they otherwise take first, as before they took
+	// one, and which the clients pass to the HTTP requests of calls for
+	// their cancellation and deadlines.
+	SetNoContext(noContext bool)
+
+	// SetTests generates a test along with the code, which marshals a
+	// value of each generated struct, its fields set to placeholder
+	// values, to XML and back, and checks that it is unchanged.
+	// EncodeFiles writes it to types_test.go of each package; after
+	// Encode, it is returned by Tests.
+	SetTests(tests bool)
+
+	// Tests returns the code of the test generated by Encode, if
+	// enabled by SetTests and any structs were generated, or nil.
+	Tests() []byte
+
+	// Renamed describes the Go names that Encode changed from the
+	// ones it would otherwise use, to avoid conflicts between types
+	// and interfaces generated for different parts of the
+	// definitions, one per name in a deterministic order.
+	Renamed() []string
 }
 
+// Mappings of xsd:decimal to Go types.
+const (
+	// DecimalBigRat maps decimals to a generated Decimal type,
+	// which holds them exactly in a math/big.Rat.
+	DecimalBigRat = "big.Rat"
+
+	// DecimalShopspring maps decimals to the Decimal type of
+	// github.com/shopspring/decimal.
+	DecimalShopspring = "shopspring"
+
+	// DecimalString maps decimals to strings, as written.
+	DecimalString = "string"
+
+	// DecimalFloat64 maps decimals to float64, which may not hold
+	// them exactly.
+	DecimalFloat64 = "float64"
+)
+
 type goEncoder struct {
 	// where to write Go code
 	w io.Writer
@@ -44,28 +236,240 @@ type goEncoder struct {
 	// http client
 	http *http.Client
 
+	// location of the definitions, and of the schemas loaded from
+	// other documents, to resolve relative imports
+	location   string
+	schemaBase map[*wsdl.Schema]string
+	importBase map[*wsdl.ImportSchema]string
+
+	// directory caching downloaded documents, if any
+	cacheDir     string
+	cacheRefresh bool
+
+	// name of the binding to generate code for, or empty for all
+	binding string
+
+	// name of the service to generate code for, or empty for all
+	service string
+
+	// name of the port to generate code for, or empty for all
+	port string
+
+	// transport URI of the bindings to generate code for, or empty
+	// for any
+	transport string
+
+	// bindings selected for code generation
+	bindings []*wsdl.Binding
+
+	// whether optional elements are declared as omitempty values
+	// rather than pointers
+	omitEmpty bool
+
+	// whether unbounded integers are declared as big.Int
+	bigIntegers bool
+
+	// Go types of schema types, by qualified or local name
+	typeMap map[string]string
+
+	// mapping of xsd:decimal, and of simple types restricting it by
+	// name, to Go types
+	decimal      string
+	decimalTypes map[string]string
+
+	// strategy of naming Go identifiers after schema names, and the
+	// acronyms written in upper case in them, by upper case word
+	naming   string
+	acronyms map[string]string
+
+	// struct tags of fields other than xml, and the convention of
+	// naming their values
+	tags      []string
+	tagNaming string
+
 	// types cache
 	stypes map[string]*wsdl.SimpleType
 	ctypes map[string]*wsdl.ComplexType
 
+	// complex types declared inline by global elements, to be cached
+	// once the named types are
+	inlineTypes []*inlineType
+
+	// names of the complex types declared inline by global elements
+	// that were renamed, by element name, and the other way around
+	elementTypes map[string]string
+	typeElements map[string]string
+
+	// names of the Go interfaces of port types, by port type name
+	interfaces map[string]string
+
+	// descriptions of the Go names changed to avoid conflicts
+	renamed []string
+
+	// import path of the packages generated by EncodeFiles per
+	// namespace, if any, and the name of the package being generated
+	// for one of them, if not the one of the binding
+	packages string
+	pkgName  string
+
+	// complex types of the namespaces of other packages, by qualified
+	// name, for the types of this one extending them
+	foreignTypes map[xml.Name]*inlineType
+
+	// whether to generate the holders of all the types with derived
+	// types, for other packages to refer to
+	allDerived bool
+
+	// target namespaces of the schemas declaring cached types
+	typeNS map[string]string
+
+	// names of the cached types by qualified name, the local names
+	// of those declared in several namespaces, and the local name of
+	// the types renamed for it
+	typeNames map[string]string
+	clashes   map[string]bool
+	typeLocal map[string]string
+
+	// definitions of the cached types, resolving type references of
+	// message parts
+	typeDefs *wsdl.Definitions
+
+	// schema of each type, for the form of its elements and attributes
+	typeSchema map[string]*wsdl.Schema
+
+	// target namespace of global elements and attributes
+	elementNS   map[string]string
+	attributeNS map[string]string
+
+	// schema of the type whose fields are being generated
+	fieldSchema *wsdl.Schema
+
 	// elements cache
 	elements map[string]*wsdl.Element
 
-	// funcs cache
-	funcs     map[string]*wsdl.Operation
-	funcnames []string
+	// global elements that can substitute for a head element, by head name
+	substitutes map[string][]*wsdl.Element
+
+	// complex types derived from a complex type by extension, directly
+	// or not, by base type name
+	derived map[string][]string
+
+	// base types of the fields and parameters declared so far that
+	// have derived types
+	derivedUsed map[string]bool
+
+	// whether the struct being generated has a field matching any element
+	anyElement bool
+
+	// fields of the struct being generated that have a default or fixed value
+	defaults []*fieldDefault
+
+	// fields of the struct being generated of nillable elements
+	nillable []string
+
+	// slice fields of the struct being generated of elements with
+	// bounded occurrences
+	occurs []*fieldOccurs
+
+	// whether to generate getters of pointer fields, and the names of
+	// the fields of the struct being generated and its pointer fields
+	getters      bool
+	fieldNames   map[string]bool
+	fieldGetters []*fieldGetter
+
+	// whether to generate String methods of enumerations and message
+	// structs, and the complex types of message parts
+	stringers    bool
+	messageTypes map[string]bool
+
+	// fields of the struct being generated, and the names of the
+	// fields of the structs of the complex types, by Go name, if
+	// methods need to be left out of those having fields of their name
+	fields       []*structField
+	structFields map[string]map[string]bool
+
+	// restrictions of simple types and the Go type of their base, by
+	// Go name
+	restrictedTypes map[string]*restrictedType
+
+	// whether to generate Clone methods, the structs having one and
+	// the types that are slices
+	clone       bool
+	cloneTypes  map[string]bool
+	cloneSlices map[string]bool
+
+	// whether to generate Validate methods checking facets, and the
+	// types having one
+	validate   bool
+	validTypes map[string]bool
+
+	// whether to generate constructors of structs
+	constructors bool
+
+	// whether to generate mocks of the interfaces of port types
+	mocks bool
+
+	// whether to generate SOAP servers of the interfaces of port types
+	servers bool
+
+	// the WSDL document served by the servers, if any
+	wsdl []byte
+
+	// whether to generate methods of port types without a context
+	noContext bool
+
+	// whether to generate a round trip test, the structs it checks and
+	// its code
+	tests     bool
+	testTypes map[string]bool
+	testCode  []byte
+
+	// global attributes cache
+	attributes map[string]*wsdl.Attribute
+
+	// named element and attribute groups cache, and groups being expanded
+	groups     map[string]*wsdl.Group
+	attrGroups map[string]*wsdl.AttributeGroup
+	expanding  map[string]bool
+
+	// funcs cache, operations of each port type sorted by name
+	funcs map[string][]*wsdl.Operation
+
+	// names of top level funcs already written, for unbound operations
+	stubs map[string]bool
 
 	// messages cache
 	messages map[string]*wsdl.Message
 
-	// soap operations cache
-	soapOps map[string]*wsdl.BindingOperation
+	// soap operations cache, indexed by port type and operation name
+	soapOps map[string]map[string]*wsdl.BindingOperation
+
+	// HTTP verb of port types bound to plain HTTP rather than SOAP
+	httpVerbs map[string]string
+
+	// default style of the operations of SOAP bound port types
+	soapStyles map[string]string
+
+	// version of SOAP of SOAP bound port types, 1.1 or 1.2
+	soapVersions map[string]string
+
+	// names of the SOAP action constants, by port type and operation
+	// name, and the set of them
+	soapActions     map[string]string
+	soapActionNames map[string]bool
+
+	// Go types of the faults of operations, by fault message name, and
+	// the set of their names
+	faultTypes map[string]*faultType
+	faultNames map[string]bool
 
 	// whether to add supporting types
-	needsDateType     bool
-	needsTimeType     bool
-	needsDateTimeType bool
+	needsTimeTypes    map[*timeType]bool
 	needsDurationType bool
+	needsDecimalType  bool
+	needsBase64Type   bool
+	needsHexType      bool
+	needsTypeRegistry bool
 	needsTag          map[string]bool
 	needsStdPkg       map[string]bool
 	needsExtPkg       map[string]bool
@@ -78,19 +482,52 @@ type goEncoder struct {
 // NewEncoder creates and initializes an Encoder that generates code to w.
 func NewEncoder(w io.Writer, genGo, genMock bool) Encoder {
 	return &goEncoder{
-		w:           w,
-		http:        http.DefaultClient,
-		stypes:      make(map[string]*wsdl.SimpleType),
-		ctypes:      make(map[string]*wsdl.ComplexType),
-		elements:    make(map[string]*wsdl.Element),
-		funcs:       make(map[string]*wsdl.Operation),
-		messages:    make(map[string]*wsdl.Message),
-		soapOps:     make(map[string]*wsdl.BindingOperation),
-		needsTag:    make(map[string]bool),
-		needsStdPkg: make(map[string]bool),
-		needsExtPkg: make(map[string]bool),
-		genGo:       genGo,
-		genMock:     genMock,
+		w:      w,
+		http:   http.DefaultClient,
+		stypes: make(map[string]*wsdl.SimpleType),
+		ctypes: make(map[string]*wsdl.ComplexType),
+
+		elementTypes: make(map[string]string),
+		typeElements: make(map[string]string),
+		interfaces:   make(map[string]string),
+
+		typeNS:       make(map[string]string),
+		typeNames:    make(map[string]string),
+		clashes:      make(map[string]bool),
+		typeLocal:    make(map[string]string),
+		typeSchema:   make(map[string]*wsdl.Schema),
+		schemaBase:   make(map[*wsdl.Schema]string),
+		importBase:   make(map[*wsdl.ImportSchema]string),
+		elementNS:    make(map[string]string),
+		attributeNS:  make(map[string]string),
+		elements:     make(map[string]*wsdl.Element),
+		groups:       make(map[string]*wsdl.Group),
+		attributes:   make(map[string]*wsdl.Attribute),
+		substitutes:  make(map[string][]*wsdl.Element),
+		derived:      make(map[string][]string),
+		derivedUsed:  make(map[string]bool),
+		attrGroups:   make(map[string]*wsdl.AttributeGroup),
+		expanding:    make(map[string]bool),
+		funcs:        make(map[string][]*wsdl.Operation),
+		stubs:        make(map[string]bool),
+		messages:     make(map[string]*wsdl.Message),
+		soapOps:      make(map[string]map[string]*wsdl.BindingOperation),
+		httpVerbs:    make(map[string]string),
+		soapStyles:   make(map[string]string),
+		soapVersions: make(map[string]string),
+
+		soapActions:     make(map[string]string),
+		soapActionNames: make(map[string]bool),
+		faultTypes:      make(map[string]*faultType),
+		faultNames:      make(map[string]bool),
+		needsTag:        make(map[string]bool),
+		fieldNames:      make(map[string]bool),
+		needsTimeTypes:  make(map[*timeType]bool),
+		needsStdPkg:     make(map[string]bool),
+		needsExtPkg:     make(map[string]bool),
+		testTypes:       make(map[string]bool),
+		genGo:           genGo,
+		genMock:         genMock,
 	}
 }
 
@@ -98,6 +535,93 @@ func (ge *goEncoder) SetClient(c *http.Client) {
 	ge.http = c
 }
 
+func (ge *goEncoder) SetBinding(name string) {
+	ge.binding = name
+}
+
+func (ge *goEncoder) SetService(name string) {
+	ge.service = name
+}
+
+func (ge *goEncoder) SetPort(name string) {
+	ge.port = name
+}
+
+func (ge *goEncoder) SetTransport(uri string) {
+	ge.transport = uri
+}
+
+func (ge *goEncoder) SetLocation(location string) {
+	ge.location = location
+}
+
+func (ge *goEncoder) SetCache(dir string, refresh bool) {
+	ge.cacheDir = dir
+	ge.cacheRefresh = refresh
+}
+
+func (ge *goEncoder) SetOmitEmpty(omitEmpty bool) {
+	ge.omitEmpty = omitEmpty
+}
+
+func (ge *goEncoder) SetBigIntegers(big bool) {
+	ge.bigIntegers = big
+}
+
+func (ge *goEncoder) SetTypeMap(types map[string]string) {
+	ge.typeMap = types
+}
+
+func (ge *goEncoder) SetDecimal(mapping string, types map[string]string) {
+	ge.decimal = mapping
+	ge.decimalTypes = types
+}
+
+func (ge *goEncoder) SetNoContext(noContext bool) {
+	ge.noContext = noContext
+}
+
+func (ge *goEncoder) Renamed() []string {
+	return ge.renamed
+}
+
+// rename records that a Go name was changed, as described by the
+// given format and arguments.
+func (ge *goEncoder) rename(format string, args ...interface{}) {
+	ge.renamed = append(ge.renamed, fmt.Sprintf(format, args...))
+}
+
+// uniqueName returns name, or name followed by a number from 2 on,
+// whichever is not taken first.
+func uniqueName(name string, taken func(string) bool) string {
+	v := name
+	for i := 2; taken(v); i++ {
+		v = name + strconv.Itoa(i)
+	}
+	return v
+}
+
+// checkDecimal returns an error if a mapping of xsd:decimal is unknown.
+func (ge *goEncoder) checkDecimal() error {
+	names := make([]string, 0, len(ge.decimalTypes))
+	for name := range ge.decimalTypes {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	mappings := []string{ge.decimal}
+	for _, name := range names {
+		mappings = append(mappings, ge.decimalTypes[name])
+	}
+	for _, m := range mappings {
+		switch m {
+		case "", DecimalBigRat, DecimalShopspring, DecimalString, DecimalFloat64:
+		default:
+			return fmt.Errorf("unknown decimal mapping %q", m)
+		}
+	}
+	return nil
+}
+
 func gofmtPath() (string, error) {
 	goroot := os.Getenv("GOROOT")
 	if goroot != "" {
@@ -111,6 +635,9 @@ func (ge *goEncoder) Encode(d *wsdl.Definitions) error {
 	if d == nil {
 		return nil
 	}
+	if ge.packages != "" {
+		return errors.New("packages can only be generated as files")
+	}
 	var b bytes.Buffer
 	err := ge.encode(&b, d)
 	if err != nil {
@@ -153,21 +680,47 @@ func (ge *goEncoder) Encode(d *wsdl.Definitions) error {
 			fmt.Fprintf(&x, "gofmt stderr:\n%s\n", errb.String())
 		}
 		fmt.Fprintf(&x, "generated code:\n%s\n", input)
-		return fmt.Errorf(x.String())
+		return errors.New(x.String())
 	}
 	return nil
 }
 
 func (ge *goEncoder) encode(w io.Writer, d *wsdl.Definitions) error {
-	err := ge.importParts(d)
+	err := ge.checkDecimal()
+	if err != nil {
+		return err
+	}
+	err = ge.checkNaming()
+	if err != nil {
+		return err
+	}
+	err = ge.checkTags()
+	if err != nil {
+		return err
+	}
+	err = ge.checkMocks()
+	if err != nil {
+		return err
+	}
+	err = ge.importParts(d)
 	if err != nil {
 		return fmt.Errorf("wsdl import: %v", err)
 	}
+	err = ge.selectBindings(d)
+	if err != nil {
+		return err
+	}
 	ge.cacheTypes(d)
 	ge.cacheFuncs(d)
 	ge.cacheMessages(d)
 	ge.cacheSOAPOperations(d)
-	pkg := ge.formatPackageName(d.Binding.Name)
+	var pkg string
+	if len(ge.bindings) > 0 {
+		pkg = ge.formatPackageName(ge.bindings[0].Name)
+	}
+	if ge.pkgName != "" {
+		pkg = ge.pkgName
+	}
 	if pkg == "" {
 		pkg = "internal"
 	}
@@ -179,8 +732,12 @@ func (ge *goEncoder) encode(w io.Writer, d *wsdl.Definitions) error {
 				ge.writeNamespace,
 				ge.writeInterfaceFuncs,
 				ge.writeGoTypes,
+				ge.writeSOAPHeaders,
+				ge.writeFaults,
 				ge.writePortType,
 				ge.writeGoFuncs,
+				ge.writeMocks,
+				ge.writeServers,
 			)
 		}
 		if ge.genMock {
@@ -193,7 +750,9 @@ func (ge *goEncoder) encode(w io.Writer, d *wsdl.Definitions) error {
 	} else {
 		// this is rpc; meh
 		ff = append(ff,
+			ge.writeInterfaceFuncs,
 			ge.writeGoFuncs,
+			ge.writeMocks,
 			ge.writeGoTypes,
 		)
 	}
@@ -204,18 +763,21 @@ func (ge *goEncoder) encode(w io.Writer, d *wsdl.Definitions) error {
 		}
 	}
 	fmt.Fprintf(w, "package %s\n\nimport (\n", pkg)
-	for pkg := range ge.needsStdPkg {
+	for _, pkg := range sortedKeys(ge.needsStdPkg) {
 		fmt.Fprintf(w, "%q\n", pkg)
 	}
 	if len(ge.needsStdPkg) > 0 {
 		fmt.Fprintf(w, "\n")
 	}
-	for pkg := range ge.needsExtPkg {
+	for _, pkg := range sortedKeys(ge.needsExtPkg) {
 		fmt.Fprintf(w, "%q\n", pkg)
 	}
 	fmt.Fprintf(w, ")\n\n")
 	_, err = io.Copy(w, &b)
-	return err
+	if err != nil {
+		return err
+	}
+	return ge.genTests(pkg)
 }
 
 func (ge *goEncoder) writeNamespace(w io.Writer, d *wsdl.Definitions) error {
@@ -226,8 +788,114 @@ func (ge *goEncoder) writeNamespace(w io.Writer, d *wsdl.Definitions) error {
 	return nil
 }
 
+// formatPackageName returns the name of the Go package generated for
+// the binding named pkg: its letters and digits, in lower case.
 func (ge *goEncoder) formatPackageName(pkg string) string {
-	return strings.Replace(strings.ToLower(pkg), ".", "", -1)
+	return strings.Map(func(r rune) rune {
+		if unicode.IsLetter(r) || unicode.IsDigit(r) {
+			return unicode.ToLower(r)
+		}
+		return -1
+	}, pkg)
+}
+
+// selectBindings picks the bindings to generate code for: the one set
+// by SetBinding or used by the port set by SetPort, or the first binding
+// of each port type otherwise, preferring SOAP bindings. When a service
+// is set, only bindings used by its ports are considered, and when a
+// transport is set, only bindings using it.
+func (ge *goEncoder) selectBindings(d *wsdl.Definitions) error {
+	bindings := d.Bindings
+	if ge.service != "" {
+		s := d.ServiceByName(ge.service)
+		if s == nil {
+			return fmt.Errorf("service %q is not defined", ge.service)
+		}
+		bindings = nil
+		for _, p := range s.Ports {
+			b := d.BindingByName(p.Binding)
+			if b == nil {
+				return fmt.Errorf("service %q port %q requires binding %q but it's not defined",
+					s.Name, p.Name, p.Binding)
+			}
+			bindings = append(bindings, b)
+		}
+	}
+	if ge.port != "" {
+		b, err := ge.portBinding(d)
+		if err != nil {
+			return err
+		}
+		bindings = []*wsdl.Binding{b}
+	}
+	if ge.transport != "" {
+		var matched []*wsdl.Binding
+		for _, b := range bindings {
+			if b.Transport() == ge.transport {
+				matched = append(matched, b)
+			}
+		}
+		if len(matched) == 0 {
+			return fmt.Errorf("no binding uses transport %q", ge.transport)
+		}
+		bindings = matched
+	}
+	if ge.binding != "" {
+		name := trimns(ge.binding)
+		for _, b := range bindings {
+			if b.Name == name {
+				ge.bindings = []*wsdl.Binding{b}
+				return nil
+			}
+		}
+		if ge.service != "" {
+			return fmt.Errorf("binding %q is not used by service %q", ge.binding, ge.service)
+		}
+		return fmt.Errorf("binding %q is not defined", ge.binding)
+	}
+	// first binding of each port type, in order of appearance, replaced
+	// by the first SOAP binding of the port type if any
+	index := make(map[string]int)
+	for _, b := range bindings {
+		pt := trimns(b.Type)
+		i, seen := index[pt]
+		if !seen {
+			index[pt] = len(ge.bindings)
+			ge.bindings = append(ge.bindings, b)
+			continue
+		}
+		if ge.bindings[i].SOAPVersion() == "" && b.SOAPVersion() != "" {
+			ge.bindings[i] = b
+		}
+	}
+	return nil
+}
+
+// portBinding returns the binding used by the port set by SetPort,
+// looking up the port in the service set by SetService, or in all
+// services.
+func (ge *goEncoder) portBinding(d *wsdl.Definitions) (*wsdl.Binding, error) {
+	name := trimns(ge.port)
+	for _, s := range d.Services {
+		if ge.service != "" && s.Name != ge.service {
+			continue
+		}
+		for _, p := range s.Ports {
+			if p.Name != name {
+				continue
+			}
+			b := d.BindingByName(p.Binding)
+			if b == nil {
+				return nil, fmt.Errorf("service %q port %q requires binding %q but it's not defined",
+					s.Name, p.Name, p.Binding)
+			}
+			return b, nil
+		}
+	}
+	if ge.service != "" {
+		return nil, fmt.Errorf("port %q is not defined by service %q", ge.port, ge.service)
+	}
+	return nil, fmt.Errorf("port %q is not defined", ge.port)
 }
 
 func (ge *goEncoder) importParts(d *wsdl.Definitions) error {
@@ -238,74 +906,485 @@ func (ge *goEncoder) importParts(d *wsdl.Definitions) error {
 	return ge.importSchema(d)
 }
 
+// importRoot downloads the documents imported by d, and those they
+// import in turn, and merges them into d. Each location is downloaded
+// once, so that circular imports terminate.
 func (ge *goEncoder) importRoot(d *wsdl.Definitions) error {
-	for _, imp := range d.Imports {
+	for _, s := range d.Schemas {
+		ge.schemaBase[s] = ge.location
+	}
+	return ge.importDefinitions(d, ge.location, d.Imports, make(map[string]bool))
+}
+
+func (ge *goEncoder) importDefinitions(d *wsdl.Definitions, base string, imports []*wsdl.Import, imported map[string]bool) error {
+	for _, imp := range imports {
 		if imp.Location == "" {
 			continue
 		}
-		err := ge.importRemote(imp.Location, &d)
+		loc := resolveLocation(base, imp.Location)
+		if imported[loc] {
+			continue
+		}
+		imported[loc] = true
+		b, err := ge.download(loc)
+		if err != nil {
+			return err
+		}
+		// wsdl:import may refer to a schema document as well
+		if rootElement(b) == "schema" {
+			var schema wsdl.Schema
+			err = xml.Unmarshal(b, &schema)
+			if err != nil {
+				return fmt.Errorf("%s: %v", loc, err)
+			}
+			if schema.TargetNamespace == "" {
+				schema.TargetNamespace = imp.Namespace
+			}
+			ge.schemaBase[&schema] = loc
+			d.Schemas = append(d.Schemas, &schema)
+			continue
+		}
+		var id wsdl.Definitions
+		err = xml.Unmarshal(b, &id)
+		if err != nil {
+			return fmt.Errorf("%s: %v", loc, err)
+		}
+		err = ge.importDefinitions(d, loc, id.Imports, imported)
 		if err != nil {
 			return err
 		}
+		for _, s := range id.Schemas {
+			ge.schemaBase[s] = loc
+		}
+		d.Merge(&id)
 	}
 	return nil
 }
 
+// rootElement returns the local name of the root element of the XML
+// document b, or an empty string if b has none.
+func rootElement(b []byte) string {
+	dec := xml.NewDecoder(bytes.NewReader(b))
+	for {
+		tok, err := dec.Token()
+		if err != nil {
+			return ""
+		}
+		if se, ok := tok.(xml.StartElement); ok {
+			return se.Name.Local
+		}
+	}
+}
+
+// importSchema downloads the schemas imported by the schemas of d, and
+// those they import in turn, and appends them to d, keeping their own
+// target namespace. Included schemas are merged into the schema
+// including them. Each location is downloaded once.
 func (ge *goEncoder) importSchema(d *wsdl.Definitions) error {
-	for _, imp := range d.Schema.Imports {
-		if imp.Location == "" {
+	included := make(map[string]bool)
+	imported := make(map[string]bool)
+	for i := 0; i < len(d.Schemas); i++ {
+		s := d.Schemas[i]
+		err := ge.includeSchema(s, ge.schemaBase[s], included)
+		if err != nil {
+			return err
+		}
+		for _, imp := range s.Imports {
+			if imp.Location == "" {
+				continue
+			}
+			// includes keep the location of the including schema,
+			// so resolve imports against the schema that declares them
+			base, ok := ge.importBase[imp]
+			if !ok {
+				base = ge.schemaBase[s]
+			}
+			loc := resolveLocation(base, imp.Location)
+			if imported[loc] {
+				continue
+			}
+			imported[loc] = true
+			var schema wsdl.Schema
+			err := ge.importRemote(loc, &schema)
+			if err != nil {
+				return err
+			}
+			if schema.TargetNamespace == "" {
+				schema.TargetNamespace = imp.Namespace
+			}
+			ge.schemaBase[&schema] = loc
+			d.Schemas = append(d.Schemas, &schema)
+		}
+	}
+	return nil
+}
+
+// includeSchema downloads the schemas included by s, recursively, and
+// merges them into s. Locations in included are not downloaded again,
+// so that circular includes terminate.
+func (ge *goEncoder) includeSchema(s *wsdl.Schema, base string, included map[string]bool) error {
+	for _, inc := range s.Includes {
+		if inc.Location == "" {
+			continue
+		}
+		loc := resolveLocation(base, inc.Location)
+		if included[loc] {
 			continue
 		}
-		err := ge.importRemote(imp.Location, &d.Schema)
+		included[loc] = true
+		var schema wsdl.Schema
+		err := ge.importRemote(loc, &schema)
 		if err != nil {
 			return err
 		}
+		err = ge.includeSchema(&schema, loc, included)
+		if err != nil {
+			return err
+		}
+		for _, imp := range schema.Imports {
+			if _, ok := ge.importBase[imp]; !ok {
+				ge.importBase[imp] = loc
+			}
+		}
+		s.Include(&schema)
 	}
 	return nil
 }
 
+// resolveLocation resolves the import location loc against base, the
+// file path or URL of the document declaring the import.
+func resolveLocation(base, loc string) string {
+	if isURL(loc) || base == "" {
+		return loc
+	}
+	if isURL(base) {
+		b, err := url.Parse(base)
+		if err != nil {
+			return loc
+		}
+		r, err := url.Parse(loc)
+		if err != nil {
+			return loc
+		}
+		return b.ResolveReference(r).String()
+	}
+	if filepath.IsAbs(loc) {
+		return loc
+	}
+	return filepath.Join(filepath.Dir(base), filepath.FromSlash(loc))
+}
+
+// isURL reports whether loc is an absolute URL rather than a file path.
+func isURL(loc string) bool {
+	u, err := url.Parse(loc)
+	// single letter schemes are windows drives
+	return err == nil && len(u.Scheme) > 1
+}
+
 // download xml from url, decode in v.
 func (ge *goEncoder) importRemote(url string, v interface{}) error {
-	resp, err := ge.http.Get(url)
+	b, err := ge.download(url)
 	if err != nil {
 		return err
 	}
-	defer resp.Body.Close()
-	return xml.NewDecoder(resp.Body).Decode(v)
+	err = xml.Unmarshal(b, v)
+	if err != nil {
+		return fmt.Errorf("%s: %v", url, err)
+	}
+	return nil
 }
 
-func (ge *goEncoder) cacheTypes(d *wsdl.Definitions) {
-	// operation types are declared as go struct types
-	for _, v := range d.Schema.Elements {
-		if v.Type == "" && v.ComplexType != nil {
-			ct := *v.ComplexType
-			ct.Name = v.Name
-			ge.ctypes[v.Name] = &ct
+// download returns the document at loc, a URL or a file path.
+func (ge *goEncoder) download(loc string) ([]byte, error) {
+	if !isURL(loc) {
+		return ioutil.ReadFile(loc)
+	}
+	if strings.HasPrefix(loc, "file://") {
+		return ioutil.ReadFile(filepath.FromSlash(strings.TrimPrefix(loc, "file://")))
+	}
+	var cached string
+	if ge.cacheDir != "" {
+		sum := sha256.Sum256([]byte(loc))
+		cached = filepath.Join(ge.cacheDir, hex.EncodeToString(sum[:])+".xml")
+		if !ge.cacheRefresh {
+			if b, err := ioutil.ReadFile(cached); err == nil {
+				return b, nil
+			}
 		}
 	}
-	// simple types map 1:1 to go basic types
-	for _, v := range d.Schema.SimpleTypes {
-		ge.stypes[v.Name] = v
+	resp, err := ge.http.Get(loc)
+	if err != nil {
+		return nil, err
 	}
-	// complex types are declared as go struct types
-	for _, v := range d.Schema.ComplexTypes {
-		ge.ctypes[v.Name] = v
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("%s: %s", loc, resp.Status)
 	}
-	// cache elements from schema
-	ge.cacheElements(d.Schema.Elements)
-	// cache elements from complex types
-	for _, ct := range ge.ctypes {
-		ge.cacheComplexTypeElements(ct)
+	b, err := ioutil.ReadAll(resp.Body)
+	if err != nil || cached == "" {
+		return b, err
 	}
+	return b, writeCache(cached, b)
 }
 
-func (ge *goEncoder) cacheComplexTypeElements(ct *wsdl.ComplexType) {
+// writeCache writes b to the cache file name, atomically so that
+// concurrent runs never read partial documents.
+func writeCache(name string, b []byte) error {
+	err := os.MkdirAll(filepath.Dir(name), 0755)
+	if err != nil {
+		return err
+	}
+	f, err := ioutil.TempFile(filepath.Dir(name), ".download")
+	if err != nil {
+		return err
+	}
+	_, err = f.Write(b)
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	if err == nil {
+		err = os.Rename(f.Name(), name)
+	}
+	if err != nil {
+		os.Remove(f.Name())
+	}
+	return err
+}
+
+func (ge *goEncoder) cacheTypes(d *wsdl.Definitions) {
+	ge.typeDefs = d
+	for _, s := range d.Schemas {
+		ge.cacheSchemaTypes(s)
+	}
+	ge.cacheElementTypes()
+	// cache elements from complex types, in the order of their names
+	// as the first element of each name wins
+	for _, name := range ge.sortedComplexTypes() {
+		ge.cacheComplexTypeElements(ge.ctypes[name])
+	}
+	ge.cacheDerivedTypes()
+}
+
+// cacheDerivedTypes records the complex types derived from each
+// complex type by extension, including those derived from them in
+// turn.
+func (ge *goEncoder) cacheDerivedTypes() {
+	for name, ct := range ge.ctypes {
+		seen := map[string]bool{name: true}
+		for {
+			cc := ct.ComplexContent
+			if cc == nil || cc.Extension == nil {
+				break
+			}
+			base := ge.typeName(ge.typeSchema[ct.Name], cc.Extension.Base)
+			if seen[base] {
+				break
+			}
+			seen[base] = true
+			ct = ge.ctypes[base]
+			if ct == nil {
+				break
+			}
+			ge.derived[base] = append(ge.derived[base], name)
+		}
+	}
+	for _, names := range ge.derived {
+		sort.Strings(names)
+	}
+}
+
+func (ge *goEncoder) cacheSchemaTypes(s *wsdl.Schema) {
+	// operation types are declared as go struct types
+	for _, v := range s.Elements {
+		if v.Type == "" && v.ComplexType != nil {
+			ct := *v.ComplexType
+			ct.Name = v.Name
+			if ct.Doc == "" {
+				ct.Doc = v.Doc
+			}
+			ge.inlineTypes = append(ge.inlineTypes, &inlineType{&ct, s})
+		}
+		ge.elementNS[v.Name] = s.TargetNamespace
+	}
+	// simple types map 1:1 to go basic types
+	for _, v := range s.SimpleTypes {
+		if name := ge.cacheTypeName(s, v.Name); name != v.Name {
+			st := *v
+			st.Name = name
+			st.Doc = ge.clashDoc(v.Doc, name, s, v.Name)
+			v = &st
+		}
+		ge.stypes[v.Name] = v
+		ge.typeNS[v.Name] = s.TargetNamespace
+		ge.typeSchema[v.Name] = s
+	}
+	// complex types are declared as go struct types
+	for _, v := range s.ComplexTypes {
+		if name := ge.cacheTypeName(s, v.Name); name != v.Name {
+			ct := *v
+			ct.Name = name
+			ct.Doc = ge.clashDoc(v.Doc, name, s, v.Name)
+			v = &ct
+		}
+		ge.ctypes[v.Name] = v
+		ge.typeNS[v.Name] = s.TargetNamespace
+		ge.typeSchema[v.Name] = s
+	}
+	// groups are expanded in place where referenced
+	for _, v := range s.Groups {
+		ge.groups[v.Name] = v
+		ge.cacheElements(v.AllElements)
+		ge.cacheChoiceElements(v.Choice)
+		if v.Sequence != nil {
+			ge.cacheElements(v.Sequence.Elements)
+			ge.cacheChoiceElements(v.Sequence.Choices...)
+		}
+	}
+	for _, v := range s.Elements {
+		if v.SubstitutionGroup != "" {
+			head := trimns(v.SubstitutionGroup)
+			ge.substitutes[head] = append(ge.substitutes[head], v)
+		}
+	}
+	for _, v := range s.Attributes {
+		ge.attributes[v.Name] = v
+		ge.attributeNS[v.Name] = s.TargetNamespace
+	}
+	for _, v := range s.AttributeGroups {
+		ge.attrGroups[v.Name] = v
+	}
+	// cache elements from schema
+	ge.cacheElements(s.Elements)
+}
+
+// cacheTypeName returns the name to cache the type local of schema s
+// by: local itself, unless taken by a type of another namespace, in
+// which case it is followed by a number from 2 on.
+func (ge *goEncoder) cacheTypeName(s *wsdl.Schema, local string) string {
+	key := "{" + s.TargetNamespace + "}" + local
+	if name, ok := ge.typeNames[key]; ok {
+		return name
+	}
+	name := local
+	if ns, ok := ge.typeNS[local]; ok && ns != s.TargetNamespace {
+		ge.clashes[local] = true
+		name = uniqueName(local, ge.typeExists)
+		ge.typeLocal[name] = local
+		ge.rename("type %s of namespace %s declared as %s, as %s is taken",
+			local, s.TargetNamespace, ge.exportedName(name), ge.exportedName(local))
+	}
+	ge.typeNames[key] = name
+	return name
+}
+
+// clashDoc returns doc, the documentation of the type local of schema
+// s, followed by a note on its Go type being renamed to name.
+func (ge *goEncoder) clashDoc(doc, name string, s *wsdl.Schema, local string) string {
+	return strings.TrimSpace(fmt.Sprintf("%s %s is the type %s of the namespace %s, whose name is taken by a type of another namespace.",
+		doc, ge.exportedName(name), local, s.TargetNamespace))
+}
+
+// typeName returns the name the type reference t, named in schema s or
+// in the definitions if nil, is cached by: its local name, unless that
+// is declared in several namespaces.
+func (ge *goEncoder) typeName(s *wsdl.Schema, t string) string {
+	local := trimns(t)
+	if !ge.clashes[local] {
+		return local
+	}
+	var name xml.Name
+	switch {
+	case s != nil:
+		name = s.ResolveQName(t)
+	case ge.typeDefs != nil:
+		name = ge.typeDefs.ResolveQName(t)
+	default:
+		return local
+	}
+	if v, ok := ge.typeNames["{"+name.Space+"}"+name.Local]; ok {
+		return v
+	}
+	return local
+}
+
+// typeLocalName returns the local name of the schema type cached by
+// the given name.
+func (ge *goEncoder) typeLocalName(name string) string {
+	if local, ok := ge.typeLocal[name]; ok {
+		return local
+	}
+	return name
+}
+
+// inlineType is a complex type declared inline by a global element of
+// schema s, named after it.
+type inlineType struct {
+	ct *wsdl.ComplexType
+	s  *wsdl.Schema
+}
+
+// cacheElementTypes caches the complex types declared inline by global
+// elements. Those named after an element whose name is taken by a named
+// type are named after it followed by Element instead, or by a number
+// too if that is taken as well.
+func (ge *goEncoder) cacheElementTypes() {
+	// simple types are looked up first, and complex types are
+	// declared with their name in title case
+	named := make(map[string]bool)
+	for name := range ge.ctypes {
+		named[ge.exportedName(name)] = true
+	}
+	taken := func(name string) bool {
+		_, exists := ge.stypes[name]
+		return exists || named[ge.exportedName(name)]
+	}
+	for _, t := range ge.inlineTypes {
+		el := t.ct.Name
+		if taken(el) {
+			name := uniqueName(el+"Element", taken)
+			named[ge.exportedName(name)] = true
+			ge.elementTypes[el] = name
+			ge.typeElements[name] = el
+			ge.rename("type of element %s declared as %s, as %s is taken",
+				el, ge.exportedName(name), ge.exportedName(el))
+			t.ct.Name = name
+			t.ct.Doc = strings.TrimSpace(fmt.Sprintf("%s %s is the type of the element %s, whose name is taken by a type of its own.",
+				t.ct.Doc, ge.exportedName(name), el))
+		}
+		ge.ctypes[t.ct.Name] = t.ct
+		ge.typeNS[t.ct.Name] = t.s.TargetNamespace
+		ge.typeSchema[t.ct.Name] = t.s
+	}
+}
+
+// elementTypeRef returns the reference to the type declared inline by
+// the global element named by ref, as a type name: ref itself unless
+// the type was renamed.
+func (ge *goEncoder) elementTypeRef(ref string) string {
+	if name, ok := ge.elementTypes[trimns(ref)]; ok {
+		return name
+	}
+	return ref
+}
+
+// typeNamespace returns the target namespace of the schema declaring
+// the named type, falling back to the target namespace of d.
+func (ge *goEncoder) typeNamespace(d *wsdl.Definitions, name string) string {
+	if ns := ge.typeNS[name]; ns != "" {
+		return ns
+	}
+	return d.TargetNamespace
+}
+
+func (ge *goEncoder) cacheComplexTypeElements(ct *wsdl.ComplexType) {
 	if ct.AllElements != nil {
 		ge.cacheElements(ct.AllElements)
 	}
 	if ct.Sequence != nil {
 		ge.cacheElements(ct.Sequence.Elements)
+		ge.cacheChoiceElements(ct.Sequence.Choices...)
 	}
+	ge.cacheChoiceElements(ct.Choice)
 	cc := ct.ComplexContent
 	if cc != nil {
 		cce := cc.Extension
@@ -315,6 +1394,10 @@ func (ge *goEncoder) cacheComplexTypeElements(ct *wsdl.ComplexType) {
 				ge.cacheComplexTypeElements(cct)
 			}
 			ge.cacheElements(seq.Elements)
+			ge.cacheChoiceElements(seq.Choices...)
+		}
+		if cce != nil {
+			ge.cacheChoiceElements(cce.Choice)
 		}
 	}
 }
@@ -334,23 +1417,39 @@ func (ge *goEncoder) cacheElements(ct []*wsdl.Element) {
 			ge.cacheElements(ct.AllElements)
 			if ct.Sequence != nil {
 				ge.cacheElements(ct.Sequence.Elements)
+				ge.cacheChoiceElements(ct.Sequence.Choices...)
 			}
+			ge.cacheChoiceElements(ct.Choice)
+		}
+	}
+}
+
+func (ge *goEncoder) cacheChoiceElements(choices ...*wsdl.Choice) {
+	for _, ch := range choices {
+		if ch != nil {
+			ge.cacheElements(ch.Elements)
 		}
 	}
 }
 
 func (ge *goEncoder) cacheFuncs(d *wsdl.Definitions) {
 	// operations are declared as boilerplate go functions
-	for _, v := range d.PortType.Operations {
-		ge.funcs[v.Name] = v
-	}
-	ge.funcnames = make([]string, len(ge.funcs))
-	i := 0
-	for k := range ge.funcs {
-		ge.funcnames[i] = k
-		i++
+	for _, pt := range d.PortTypes {
+		ops := make(map[string]*wsdl.Operation)
+		names := make([]string, 0, len(pt.Operations))
+		for _, v := range pt.Operations {
+			if _, exists := ops[v.Name]; !exists {
+				names = append(names, v.Name)
+			}
+			ops[v.Name] = v
+		}
+		sort.Strings(names)
+		funcs := make([]*wsdl.Operation, len(names))
+		for i, name := range names {
+			funcs[i] = ops[name]
+		}
+		ge.funcs[pt.Name] = funcs
 	}
-	sort.Strings(ge.funcnames)
 }
 
 func (ge *goEncoder) cacheMessages(d *wsdl.Definitions) {
@@ -360,19 +1459,124 @@ func (ge *goEncoder) cacheMessages(d *wsdl.Definitions) {
 }
 
 func (ge *goEncoder) cacheSOAPOperations(d *wsdl.Definitions) {
-	for _, v := range d.Binding.Operations {
-		ge.soapOps[v.Name] = v
+	for _, b := range ge.bindings {
+		if len(b.Operations) == 0 {
+			continue
+		}
+		pt := trimns(b.Type)
+		if b.HTTPBinding != nil {
+			ge.httpVerbs[pt] = strings.ToUpper(b.HTTPBinding.Verb)
+		}
+		if b.SOAPBinding != nil {
+			ge.soapStyles[pt] = b.SOAPBinding.Style
+			ge.soapVersions[pt] = b.SOAPVersion()
+		}
+		ops, exists := ge.soapOps[pt]
+		if !exists {
+			ops = make(map[string]*wsdl.BindingOperation)
+			ge.soapOps[pt] = ops
+		}
+		for _, v := range b.Operations {
+			ops[v.Name] = v
+		}
+	}
+}
+
+// soapOp returns the SOAP binding of the given operation of port type pt,
+// or nil if the operation is not bound.
+func (ge *goEncoder) soapOp(pt *wsdl.PortType, op *wsdl.Operation) *wsdl.BindingOperation {
+	return ge.soapOps[pt.Name][op.Name]
+}
+
+// soapActionConst returns the name of the constant of the SOAP action
+// of the given operation of port type pt: the operation name followed
+// by Action, prefixed by the port type name in case of conflicts.
+func (ge *goEncoder) soapActionConst(pt *wsdl.PortType, op *wsdl.Operation) string {
+	key := pt.Name + " " + op.Name
+	if name, ok := ge.soapActions[key]; ok {
+		return name
+	}
+	name := ge.exportedName(op.Name) + "Action"
+	if ge.soapActionNames[name] || ge.typeExists(name) {
+		name = ge.exportedName(pt.Name) + name
+	}
+	ge.soapActions[key] = name
+	ge.soapActionNames[name] = true
+	return name
+}
+
+// typeExists returns whether a Go type of the given name is generated
+// from the schemas.
+func (ge *goEncoder) typeExists(name string) bool {
+	if _, exists := ge.stypes[name]; exists {
+		return true
+	}
+	if _, exists := ge.ctypes[name]; exists {
+		return true
+	}
+	for ct := range ge.ctypes {
+		if ge.exportedName(ct) == name {
+			return true
+		}
+	}
+	return false
+}
+
+// interfaceName returns the name of the Go interface of port type pt:
+// its name, followed by PortType if a type of the same name is
+// generated from the schemas.
+func (ge *goEncoder) interfaceName(pt *wsdl.PortType) string {
+	if name, ok := ge.interfaces[pt.Name]; ok {
+		return name
+	}
+	name := ge.exportedName(pt.Name)
+	if ge.typeExists(name) {
+		name = uniqueName(name+"PortType", ge.typeExists)
+		ge.rename("port type %s declared as %s, as %s is taken",
+			pt.Name, name, ge.exportedName(pt.Name))
+	}
+	ge.interfaces[pt.Name] = name
+	return name
+}
+
+// rpcStyle returns whether the given operation of port type pt is
+// bound to SOAP with the rpc style, where parts of messages are wrapped
+// in an element named after the operation.
+func (ge *goEncoder) rpcStyle(pt *wsdl.PortType, op *wsdl.Operation) bool {
+	bo := ge.soapOp(pt, op)
+	if bo == nil || ge.httpVerbs[pt.Name] != "" {
+		return false
+	}
+	style := ge.soapStyles[pt.Name]
+	if bo.Operation != nil && bo.Operation.Style != "" {
+		style = bo.Operation.Style
 	}
+	return style == "rpc"
+}
+
+// rpcEncoded returns whether the given operation of port type pt is
+// bound to SOAP with the rpc style and encoded use.
+func (ge *goEncoder) rpcEncoded(pt *wsdl.PortType, op *wsdl.Operation) bool {
+	bo := ge.soapOp(pt, op)
+	return ge.rpcStyle(pt, op) && bo.Input != nil && bo.Input.Use == "encoded"
 }
 
 var interfaceTypeT = template.Must(template.New("interfaceType").Parse(`
+{{- if .Impl }}
 // New{{.Name}} creates an initializes a {{.Name}}.
 func New{{.Name}}(cli *soap.Client) {{.Name}} {
 	return &{{.Impl}}{cli}
 }
-
+{{ end }}
 // {{.Name}} was auto-generated from WSDL
 // and defines interface for the remote service. Useful for testing.
+{{- if not .Impl }}
+// Its port type is not bound to a protocol, so no client implements it.
+{{- end }}
+{{- if .PolicyDoc }}
+//
+{{.PolicyDoc}}
+{{- end }}
 type {{.Name}} interface {
 {{- range .Funcs }}
 {{.Doc}}{{.Name}}({{.Input}}) ({{.Output}})
@@ -382,32 +1586,67 @@ type {{.Name}} interface {
 
 type interfaceTypeFunc struct{ Doc, Name, Input, Output string }
 
-// writeInterfaceFuncs writes Go interface definitions from WSDL types to w.
+// writeInterfaceFuncs writes Go interface definitions from WSDL types to w,
+// one interface for each port type that has SOAP bound operations, and
+// one for each port type that has operations but none bound, so that
+// code can depend on it regardless of the binding.
 // Functions are written in the same order of the WSDL document.
 func (ge *goEncoder) writeInterfaceFuncs(w io.Writer, d *wsdl.Definitions) error {
-	funcs := make([]*interfaceTypeFunc, len(ge.funcs))
+	for _, pt := range d.PortTypes {
+		if len(ge.funcs[pt.Name]) == 0 {
+			continue
+		}
+		err := ge.writeInterfaceType(w, d, pt)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (ge *goEncoder) writeInterfaceType(w io.Writer, d *wsdl.Definitions, pt *wsdl.PortType) error {
+	funcs := make([]*interfaceTypeFunc, len(ge.funcs[pt.Name]))
 	// Looping over the operations to determine what are the interface
 	// functions.
 	i := 0
-	for _, fn := range ge.funcnames {
-		op := ge.funcs[fn]
-		if _, exists := ge.soapOps[op.Name]; !exists {
+	bound := len(ge.soapOps[pt.Name]) > 0
+	for _, op := range ge.funcs[pt.Name] {
+		if bound && ge.soapOp(pt, op) == nil {
 			// TODO: rpc?
 			continue
 		}
-		inParams, err := ge.inputParams(op)
+		inParams, err := ge.inputParams(pt, op)
 		if err != nil {
 			return err
 		}
-		outParams, err := ge.outputParams(op)
+		outParams, err := ge.outputParams(pt, op)
 		if err != nil {
 			return err
 		}
+		inParams = ge.headerParams(pt, op, inParams)
+		inParams = ge.contextParams(inParams)
 		fixParamConflicts(inParams, outParams)
 
-		name := strings.Title(op.Name)
+		name := ge.exportedName(op.Name)
 		var doc bytes.Buffer
 		writeComments(&doc, name, op.Doc)
+		if s := ge.messageDoc("input", op.Input); s != "" {
+			writeComments(&doc, name, s)
+		}
+		if s := ge.messageDoc("output", op.Output); s != "" {
+			writeComments(&doc, name, s)
+		}
+		if bo := ge.soapOp(pt, op); bo != nil {
+			if s := mimeDoc("input", bo.InputMIME()); s != "" {
+				writeComments(&doc, name, s)
+			}
+			if s := mimeDoc("output", bo.OutputMIME()); s != "" {
+				writeComments(&doc, name, s)
+			}
+			if s := policyDoc("operation", d.ResolvePolicies(bo.Policies, bo.PolicyReferences)); s != "" {
+				writeComments(&doc, name, s)
+			}
+		}
 		funcs[i] = &interfaceTypeFunc{
 			Doc:    doc.String(),
 			Name:   name,
@@ -416,14 +1655,29 @@ func (ge *goEncoder) writeInterfaceFuncs(w io.Writer, d *wsdl.Definitions) error
 		}
 		i++
 	}
-	n := d.PortType.Name
+	var policies []*wsdl.Policy
+	for _, b := range ge.bindings {
+		if trimns(b.Type) == pt.Name {
+			policies = append(policies, d.ResolvePolicies(b.Policies, b.PolicyReferences)...)
+		}
+	}
+	var policy bytes.Buffer
+	if s := policyDoc("binding", policies); s != "" {
+		writeComments(&policy, "", s)
+	}
+	impl := ge.unexportedName(pt.Name)
+	if !bound {
+		impl = ""
+	}
 	return interfaceTypeT.Execute(w, &struct {
-		Name  string
-		Impl  string // private type that implements the interface
-		Funcs []*interfaceTypeFunc
+		Name      string
+		Impl      string // private type that implements the interface
+		PolicyDoc string
+		Funcs     []*interfaceTypeFunc
 	}{
-		strings.Title(n),
-		strings.ToLower(n)[:1] + n[1:],
+		ge.interfaceName(pt),
+		impl,
+		strings.TrimSuffix(policy.String(), "\n"),
 		funcs[:i],
 	})
 }
@@ -440,17 +1694,21 @@ var _ {{.Interface}} = Mock{{.Interface}}{}
 `))
 
 func (ge *goEncoder) writeMockPortType(w io.Writer, d *wsdl.Definitions) error {
-	if len(ge.funcs) == 0 {
-		return nil
+	for _, pt := range d.PortTypes {
+		if len(ge.funcs[pt.Name]) == 0 || len(ge.soapOps[pt.Name]) == 0 {
+			continue
+		}
+		ge.needsExtPkg["github.com/maraino/go-mock"] = true
+		err := mockPortTypeT.Execute(w, &struct {
+			Interface string
+		}{
+			ge.interfaceName(pt),
+		})
+		if err != nil {
+			return err
+		}
 	}
-
-	ge.needsExtPkg["github.com/maraino/go-mock"] = true
-	n := d.PortType.Name
-	return mockPortTypeT.Execute(w, &struct {
-		Interface string
-	}{
-		strings.Title(n),
-	})
+	return nil
 }
 
 var portTypeT = template.Must(template.New("portType").Parse(`
@@ -458,21 +1716,53 @@ var portTypeT = template.Must(template.New("portType").Parse(`
 type {{.Name}} struct {
 	cli *soap.Client
 }
-
+{{ if .Actions }}
+// SOAP actions of the operations of the {{.Interface}} interface.
+const (
+{{- range .Actions }}
+	{{.Name}} = {{printf "%q" .Value}}
+{{- end }}
+)
+{{ end }}
 `))
 
 func (ge *goEncoder) writePortType(w io.Writer, d *wsdl.Definitions) error {
-	if len(ge.funcs) == 0 {
-		return nil
+	for _, pt := range d.PortTypes {
+		if len(ge.funcs[pt.Name]) == 0 || len(ge.soapOps[pt.Name]) == 0 {
+			continue
+		}
+		var actions []*soapFuncField
+		if ge.httpVerbs[pt.Name] == "" {
+			for _, op := range ge.funcs[pt.Name] {
+				bo := ge.soapOp(pt, op)
+				if bo == nil {
+					continue
+				}
+				var action string
+				if bo.Operation != nil {
+					action = bo.Operation.SoapAction
+				}
+				actions = append(actions, &soapFuncField{
+					Name:  ge.soapActionConst(pt, op),
+					Value: action,
+				})
+			}
+		}
+		n := pt.Name
+		err := portTypeT.Execute(w, &struct {
+			Name      string
+			Interface string
+			Actions   []*soapFuncField
+		}{
+			ge.unexportedName(n),
+			ge.interfaceName(pt),
+			actions,
+		})
+		if err != nil {
+			return err
+		}
 	}
-	n := d.PortType.Name
-	return portTypeT.Execute(w, &struct {
-		Name      string
-		Interface string
-	}{
-		strings.ToLower(n)[:1] + n[1:],
-		strings.Title(n),
-	})
+	return nil
 }
 
 // writeMockFuncs writes Mock function definitions from WSDL types to w.
@@ -488,40 +1778,60 @@ func (ge *goEncoder) writeGoFuncs(w io.Writer, d *wsdl.Definitions) error {
 }
 
 func (ge *goEncoder) writeFuncs(w io.Writer, d *wsdl.Definitions, mockFuncs bool) error {
-	if d.Binding.Type != "" {
-		a, b := trimns(d.Binding.Type), trimns(d.PortType.Name)
-		if a != b {
+	for _, b := range ge.bindings {
+		if b.Type == "" {
+			continue
+		}
+		if _, exists := ge.funcs[trimns(b.Type)]; !exists {
 			return fmt.Errorf(
 				"binding %q requires port type %q but it's not defined",
-				d.Binding.Name, d.Binding.Type)
+				b.Name, b.Type)
 		}
 	}
-	if len(ge.funcs) == 0 {
-		return nil
+	for _, pt := range d.PortTypes {
+		err := ge.writePortTypeFuncs(w, d, pt, mockFuncs)
+		if err != nil {
+			return err
+		}
 	}
-	for _, fn := range ge.funcnames {
-		op := ge.funcs[fn]
+	return nil
+}
 
-		inParams, err := ge.inputParams(op)
+func (ge *goEncoder) writePortTypeFuncs(w io.Writer, d *wsdl.Definitions, pt *wsdl.PortType, mockFuncs bool) error {
+	for _, op := range ge.funcs[pt.Name] {
+		inParams, err := ge.inputParams(pt, op)
 		if err != nil {
 			return err
 		}
-		outParams, err := ge.outputParams(op)
+		outParams, err := ge.outputParams(pt, op)
 		if err != nil {
 			return err
 		}
+		inParams = ge.headerParams(pt, op, inParams)
+		inParams = ge.contextParams(inParams)
 		fixParamConflicts(inParams, outParams)
 
 		if mockFuncs {
-			ge.writeMockFunc(w, d, op, inParams, outParams)
+			ge.writeMockFunc(w, pt, op, inParams, outParams)
 		} else {
-			ok := ge.writeSOAPFunc(w, d, op, inParams, outParams)
+			var ok bool
+			if ge.httpVerbs[pt.Name] != "" {
+				ok = ge.writeHTTPFunc(w, pt, op, inParams, outParams)
+			} else {
+				ok = ge.writeSOAPFunc(w, d, pt, op, inParams, outParams)
+			}
 			if !ok {
 				writeComments(w, op.Name, op.Doc)
-				ge.needsStdPkg["errors"] = true
 				ge.needsStdPkg["context"] = true
-				inParams = append([]*parameter{&parameter{Name: "ctx", Type: "context.Context"}}, inParams...)
-				fn := ge.fixFuncNameConflicts(strings.Title(op.Name))
+				if ge.noContext {
+					inParams = append([]*parameter{contextParam}, inParams...)
+				}
+				fn := ge.fixFuncNameConflicts(ge.exportedName(op.Name))
+				if fn != ge.exportedName(op.Name) {
+					ge.rename("operation %s declared as %s, as %s is taken",
+						op.Name, fn, ge.exportedName(op.Name))
+				}
+				ge.stubs[fn] = true
 				fmt.Fprintf(w, "func %s(%s) (%s) {\nreturn\n}\n\n",
 					fn,
 					asGoParamsString(inParams),
@@ -559,8 +1869,8 @@ func (m Mock{{.Interface}}) {{.Name}}( {{functionParamString .InParams}}) ({{fun
 }
 `))
 
-func (ge *goEncoder) writeMockFunc(w io.Writer, d *wsdl.Definitions, op *wsdl.Operation, inParams, outParams []*parameter) bool {
-	if _, exists := ge.soapOps[op.Name]; !exists {
+func (ge *goEncoder) writeMockFunc(w io.Writer, pt *wsdl.PortType, op *wsdl.Operation, inParams, outParams []*parameter) bool {
+	if ge.soapOp(pt, op) == nil {
 		return false
 	}
 
@@ -570,8 +1880,8 @@ func (ge *goEncoder) writeMockFunc(w io.Writer, d *wsdl.Definitions, op *wsdl.Op
 		InParams  []*parameter
 		OutParams []*parameter
 	}{
-		strings.Title(d.PortType.Name),
-		strings.Title(op.Name),
+		ge.interfaceName(pt),
+		ge.exportedName(op.Name),
 		inParams,
 		outParams,
 	})
@@ -590,21 +1900,43 @@ var soapFuncT = template.Must(template.New("soapFunc").Funcs(template.FuncMap{
 // {{.Name}} was was auto-generated from WSDL
 func (p *{{.PortType}}) {{.Name}}( {{functionParamString .InParams}}) ({{functionParamString .OutParams}}) {
 	// request message
+{{- if .WrapperIn }}
+	message := &{{.WrapperIn.Type}}{
+{{- range .Fields }}
+		{{.Name}}: {{.Value}},
+{{- end }}
+	}
+{{- else }}
 	message := struct {
 		XMLName xml.Name ` + "`" + `xml:"{{.MessageNameIn}}"` + "`" + `
-{{- range .InParams }}
-		{{fieldNameString .Name}} {{.Type}} ` + "`" + `xml:"{{.XMLName}}"` + "`" + `
+{{- if .Encoded }}
+		soap.RPCEncoded
+{{- else if .RPCLiteral }}
+		soap.RPCLiteral
+{{- end }}
+{{- range .Fields }}
+		{{.Name}} {{.Type}} ` + "`" + `xml:"{{.Tag}}"` + "`" + `
 {{- end }}
 	}{
-{{- range .InParams}}
-		{{fieldNameString .Name}}: {{.Name}},
+{{- if .Encoded }}
+		RPCEncoded: soap.NewRPCEncoded({{printf "%q" .Encoded.Namespace}}, {{printf "%q" .Encoded.EncodingStyle}}),
+{{- else if .RPCLiteral }}
+		RPCLiteral: soap.RPCLiteral{Namespace: {{printf "%q" .RPCLiteral.Namespace}}},
+{{- end }}
+{{- range .Fields }}
+		{{.Name}}: {{.Value}},
 {{- end }}
 	}
+{{- end }}
+{{- if not .OneWay }}
 
 	// response message
 	out := struct {
 		XMLName xml.Name ` + "`xml:\"Envelope\"`" + `
 		Body struct {
+{{- if .WrapperOut }}
+			Message {{.WrapperOut.Type}} ` + "`" + `xml:"{{.WrapperOut.Tag}}"` + "`" + `
+{{- else }}
 			Message struct {
 {{- range .OutParams }}
 {{- 	if ne .Name "err" }}
@@ -612,103 +1944,671 @@ func (p *{{.PortType}}) {{.Name}}( {{functionParamString .InParams}}) ({{functio
 {{- 	end }}
 {{- end }}
 			} ` + "`" + `xml:"{{.MessageNameOut}}"` + "`" + `
+{{- end }}
 		}
 	}{}
-	
-	// simple context to pass SOAPAction--later versions to offer better call control
-	ctx := context.WithValue( context.Background(), "SOAPAction", "{{.SoapAction}}" )
-	if err = p.cli.RoundTrip(ctx, message, &out); err != nil {
+{{- end }}
+
+{{ if .Context -}}
+	ctx = soap.WithSOAPAction(ctx, {{.SoapAction}})
+{{- else -}}
+	ctx := soap.WithSOAPAction(context.Background(), {{.SoapAction}})
+{{- end }}
+{{- if .SOAP12 }}
+	ctx = soap.WithEnvelope(ctx, soap.Envelope12Namespace)
+{{- end }}
+{{- if .Action }}
+	ctx = soap.WithAction(ctx, {{printf "%q" .Action}})
+{{- end }}
+{{- if .Header }}
+	if {{.Header.Name}} != nil {
+		ctx = soap.WithHeader(ctx, {{.Header.Name}})
+	}
+{{- end }}
+	if err = p.cli.RoundTrip(ctx, message, {{if .OneWay}}nil{{else}}&out{{end}}); err != nil {
+{{- if .Faults }}
+		var f *soap.Fault
+		if errors.As(err, &f) {
+{{- range $i, $f := .Faults }}
+			{{if $i}}} else {{end}}if detail := new({{.Type}}); f.DecodeDetail(xml.Name{Space: {{printf "%q" .Space}}, Local: {{printf "%q" .Local}}}, detail) {
+				err = &{{.Name}}{f, detail}
+{{- end }}
+			}
+		}
+{{- end }}
 		return
 	}
 
-{{- range .OutParams }}
-{{- 	if ne .Name "err" }}
-	{{.Name}} = out.Body.Message.{{fieldNameString .Name}}
-{{- 	end }}
+{{- range .OutFields }}
+	{{.Value}} = out.Body.Message.{{.Name}}
 {{- end }}
 
 	return
 }
 `))
 
-func (ge *goEncoder) writeSOAPFunc(w io.Writer, d *wsdl.Definitions, op *wsdl.Operation, inParams, outParams []*parameter) bool {
-	if _, exists := ge.soapOps[op.Name]; !exists {
+// soapFuncField is a field of the request message of a SOAP operation.
+type soapFuncField struct{ Name, Type, Tag, Value string }
+
+func (ge *goEncoder) writeSOAPFunc(w io.Writer, d *wsdl.Definitions, pt *wsdl.PortType, op *wsdl.Operation, inParams, outParams []*parameter) bool {
+	soapOp := ge.soapOp(pt, op)
+	if soapOp == nil {
 		return false
 	}
 	ge.needsStdPkg["context"] = true
 	ge.needsStdPkg["encoding/xml"] = true
 	ge.needsExtPkg["github.com/seamuncle/wsdl2go/soap"] = true
 
-	var soapAction string
-	soapOp := ge.soapOps[op.Name]
-	if soapOp.Operation != nil {
-		soapAction = soapOp.Operation.SoapAction
+	soapAction := ge.soapActionConst(pt, op)
+	var header *parameter
+	bodyParams := inParams
+	if !ge.noContext {
+		bodyParams = bodyParams[1:]
+	}
+	if ge.soapHeaderType(pt, op) != "" {
+		header, bodyParams = bodyParams[0], bodyParams[1:]
+	}
+	messageNameIn := trimns(op.Name)
+	var messageNameOut string
+	if op.Output != nil {
+		messageNameOut = trimns(op.Output.Message)
+	}
+	var encoded, rpcLiteral *wsdl.BindingIO
+	if ge.rpcStyle(pt, op) {
+		if ge.rpcEncoded(pt, op) {
+			encoded = soapOp.Input
+		} else if soapOp.Input != nil && soapOp.Input.Namespace != "" {
+			rpcLiteral = soapOp.Input
+		}
+		if soapOp.Input != nil && soapOp.Input.Namespace != "" {
+			messageNameIn = "rpc:" + messageNameIn
+		}
+		messageNameOut = trimns(op.Name) + "Response"
+	}
+	var action string
+	if op.Input != nil {
+		action = op.Input.Action
+	}
+	wrapperIn, wrapperOut := ge.wrappedInput(pt, op), ge.wrappedOutput(pt, op)
+	faults := ge.operationFaults(pt, op)
+	if len(faults) > 0 {
+		ge.needsStdPkg["errors"] = true
+	}
+	fields := make([]*soapFuncField, len(bodyParams))
+	for i, p := range bodyParams {
+		fields[i] = &soapFuncField{
+			Name:  strings.Title(p.Name),
+			Type:  p.Type,
+			Tag:   p.XMLName,
+			Value: p.Name,
+		}
+		if wrapperIn != nil {
+			fields[i].Name = wrapperIn.Fields[i].Name
+		} else if encoded != nil && p.part != nil {
+			fields[i].Type = "soap.Encoded"
+			fields[i].Tag = p.part.Name
+			fields[i].Value = ge.encodedValue(d, p)
+		}
+	}
+	outFields := make([]*soapFuncField, len(outParams)-1)
+	for i, p := range outParams[:len(outParams)-1] {
+		outFields[i] = &soapFuncField{Name: strings.Title(p.Name), Value: p.Name}
+		if wrapperOut != nil {
+			outFields[i].Name = wrapperOut.Fields[i].Name
+		}
 	}
 	soapFuncT.Execute(w, &struct {
 		PortType       string
 		Name           string
 		InParams       []*parameter
+		Fields         []*soapFuncField
+		OutFields      []*soapFuncField
+		WrapperIn      *wrapper
+		WrapperOut     *wrapper
+		Encoded        *wsdl.BindingIO
+		RPCLiteral     *wsdl.BindingIO
+		Action         string
+		Header         *parameter
+		Context        bool
 		SoapAction     string
+		SOAP12         bool
+		OneWay         bool
+		Faults         []*faultType
 		OutParams      []*parameter
 		MessageNameIn  string
 		MessageNameOut string
 	}{
-		strings.ToLower(d.PortType.Name[:1]) + d.PortType.Name[1:],
-		strings.Title(op.Name),
+		ge.unexportedName(pt.Name),
+		ge.exportedName(op.Name),
 		inParams,
+		fields,
+		outFields,
+		wrapperIn,
+		wrapperOut,
+		encoded,
+		rpcLiteral,
+		action,
+		header,
+		!ge.noContext,
 		soapAction,
+		ge.soapVersions[pt.Name] == "1.2",
+		op.Output == nil,
+		faults,
 		outParams,
-		trimns(op.Name),
-		trimns(op.Output.Message),
+		messageNameIn,
+		messageNameOut,
 	})
 	return true
 }
 
-// returns list of function input parameters.
-func (ge *goEncoder) inputParams(op *wsdl.Operation) ([]*parameter, error) {
-	if op.Input == nil {
-		return []*parameter{}, nil
+var httpFuncT = template.Must(template.New("httpFunc").Funcs(template.FuncMap{
+	"functionParamString": func(params []*parameter) string {
+		return asGoParamsString(params)
+	},
+}).Parse(`
+// {{.Name}} was was auto-generated from WSDL
+func (p *{{.PortType}}) {{.Name}}( {{functionParamString .InParams}}) ({{functionParamString .OutParams}}) {
+	params := url.Values{}
+{{- range .Params }}
+	params.Set("{{.XMLName}}", fmt.Sprint({{.Name}}))
+{{- end }}
+	err = p.cli.RoundTripHTTP({{if .Context}}ctx{{else}}context.Background(){{end}}, "{{.Verb}}", "{{.Location}}", params, {{if .Out}}&{{.Out.Name}}{{else}}nil{{end}})
+	return
+}
+`))
+
+// writeHTTPFunc writes the function of an operation of a port type bound
+// to plain HTTP. Its input parts are sent as query string or form values,
+// and its response decoded onto its first output part.
+func (ge *goEncoder) writeHTTPFunc(w io.Writer, pt *wsdl.PortType, op *wsdl.Operation, inParams, outParams []*parameter) bool {
+	httpOp := ge.soapOp(pt, op)
+	if httpOp == nil {
+		return false
 	}
-	im := trimns(op.Input.Message)
-	req, ok := ge.messages[im]
-	if !ok {
-		return nil, fmt.Errorf("operation %q wants input message %q but it's not defined", op.Name, im)
+	ge.needsStdPkg["context"] = true
+	ge.needsStdPkg["net/url"] = true
+	params := inParams
+	if !ge.noContext {
+		params = params[1:]
 	}
+	if len(params) > 0 {
+		ge.needsStdPkg["fmt"] = true
+	}
+	ge.needsExtPkg["github.com/seamuncle/wsdl2go/soap"] = true
 
-	var parts []*wsdl.Part
-	if len(op.ParameterOrder) != 0 {
-		order := strings.Split(op.ParameterOrder, " ")
-		parts = make([]*wsdl.Part, len(order))
-
-		// Use a map to run O( len(parts)+len(order) )
-		partLookup := map[string]*wsdl.Part{}
-		for _, part := range req.Parts {
-			partLookup[part.Name] = part
-		}
-		for i, o := range order {
-			parts[i] = partLookup[o]
+	var location string
+	if httpOp.HTTP != nil {
+		location = httpOp.HTTP.Location
+	}
+	var out *parameter
+	if len(outParams) > 1 {
+		out = outParams[0]
+	}
+	if m := httpOp.OutputMIME(); m != nil && m.MimeXML != nil {
+		for _, p := range outParams[:len(outParams)-1] {
+			if p.XMLName == m.MimeXML.Part {
+				out = p
+			}
 		}
+	}
+	httpFuncT.Execute(w, &struct {
+		PortType  string
+		Name      string
+		InParams  []*parameter
+		OutParams []*parameter
+		Params    []*parameter
+		Context   bool
+		Verb      string
+		Location  string
+		Out       *parameter
+	}{
+		ge.unexportedName(pt.Name),
+		ge.exportedName(op.Name),
+		inParams,
+		outParams,
+		params,
+		!ge.noContext,
+		ge.httpVerbs[pt.Name],
+		location,
+		out,
+	})
+	return true
+}
 
-	} else {
-		parts = req.Parts
+// encodedValue returns the soap.Encoded value of the given parameter,
+// typed after its message part.
+func (ge *goEncoder) encodedValue(d *wsdl.Definitions, p *parameter) string {
+	typ := p.part.Type
+	if typ == "" {
+		typ = p.part.Element
+	}
+	name := d.ResolveQName(typ)
+	if name.Space == "" {
+		name.Space = ge.typeNS[name.Local]
+	}
+	v := fmt.Sprintf("soap.Encoded{Type: %s", xmlNameLiteral(name))
+	if ct, ok := ge.ctypes[ge.typeName(nil, typ)]; ok && isArray(ct) {
+		itemType := strings.TrimRight(ct.ComplexContent.Restriction.Attribute.Value, "[]")
+		item := xml.Name{Local: trimns(itemType)}
+		if s, ok := ge.typeSchema[ct.Name]; ok {
+			item = s.ResolveQName(itemType)
+		}
+		v += fmt.Sprintf(", ItemType: %s", xmlNameLiteral(item))
+	}
+	return v + ", Value: " + p.Name + "}"
+}
+
+// xmlNameLiteral returns the Go literal of name, using the constants
+// of the soap package for its well known namespaces.
+func xmlNameLiteral(name xml.Name) string {
+	space := fmt.Sprintf("%q", name.Space)
+	switch name.Space {
+	case wsdl.XMLSchemaNamespace:
+		space = "soap.XSDNamespace"
+	case "http://schemas.xmlsoap.org/soap/encoding/":
+		space = "soap.EncodingNamespace"
+	}
+	return fmt.Sprintf("xml.Name{Space: %s, Local: %q}", space, name.Local)
+}
+
+// soapHeaderType returns the name of the Go type holding the SOAP
+// header blocks of the input of the given operation, or "" if its
+// binding declares none.
+func (ge *goEncoder) soapHeaderType(pt *wsdl.PortType, op *wsdl.Operation) string {
+	bo := ge.soapOp(pt, op)
+	if bo == nil || len(bo.InputHeaders) == 0 || ge.httpVerbs[pt.Name] != "" {
+		return ""
+	}
+	name := ge.exportedName(op.Name) + "Header"
+	if ge.typeExists(name) {
+		name = ge.exportedName(op.Name) + "SOAPHeader"
+	}
+	return name
+}
+
+// headerParams returns the input parameters of the given operation with
+// its SOAP header blocks, if any, as the first parameter. Parts of the
+// input message sent as header blocks are removed from the body.
+// contextParam is the first parameter of the methods of port types,
+// the context of calls.
+var contextParam = &parameter{Name: "ctx", Type: "context.Context"}
+
+// contextParams returns inParams prefixed by contextParam, unless
+// disabled by SetNoContext.
+func (ge *goEncoder) contextParams(inParams []*parameter) []*parameter {
+	if ge.noContext {
+		return inParams
+	}
+	ge.needsStdPkg["context"] = true
+	return append([]*parameter{contextParam}, inParams...)
+}
+
+func (ge *goEncoder) headerParams(pt *wsdl.PortType, op *wsdl.Operation, inParams []*parameter) []*parameter {
+	typ := ge.soapHeaderType(pt, op)
+	if typ == "" {
+		return inParams
+	}
+	params := []*parameter{{Name: "header", Type: "*" + typ}}
+	for _, p := range inParams {
+		if op.Input == nil || !isHeaderPart(op.Input.Message, p.XMLName, ge.soapOp(pt, op).InputHeaders) {
+			params = append(params, p)
+		}
+	}
+	return params
+}
+
+// isHeaderPart returns whether the named part of message is sent as
+// one of the given header blocks.
+func isHeaderPart(message, part string, headers []*wsdl.SOAPHeader) bool {
+	for _, h := range headers {
+		if trimns(h.Message) == trimns(message) && h.Part == part {
+			return true
+		}
+	}
+	return false
+}
+
+var soapHeaderT = template.Must(template.New("soapHeader").Parse(`
+// {{.Name}} holds the SOAP header blocks of {{.Op}} requests.
+// Nil fields are not sent.
+type {{.Name}} struct {
+{{- range .Fields }}
+	{{.Name}} {{.Type}}
+{{- end }}
+}
+
+// MarshalXML implements the xml.Marshaler interface. Each field of h
+// is encoded as a header block of its own.
+func (h {{.Name}}) MarshalXML(e *xml.Encoder, start xml.StartElement) error {
+{{- range .Fields }}
+	if h.{{.Name}} != nil {
+		err := e.EncodeElement(h.{{.Name}}, xml.StartElement{Name: xml.Name{Space: {{printf "%q" .Space}}, Local: {{printf "%q" .Local}}}})
+		if err != nil {
+			return err
+		}
+	}
+{{- end }}
+	return nil
+}
+`))
+
+type soapHeaderField struct{ Name, Type, Space, Local string }
+
+// writeSOAPHeaders writes the Go types holding the SOAP header blocks
+// of the operations whose binding declares them.
+func (ge *goEncoder) writeSOAPHeaders(w io.Writer, d *wsdl.Definitions) error {
+	for _, pt := range d.PortTypes {
+		for _, op := range ge.funcs[pt.Name] {
+			name := ge.soapHeaderType(pt, op)
+			if name == "" {
+				continue
+			}
+			var fields []*soapHeaderField
+			for _, h := range ge.soapOp(pt, op).InputHeaders {
+				f, err := ge.soapHeaderField(h)
+				if err != nil {
+					return fmt.Errorf("operation %q: %v", op.Name, err)
+				}
+				fields = append(fields, f)
+			}
+			ge.needsStdPkg["encoding/xml"] = true
+			err := soapHeaderT.Execute(w, &struct {
+				Name   string
+				Op     string
+				Fields []*soapHeaderField
+			}{name, ge.exportedName(op.Name), fields})
+			if err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// soapHeaderField returns the field of the message part sent as the
+// header block h.
+func (ge *goEncoder) soapHeaderField(h *wsdl.SOAPHeader) (*soapHeaderField, error) {
+	m, ok := ge.messages[trimns(h.Message)]
+	if !ok {
+		return nil, fmt.Errorf("header message %q is not defined", h.Message)
+	}
+	for _, part := range m.Parts {
+		if part.Name != h.Part {
+			continue
+		}
+		f := &soapHeaderField{Name: ge.exportedName(part.Name)}
+		switch {
+		case part.Element != "":
+			f.Local = trimns(part.Element)
+			f.Space = ge.elementNS[f.Local]
+			if el, ok := ge.globalElement(f.Local); ok {
+				f.Type = ge.elementGoType(el)
+			} else {
+				f.Type = ge.wsdl2goType(ge.elementTypeRef(part.Element))
+			}
+		default:
+			f.Local = part.Name
+			f.Space = h.Namespace
+			f.Type = ge.wsdl2goType(part.Type)
+		}
+		f.Type = optionalType(f.Type)
+		return f, nil
+	}
+	return nil, fmt.Errorf("header message %q has no part %q", h.Message, h.Part)
+}
+
+// policyRequirements are the requirements of WS-Policy assertions that
+// are worth documenting, by local name of the assertion.
+var policyRequirements = []struct {
+	Assertions  []string
+	Requirement string
+}{
+	{[]string{"Addressing", "UsingAddressing"}, "WS-Addressing"},
+	{[]string{"UsernameToken"}, "WS-Security UsernameToken"},
+	{[]string{"X509Token", "AsymmetricBinding", "SymmetricBinding"}, "WS-Security signatures or encryption"},
+	{[]string{"SamlToken", "IssuedToken"}, "WS-Security SAML or issued tokens"},
+	{[]string{"TransportBinding", "HttpsToken"}, "HTTPS"},
+}
+
+// policyDoc describes the requirements of the WS-Policy of a binding or
+// operation, if any, for its documentation.
+func policyDoc(of string, policies []*wsdl.Policy) string {
+	var reqs []string
+	for _, r := range policyRequirements {
+		found := false
+		for _, p := range policies {
+			for _, a := range r.Assertions {
+				found = found || p.Contains(a)
+			}
+		}
+		if found {
+			reqs = append(reqs, r.Requirement)
+		}
+	}
+	if len(reqs) == 0 {
+		return ""
+	}
+	return fmt.Sprintf("The WS-Policy of the %s requires: %s.", of, strings.Join(reqs, ", "))
+}
+
+// mimeDoc describes the multipart/related MIME binding of the input or
+// output of an operation, if any, for its documentation.
+func mimeDoc(dir string, m *wsdl.MIME) string {
+	if m == nil || m.MultipartRelated == nil || len(m.MultipartRelated.Parts) == 0 {
+		return ""
+	}
+	parts := make([]string, len(m.MultipartRelated.Parts))
+	for i, p := range m.MultipartRelated.Parts {
+		var types []string
+		if p.Body != nil {
+			types = append(types, "SOAP body")
+		}
+		for _, c := range p.Contents {
+			types = append(types, c.Type)
+		}
+		parts[i] = p.Name
+		if len(types) > 0 {
+			parts[i] += " (" + strings.Join(types, ", ") + ")"
+		}
+	}
+	return fmt.Sprintf("The %s is a multipart/related MIME message with parts: %s.",
+		dir, strings.Join(parts, "; "))
+}
+
+// messageDoc returns the documentation of the message of an operation
+// input or output, if any.
+func (ge *goEncoder) messageDoc(dir string, v *wsdl.IO) string {
+	if v == nil {
+		return ""
+	}
+	m, ok := ge.messages[trimns(v.Message)]
+	if !ok || strings.TrimSpace(m.Doc) == "" {
+		return ""
+	}
+	return fmt.Sprintf("The %s message %s: %s", dir, m.Name, m.Doc)
+}
+
+// returns list of function input parameters.
+func (ge *goEncoder) inputParams(pt *wsdl.PortType, op *wsdl.Operation) ([]*parameter, error) {
+	if op.Input == nil {
+		return []*parameter{}, nil
+	}
+	if w := ge.wrappedInput(pt, op); w != nil {
+		ge.needsTag[w.Type] = true
+		return ge.wrapperParams(w), nil
+	}
+	im := trimns(op.Input.Message)
+	req, ok := ge.messages[im]
+	if !ok {
+		return nil, fmt.Errorf("operation %q wants input message %q but it's not defined", op.Name, im)
 	}
 
-	return ge.genParams(parts, true), nil
+	parts := orderParts(req.Parts, op.ParameterOrder)
+
+	// Parts of rpc messages are named after the part rather than after
+	// their type.
+	return ge.genParams(parts, !ge.rpcStyle(pt, op)), nil
 }
 
 // returns list of function output parameters plus error.
-func (ge *goEncoder) outputParams(op *wsdl.Operation) ([]*parameter, error) {
+func (ge *goEncoder) outputParams(pt *wsdl.PortType, op *wsdl.Operation) ([]*parameter, error) {
 	errP := &parameter{Name: "err", Type: "error", XMLName: "Err"}
 	if op.Output == nil {
 		return []*parameter{errP}, nil
 	}
+	if w := ge.wrappedOutput(pt, op); w != nil {
+		return append(ge.wrapperParams(w), errP), nil
+	}
 	om := trimns(op.Output.Message)
 	resp, ok := ge.messages[om]
 	if !ok {
 		return nil, fmt.Errorf("operation %q wants output message %q but it's not defined", op.Name, om)
 	}
 
-	return append(ge.genParams(resp.Parts, false), errP), nil
+	parts := orderParts(resp.Parts, op.ParameterOrder)
+	return append(ge.genParams(parts, false), errP), nil
+}
+
+// orderParts returns the parts of a message in the given parameter
+// order, a space separated list of part names of both the input and
+// output messages of an operation. Parts missing from the order, such
+// as the return value of the operation, come first, in message order.
+func orderParts(parts []*wsdl.Part, parameterOrder string) []*wsdl.Part {
+	order := strings.Fields(parameterOrder)
+	if len(order) == 0 {
+		return parts
+	}
+	listed := make(map[string]bool, len(order))
+	for _, o := range order {
+		listed[o] = true
+	}
+	// Use a map to run O( len(parts)+len(order) )
+	partLookup := make(map[string]*wsdl.Part, len(parts))
+	ordered := make([]*wsdl.Part, 0, len(parts))
+	for _, part := range parts {
+		partLookup[part.Name] = part
+		if !listed[part.Name] {
+			ordered = append(ordered, part)
+		}
+	}
+	for _, o := range order {
+		if part, ok := partLookup[o]; ok {
+			ordered = append(ordered, part)
+			delete(partLookup, o)
+		}
+	}
+	return ordered
+}
+
+// wrapper is the single element of a message of a wrapped
+// document/literal operation. Its child elements are unwrapped as the
+// parameters of the operation function.
+type wrapper struct {
+	Type   string // Go type of the element, not a pointer
+	Tag    string // XML tag of the element
+	Fields []*elementField
+}
+
+// wrapperParams returns the parameters of the operation function
+// unwrapped from w.
+func (ge *goEncoder) wrapperParams(w *wrapper) []*parameter {
+	params := make([]*parameter, len(w.Fields))
+	for i, f := range w.Fields {
+		params[i] = &parameter{
+			Name:    ge.unexportedName(f.Name),
+			Type:    f.Type,
+			XMLName: f.el.Name,
+		}
+	}
+	return params
+}
+
+// wrappedInput returns the wrapper of the input of the given operation
+// of port type pt, or nil if the operation is not wrapped
+// document/literal: its input must be a single element named after the
+// operation, of a complex type made of a sequence of elements.
+func (ge *goEncoder) wrappedInput(pt *wsdl.PortType, op *wsdl.Operation) *wrapper {
+	bo := ge.soapOp(pt, op)
+	if bo == nil || op.Input == nil || ge.httpVerbs[pt.Name] != "" || ge.rpcStyle(pt, op) {
+		return nil
+	}
+	if bo.Input != nil && bo.Input.Use == "encoded" {
+		return nil
+	}
+	w := ge.wrapper(op.Input.Message, bo.InputHeaders, op.Name)
+	if w == nil || len(w.Fields) == 0 {
+		return nil
+	}
+	return w
+}
+
+// wrappedOutput returns the wrapper of the output of the given operation
+// of port type pt, or nil if either its input or its output is not
+// wrapped. Unlike the input, the output element may have any name.
+func (ge *goEncoder) wrappedOutput(pt *wsdl.PortType, op *wsdl.Operation) *wrapper {
+	if op.Output == nil || ge.wrappedInput(pt, op) == nil {
+		return nil
+	}
+	return ge.wrapper(op.Output.Message, ge.soapOp(pt, op).OutputHeaders, "")
+}
+
+// wrapper returns the wrapper of the named message, or nil if the
+// message does not consist of a single element, of the given name if
+// not empty, besides its parts sent as header blocks.
+func (ge *goEncoder) wrapper(message string, headers []*wsdl.SOAPHeader, name string) *wrapper {
+	m, ok := ge.messages[trimns(message)]
+	if !ok {
+		return nil
+	}
+	var parts []*wsdl.Part
+	for _, p := range m.Parts {
+		if !isHeaderPart(message, p.Name, headers) {
+			parts = append(parts, p)
+		}
+	}
+	if len(parts) != 1 || parts[0].Element == "" {
+		return nil
+	}
+	local := trimns(parts[0].Element)
+	if name != "" && local != name {
+		return nil
+	}
+	ct, ok := ge.ctypes[trimns(ge.elementTypeRef(local))]
+	if !ok || ct.Abstract || isMixed(ct) || ct.ComplexContent != nil || ct.SimpleContent != nil ||
+		ct.Choice != nil || ct.Group != nil || len(ct.Attributes) > 0 ||
+		len(ct.AttributeGroups) > 0 || ct.AnyAttribute != nil {
+		return nil
+	}
+	elements := ct.AllElements
+	if seq := ct.Sequence; seq != nil {
+		if len(elements) > 0 || len(seq.Choices) > 0 || len(seq.Groups) > 0 || len(seq.Any) > 0 {
+			return nil
+		}
+		elements = seq.Elements
+	}
+	typ := ge.wsdl2goType(ge.elementTypeRef(parts[0].Element))
+	if !strings.HasPrefix(typ, "*") {
+		return nil
+	}
+	if s, ok := ge.typeSchema[ct.Name]; ok {
+		defer func(s *wsdl.Schema) { ge.fieldSchema = s }(ge.fieldSchema)
+		ge.fieldSchema = s
+	}
+	w := &wrapper{
+		Type: strings.TrimPrefix(typ, "*"),
+		Tag:  qualify(ge.elementNS[local], local),
+	}
+	for _, el := range elements {
+		if el.Ref != "" && len(ge.substitutes[trimns(el.Ref)]) > 0 {
+			return nil
+		}
+		f := ge.elementField(el, false)
+		if f == nil {
+			return nil
+		}
+		w.Fields = append(w.Fields, f)
+	}
+	return w
 }
 
 var isGoKeyword = map[string]bool{
@@ -743,6 +2643,9 @@ type parameter struct {
 	Name    string
 	Type    string
 	XMLName string
+
+	// message part of the parameter, if any
+	part *wsdl.Part
 }
 
 func (p parameter) asGo() string {
@@ -758,43 +2661,18 @@ func asGoParamsString(params []*parameter) string {
 	return strings.Join(goP, ", ")
 }
 
-// these are used by scrubName--not recompiled
-var idFinder = regexp.MustCompile("(.*)Id$")
-var underscoreFinder = regexp.MustCompile("(.+)_(.+)")
-
-func scrubName(unscrubbed string) (name string) {
-
-	name = unscrubbed
-
-	// Golint wants fields and variable names with "ID" instead of "Id"
-	name = idFinder.ReplaceAllString(name, "${1}ID")
-
-	// Golint doesn't want fields and variable names with "_" anywhere--remove mid-word matches,
-	name = underscoreFinder.ReplaceAllString(name, "${1}${2}")
-
-	// replace edge underscores with "Var"-- set n as 2 because there are maximum 2 edge cases
-	name = strings.Replace(name, "_", "Var", 2)
-
-	// Because other languages just don't care
-	if isGoKeyword[name] {
-		name = name + name
-	}
-
-	return name
-}
-
 func (ge *goEncoder) genParams(parts []*wsdl.Part, needsTag bool) []*parameter {
 	params := make([]*parameter, len(parts))
 	for i, part := range parts {
 
-		name := scrubName(part.Name)
+		name := ge.goName(part.Name)
 
 		var t string
 		switch {
 		case part.Type != "":
 			t = ge.wsdl2goType(part.Type)
 		case part.Element != "":
-			t = ge.wsdl2goType(part.Element)
+			t = ge.wsdl2goType(ge.elementTypeRef(part.Element))
 		}
 
 		xmlName := part.Name
@@ -811,6 +2689,7 @@ func (ge *goEncoder) genParams(parts []*wsdl.Part, needsTag bool) []*parameter {
 			Name:    name,
 			Type:    t,
 			XMLName: xmlName,
+			part:    part,
 		}
 		if needsTag {
 			ge.needsTag[strings.TrimPrefix(t, "*")] = true
@@ -829,6 +2708,16 @@ func (ge *goEncoder) fixFuncNameConflicts(name string) string {
 		name += "Func"
 		return ge.fixFuncNameConflicts(name)
 	}
+	if ge.stubs[name] {
+		name += "Func"
+		return ge.fixFuncNameConflicts(name)
+	}
+	for _, iface := range ge.interfaces {
+		if iface == name {
+			name += "Func"
+			return ge.fixFuncNameConflicts(name)
+		}
+	}
 	return name
 }
 
@@ -841,7 +2730,7 @@ func fixParamConflicts(in, out []*parameter) {
 	for _, req := range in {
 		for i, resp := range out {
 			if req.Name == resp.Name {
-				resp.Name = "resp" + strings.Title(resp.Name) + string(i)
+				resp.Name = "resp" + strings.Title(resp.Name) + strconv.Itoa(i)
 				retest = true
 			}
 		}
@@ -854,47 +2743,302 @@ func fixParamConflicts(in, out []*parameter) {
 // Converts types from wsdl type to Go type.
 func (ge *goEncoder) wsdl2goType(t string) string {
 	// TODO: support other types.
+	if typ, ok := ge.mappedType(t); ok {
+		return typ
+	}
 	v := trimns(t)
-	if _, exists := ge.stypes[v]; exists {
-		return v
+	if !ge.isBuiltinType(t) {
+		name := ge.typeName(ge.fieldSchema, t)
+		if _, exists := ge.stypes[name]; exists {
+			return ge.goName(name)
+		}
+		if ct, exists := ge.ctypes[name]; exists {
+			if ct.Abstract || len(ge.derived[name]) > 0 {
+				ge.derivedUsed[name] = true
+				return ge.derivedType(name)
+			}
+			return "*" + ge.exportedName(name)
+		}
 	}
 	switch strings.ToLower(v) {
-	case "int", "integer":
+	case "integer", "negativeinteger", "nonpositiveinteger":
+		if ge.bigIntegers {
+			ge.needsStdPkg["math/big"] = true
+			return "big.Int"
+		}
+		return "int"
+	case "nonnegativeinteger", "positiveinteger":
+		if ge.bigIntegers {
+			ge.needsStdPkg["math/big"] = true
+			return "big.Int"
+		}
+		return "uint"
+	case "int":
 		return "int"
 	case "long":
 		return "int64"
+	case "short":
+		return "int16"
+	case "byte":
+		return "int8"
+	case "unsignedlong":
+		return "uint64"
+	case "unsignedint":
+		return "uint32"
+	case "unsignedshort":
+		return "uint16"
+	case "unsignedbyte":
+		return "uint8"
 	case "float", "double":
 		return "float64"
 	case "decimal":
-		// big.Float works well enough with XML serialization,
-		// but falls short for JSON and YAML--may need a custom type to wrap this
-		ge.needsStdPkg["math/big"] = true
-		return "big.Float"
+		return ge.decimalType(ge.decimal)
 	case "boolean":
 		return "bool"
-	case "hexbinary", "base64binary":
-		return "[]byte"
-	case "string", "anyuri", "token", "qname":
+	case "hexbinary":
+		ge.needsHexType = true
+		return "HexBinary"
+	case "base64binary":
+		ge.needsBase64Type = true
+		return "Base64Binary"
+	case "qname":
+		ge.needsExtPkg["github.com/seamuncle/wsdl2go/soap"] = true
+		return "soap.QName"
+	case "string", "anyuri", "token":
+		return "string"
+	case "date", "time", "datetime", "gyear", "gyearmonth", "gmonth", "gmonthday", "gday":
+		for _, tt := range timeTypes {
+			if strings.EqualFold(tt.XSD, v) {
+				ge.needsTimeTypes[tt] = true
+				return tt.Name
+			}
+		}
 		return "string"
-	case "date":
-		ge.needsDateType = true
-		return "Date"
-	case "time":
-		ge.needsTimeType = true
-		return "Time"
-	case "nonnegativeinteger":
-		return "uint"
-	case "datetime":
-		ge.needsDateTimeType = true
-		return "DateTime"
 	case "duration":
 		ge.needsDurationType = true
 		return "Duration"
-	case "anysequence", "anytype", "anysimpletype":
+	case "anytype":
+		ge.needsExtPkg["github.com/seamuncle/wsdl2go/soap"] = true
+		ge.needsTypeRegistry = true
+		return "soap.AnyType"
+	case "anysequence", "anysimpletype":
 		return "interface{}"
 	default:
-		return "*" + strings.Title(strings.Replace(v, ".", "", -1))
+		return "*" + ge.exportedName(v)
+	}
+}
+
+// decimalType returns the Go type of xsd:decimal for the given
+// mapping.
+func (ge *goEncoder) decimalType(mapping string) string {
+	switch mapping {
+	case DecimalShopspring:
+		ge.needsExtPkg["github.com/shopspring/decimal"] = true
+		return "decimal.Decimal"
+	case DecimalString, DecimalFloat64:
+		return mapping
+	}
+	ge.needsDecimalType = true
+	return "Decimal"
+}
+
+// genRestriction writes the type of the simple type st, a restriction
+// of its base type, and the constants and methods of its enumeration.
+// Restrictions of xsd:decimal may have their own mapping.
+func (ge *goEncoder) genRestriction(w io.Writer, st *wsdl.SimpleType) {
+	r := st.Restriction
+	if m, ok := ge.decimalTypes[st.Name]; ok && ge.isBuiltinType(r.Base) && trimns(r.Base) == "decimal" {
+		defer func(m string) { ge.decimal = m }(ge.decimal)
+		ge.decimal = m
+	}
+	name := ge.goName(st.Name)
+	base := ge.wsdl2goType(r.Base)
+	writeComments(w, name, st.Doc)
+	if embedsBase[base] {
+		// a defined type would not have the methods of base, and
+		// its values can not be written as literals
+		fmt.Fprintf(w, "type %s struct {\n%s\n}\n\n", name, base)
+		ge.genFacetValidate(w, name, base, r)
+		return
+	}
+	fmt.Fprintf(w, "type %s %s\n\n", name, base)
+	ge.genEnumConsts(w, name, r)
+	ge.genValidator(w, name, r)
+	ge.genFacetValidate(w, name, base, r)
+	ge.genEnumString(w, name, r)
+}
+
+// embedsBase lists the Go types whose methods encode and decode them:
+// restrictions of these types embed them rather than being defined
+// as them, so as to keep their methods.
+var embedsBase = map[string]bool{
+	"big.Float":       true,
+	"big.Int":         true,
+	"Decimal":         true,
+	"decimal.Decimal": true,
+	"Date":            true,
+	"Time":            true,
+	"DateTime":        true,
+	"Duration":        true,
+	"GYear":           true,
+	"GYearMonth":      true,
+	"GMonth":          true,
+	"GMonthDay":       true,
+	"GDay":            true,
+	"Base64Binary":    true,
+	"HexBinary":       true,
+}
+
+// restrictedType is the restriction of a simple type.
+type restrictedType struct {
+	r    *wsdl.Restriction
+	base string // Go type
+}
+
+// cacheRestrictedTypes records the restrictions of the simple types to
+// be generated and the Go type of their base, as genRestriction
+// declares them.
+func (ge *goEncoder) cacheRestrictedTypes() {
+	defer func(s *wsdl.Schema, m string) { ge.fieldSchema, ge.decimal = s, m }(ge.fieldSchema, ge.decimal)
+	decimal := ge.decimal
+	ge.restrictedTypes = make(map[string]*restrictedType)
+	for name, st := range ge.stypes {
+		r := st.Restriction
+		if r == nil || ge.isCachedTypeMapped(name) {
+			continue
+		}
+		ge.fieldSchema = ge.typeSchema[name]
+		ge.decimal = decimal
+		if m, ok := ge.decimalTypes[st.Name]; ok && ge.isBuiltinType(r.Base) && trimns(r.Base) == "decimal" {
+			ge.decimal = m
+		}
+		ge.restrictedTypes[ge.goName(st.Name)] = &restrictedType{r, ge.wsdl2goType(r.Base)}
+	}
+}
+
+// embeddedType returns the type embedded by the struct of the Go type
+// typ, a restriction of it, or of a restriction of it, if any.
+func (ge *goEncoder) embeddedType(typ string) string {
+	for seen := make(map[string]bool); !seen[typ]; {
+		seen[typ] = true
+		rt, ok := ge.restrictedTypes[typ]
+		if !ok {
+			return ""
+		}
+		if embedsBase[rt.base] {
+			return rt.base
+		}
+		typ = rt.base
+	}
+	return ""
+}
+
+// mappedType returns the Go type the type reference t is mapped to by
+// SetTypeMap, if any, and records the import of its package.
+func (ge *goEncoder) mappedType(t string) (string, bool) {
+	if len(ge.typeMap) == 0 {
+		return "", false
+	}
+	name := xml.Name{Local: trimns(t)}
+	if i := strings.Index(t, "}"); strings.HasPrefix(t, "{") && i > 0 {
+		// qualified name of a type of another package
+		name = xml.Name{Space: t[1:i], Local: t[i+1:]}
+	} else if ge.fieldSchema != nil {
+		name = ge.fieldSchema.ResolveQName(t)
+	} else if ns, ok := ge.typeNS[name.Local]; ok {
+		name.Space = ns
+	}
+	typ, ok := ge.typeMap["{"+name.Space+"}"+name.Local]
+	if !ok {
+		typ, ok = ge.typeMap[name.Local]
 	}
+	if !ok {
+		return "", false
+	}
+	pkg, ref := goTypeRef(typ)
+	if pkg != "" {
+		if strings.Contains(strings.SplitN(pkg, "/", 2)[0], ".") {
+			ge.needsExtPkg[pkg] = true
+		} else {
+			ge.needsStdPkg[pkg] = true
+		}
+	}
+	return ref, true
+}
+
+// isMappedType reports whether the schema type name, declared in the
+// namespace ns, is mapped to a Go type by SetTypeMap, in which case
+// no Go type is generated for it.
+func (ge *goEncoder) isMappedType(ns, name string) bool {
+	_, qualified := ge.typeMap["{"+ns+"}"+name]
+	_, local := ge.typeMap[name]
+	return qualified || local
+}
+
+// isCachedTypeMapped reports whether the cached type of the given name
+// is mapped to a Go type by SetTypeMap.
+func (ge *goEncoder) isCachedTypeMapped(name string) bool {
+	return ge.isMappedType(ge.typeNS[name], ge.typeLocalName(name))
+}
+
+// versionElement matches the major version element of import paths.
+var versionElement = regexp.MustCompile(`^v[0-9]+$`)
+
+// goTypeRef splits the Go type typ, such as
+// *github.com/google/uuid.UUID, into the import path of the package
+// declaring it, if any, and the type as referred to by generated code,
+// such as *uuid.UUID.
+func goTypeRef(typ string) (pkg, ref string) {
+	name := strings.TrimLeft(typ, "*[]")
+	prefix := typ[:len(typ)-len(name)]
+	i := strings.LastIndex(name, ".")
+	if i < 0 {
+		return "", typ
+	}
+	pkg = name[:i]
+	return pkg, prefix + packageName(pkg) + name[i:]
+}
+
+// packageName returns the name of the package of the given import path,
+// assumed to be its last element, but for major versions and a go-
+// prefix.
+func packageName(path string) string {
+	elems := strings.Split(path, "/")
+	name := elems[len(elems)-1]
+	if len(elems) > 1 && versionElement.MatchString(name) {
+		name = elems[len(elems)-2]
+	}
+	if j := strings.Index(name, ".v"); j > 0 {
+		// gopkg.in/yaml.v2
+		name = name[:j]
+	}
+	return strings.TrimPrefix(name, "go-")
+}
+
+// numericBits maps the Go numeric types of XML Schema numeric types
+// to their size in bits.
+var numericBits = map[string]int{
+	"int":     64,
+	"int8":    8,
+	"int16":   16,
+	"int32":   32,
+	"int64":   64,
+	"uint":    64,
+	"uint8":   8,
+	"uint16":  16,
+	"uint32":  32,
+	"uint64":  64,
+	"float64": 64,
+}
+
+// isBuiltinType reports whether the type reference t resolves to the
+// XML Schema namespace in the schema whose types are being generated.
+// Schema types shadow built-in types of the same name otherwise.
+func (ge *goEncoder) isBuiltinType(t string) bool {
+	if ge.fieldSchema == nil {
+		return false
+	}
+	return ge.fieldSchema.ResolveQName(t).Space == wsdl.XMLSchemaNamespace
 }
 
 func trimns(s string) string {
@@ -911,35 +3055,49 @@ func trimns(s string) string {
 // generate, simple types, then complex types.
 func (ge *goEncoder) writeGoTypes(w io.Writer, d *wsdl.Definitions) error {
 	var b bytes.Buffer
+	ge.cacheMessageTypes(d)
+	ge.cacheRestrictedTypes()
+	err := ge.cacheStructFields(d)
+	if err != nil {
+		return err
+	}
+	ge.cacheCloneTypes()
+	ge.cacheValidTypes()
 	for _, name := range ge.sortedSimpleTypes() {
 		st := ge.stypes[name]
+		if ge.isCachedTypeMapped(name) {
+			continue
+		}
+		ge.fieldSchema = ge.typeSchema[name]
 		if st.Restriction != nil {
-			writeComments(&b, st.Name, "")
-			fmt.Fprintf(&b, "type %s %s\n\n", scrubName(st.Name), ge.wsdl2goType(st.Restriction.Base))
-			ge.genValidator(&b, st.Name, st.Restriction)
+			ge.genRestriction(&b, st)
 		} else if st.Union != nil {
-			types := strings.Split(st.Union.MemberTypes, " ")
-			ntypes := make([]string, len(types))
-			for i, t := range types {
-				t = strings.TrimSpace(t)
-				if t == "" {
-					continue
-				}
-				ntypes[i] = ge.wsdl2goType(t)
-			}
-			doc := st.Name + " is a union of: " + strings.Join(ntypes, ", ")
-			writeComments(&b, st.Name, doc)
-			fmt.Fprintf(&b, "type %s interface{}\n\n", st.Name)
+			ge.genUnion(&b, st)
+		} else if st.List != nil {
+			ge.genList(&b, st)
 		}
 	}
-	var err error
+	ge.fieldSchema = nil
 	for _, name := range ge.sortedComplexTypes() {
 		ct := ge.ctypes[name]
-		err = ge.genGoStruct(&b, d, ct)
+		if ge.isCachedTypeMapped(name) {
+			continue
+		}
+		if ge.allDerived && (ct.Abstract || len(ge.derived[name]) > 0) {
+			ge.derivedUsed[name] = true
+		}
+		err = ge.genGoStruct(&b, d, ct)
 		if err != nil {
 			return err
 		}
 	}
+	for _, name := range ge.sortedSubstitutionGroups() {
+		ge.genSubstitutionGroup(&b, d, name)
+	}
+	for _, name := range ge.sortedDerivedTypes() {
+		ge.genDerivedType(&b, name)
+	}
+	ge.genTypeRegistry(&b, d)
 	ge.genDateTypes(w) // must be called last
 	_, err = io.Copy(w, &b)
 	return err
@@ -967,243 +3125,2073 @@ func (ge *goEncoder) sortedComplexTypes() []string {
 	return keys
 }
 
+func (ge *goEncoder) sortedSubstitutionGroups() []string {
+	keys := make([]string, 0, len(ge.substitutes))
+	for k := range ge.substitutes {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// sortedKeys returns the members of the set m in order, so that the
+// code generated from them does not depend on the order maps are
+// iterated in.
+func sortedKeys(m map[string]bool) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
 func (ge *goEncoder) genDateTypes(w io.Writer) {
-	cases := []struct {
-		needs bool
-		name  string
-		code  string
-	}{
-		{
-			needs: ge.needsDateType,
-			name:  "Date",
-			code:  "type Date string\n\n",
-		},
-		{
-			needs: ge.needsTimeType,
-			name:  "Time",
-			code:  "type Time string\n\n",
-		},
-		{
-			needs: ge.needsDateTimeType,
-			name:  "DateTime",
-			code:  "type DateTime string\n\n",
-		},
-		{
-			needs: ge.needsDurationType,
-			name:  "Duration",
-			code:  "type Duration string\n\n",
-		},
-	}
-	for _, c := range cases {
-		if !c.needs {
+	for _, tt := range timeTypes {
+		if !ge.needsTimeTypes[tt] {
 			continue
 		}
-		writeComments(w, c.name, c.name+" in WSDL format.")
-		io.WriteString(w, c.code)
+		for _, pkg := range []string{"encoding/xml", "fmt", "strings", "time"} {
+			ge.needsStdPkg[pkg] = true
+		}
+		var doc bytes.Buffer
+		writeComments(&doc, tt.Name, tt.Doc)
+		timeTypeT.Execute(w, &struct {
+			*timeType
+			Comments string
+		}{tt, doc.String()})
 	}
-}
-
-var validatorT = template.Must(template.New("validator").Parse(`
-// Validate validates {{.TypeName}}.
-func (v {{.TypeName}}) Validate() bool {
-	for _, vv := range []{{.Type}} {
-		{{range .Args}}{{.}},{{"\n"}}{{end}}
-	}{
-		if reflect.DeepEqual(v, vv) {
-			return true
+	if ge.needsDecimalType {
+		ge.needsStdPkg["math/big"] = true
+		ge.needsStdPkg["fmt"] = true
+		ge.needsStdPkg["strings"] = true
+		io.WriteString(w, decimalType)
+	}
+	if ge.needsBase64Type {
+		for _, pkg := range []string{"encoding/base64", "fmt", "strings"} {
+			ge.needsStdPkg[pkg] = true
 		}
+		io.WriteString(w, base64Type)
+	}
+	if ge.needsHexType {
+		for _, pkg := range []string{"encoding/hex", "fmt", "strings"} {
+			ge.needsStdPkg[pkg] = true
+		}
+		io.WriteString(w, hexType)
+	}
+	if ge.needsDurationType {
+		for _, pkg := range []string{"encoding/xml", "fmt", "regexp", "strconv", "strings", "time"} {
+			ge.needsStdPkg[pkg] = true
+		}
+		io.WriteString(w, durationType)
 	}
-	return false
 }
-`))
 
-func (ge *goEncoder) genValidator(w io.Writer, typeName string, r *wsdl.Restriction) {
-	if len(r.Enum) == 0 {
-		return
-	}
-	args := make([]string, len(r.Enum))
-	t := ge.wsdl2goType(r.Base)
-	for i, v := range r.Enum {
-		if t == "string" {
-			args[i] = strconv.Quote(v.Value)
+const decimalType = `
+// Decimal is a value of xsd:decimal, held exactly by a big.Rat.
+type Decimal struct {
+	big.Rat
+}
+
+// String returns v in the lexical form of xsd:decimal. Values that
+// have no finite decimal representation, which can only be set from
+// Go, are rounded to 20 decimals.
+func (v Decimal) String() string {
+	// a fraction has a finite decimal representation if its
+	// denominator has no other prime factors than 2 and 5
+	d := new(big.Int).Set(v.Denom())
+	var twos, fives int
+	two, five, r := big.NewInt(2), big.NewInt(5), new(big.Int)
+	for d.BitLen() > 1 {
+		if r.Mod(d, two).Sign() == 0 {
+			d.Quo(d, two)
+			twos++
+		} else if r.Mod(d, five).Sign() == 0 {
+			d.Quo(d, five)
+			fives++
 		} else {
-			args[i] = v.Value
+			return v.FloatString(20)
 		}
 	}
-	ge.needsStdPkg["reflect"] = true
-	validatorT.Execute(w, &struct {
-		TypeName string
-		Type     string
-		Args     []string
-	}{
-		typeName,
-		t,
-		args,
-	})
+	if twos < fives {
+		twos = fives
+	}
+	return v.FloatString(twos)
 }
 
-func isArray(ct *wsdl.ComplexType) bool {
-	return ct.ComplexContent != nil &&
-		ct.ComplexContent.Restriction != nil &&
-		trimns(ct.ComplexContent.Restriction.Base) == "Array" &&
-		ct.ComplexContent.Restriction.Attribute != nil &&
-		ct.ComplexContent.Restriction.Attribute.Key == "arrayType"
+// MarshalText implements the encoding.TextMarshaler interface.
+func (v Decimal) MarshalText() ([]byte, error) {
+	return []byte(v.String()), nil
 }
 
-func (ge *goEncoder) genGoStruct(w io.Writer, d *wsdl.Definitions, ct *wsdl.ComplexType) error {
-	if ct.Abstract {
+// UnmarshalText implements the encoding.TextUnmarshaler interface.
+func (v *Decimal) UnmarshalText(b []byte) error {
+	s := strings.TrimSpace(string(b))
+	if s == "" {
+		v.SetInt64(0)
 		return nil
 	}
-	c := 0
-	if len(ct.AllElements) == 0 {
-		c++
+	r, ok := new(big.Rat).SetString(s)
+	if !ok || strings.Contains(s, "/") {
+		return fmt.Errorf("Decimal: invalid value %q", s)
 	}
-	if ct.ComplexContent == nil || ct.ComplexContent.Extension == nil {
-		c++
+	v.Set(r)
+	return nil
+}
+
+`
+
+const base64Type = `
+// Base64Binary is a value of xsd:base64Binary: bytes written in base64.
+type Base64Binary []byte
+
+// MarshalText implements the encoding.TextMarshaler interface.
+func (b Base64Binary) MarshalText() ([]byte, error) {
+	text := make([]byte, base64.StdEncoding.EncodedLen(len(b)))
+	base64.StdEncoding.Encode(text, b)
+	return text, nil
+}
+
+// UnmarshalText implements the encoding.TextUnmarshaler interface.
+// Whitespace, such as line breaks, is ignored.
+func (b *Base64Binary) UnmarshalText(text []byte) error {
+	v, err := base64.StdEncoding.DecodeString(strings.Join(strings.Fields(string(text)), ""))
+	if err != nil {
+		return fmt.Errorf("Base64Binary: %v", err)
 	}
-	if ct.Sequence == nil {
-		c++
-	} else if len(ct.Sequence.ComplexTypes) == 0 && len(ct.Sequence.Elements) == 0 {
-		c++
+	*b = v
+	return nil
+}
+
+`
+
+const hexType = `
+// HexBinary is a value of xsd:hexBinary: bytes written as pairs of
+// hexadecimal digits.
+type HexBinary []byte
+
+// MarshalText implements the encoding.TextMarshaler interface.
+func (b HexBinary) MarshalText() ([]byte, error) {
+	return []byte(strings.ToUpper(hex.EncodeToString(b))), nil
+}
+
+// UnmarshalText implements the encoding.TextUnmarshaler interface.
+func (b *HexBinary) UnmarshalText(text []byte) error {
+	v, err := hex.DecodeString(strings.TrimSpace(string(text)))
+	if err != nil {
+		return fmt.Errorf("HexBinary: %v", err)
 	}
-	name := strings.Title(ct.Name)
-	writeComments(w, name, ct.Doc)
+	*b = v
+	return nil
+}
 
-	// Do array search
-	if isArray(ct) {
-		wsdlType := ct.ComplexContent.Restriction.Attribute.Value
-		wsdlArrayOf := trimns(wsdlType)
-		matches := regexp.MustCompile("([^\\[\\]]+)(.+)").FindStringSubmatch(wsdlArrayOf)
-		goArrayOf := matches[1]
-		dimensions := matches[2]
-		fmt.Fprintf(w, "type %s %s%s\n\n", name, dimensions, goArrayOf)
-		return nil
+`
+
+const durationType = `
+// Duration is a value of xsd:duration, such as P1Y2M3DT4H5M6.7S.
+// Years and months have no fixed length, so only durations without
+// them convert to a time.Duration.
+type Duration struct {
+	Negative bool
+	Years    int
+	Months   int
+	Days     int
+	Hours    int
+	Minutes  int
+	Seconds  float64
+}
+
+// NewDuration returns the Duration of d, in hours, minutes and seconds.
+func NewDuration(d time.Duration) Duration {
+	var v Duration
+	if d < 0 {
+		v.Negative = true
+		d = -d
 	}
+	v.Hours = int(d / time.Hour)
+	v.Minutes = int(d % time.Hour / time.Minute)
+	v.Seconds = (d % time.Minute).Seconds()
+	return v
+}
 
-	if ct.Sequence != nil && ct.Sequence.Any != nil {
-		fmt.Fprintf(w, "type %s []interface{}\n\n", name)
-		return nil
+// Duration returns v as a time.Duration, and whether v could be
+// converted: it can not if it has years or months.
+func (v Duration) Duration() (time.Duration, bool) {
+	if v.Years != 0 || v.Months != 0 {
+		return 0, false
 	}
-	if c > 2 {
-		fmt.Fprintf(w, "type %s struct {}\n\n", name)
+	d := time.Duration(v.Days)*24*time.Hour +
+		time.Duration(v.Hours)*time.Hour +
+		time.Duration(v.Minutes)*time.Minute +
+		time.Duration(v.Seconds*float64(time.Second))
+	if v.Negative {
+		d = -d
+	}
+	return d, true
+}
+
+// String returns v in the lexical form of xsd:duration.
+func (v Duration) String() string {
+	var date, clock string
+	for _, f := range []struct {
+		s    *string
+		n    int
+		unit string
+	}{
+		{&date, v.Years, "Y"},
+		{&date, v.Months, "M"},
+		{&date, v.Days, "D"},
+		{&clock, v.Hours, "H"},
+		{&clock, v.Minutes, "M"},
+	} {
+		if f.n != 0 {
+			*f.s += strconv.Itoa(f.n) + f.unit
+		}
+	}
+	if v.Seconds != 0 || date == "" && clock == "" {
+		clock += strconv.FormatFloat(v.Seconds, 'f', -1, 64) + "S"
+	}
+	s := "P" + date
+	if clock != "" {
+		s += "T" + clock
+	}
+	if v.Negative {
+		return "-" + s
+	}
+	return s
+}
+
+var durationPattern = regexp.MustCompile(
+	` + "`" + `^(-)?P(?:(\d+)Y)?(?:(\d+)M)?(?:(\d+)D)?(?:T(?:(\d+)H)?(?:(\d+)M)?(?:(\d+(?:\.\d*)?)S)?)?$` + "`" + `)
+
+func (v *Duration) parse(s string) error {
+	s = strings.TrimSpace(s)
+	if s == "" {
+		*v = Duration{}
 		return nil
 	}
-	fmt.Fprintf(w, "type %s struct {\n", name)
-	if ge.needsTag[name] {
-		fmt.Fprintf(w, "XMLName xml.Name `xml:\"%s %s\" json:\"-\" yaml:\"-\"`\n",
-			d.TargetNamespace, ct.Name)
+	// the pattern matches durations without any field, or a T
+	// without any field after it, which are invalid
+	m := durationPattern.FindStringSubmatch(s)
+	if m == nil || strings.Join(m[2:], "") == "" || strings.HasSuffix(s, "T") {
+		return fmt.Errorf("Duration: invalid value %q", s)
 	}
-	err := ge.genStructFields(w, d, ct)
-	if err != nil {
-		return err
+	d := Duration{Negative: m[1] != ""}
+	for i, p := range []*int{&d.Years, &d.Months, &d.Days, &d.Hours, &d.Minutes} {
+		if m[i+2] == "" {
+			continue
+		}
+		n, err := strconv.Atoi(m[i+2])
+		if err != nil {
+			return fmt.Errorf("Duration: invalid value %q: %v", s, err)
+		}
+		*p = n
 	}
-	fmt.Fprintf(w, "}\n\n")
+	if m[7] != "" {
+		f, err := strconv.ParseFloat(m[7], 64)
+		if err != nil {
+			return fmt.Errorf("Duration: invalid value %q: %v", s, err)
+		}
+		d.Seconds = f
+	}
+	*v = d
 	return nil
 }
 
-func (ge *goEncoder) genStructFields(w io.Writer, d *wsdl.Definitions, ct *wsdl.ComplexType) error {
-	err := ge.genComplexContent(w, d, ct)
-	if err != nil {
+// MarshalXML implements the xml.Marshaler interface.
+func (v Duration) MarshalXML(e *xml.Encoder, start xml.StartElement) error {
+	return e.EncodeElement(v.String(), start)
+}
+
+// UnmarshalXML implements the xml.Unmarshaler interface.
+func (v *Duration) UnmarshalXML(d *xml.Decoder, start xml.StartElement) error {
+	var s string
+	if err := d.DecodeElement(&s, &start); err != nil {
 		return err
 	}
-	return ge.genElements(w, ct)
+	return v.parse(s)
 }
 
-func (ge *goEncoder) genComplexContent(w io.Writer, d *wsdl.Definitions, ct *wsdl.ComplexType) error {
-	if ct.ComplexContent == nil || ct.ComplexContent.Extension == nil {
+// MarshalXMLAttr implements the xml.MarshalerAttr interface.
+func (v Duration) MarshalXMLAttr(name xml.Name) (xml.Attr, error) {
+	return xml.Attr{Name: name, Value: v.String()}, nil
+}
+
+// UnmarshalXMLAttr implements the xml.UnmarshalerAttr interface.
+func (v *Duration) UnmarshalXMLAttr(attr xml.Attr) error {
+	return v.parse(attr.Value)
+}
+
+`
+
+var timeTypeT = template.Must(template.New("timeType").Parse(`
+{{.Comments}}type {{.Name}} struct {
+	time.Time
+}
+
+// String returns v in the lexical form of xsd:{{.XSD}}.
+func (v {{.Name}}) String() string {
+{{- if .UTCLayout}}
+	if v.Location() == time.UTC {
+		return v.Format({{.UTCLayout}})
+	}
+{{- end}}
+	return v.Format({{.Layout}})
+}
+
+func (v *{{.Name}}) parse(s string) error {
+	s = strings.TrimSpace(s)
+	if s == "" {
+		*v = {{.Name}}{}
 		return nil
 	}
-	ext := ct.ComplexContent.Extension
-	if ext.Base != "" {
-		base, exists := ge.ctypes[trimns(ext.Base)]
-		if exists {
-			err := ge.genStructFields(w, d, base)
-			if err != nil {
-				return err
-			}
+	// fractional seconds are accepted by both layouts
+	for _, layout := range []string{ {{- .ZoneLayout}}, {{.LocalLayout -}} } {
+		t, err := time.Parse(layout, s)
+		if err == nil {
+			*v = {{.Name}}{t}
+			return nil
 		}
 	}
-	if ext.Sequence == nil {
-		return nil
+	return fmt.Errorf("{{.Name}}: invalid value %q", s)
+}
+
+// MarshalXML implements the xml.Marshaler interface.
+func (v {{.Name}}) MarshalXML(e *xml.Encoder, start xml.StartElement) error {
+	return e.EncodeElement(v.String(), start)
+}
+
+// UnmarshalXML implements the xml.Unmarshaler interface.
+func (v *{{.Name}}) UnmarshalXML(d *xml.Decoder, start xml.StartElement) error {
+	var s string
+	if err := d.DecodeElement(&s, &start); err != nil {
+		return err
 	}
-	seq := ext.Sequence
-	for _, v := range seq.ComplexTypes {
-		err := ge.genElements(w, v)
-		if err != nil {
-			return err
+	return v.parse(s)
+}
+
+// MarshalXMLAttr implements the xml.MarshalerAttr interface.
+func (v {{.Name}}) MarshalXMLAttr(name xml.Name) (xml.Attr, error) {
+	return xml.Attr{Name: name, Value: v.String()}, nil
+}
+
+// UnmarshalXMLAttr implements the xml.UnmarshalerAttr interface.
+func (v *{{.Name}}) UnmarshalXMLAttr(attr xml.Attr) error {
+	return v.parse(attr.Value)
+}
+
+`))
+
+// timeType describes a type generated for an XML Schema date or time
+// type, which wraps time.Time.
+type timeType struct {
+	Name, XSD string
+	Doc       string
+	// Go expressions of the layouts
+	Layout      string // to write values
+	UTCLayout   string // to write values in UTC, if not Layout
+	ZoneLayout  string // to read values with a time zone
+	LocalLayout string // to read values without one
+}
+
+var (
+	dateTimeType = &timeType{
+		Name:        "DateTime",
+		XSD:         "dateTime",
+		Doc:         "DateTime is a value of xsd:dateTime. Values without a time zone are read as UTC.",
+		Layout:      `time.RFC3339Nano`,
+		ZoneLayout:  `time.RFC3339`,
+		LocalLayout: `"2006-01-02T15:04:05"`,
+	}
+	dateType = &timeType{
+		Name: "Date",
+		XSD:  "date",
+		Doc: "Date is a value of xsd:date: the day of its Time, with an optional time zone. " +
+			"Values without a time zone are read as UTC, and values in UTC are written without one.",
+		Layout:      `"2006-01-02Z07:00"`,
+		UTCLayout:   `"2006-01-02"`,
+		ZoneLayout:  `"2006-01-02Z07:00"`,
+		LocalLayout: `"2006-01-02"`,
+	}
+	timeOfDayType = &timeType{
+		Name: "Time",
+		XSD:  "time",
+		Doc: "Time is a value of xsd:time: the time of day of its Time, with an optional time zone. " +
+			"Values without a time zone are read as UTC, and values in UTC are written without one.",
+		Layout:      `"15:04:05.999999999Z07:00"`,
+		UTCLayout:   `"15:04:05.999999999"`,
+		ZoneLayout:  `"15:04:05Z07:00"`,
+		LocalLayout: `"15:04:05"`,
+	}
+)
+
+// timeTypes lists the types generated for XML Schema date and time
+// types, in the order they are written.
+var timeTypes = []*timeType{
+	dateType,
+	timeOfDayType,
+	dateTimeType,
+	gType("GYear", "gYear", "a year", `"2006"`),
+	gType("GYearMonth", "gYearMonth", "a month of a year", `"2006-01"`),
+	gType("GMonth", "gMonth", "a month of every year", `"--01"`),
+	gType("GMonthDay", "gMonthDay", "a day of every year", `"--01-02"`),
+	gType("GDay", "gDay", "a day of every month", `"---02"`),
+}
+
+// gType returns the timeType of one of the Gregorian XML Schema types,
+// which hold the given part of a date, written with layout.
+func gType(name, xsd, part, layout string) *timeType {
+	zone := layout[:len(layout)-1] + `Z07:00"`
+	return &timeType{
+		Name: name,
+		XSD:  xsd,
+		Doc: fmt.Sprintf("%s is a value of xsd:%s, %s, with an optional time zone. "+
+			"Only that part of its Time is used. Values without a time zone are read as UTC, "+
+			"and values in UTC are written without one.", name, xsd, part),
+		Layout:      zone,
+		UTCLayout:   layout,
+		ZoneLayout:  zone,
+		LocalLayout: layout,
+	}
+}
+
+var listT = template.Must(template.New("list").Parse(`
+{{.Doc}}// {{.Name}} is a whitespace separated list of {{.Type}}.
+type {{.Name}} []{{.Type}}
+
+// String returns the items of v separated by spaces, each written as
+// the value of an attribute or as text, if its type can be.
+func (v {{.Name}}) String() string {
+	items := make([]string, len(v))
+	for i, item := range v {
+		switch x := interface{}(item).(type) {
+		case xml.MarshalerAttr:
+			attr, _ := x.MarshalXMLAttr(xml.Name{})
+			items[i] = attr.Value
+		case encoding.TextMarshaler:
+			text, _ := x.MarshalText()
+			items[i] = string(text)
+		default:
+			items[i] = fmt.Sprint(item)
 		}
 	}
-	for _, v := range ext.Sequence.Elements {
-		ge.genElementField(w, v)
+	return strings.Join(items, " ")
+}
+
+func (v *{{.Name}}) parse(s string) error {
+	fields := strings.Fields(s)
+	items := make({{.Name}}, len(fields))
+	for i, f := range fields {
+		var err error
+		switch x := interface{}(&items[i]).(type) {
+		case xml.UnmarshalerAttr:
+			err = x.UnmarshalXMLAttr(xml.Attr{Value: f})
+		case encoding.TextUnmarshaler:
+			err = x.UnmarshalText([]byte(f))
+		default:
+			_, err = fmt.Sscan(f, x)
+		}
+		if err != nil {
+			return fmt.Errorf("{{.Name}}: invalid item %q: %v", f, err)
+		}
 	}
+	*v = items
 	return nil
 }
 
-func (ge *goEncoder) genElements(w io.Writer, ct *wsdl.ComplexType) error {
-	for _, el := range ct.AllElements {
-		ge.genElementField(w, el)
+// MarshalXML implements the xml.Marshaler interface.
+func (v {{.Name}}) MarshalXML(e *xml.Encoder, start xml.StartElement) error {
+	return e.EncodeElement(v.String(), start)
+}
+
+// UnmarshalXML implements the xml.Unmarshaler interface.
+func (v *{{.Name}}) UnmarshalXML(d *xml.Decoder, start xml.StartElement) error {
+	var s string
+	if err := d.DecodeElement(&s, &start); err != nil {
+		return err
+	}
+	return v.parse(s)
+}
+
+// MarshalXMLAttr implements the xml.MarshalerAttr interface.
+func (v {{.Name}}) MarshalXMLAttr(name xml.Name) (xml.Attr, error) {
+	return xml.Attr{Name: name, Value: v.String()}, nil
+}
+
+// UnmarshalXMLAttr implements the xml.UnmarshalerAttr interface.
+func (v *{{.Name}}) UnmarshalXMLAttr(attr xml.Attr) error {
+	return v.parse(attr.Value)
+}
+`))
+
+var unionT = template.Must(template.New("union").Parse(`
+{{.Doc}}type {{.Name}} struct {
+	Value interface{}
+}
+
+// String returns the value of v as text.
+func (v {{.Name}}) String() string {
+	if v.Value == nil {
+		return ""
+	}
+	return fmt.Sprint(v.Value)
+}
+
+// parse sets the value of v to s as the first member type s is
+// valid for.
+func (v *{{.Name}}) parse(s string) error {
+	var b bytes.Buffer
+	b.WriteString("<v>")
+	xml.EscapeText(&b, []byte(s))
+	b.WriteString("</v>")
+	{{range .Members}}{
+		var x {{.Type}}
+		if err := xml.Unmarshal(b.Bytes(), &x); err == nil{{if .Validate}} && x.Valid(){{end}}{{range $i, $v := .Enum}}{{if eq $i 0}} && (x == {{$v}}{{else}} || x == {{$v}}{{end}}{{end}}{{if .Enum}}){{end}} {
+			v.Value = x
+			return nil
+		}
 	}
-	if ct.Sequence == nil {
+	{{end}}return fmt.Errorf("{{.Name}}: invalid value %q", s)
+}
+
+// MarshalXML implements the xml.Marshaler interface.
+func (v {{.Name}}) MarshalXML(e *xml.Encoder, start xml.StartElement) error {
+	if v.Value == nil {
 		return nil
 	}
-	for _, el := range ct.Sequence.Elements {
-		ge.genElementField(w, el)
+	return e.EncodeElement(v.Value, start)
+}
+
+// UnmarshalXML implements the xml.Unmarshaler interface.
+func (v *{{.Name}}) UnmarshalXML(d *xml.Decoder, start xml.StartElement) error {
+	var s string
+	if err := d.DecodeElement(&s, &start); err != nil {
+		return err
 	}
-	return nil
+	return v.parse(s)
 }
 
-func (ge *goEncoder) genElementField(w io.Writer, el *wsdl.Element) {
-	if el.Ref != "" {
-		ref := trimns(el.Ref)
-		nel, ok := ge.elements[ref]
-		if !ok {
-			return
-		}
-		el = nel
+// MarshalXMLAttr implements the xml.MarshalerAttr interface.
+func (v {{.Name}}) MarshalXMLAttr(name xml.Name) (xml.Attr, error) {
+	if m, ok := v.Value.(xml.MarshalerAttr); ok {
+		return m.MarshalXMLAttr(name)
 	}
-	if el.Type == "" {
-		el.Type = "string"
+	if v.Value == nil {
+		return xml.Attr{}, nil
 	}
-	var slicetype string
-	if el.Type == "" && el.ComplexType != nil {
-		seq := el.ComplexType.Sequence
-		if seq != nil {
-			if len(seq.Elements) == 1 {
-				n := el.Name
-				el = el.ComplexType.Sequence.Elements[0]
-				slicetype = el.Name
-				el.Name = n
-			} else if len(seq.Any) == 1 {
-				el = &wsdl.Element{
-					Name: el.Name,
-					Type: "anysequence",
-					Min:  seq.Any[0].Min,
-					Max:  seq.Any[0].Max,
-				}
-				slicetype = el.Name
+	return xml.Attr{Name: name, Value: v.String()}, nil
+}
+
+// UnmarshalXMLAttr implements the xml.UnmarshalerAttr interface.
+func (v *{{.Name}}) UnmarshalXMLAttr(attr xml.Attr) error {
+	return v.parse(attr.Value)
+}
+`))
+
+// unionMember is a member type of a union, with the enumeration its
+// values are restricted to, if any.
+type unionMember struct {
+	Type     string
+	Validate bool     // the type has a Validate method
+	Enum     []string // Go literals of an anonymous enumeration
+}
+
+// genUnion writes a struct type for the union simple type st. Its
+// value is parsed as the first member type, in order, that accepts
+// it, and encoded as the member type it holds.
+func (ge *goEncoder) genUnion(w io.Writer, st *wsdl.SimpleType) {
+	var members []*unionMember
+	for _, t := range strings.Fields(st.Union.MemberTypes) {
+		m := &unionMember{Type: ge.wsdl2goType(t)}
+		if !ge.isBuiltinType(t) {
+			if mt, ok := ge.stypes[ge.typeName(ge.fieldSchema, t)]; ok && mt.Restriction != nil {
+				m.Validate = len(mt.Restriction.Enum) > 0
 			}
 		}
+		members = append(members, m)
 	}
-	tag := el.Name
-	name := scrubName(strings.Title(el.Name))
-	fmt.Fprintf(w, "%s ", name)
-	if el.Max != "" && el.Max != "1" {
-		fmt.Fprintf(w, "[]")
-		if slicetype != "" {
-			tag = el.Name + ">" + slicetype
+	for _, mt := range st.Union.SimpleTypes {
+		r := mt.Restriction
+		if r == nil {
+			continue
+		}
+		m := &unionMember{Type: ge.wsdl2goType(r.Base)}
+		for _, e := range r.Enum {
+			switch m.Type {
+			case "string":
+				m.Enum = append(m.Enum, strconv.Quote(e.Value))
+			case "bool":
+				m.Enum = append(m.Enum, e.Value)
+			default:
+				if _, ok := numericBits[m.Type]; ok {
+					m.Enum = append(m.Enum, e.Value)
+				}
+			}
 		}
+		members = append(members, m)
 	}
-	typ := ge.wsdl2goType(el.Type)
-	if el.Nillable || el.Min == 0 {
-		tag += ",omitempty"
+	types := make([]string, len(members))
+	for i, m := range members {
+		types[i] = m.Type
+	}
+	var doc bytes.Buffer
+	name := ge.goName(st.Name)
+	s := name + " is a union of: " + strings.Join(types, ", ") + "."
+	if st.Doc != "" {
+		s = st.Doc + " " + s
+	}
+	writeComments(&doc, name, s)
+	ge.needsStdPkg["bytes"] = true
+	ge.needsStdPkg["encoding/xml"] = true
+	ge.needsStdPkg["fmt"] = true
+	unionT.Execute(w, &struct {
+		Doc     string
+		Name    string
+		Members []*unionMember
+	}{
+		doc.String(),
+		name,
+		members,
+	})
+}
+
+// derivedType returns the name of the type holding values of the
+// complex type base or of the types derived from it.
+func (ge *goEncoder) derivedType(base string) string {
+	return "Any" + ge.exportedName(base)
+}
+
+func (ge *goEncoder) sortedDerivedTypes() []string {
+	names := make([]string, 0, len(ge.derivedUsed))
+	for name := range ge.derivedUsed {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+var derivedTypeT = template.Must(template.New("derivedType").Parse(`
+{{.Doc}}type {{.Name}} struct {
+	Value {{.Value}}
+}
+{{if .Base}}
+// {{.Value}} is implemented by the types of the values of {{.Name}}.
+type {{.Value}} interface {
+	is{{.Value}}()
+}
+{{range .Types}}
+func (*{{.}}) is{{$.Value}}() {}
+{{end}}{{end}}
+// MarshalXML implements the xml.Marshaler interface.
+func (v {{.Name}}) MarshalXML(e *xml.Encoder, start xml.StartElement) error {
+	return soap.MarshalDerived(e, start, v.Value, {{if .Base}}(*{{.Base}})(nil){{else}}nil{{end}})
+}
+
+// UnmarshalXML implements the xml.Unmarshaler interface.
+func (v *{{.Name}}) UnmarshalXML(d *xml.Decoder, start xml.StartElement) error {
+	value, err := soap.UnmarshalDerived(d, start, {{if .Base}}(*{{.Base}})(nil){{else}}nil{{end}})
+	if err != nil {
+		return err
+	}
+	if value, ok := value.({{.Value}}); ok {
+		v.Value = value
+		return nil
+	}
+	return fmt.Errorf("{{.Name}}: unexpected type %T", value)
+}
+
+`))
+
+// genDerivedType writes the type holding values of the complex type
+// base or of the types derived from it. The values of an abstract
+// type are held by the interface generated for it.
+func (ge *goEncoder) genDerivedType(w io.Writer, base string) {
+	ct := ge.ctypes[base]
+	var types []string
+	var goBase string
+	value := ge.exportedName(base)
+	if !ct.Abstract {
+		goBase = value
+		value = ge.derivedType(base) + "Value"
+		types = append(types, goBase)
+	}
+	members := ge.concreteDerivedTypes(base)
+	for _, name := range members {
+		types = append(types, ge.exportedName(name))
+	}
+	ge.needsStdPkg["encoding/xml"] = true
+	ge.needsStdPkg["fmt"] = true
+	ge.needsExtPkg["github.com/seamuncle/wsdl2go/soap"] = true
+	ge.needsTypeRegistry = true
+	name := ge.derivedType(base)
+	doc := fmt.Sprintf("%s holds a value of a type derived from the abstract type %s.", name, base)
+	if len(members) > 0 {
+		doc = fmt.Sprintf("%s holds a value of a type derived from the abstract type %s: %s.", name, base, strings.Join(members, ", "))
+	}
+	if goBase != "" {
+		doc = fmt.Sprintf("%s holds a %s, or a value of a type derived from it: %s.", name, base, strings.Join(members, ", "))
+	}
+	var b bytes.Buffer
+	writeComments(&b, name, doc+" Values of derived types are encoded with an xsi:type attribute naming their type.")
+	derivedTypeT.Execute(w, &struct {
+		Doc   string
+		Name  string
+		Base  string
+		Value string
+		Types []string
+	}{
+		b.String(),
+		name,
+		goBase,
+		value,
+		types,
+	})
+}
+
+// concreteDerivedTypes returns the names of the complex types derived
+// from base that are not abstract.
+func (ge *goEncoder) concreteDerivedTypes(base string) []string {
+	var names []string
+	for _, name := range ge.derived[base] {
+		if !ge.ctypes[name].Abstract {
+			names = append(names, name)
+		}
+	}
+	return names
+}
+
+var abstractTypeT = template.Must(template.New("abstractType").Parse(`
+{{.Doc}}type {{.Name}} interface {
+	{{- with .Base}}
+	{{.}}
+	{{- end}}
+	is{{.Name}}()
+}
+{{range .Types}}
+func (*{{.}}) is{{$.Name}}() {}
+{{end}}
+`))
+
+// genAbstractType writes an interface for the abstract complex type
+// ct, implemented by the types derived from it. Values of abstract
+// types are held by fields of the type returned by derivedType.
+func (ge *goEncoder) genAbstractType(w io.Writer, ct *wsdl.ComplexType) {
+	name := ge.exportedName(ct.Name)
+	var base string
+	if cc := ct.ComplexContent; cc != nil && cc.Extension != nil {
+		if bt, ok := ge.ctypes[ge.typeName(ge.typeSchema[ct.Name], cc.Extension.Base)]; ok && bt.Abstract && bt != ct {
+			base = ge.exportedName(bt.Name)
+		}
+	}
+	members := ge.concreteDerivedTypes(ct.Name)
+	var types []string
+	for _, m := range members {
+		types = append(types, ge.exportedName(m))
+	}
+	s := fmt.Sprintf("%s is implemented by the types derived from the abstract type %s", name, ct.Name)
+	if len(members) > 0 {
+		s += ": " + strings.Join(members, ", ")
+	}
+	s += "."
+	if ct.Doc != "" {
+		s = ct.Doc + " " + s
+	}
+	var doc bytes.Buffer
+	writeComments(&doc, name, s)
+	abstractTypeT.Execute(w, &struct {
+		Doc   string
+		Name  string
+		Base  string
+		Types []string
+	}{
+		doc.String(),
+		name,
+		base,
+		types,
+	})
+}
+
+var typeRegistryT = template.Must(template.New("typeRegistry").Parse(`
+func init() {
+{{- range .}}
+	soap.RegisterType(xml.Name{Space: {{printf "%q" .Space}}, Local: {{printf "%q" .Local}}}, (*{{.Type}})(nil))
+{{- end}}
+}
+
+`))
+
+// registeredType is a named schema type and the Go type generated
+// for it.
+type registeredType struct{ Space, Local, Type string }
+
+// genTypeRegistry registers the generated types of the named schema
+// types with the soap package, if any value of xsd:anyType may need
+// them to be decoded.
+func (ge *goEncoder) genTypeRegistry(w io.Writer, d *wsdl.Definitions) {
+	if !ge.needsTypeRegistry {
+		return
+	}
+	var types []*registeredType
+	for _, s := range d.Schemas {
+		for _, st := range s.SimpleTypes {
+			name := ge.typeNames["{"+s.TargetNamespace+"}"+st.Name]
+			if ge.typeSchema[name] != s || st.Restriction == nil && st.Union == nil && st.List == nil {
+				continue
+			}
+			typ, ok := ge.mappedType("{" + s.TargetNamespace + "}" + st.Name)
+			if !ok {
+				typ = ge.goName(name)
+			}
+			types = append(types, &registeredType{s.TargetNamespace, st.Name, typ})
+		}
+		for _, ct := range s.ComplexTypes {
+			name := ge.typeNames["{"+s.TargetNamespace+"}"+ct.Name]
+			if ge.typeSchema[name] != s || ct.Abstract || ge.isMappedType(s.TargetNamespace, ct.Name) {
+				continue
+			}
+			types = append(types, &registeredType{s.TargetNamespace, ct.Name, ge.exportedName(name)})
+		}
+	}
+	if len(types) == 0 {
+		return
+	}
+	ge.needsStdPkg["encoding/xml"] = true
+	typeRegistryT.Execute(w, types)
+}
+
+// genList writes a slice type for the list simple type st, which is
+// encoded as a whitespace separated string.
+func (ge *goEncoder) genList(w io.Writer, st *wsdl.SimpleType) {
+	item := st.List.ItemType
+	if item == "" && st.List.SimpleType != nil && st.List.SimpleType.Restriction != nil {
+		item = st.List.SimpleType.Restriction.Base
+	}
+	typ := "string"
+	if item != "" {
+		typ = ge.wsdl2goType(item)
+	}
+	ge.needsStdPkg["encoding"] = true
+	ge.needsStdPkg["encoding/xml"] = true
+	ge.needsStdPkg["fmt"] = true
+	ge.needsStdPkg["strings"] = true
+	name := ge.goName(st.Name)
+	var doc bytes.Buffer
+	if st.Doc != "" {
+		writeComments(&doc, name, st.Doc)
+		doc.WriteString("//\n")
+	}
+	listT.Execute(w, &struct {
+		Doc  string
+		Name string
+		Type string
+	}{
+		doc.String(),
+		name,
+		typ,
+	})
+}
+
+var validatorT = template.Must(template.New("validator").Parse(`
+// Values returns the enumerated values of {{.TypeName}}.
+func ({{.TypeName}}) Values() []{{.TypeName}} {
+	return []{{.TypeName}}{
+		{{range .Args}}{{.}},{{"\n"}}{{end}}
+	}
+}
+
+// Valid reports whether v is one of the enumerated values of {{.TypeName}}.
+func (v {{.TypeName}}) Valid() bool {
+	for _, vv := range v.Values() {
+		if {{if .Comparable}}v == vv{{else}}reflect.DeepEqual(v, vv){{end}} {
+			return true
+		}
+	}
+	return false
+}
+
+{{- if not .Checked}}
+
+// Validate validates {{.TypeName}}. It is equivalent to Valid.
+func (v {{.TypeName}}) Validate() bool {
+	return v.Valid()
+}
+{{- end}}
+`))
+
+func (ge *goEncoder) genValidator(w io.Writer, typeName string, r *wsdl.Restriction) {
+	if len(r.Enum) == 0 {
+		return
+	}
+	t := ge.wsdl2goType(r.Base)
+	values := ge.enumValues(typeName, r)
+	args := make([]string, len(values))
+	for i, v := range values {
+		if v.Name != "" {
+			args[i] = v.Name
+			continue
+		}
+		args[i] = v.Doc + v.Value
+	}
+	comparable := ge.basicType(r.Base) != ""
+	if !comparable {
+		ge.needsStdPkg["reflect"] = true
+	}
+	validatorT.Execute(w, &struct {
+		TypeName   string
+		Type       string
+		Comparable bool
+		Checked    bool // by the Validate method of genFacetValidate
+		Args       []string
+	}{
+		typeName,
+		t,
+		comparable,
+		ge.validTypes[typeName],
+		args,
+	})
+}
+
+// enumValue is a value of an enumerated simple type.
+type enumValue struct {
+	Name  string // of the constant declared for it, if any
+	Value string // as a Go literal
+	Doc   string
+}
+
+// enumValues returns the values of the enumeration of r, the
+// restriction of the simple type typeName. Values of types based on
+// a Go basic type are named by constants, prefixed by typeName.
+func (ge *goEncoder) enumValues(typeName string, r *wsdl.Restriction) []*enumValue {
+	basic := ge.basicType(r.Base)
+	seen := make(map[string]bool)
+	values := make([]*enumValue, len(r.Enum))
+	for i, e := range r.Enum {
+		v := &enumValue{Value: e.Value}
+		if basic == "string" {
+			v.Value = strconv.Quote(e.Value)
+		}
+		if e.Doc != "" {
+			var doc bytes.Buffer
+			writeComments(&doc, "", e.Doc)
+			v.Doc = doc.String()
+		}
+		if basic != "" {
+			name := typeName + enumConstName(e.Value)
+			for n := 2; seen[name]; n++ {
+				name = typeName + enumConstName(e.Value) + strconv.Itoa(n)
+			}
+			seen[name] = true
+			v.Name = name
+		}
+		values[i] = v
+	}
+	return values
+}
+
+// basicType returns the Go basic type the simple type t is based on,
+// following restrictions of other simple types, or an empty string if
+// it is not based on one that can be declared as a constant.
+func (ge *goEncoder) basicType(t string) string {
+	seen := make(map[string]bool)
+	for {
+		typ := ge.wsdl2goType(t)
+		if _, ok := numericBits[typ]; ok || typ == "string" || typ == "bool" {
+			return typ
+		}
+		st, ok := ge.stypes[typ]
+		if !ok || st.Restriction == nil || seen[typ] {
+			return ""
+		}
+		seen[typ] = true
+		t = st.Restriction.Base
+	}
+}
+
+// enumConstName returns the suffix of the name of the constant of
+// the enumerated value v: its words, title cased, with anything else
+// than letters and digits dropped.
+func enumConstName(v string) string {
+	var name string
+	if strings.HasPrefix(v, "-") {
+		name = "Minus"
+	}
+	words := strings.FieldsFunc(v, func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+	for _, word := range words {
+		name += strings.Title(word)
+	}
+	if name == "" {
+		return "Empty"
+	}
+	return name
+}
+
+var enumConstsT = template.Must(template.New("enumConsts").Parse(`
+// Enumerated values of {{.Type}}.
+const (
+{{- range .Values}}
+	{{.Doc}}{{.Name}} {{$.Type}} = {{.Value}}
+{{- end}}
+)
+
+`))
+
+// genEnumConsts writes the constants naming the values of the
+// enumeration of r, the restriction of the simple type typeName.
+func (ge *goEncoder) genEnumConsts(w io.Writer, typeName string, r *wsdl.Restriction) {
+	if len(r.Enum) == 0 || ge.basicType(r.Base) == "" {
+		return
+	}
+	enumConstsT.Execute(w, &struct {
+		Type   string
+		Values []*enumValue
+	}{
+		typeName,
+		ge.enumValues(typeName, r),
+	})
+}
+
+func isArray(ct *wsdl.ComplexType) bool {
+	return ct.ComplexContent != nil &&
+		ct.ComplexContent.Restriction != nil &&
+		trimns(ct.ComplexContent.Restriction.Base) == "Array" &&
+		ct.ComplexContent.Restriction.Attribute != nil &&
+		ct.ComplexContent.Restriction.Attribute.Key == "arrayType"
+}
+
+func (ge *goEncoder) genGoStruct(w io.Writer, d *wsdl.Definitions, ct *wsdl.ComplexType) error {
+	if ct.Abstract {
+		ge.genAbstractType(w, ct)
+		return nil
+	}
+	ge.anyElement = false
+	ge.defaults = nil
+	ge.nillable = nil
+	ge.occurs = nil
+	ge.fieldGetters = nil
+	ge.fields = nil
+	ge.fieldNames = make(map[string]bool)
+	name := ge.exportedName(ct.Name)
+	writeComments(w, name, ct.Doc)
+
+	// Do array search
+	if isArray(ct) {
+		wsdlType := ct.ComplexContent.Restriction.Attribute.Value
+		wsdlArrayOf := trimns(wsdlType)
+		matches := regexp.MustCompile("([^\\[\\]]+)(.+)").FindStringSubmatch(wsdlArrayOf)
+		goArrayOf := matches[1]
+		dimensions := matches[2]
+		fmt.Fprintf(w, "type %s %s%s\n\n", name, dimensions, goArrayOf)
+		return nil
+	}
+	if ge.tests {
+		ge.testTypes[name] = true
+	}
+
+	if !hasFields(ct) && !isMixed(ct) {
+		fmt.Fprintf(w, "type %s struct {}\n\n", name)
+		ge.genValidateStruct(w, name)
+		ge.genClone(w, name)
+		return nil
+	}
+	fmt.Fprintf(w, "type %s struct {\n", name)
+	if ge.needsTag[name] {
+		local := ge.typeLocalName(ct.Name)
+		if el, ok := ge.typeElements[ct.Name]; ok {
+			local = el
+		}
+		fmt.Fprintf(w, "XMLName xml.Name %s\n",
+			ge.fieldTag(ge.typeNamespace(d, ct.Name)+" "+local, "-", false))
+	}
+	err := ge.genStructFields(w, d, ct)
+	if err != nil {
+		return err
+	}
+	if ge.hasAnyAttribute(ct) {
+		ge.needsStdPkg["encoding/xml"] = true
+		ge.addField("Attrs", "[]xml.Attr", "[]xml.Attr", false)
+		fmt.Fprintf(w, "Attrs []xml.Attr %s\n", ge.fieldTag(",any,attr", "-", false))
+	}
+	if isMixed(ct) {
+		// text interleaved with child elements is accumulated here
+		fmt.Fprintf(w, "Text string %s\n", ge.fieldTag(",chardata", "text", true))
+	}
+	fmt.Fprintf(w, "}\n\n")
+	ge.genConstructor(w, d, name)
+	ge.genSetDefaults(w, name)
+	ge.genMarshalXML(w, name, ge.hasAnyAttribute(ct))
+	ge.genValidateStruct(w, name)
+	ge.genGetters(w, name)
+	ge.genMessageString(w, ct.Name, name)
+	ge.genClone(w, name)
+	return nil
+}
+
+// cacheStructFields records the names of the fields of the structs of
+// the complex types, if methods are to be generated that must be left
+// out of the structs having a field of their name. The fields are
+// generated beforehand to that end.
+func (ge *goEncoder) cacheStructFields(d *wsdl.Definitions) error {
+	ge.structFields = make(map[string]map[string]bool)
+	if !ge.clone && !ge.validate {
+		return nil
+	}
+	for _, name := range ge.sortedComplexTypes() {
+		ct := ge.ctypes[name]
+		if ct.Abstract || isArray(ct) || ge.isCachedTypeMapped(name) {
+			continue
+		}
+		ge.anyElement = false
+		ge.fieldNames = make(map[string]bool)
+		err := ge.genStructFields(ioutil.Discard, d, ct)
+		if err != nil {
+			return err
+		}
+		ge.structFields[ge.exportedName(name)] = ge.fieldNames
+	}
+	ge.fields = nil
+	ge.fieldNames = make(map[string]bool)
+	ge.defaults = nil
+	ge.nillable = nil
+	ge.occurs = nil
+	ge.fieldGetters = nil
+	return nil
+}
+
+// isMixed reports whether ct allows text content between its elements.
+func isMixed(ct *wsdl.ComplexType) bool {
+	return ct.Mixed || ct.ComplexContent != nil && ct.ComplexContent.Mixed
+}
+
+// baseType returns the complex type named by base, the base of an
+// extension, looking it up among the types of other packages first.
+func (ge *goEncoder) baseType(base string) (*wsdl.ComplexType, bool) {
+	if ge.fieldSchema != nil && len(ge.foreignTypes) > 0 {
+		if t, ok := ge.foreignTypes[ge.fieldSchema.ResolveQName(base)]; ok {
+			return t.ct, true
+		}
+	}
+	ct, ok := ge.ctypes[ge.typeName(ge.fieldSchema, base)]
+	return ct, ok
+}
+
+// complexTypeSchema returns the schema declaring ct, which may be a
+// type of another package.
+func (ge *goEncoder) complexTypeSchema(ct *wsdl.ComplexType) (*wsdl.Schema, bool) {
+	if ge.ctypes[ct.Name] != ct {
+		for _, t := range ge.foreignTypes {
+			if t.ct == ct {
+				return t.s, true
+			}
+		}
+	}
+	s, ok := ge.typeSchema[ct.Name]
+	return s, ok
+}
+
+// hasFields reports whether ct declares any element, either directly
+// or by extending another type.
+func hasFields(ct *wsdl.ComplexType) bool {
+	if len(ct.AllElements) > 0 || ct.Group != nil ||
+		len(ct.Attributes) > 0 || len(ct.AttributeGroups) > 0 {
+		return true
+	}
+	if ct.ComplexContent != nil && ct.ComplexContent.Extension != nil {
+		return true
+	}
+	if ct.SimpleContent != nil {
+		return true
+	}
+	if seq := ct.Sequence; seq != nil && (len(seq.ComplexTypes) > 0 ||
+		len(seq.Elements) > 0 || len(seq.Choices) > 0 || len(seq.Groups) > 0 || len(seq.Any) > 0) {
+		return true
+	}
+	return ct.Choice != nil && (len(ct.Choice.Elements) > 0 || len(ct.Choice.Groups) > 0)
+}
+
+// hasAnyAttribute reports whether ct, its base types or its attribute
+// groups allow attributes not declared by the schema.
+func (ge *goEncoder) hasAnyAttribute(ct *wsdl.ComplexType) bool {
+	if ct.AnyAttribute != nil || ge.attrGroupsHaveAny(ct.AttributeGroups) {
+		return true
+	}
+	var ext *wsdl.Extension
+	if ct.ComplexContent != nil {
+		ext = ct.ComplexContent.Extension
+	} else if ct.SimpleContent != nil {
+		ext = ct.SimpleContent.Extension
+	}
+	if ext == nil {
+		return false
+	}
+	if ext.AnyAttribute != nil || ge.attrGroupsHaveAny(ext.AttributeGroups) {
+		return true
+	}
+	base, exists := ge.baseType(ext.Base)
+	return exists && base != ct && ge.hasAnyAttribute(base)
+}
+
+func (ge *goEncoder) attrGroupsHaveAny(refs []*wsdl.AttributeGroup) bool {
+	for _, ref := range refs {
+		g, ok := ge.attrGroups[trimns(ref.Ref)]
+		key := "attributeGroup " + trimns(ref.Ref)
+		if !ok || ge.expanding[key] {
+			continue
+		}
+		ge.expanding[key] = true
+		found := g.AnyAttribute != nil || ge.attrGroupsHaveAny(g.AttributeGroups)
+		delete(ge.expanding, key)
+		if found {
+			return true
+		}
+	}
+	return false
+}
+
+func (ge *goEncoder) genStructFields(w io.Writer, d *wsdl.Definitions, ct *wsdl.ComplexType) error {
+	if s, ok := ge.complexTypeSchema(ct); ok {
+		defer func(s *wsdl.Schema) { ge.fieldSchema = s }(ge.fieldSchema)
+		ge.fieldSchema = s
+	}
+	err := ge.genComplexContent(w, d, ct)
+	if err != nil {
+		return err
+	}
+	err = ge.genSimpleContent(w, d, ct)
+	if err != nil {
+		return err
+	}
+	err = ge.genElements(w, ct)
+	if err != nil {
+		return err
+	}
+	for _, a := range ct.Attributes {
+		ge.genAttributeField(w, a)
+	}
+	ge.genAttributeGroupFields(w, ct.AttributeGroups)
+	return nil
+}
+
+func (ge *goEncoder) genComplexContent(w io.Writer, d *wsdl.Definitions, ct *wsdl.ComplexType) error {
+	if ct.ComplexContent == nil || ct.ComplexContent.Extension == nil {
+		return nil
+	}
+	ext := ct.ComplexContent.Extension
+	if ext.Base != "" {
+		base, exists := ge.baseType(ext.Base)
+		if exists {
+			err := ge.genStructFields(w, d, base)
+			if err != nil {
+				return err
+			}
+		}
+	}
+	ge.genChoiceFields(w, ext.Choice)
+	ge.genGroupFields(w, ext.Group)
+	for _, a := range ext.Attributes {
+		ge.genAttributeField(w, a)
+	}
+	ge.genAttributeGroupFields(w, ext.AttributeGroups)
+	if ext.Sequence == nil {
+		return nil
+	}
+	seq := ext.Sequence
+	for _, v := range seq.ComplexTypes {
+		err := ge.genElements(w, v)
+		if err != nil {
+			return err
+		}
+	}
+	ge.genSequenceFields(w, seq)
+	return nil
+}
+
+// genSimpleContent writes the text body of ct as a Value field,
+// followed by the attributes the simple content extension declares.
+// When the base is itself a complex type with simple content, its
+// fields are written instead of the Value field.
+func (ge *goEncoder) genSimpleContent(w io.Writer, d *wsdl.Definitions, ct *wsdl.ComplexType) error {
+	if ct.SimpleContent == nil {
+		return nil
+	}
+	var base string
+	ext := ct.SimpleContent.Extension
+	if ext != nil {
+		base = ext.Base
+	} else if r := ct.SimpleContent.Restriction; r != nil {
+		base = r.Base
+	}
+	if bt, exists := ge.baseType(base); exists && bt != ct {
+		err := ge.genStructFields(w, d, bt)
+		if err != nil {
+			return err
+		}
+	} else {
+		typ := "string"
+		if base != "" {
+			typ = ge.wsdl2goType(base)
+		}
+		ge.addField("Value", typ, typ, true)
+		fmt.Fprintf(w, "Value %s %s\n", typ, ge.fieldTag(",chardata", "value", true))
+	}
+	if ext == nil {
+		return nil
+	}
+	for _, a := range ext.Attributes {
+		ge.genAttributeField(w, a)
+	}
+	ge.genAttributeGroupFields(w, ext.AttributeGroups)
+	return nil
+}
+
+func (ge *goEncoder) genElements(w io.Writer, ct *wsdl.ComplexType) error {
+	for _, el := range ct.AllElements {
+		ge.genElementField(w, el, false)
+	}
+	ge.genChoiceFields(w, ct.Choice)
+	ge.genGroupFields(w, ct.Group)
+	ge.genSequenceFields(w, ct.Sequence)
+	return nil
+}
+
+func (ge *goEncoder) genSequenceFields(w io.Writer, seq *wsdl.Sequence) {
+	if seq == nil {
+		return
+	}
+	for _, p := range seq.Particles() {
+		switch p := p.(type) {
+		case *wsdl.Element:
+			ge.genElementField(w, p, false)
+		case *wsdl.Choice:
+			ge.genChoiceFields(w, p)
+		case *wsdl.Group:
+			ge.genGroupFields(w, p)
+		case *wsdl.AnyElement:
+			ge.genAnyField(w, p)
+		}
+	}
+}
+
+// genAnyField writes the field holding the elements matched by the
+// wildcard a, as raw XML. encoding/xml supports a single field matching
+// any element per struct, so further wildcards are left out.
+func (ge *goEncoder) genAnyField(w io.Writer, a *wsdl.AnyElement) {
+	if ge.anyElement {
+		return
+	}
+	ge.anyElement = true
+	ge.needsExtPkg["github.com/seamuncle/wsdl2go/soap"] = true
+	typ := "*soap.RawElement"
+	if a.Max != "" && a.Max != "1" {
+		typ = "[]soap.RawElement"
+	}
+	ge.addField("Any", typ, typ, false)
+	fmt.Fprintf(w, "Any %s %s\n", typ, ge.fieldTag(",any", "-", false))
+}
+
+// genGroupFields expands the group referenced by ref in place.
+func (ge *goEncoder) genGroupFields(w io.Writer, ref *wsdl.Group) {
+	g := ge.resolveGroup(ref)
+	if g == nil {
+		return
+	}
+	ge.expanding[g.Name] = true
+	defer delete(ge.expanding, g.Name)
+	for _, el := range g.AllElements {
+		ge.genElementField(w, el, false)
+	}
+	ge.genChoiceFields(w, g.Choice)
+	ge.genSequenceFields(w, g.Sequence)
+}
+
+// resolveGroup returns the named group referenced by ref, or nil if
+// it's not defined or already being expanded.
+func (ge *goEncoder) resolveGroup(ref *wsdl.Group) *wsdl.Group {
+	if ref == nil || ref.Ref == "" {
+		return nil
+	}
+	g, ok := ge.groups[trimns(ref.Ref)]
+	if !ok || ge.expanding[g.Name] {
+		return nil
+	}
+	return g
+}
+
+// groupElements returns all elements of the group referenced by ref.
+func (ge *goEncoder) groupElements(ref *wsdl.Group) []*wsdl.Element {
+	g := ge.resolveGroup(ref)
+	if g == nil {
+		return nil
+	}
+	ge.expanding[g.Name] = true
+	defer delete(ge.expanding, g.Name)
+	elements := append([]*wsdl.Element{}, g.AllElements...)
+	if g.Choice != nil {
+		elements = append(elements, g.Choice.Elements...)
+		for _, v := range g.Choice.Groups {
+			elements = append(elements, ge.groupElements(v)...)
+		}
+	}
+	if seq := g.Sequence; seq != nil {
+		for _, p := range seq.Particles() {
+			switch p := p.(type) {
+			case *wsdl.Element:
+				elements = append(elements, p)
+			case *wsdl.Choice:
+				elements = append(elements, p.Elements...)
+			case *wsdl.Group:
+				elements = append(elements, ge.groupElements(p)...)
+			}
+		}
+	}
+	return elements
+}
+
+// genAttributeGroupFields expands the attribute groups referenced by
+// refs in place.
+func (ge *goEncoder) genAttributeGroupFields(w io.Writer, refs []*wsdl.AttributeGroup) {
+	for _, ref := range refs {
+		g, ok := ge.attrGroups[trimns(ref.Ref)]
+		key := "attributeGroup " + trimns(ref.Ref)
+		if !ok || ge.expanding[key] {
+			continue
+		}
+		ge.expanding[key] = true
+		for _, a := range g.Attributes {
+			ge.genAttributeField(w, a)
+		}
+		ge.genAttributeGroupFields(w, g.AttributeGroups)
+		delete(ge.expanding, key)
+	}
+}
+
+// genAttributeField writes the struct field of attribute a. Optional
+// attributes are declared as pointers and omitted when nil.
+func (ge *goEncoder) genAttributeField(w io.Writer, a *wsdl.Attribute) {
+	use, doc := a.Use, a.Doc
+	var ns string
+	if a.Ref != "" {
+		ref, ok := ge.attributes[trimns(a.Ref)]
+		if !ok {
+			return
+		}
+		a = ref
+		ns = ge.attributeNS[a.Name]
+	} else if s := ge.fieldSchema; s != nil && qualified(a.Form, s.AttributeFormDefault) {
+		ns = s.TargetNamespace
+	}
+	if a.Name == "" || use == "prohibited" {
+		return
+	}
+	typ := "string"
+	if a.Type != "" {
+		typ = ge.wsdl2goType(a.Type)
+	}
+	value := typ
+	var omit string
+	if use != "required" {
+		typ = optionalType(typ)
+		omit = ",omitempty"
+	}
+	name := ge.exportedName(a.Name)
+	ge.addField(name, typ, value, use == "required" && a.Fixed == "")
+	writeFieldComments(w, doc, a.Doc)
+	fmt.Fprintf(w, "%s %s %s\n", name, typ,
+		ge.fieldTag(qualify(ns, a.Name)+",attr"+omit, a.Name, omit != ""))
+	ge.addDefault(name, typ, a.Type, a.Default, a.Fixed)
+}
+
+// genChoiceFields writes one optional field for each alternative of
+// the choice. At most one of them is expected to be set.
+func (ge *goEncoder) genChoiceFields(w io.Writer, ch *wsdl.Choice) {
+	if ch == nil {
+		return
+	}
+	elements := append([]*wsdl.Element{}, ch.Elements...)
+	for _, g := range ch.Groups {
+		elements = append(elements, ge.groupElements(g)...)
+	}
+	if len(elements) == 0 {
+		return
+	}
+	names := make([]string, 0, len(elements))
+	for _, el := range elements {
+		n := el.Name
+		if n == "" {
+			n = trimns(el.Ref)
+		}
+		names = append(names, ge.exportedName(n))
+	}
+	repeated := ch.Max != "" && ch.Max != "1"
+	if repeated {
+		fmt.Fprintf(w, "// %s are alternatives of a repeated choice.\n", strings.Join(names, ", "))
+	} else {
+		fmt.Fprintf(w, "// Only one of %s may be set.\n", strings.Join(names, ", "))
+	}
+	for _, el := range elements {
+		if repeated && (el.Max == "" || el.Max == "1") {
+			v := *el
+			v.Max = ch.Max
+			el = &v
+		}
+		ge.genElementField(w, el, true)
+	}
+}
+
+// genElementField writes the struct field of el. Optional fields of
+// basic types are declared as pointers so unset values are omitted.
+func (ge *goEncoder) genElementField(w io.Writer, el *wsdl.Element, optional bool) {
+	if el.Ref != "" && len(ge.substitutes[trimns(el.Ref)]) > 0 {
+		ge.genSubstitutionGroupField(w, el)
+		return
+	}
+	if el.Max == "0" {
+		// prohibited
+		return
+	}
+	f := ge.elementField(el, optional)
+	if f == nil {
+		return
+	}
+	writeFieldComments(w, f.Doc)
+	ge.addField(f.Name, f.Type, f.value, !optional && f.el.Min > 0 && f.el.Fixed == "")
+	fmt.Fprintf(w, "%s %s %s\n", f.Name, f.Type,
+		ge.fieldTag(qualify(f.NS, f.Tag), f.el.Name, strings.HasSuffix(f.Tag, ",omitempty")))
+	if f.el.Nillable && f.el.Min > 0 && !optional && strings.HasPrefix(f.Type, "*") {
+		ge.nillable = append(ge.nillable, f.Name)
+	}
+	if !strings.HasPrefix(f.Type, "[]") {
+		ge.addDefault(f.Name, f.Type, f.el.Type, f.el.Default, f.el.Fixed)
+		return
+	}
+	o := &fieldOccurs{Name: f.Name, Local: f.el.Name}
+	if max, err := strconv.Atoi(f.el.Max); err == nil {
+		o.Max = max
+	}
+	if !optional && f.el.Min > 1 {
+		o.Min = f.el.Min
+	}
+	if o.Min > 0 || o.Max > 0 {
+		ge.occurs = append(ge.occurs, o)
+	}
+}
+
+// fieldOccurs is the number of occurrences allowed for the elements of
+// a slice field, if bounded.
+type fieldOccurs struct {
+	Name  string
+	Local string // element name
+	Min   int
+	Max   int // 0 if unbounded
+}
+
+var validateStructT = template.Must(template.New("validateStruct").Parse(`
+{{- if .Checked}}
+// Validate checks that the fields of t satisfy the constraints of the
+// schema, and those of the values they refer to in turn.
+func (t *{{.Name}}) Validate() error {
+	if t == nil {
+		return nil
+	}
+{{- else}}
+// Validate checks that the repeated elements of {{.Name}} occur as
+// many times as the schema allows.
+func (t *{{.Name}}) Validate() error {
+{{- end}}
+{{- range .Fields}}
+{{- if .Min}}
+	if n := len(t.{{.Name}}); n < {{.Min}} {
+		return fmt.Errorf("{{$.Name}}: %d {{.Local}} elements, want at least {{.Min}}", n)
+	}
+{{- end}}
+{{- if .Max}}
+	if n := len(t.{{.Name}}); n > {{.Max}} {
+		return fmt.Errorf("{{$.Name}}: %d {{.Local}} elements, want at most {{.Max}}", n)
+	}
+{{- end}}
+{{- end}}
+{{- range .Checks}}
+	{{.}}
+{{- end}}
+	return nil
+}
+
+`))
+
+// genValidateStruct writes a Validate method for the struct being
+// generated if it has slice fields of bounded elements, or if
+// SetValidate enabled it, in which case the values of its fields are
+// validated too.
+func (ge *goEncoder) genValidateStruct(w io.Writer, name string) {
+	occurs := ge.occurs
+	ge.occurs = nil
+	checked := ge.validate && ge.validTypes[name]
+	if len(occurs) == 0 && !checked {
+		return
+	}
+	checks := ge.genValidateFields(name)
+	if len(occurs) > 0 || len(checks) > 0 {
+		ge.needsStdPkg["fmt"] = true
+	}
+	validateStructT.Execute(w, &struct {
+		Name    string
+		Checked bool
+		Fields  []*fieldOccurs
+		Checks  []string
+	}{name, checked, occurs, checks})
+}
+
+// elementField is the struct field of an element.
+type elementField struct {
+	Name string
+	Type string
+	Tag  string // without namespace
+	NS   string
+	Doc  string
+
+	// Go type of the value of the element, of which Type is a pointer
+	// if the element is optional
+	value string
+
+	// the element, or the global element it refers to
+	el *wsdl.Element
+}
+
+// elementField returns the struct field of el, or nil if el refers to
+// an unknown element.
+func (ge *goEncoder) elementField(el *wsdl.Element, optional bool) *elementField {
+	var ns string
+	doc := el.Doc
+	if el.Ref != "" {
+		ref := trimns(el.Ref)
+		nel, ok := ge.elements[ref]
+		if !ok {
+			return nil
+		}
+		// occurrences are given by the reference
+		v := *nel
+		v.Min, v.Max = el.Min, el.Max
+		el = &v
+		if doc == "" {
+			doc = el.Doc
+		}
+		ns = ge.elementNS[ref]
+	} else if s := ge.fieldSchema; s != nil && qualified(el.Form, s.ElementFormDefault) {
+		ns = s.TargetNamespace
+	}
+	if el.Type == "" {
+		el.Type = "string"
+	}
+	var slicetype string
+	if el.Type == "" && el.ComplexType != nil {
+		seq := el.ComplexType.Sequence
+		if seq != nil {
+			if len(seq.Elements) == 1 {
+				n := el.Name
+				el = el.ComplexType.Sequence.Elements[0]
+				slicetype = el.Name
+				el.Name = n
+			} else if len(seq.Any) == 1 {
+				el = &wsdl.Element{
+					Name: el.Name,
+					Type: "anysequence",
+					Min:  seq.Any[0].Min,
+					Max:  seq.Any[0].Max,
+				}
+				slicetype = el.Name
+			}
+		}
+	}
+	tag := el.Name
+	name := ge.exportedName(el.Name)
+	typ := ge.wsdl2goType(el.Type)
+	value := typ
+	if el.Max != "" && el.Max != "1" {
+		typ = "[]" + typ
+		if slicetype != "" {
+			tag = el.Name + ">" + slicetype
+		}
+	} else if optional || el.Nillable || el.Min == 0 && !ge.omitEmpty {
+		typ = optionalType(typ)
+	}
+	if el.Nillable || el.Min == 0 || optional {
+		tag += ",omitempty"
+	}
+	return &elementField{Name: name, Type: typ, Tag: tag, NS: ns, Doc: doc, value: value, el: el}
+}
+
+// qualified reports whether a local element or attribute is in the
+// target namespace of its schema, given its form and the schema's
+// default form.
+func qualified(form, formDefault string) bool {
+	if form != "" {
+		return form == "qualified"
+	}
+	return formDefault == "qualified"
+}
+
+// qualify prepends the namespace ns, if any, to the xml tag.
+func qualify(ns, tag string) string {
+	if ns == "" {
+		return tag
+	}
+	return ns + " " + tag
+}
+
+// fieldDefault is a struct field with a default or fixed value.
+type fieldDefault struct {
+	Name    string
+	Pointer bool   // whether the field is a pointer
+	Value   string // Go expression of the value
+	Zero    string // Go literal of the zero value, if not Pointer
+	Fixed   bool
+}
+
+// addDefault records the default or fixed value of the field name, of
+// Go type typ, for the struct being generated. Values of types that
+// have no Go literal are ignored.
+func (ge *goEncoder) addDefault(name, typ, xsdType, def, fixed string) {
+	value, isFixed := def, false
+	if fixed != "" {
+		value, isFixed = fixed, true
+	}
+	if value == "" {
+		return
+	}
+	if xsdType == "" {
+		xsdType = "string"
+	}
+	lit, zero, ok := ge.defaultLiteral(xsdType, value)
+	if !ok {
+		return
+	}
+	ptr := strings.HasPrefix(typ, "*")
+	switch t := strings.TrimPrefix(typ, "*"); {
+	case !ptr, t == "string", t == "int", t == "bool", strings.HasSuffix(lit, ")"):
+	default:
+		// the pointer needs the exact type, not that of the literal
+		lit = t + "(" + lit + ")"
+	}
+	ge.defaults = append(ge.defaults, &fieldDefault{
+		Name:    name,
+		Pointer: ptr,
+		Value:   lit,
+		Zero:    zero,
+		Fixed:   isFixed,
+	})
+}
+
+// defaultLiteral returns the Go literal of value and the literal of the
+// zero value of the Go type of xsdType.
+func (ge *goEncoder) defaultLiteral(xsdType, value string) (lit, zero string, ok bool) {
+	typ := ge.wsdl2goType(xsdType)
+	switch typ {
+	case "string":
+		return strconv.Quote(value), `""`, true
+	case "int", "int8", "int16", "int32", "int64":
+		_, err := strconv.ParseInt(value, 10, numericBits[typ])
+		return value, "0", err == nil
+	case "uint", "uint8", "uint16", "uint32", "uint64":
+		_, err := strconv.ParseUint(value, 10, numericBits[typ])
+		return value, "0", err == nil
+	case "float64":
+		f, err := strconv.ParseFloat(value, 64)
+		if err != nil || math.IsInf(f, 0) || math.IsNaN(f) {
+			return "", "", false
+		}
+		return value, "0", true
+	case "bool":
+		switch value {
+		case "true", "1":
+			return "true", "false", true
+		case "false", "0":
+			return "false", "false", true
+		}
+		return "", "", false
+	}
+	st, exists := ge.stypes[ge.typeName(ge.fieldSchema, xsdType)]
+	if !exists || st.Restriction == nil {
+		return "", "", false
+	}
+	lit, zero, ok = ge.defaultLiteral(st.Restriction.Base, value)
+	return typ + "(" + lit + ")", zero, ok
+}
+
+var setDefaultsT = template.Must(template.New("setDefaults").Parse(`
+// SetDefaults sets the fields of {{.Name}} that have a default value in
+// the schema and are unset to that value, and the fields that have a
+// fixed value to that value.
+func (t *{{.Name}}) SetDefaults() {
+{{- range .Fields}}
+{{- if .Pointer}}
+	{{- if not .Fixed}}
+	if t.{{.Name}} == nil {
+	{{- else}}
+	{
+	{{- end}}
+		v := {{.Value}}
+		t.{{.Name}} = &v
+	}
+{{- else}}
+	{{- if not .Fixed}}
+	if {{if eq .Zero "false"}}!t.{{.Name}}{{else}}t.{{.Name}} == {{.Zero}}{{end}} {
+		t.{{.Name}} = {{.Value}}
+	}
+	{{- else}}
+	t.{{.Name}} = {{.Value}}
+	{{- end}}
+{{- end}}
+{{- end}}
+}
+
+`))
+
+// genSetDefaults writes the SetDefaults method of the struct name from
+// the default and fixed values of its fields, if any.
+func (ge *goEncoder) genSetDefaults(w io.Writer, name string) {
+	if len(ge.defaults) == 0 {
+		return
+	}
+	setDefaultsT.Execute(w, &struct {
+		Name   string
+		Fields []*fieldDefault
+	}{name, ge.defaults})
+	ge.defaults = nil
+}
+
+var marshalXMLT = template.Must(template.New("marshalXML").Parse(`
+{{- if .Attrs}}
+// UnmarshalXML implements the xml.Unmarshaler interface. The namespace
+// declarations of the element are not kept in Attrs.
+func (t *{{.Name}}) UnmarshalXML(d *xml.Decoder, start xml.StartElement) error {
+	type plain {{.Name}}
+	if err := d.DecodeElement((*plain)(t), &start); err != nil {
+		return err
+	}
+	t.Attrs = soap.ForeignAttrs(t.Attrs)
+	return nil
+}
+{{end}}
+// MarshalXML implements the xml.Marshaler interface.
+{{- if .Fields}} The nil fields of
+// nillable elements are encoded with xsi:nil="true".{{end}}
+{{- if .Attrs}} The attributes of
+// Attrs are encoded in their own namespace, leaving out namespace
+// declarations.{{end}}
+func (t {{.Name}}) MarshalXML(e *xml.Encoder, start xml.StartElement) error {
+	{{- if .Attrs}}
+	t.Attrs = soap.ForeignAttrs(t.Attrs)
+	{{- end}}
+	return soap.MarshalNillable(e, start, t{{range .Fields}}, "{{.}}"{{end}})
+}
+
+`))
+
+// genMarshalXML writes the MarshalXML method of the struct being
+// generated if it has fields of nillable elements, or the methods
+// keeping the attributes of an anyAttribute, attrs, without namespace
+// declarations.
+func (ge *goEncoder) genMarshalXML(w io.Writer, name string, attrs bool) {
+	if len(ge.nillable) == 0 && !attrs {
+		return
+	}
+	ge.needsStdPkg["encoding/xml"] = true
+	ge.needsExtPkg["github.com/seamuncle/wsdl2go/soap"] = true
+	marshalXMLT.Execute(w, &struct {
+		Name   string
+		Fields []string
+		Attrs  bool
+	}{name, ge.nillable, attrs})
+	ge.nillable = nil
+}
+
+// genSubstitutionGroupField writes the field of an element referencing
+// the head of a substitution group. It matches any element of the group.
+//
+// encoding/xml supports a single field matching any element per struct,
+// so further references only match the head element itself.
+func (ge *goEncoder) genSubstitutionGroupField(w io.Writer, el *wsdl.Element) {
+	head := trimns(el.Ref)
+	name := ge.exportedName(head)
+	typ := ge.substitutionGroupType(head)
+	if el.Max != "" && el.Max != "1" {
+		typ = "[]" + typ
+	}
+	tag := head
+	if !ge.anyElement {
+		ge.anyElement = true
+		tag = ",any"
+	}
+	ge.addField(name, typ, typ, false)
+	fmt.Fprintf(w, "%s %s %s\n", name, typ, ge.fieldTag(tag, head, true))
+}
+
+func (ge *goEncoder) substitutionGroupType(head string) string {
+	return ge.exportedName(head) + "Group"
+}
+
+var substitutionGroupT = template.Must(template.New("substitutionGroup").Parse(`
+// {{.Name}} holds a {{.Head}} element or any element of its substitution
+// group: {{.Members}}.
+type {{.Name}} struct {
+	Value {{.Iface}}
+}
+{{if ne .Iface "interface{}"}}
+// {{.Iface}} is implemented by the types of the elements of {{.Name}}.
+type {{.Iface}} interface {
+	is{{.Iface}}()
+}
+{{range .Types}}
+func ({{.}}) is{{$.Iface}}() {}
+{{end}}
+{{- end}}
+// MarshalXML implements the xml.Marshaler interface.
+func (g {{.Name}}) MarshalXML(e *xml.Encoder, start xml.StartElement) error {
+	if g.Value == nil {
+		return nil
+	}
+	switch g.Value.(type) {
+{{- range .Types}}
+	case {{.}}:
+		start.Name = xml.Name{Local: "{{index $.Locals .}}"}
+{{- end}}
+	}
+	return e.EncodeElement(g.Value, start)
+}
+
+// UnmarshalXML implements the xml.Unmarshaler interface.
+func (g *{{.Name}}) UnmarshalXML(d *xml.Decoder, start xml.StartElement) error {
+	switch start.Name.Local {
+{{- range .Elements}}
+	case "{{.Local}}":
+		v := new({{.Type}})
+		if err := d.DecodeElement(v, &start); err != nil {
+			return err
+		}
+		g.Value = v
+		return nil
+{{- end}}
+	}
+	return d.Skip()
+}
+`))
+
+type substitutionGroupElement struct{ Local, Type string }
+
+// genSubstitutionGroup writes the type holding any element of the
+// substitution group of head. When all elements are of generated struct
+// types, these implement an interface specific to the group.
+func (ge *goEncoder) genSubstitutionGroup(w io.Writer, d *wsdl.Definitions, head string) {
+	members := ge.substitutes[head]
+	if h, ok := ge.globalElement(head); ok && !h.Abstract {
+		members = append([]*wsdl.Element{h}, members...)
+	}
+	var names []string
+	var elements []*substitutionGroupElement
+	var types []string
+	locals := make(map[string]string)
+	marker := true
+	for _, el := range members {
+		if el.Abstract {
+			continue
+		}
+		typ := ge.elementGoType(el)
+		elem := strings.TrimPrefix(typ, "*")
+		ctname := el.Name
+		if el.Type != "" {
+			ctname = ge.typeName(ge.fieldSchema, el.Type)
+		}
+		if _, isStruct := ge.ctypes[ctname]; !isStruct || typ == elem {
+			marker = false
+		}
+		names = append(names, el.Name)
+		elements = append(elements, &substitutionGroupElement{el.Name, elem})
+		if _, exists := locals["*"+elem]; !exists {
+			locals["*"+elem] = el.Name
+			types = append(types, "*"+elem)
+		}
+	}
+	name := ge.substitutionGroupType(head)
+	iface := "interface{}"
+	if marker && len(types) > 0 {
+		iface = name + "Member"
+	}
+	ge.needsStdPkg["encoding/xml"] = true
+	substitutionGroupT.Execute(w, &struct {
+		Name     string
+		Head     string
+		Members  string
+		Iface    string
+		Types    []string
+		Locals   map[string]string
+		Elements []*substitutionGroupElement
+	}{
+		name,
+		head,
+		strings.Join(names, ", "),
+		iface,
+		types,
+		locals,
+		elements,
+	})
+}
+
+// globalElement returns the element declared at schema level with the
+// given name.
+func (ge *goEncoder) globalElement(name string) (*wsdl.Element, bool) {
+	if el, ok := ge.elements[name]; ok {
+		return el, true
+	}
+	for _, head := range ge.sortedSubstitutionGroups() {
+		for _, v := range ge.substitutes[head] {
+			if v.Name == name {
+				return v, true
+			}
+		}
+	}
+	return nil, false
+}
+
+// elementGoType returns the Go type of el, which is either named by its
+// type attribute or declared inline.
+func (ge *goEncoder) elementGoType(el *wsdl.Element) string {
+	if el.Type != "" {
+		return ge.wsdl2goType(el.Type)
+	}
+	if el.ComplexType != nil {
+		return ge.wsdl2goType(ge.elementTypeRef(el.Name))
+	}
+	return "string"
+}
+
+// optionalType returns the pointer type of the given Go type, unless it
+// can already be nil.
+func optionalType(typ string) string {
+	switch typ {
+	case "interface{}", "soap.AnyType", "Base64Binary", "HexBinary":
+		return typ
+	}
+	if strings.HasPrefix(typ, "*") || strings.HasPrefix(typ, "[]") {
+		return typ
+	}
+	return "*" + typ
+}
+
+// writeFieldComments writes the first non-empty documentation of a
+// struct field, if any. Undocumented fields get no comment.
+func writeFieldComments(w io.Writer, docs ...string) {
+	for _, doc := range docs {
+		if strings.TrimSpace(doc) != "" {
+			writeComments(w, "", doc)
+			return
+		}
 	}
-	fmt.Fprintf(w, "%s `xml:\"%s\" json:\"%s\" yaml:\"%s\"`\n",
-		typ, tag, tag, tag)
 }
 
 // writeComments writes comments to w, capped at ~80 columns.
 func writeComments(w io.Writer, typeName, comment string) {
-	comment = strings.Trim(strings.Replace(comment, "\n", " ", -1), " ")
+	comment = strings.Join(strings.Fields(comment), " ")
 	if comment == "" {
 		comment = strings.Title(typeName) + " was auto-generated from WSDL."
 	}
@@ -2,6 +2,7 @@ package wsdlgo
 
 import (
 	"bytes"
+	"flag"
 	"fmt"
 	"io"
 	"io/ioutil"
@@ -11,11 +12,15 @@ import (
 	"os"
 	"os/exec"
 	"path/filepath"
+	"regexp"
+	"strings"
 	"testing"
 
 	"github.com/seamuncle/wsdl2go/wsdl"
 )
 
+var update = flag.Bool("update", false, "update golden files")
+
 func LoadDefinition(t *testing.T, filename string, want error) *wsdl.Definitions {
 	f, err := os.Open(filepath.Join("testdata", filename))
 	if err != nil {
@@ -33,6 +38,7 @@ var EncoderCases = []struct {
 	F string
 	G string
 	E error
+	O func(Encoder) // optional encoder settings
 }{
 	{F: "broken.wsdl", E: io.EOF},
 	{F: "w3cexample1.wsdl", G: "w3cexample1.golden", E: nil},
@@ -40,8 +46,114 @@ var EncoderCases = []struct {
 	{F: "w3example1.wsdl", G: "w3example1.golden", E: nil},
 	{F: "w3example2.wsdl", G: "w3example2.golden", E: nil},
 	{F: "memcache.wsdl", G: "memcache.golden", E: nil},
-	{F: "importer.wsdl", G: "memcache.golden", E: nil},
+	{F: "importer.wsdl", G: "importer.golden", E: nil},
+	{F: "includer.wsdl", G: "includer.golden", E: nil},
+	{F: "importcycle.wsdl", G: "importcycle.golden", E: nil},
+	{F: "relative/service.wsdl", G: "relative.golden", E: nil,
+		O: func(e Encoder) { e.SetLocation("testdata/relative/service.wsdl") }},
+	{F: "relative/service.wsdl", G: "relative.golden", E: nil,
+		O: func(e Encoder) { e.SetLocation("http://localhost:9999/relative/service.wsdl") }},
 	{F: "data.wsdl", G: "data.golden", E: nil},
+	{F: "choice.wsdl", G: "choice.golden", E: nil},
+	{F: "group.wsdl", G: "group.golden", E: nil},
+	{F: "attribute.wsdl", G: "attribute.golden", E: nil},
+	{F: "attributegroup.wsdl", G: "attributegroup.golden", E: nil},
+	{F: "anyattribute.wsdl", G: "anyattribute.golden", E: nil},
+	{F: "list.wsdl", G: "list.golden", E: nil},
+	{F: "union.wsdl", G: "union.golden", E: nil},
+	{F: "anytype.wsdl", G: "anytype.golden", E: nil},
+	{F: "any.wsdl", G: "any.golden", E: nil},
+	{F: "derived.wsdl", G: "derived.golden", E: nil},
+	{F: "abstract.wsdl", G: "abstract.golden", E: nil},
+	{F: "enum.wsdl", G: "enum.golden", E: nil},
+	{F: "datetime.wsdl", G: "datetime.golden", E: nil},
+	{F: "binary.wsdl", G: "binary.golden", E: nil},
+	{F: "decimal.wsdl", G: "decimal.golden", E: nil},
+	{F: "rpcdecimal.wsdl", G: "rpcdecimal.golden", E: nil},
+	{F: "decimal.wsdl", G: "decimal-rat.golden", E: nil,
+		O: func(e Encoder) { e.SetDecimal(DecimalBigRat, map[string]string{"Rate": DecimalString}) }},
+	{F: "numeric.wsdl", G: "numeric.golden", E: nil},
+	{F: "numeric.wsdl", G: "numeric-bigint.golden", E: nil,
+		O: func(e Encoder) { e.SetBigIntegers(true) }},
+	{F: "typemap.wsdl", G: "typemap.golden", E: nil,
+		O: func(e Encoder) {
+			e.SetTypeMap(map[string]string{
+				"GUID": "github.com/google/uuid.UUID",
+				"Link": "*net/url.URL",
+				"{http://www.w3.org/2001/XMLSchema}dateTime": "time.Time",
+				"{http://example.com/typemap}Amount":         "math/big.Rat",
+			})
+		}},
+	{F: "collisions.wsdl", G: "collisions.golden", E: nil},
+	{F: "typeclash.wsdl", G: "typeclash.golden", E: nil},
+	{F: "naming.wsdl", G: "naming.golden", E: nil},
+	{F: "naming.wsdl", G: "naming-underscore.golden", E: nil,
+		O: func(e Encoder) { e.SetNaming(NamingUnderscore, []string{"ID", "URL", "HTTP"}) }},
+	{F: "naming.wsdl", G: "tags-snake.golden", E: nil,
+		O: func(e Encoder) { e.SetTags([]string{"json", "bson"}, TagNamingSnake) }},
+	{F: "naming.wsdl", G: "tags-camel.golden", E: nil,
+		O: func(e Encoder) { e.SetTags([]string{"json"}, TagNamingCamel) }},
+	{F: "naming.wsdl", G: "getters.golden", E: nil,
+		O: func(e Encoder) { e.SetGetters(true) }},
+	{F: "enum.wsdl", G: "enum-stringers.golden", E: nil,
+		O: func(e Encoder) { e.SetStringers(true) }},
+	{F: "split.wsdl", G: "stringers.golden", E: nil,
+		O: func(e Encoder) { e.SetStringers(true) }},
+	{F: "clone.wsdl", G: "clone.golden", E: nil,
+		O: func(e Encoder) { e.SetClone(true) }},
+	{F: "validate.wsdl", G: "validate.golden", E: nil,
+		O: func(e Encoder) { e.SetValidate(true) }},
+	{F: "defaults.wsdl", G: "constructors.golden", E: nil,
+		O: func(e Encoder) { e.SetConstructors(true) }},
+	{F: "substitutiongroup.wsdl", G: "substitutiongroup.golden", E: nil},
+	{F: "mixed.wsdl", G: "mixed.golden", E: nil},
+	{F: "simplecontent.wsdl", G: "simplecontent.golden", E: nil},
+	{F: "defaults.wsdl", G: "defaults.golden", E: nil},
+	{F: "nillable.wsdl", G: "nillable.golden", E: nil},
+	{F: "occurs.wsdl", G: "occurs.golden", E: nil},
+	{F: "nillable.wsdl", G: "nillable-omitempty.golden", E: nil,
+		O: func(e Encoder) { e.SetOmitEmpty(true) }},
+	{F: "form.wsdl", G: "form.golden", E: nil},
+	{F: "qname.wsdl", G: "qname.golden", E: nil},
+	{F: "httpbinding.wsdl", G: "httpbinding.golden", E: nil},
+	{F: "mime.wsdl", G: "mime.golden", E: nil},
+	{F: "soapheader.wsdl", G: "soapheader.golden", E: nil},
+	{F: "soap12.wsdl", G: "soap12.golden", E: nil},
+	{F: "oneway.wsdl", G: "oneway.golden", E: nil},
+	{F: "faults.wsdl", G: "faults.golden", E: nil},
+	{F: "rpcencoded.wsdl", G: "rpcencoded.golden", E: nil},
+	{F: "rpcliteral.wsdl", G: "rpcliteral.golden", E: nil},
+	{F: "wrapped.wsdl", G: "wrapped.golden", E: nil},
+	{F: "paramorder.wsdl", G: "paramorder.golden", E: nil},
+	{F: "policy.wsdl", G: "policy.golden", E: nil},
+	{F: "documentation.wsdl", G: "documentation.golden", E: nil},
+	{F: "multiporttype.wsdl", G: "multiporttype.golden", E: nil},
+	{F: "multiporttype.wsdl", G: "multiporttype-admin.golden", E: nil,
+		O: func(e Encoder) { e.SetBinding("tns:GlossaryAdminBinding") }},
+	{F: "multiporttype.wsdl", G: "multiporttype-admin.golden", E: nil,
+		O: func(e Encoder) { e.SetService("GlossaryAdminService") }},
+	{F: "multiporttype.wsdl", G: "multiporttype-admin.golden", E: nil,
+		O: func(e Encoder) { e.SetPort("GlossaryAdminPort") }},
+	{F: "multiporttype.wsdl", G: "multiporttype-smtp.golden", E: nil,
+		O: func(e Encoder) { e.SetTransport("http://schemas.xmlsoap.org/soap/smtp") }},
+	{F: "multiporttype.wsdl", G: "mocks.golden", E: nil,
+		O: func(e Encoder) {
+			e.SetBinding("tns:GlossaryAdminBinding")
+			e.SetMocks(true)
+		}},
+	{F: "soapheader.wsdl", G: "servers.golden", E: nil,
+		O: func(e Encoder) {
+			e.SetServers(true)
+			doc, _ := ioutil.ReadFile(filepath.Join("testdata", "soapheader.wsdl"))
+			e.SetWSDL(doc)
+		}},
+	{F: "rpcliteral.wsdl", G: "servers-rpc.golden", E: nil,
+		O: func(e Encoder) { e.SetServers(true) }},
+	{F: "soapheader.wsdl", G: "nocontext.golden", E: nil,
+		O: func(e Encoder) {
+			e.SetServers(true)
+			e.SetNoContext(true)
+		}},
 }
 
 func NewTestServer(t *testing.T) *httptest.Server {
@@ -66,13 +178,24 @@ func TestEncoder(t *testing.T) {
 		var err error
 		var want []byte
 		var have bytes.Buffer
-		err = NewEncoder(&have).Encode(d)
+		enc := NewEncoder(&have, true, false)
+		if tc.O != nil {
+			tc.O(enc)
+		}
+		err = enc.Encode(d)
 		if err != nil {
 			t.Errorf("test %d, encoding %q: %v", i, tc.F, err)
 		}
 		if tc.G == "" {
 			continue
 		}
+		if *update {
+			err = ioutil.WriteFile(filepath.Join("testdata", tc.G), have.Bytes(), 0644)
+			if err != nil {
+				t.Errorf("test %d: cannot update golden file %q: %v", i, tc.G, err)
+			}
+			continue
+		}
 		want, err = ioutil.ReadFile(filepath.Join("testdata", tc.G))
 		if err != nil {
 			t.Errorf("test %d: missing golden file %q: %v", i, tc.G, err)
@@ -85,6 +208,279 @@ func TestEncoder(t *testing.T) {
 	}
 }
 
+// TestEncoderDeterministic checks that encoding the same definitions
+// again yields the same code, whatever the order maps are iterated in.
+func TestEncoderDeterministic(t *testing.T) {
+	s := NewTestServer(t)
+	defer s.Close()
+	for i, tc := range EncoderCases {
+		var first []byte
+		for n := 0; n < 5; n++ {
+			d := LoadDefinition(t, tc.F, tc.E)
+			var have bytes.Buffer
+			enc := NewEncoder(&have, true, false)
+			if tc.O != nil {
+				tc.O(enc)
+			}
+			err := enc.Encode(d)
+			if err != nil {
+				t.Fatalf("test %d, encoding %q: %v", i, tc.F, err)
+			}
+			if n == 0 {
+				first = have.Bytes()
+			} else if !bytes.Equal(have.Bytes(), first) {
+				t.Fatalf("test %d, encoding %q again yields different code", i, tc.F)
+			}
+		}
+	}
+}
+
+// TestEncodeFiles checks the files of split code against those of the
+// testdata/split directory.
+func TestEncodeFiles(t *testing.T) {
+	d := LoadDefinition(t, "split.wsdl", nil)
+	enc := NewEncoder(ioutil.Discard, true, true)
+	files, err := enc.EncodeFiles(d)
+	if err != nil {
+		t.Fatal(err)
+	}
+	checkGoldenFiles(t, filepath.Join("testdata", "split"), files)
+}
+
+// TestEncodePackages checks the files of the packages generated per
+// namespace against those of the testdata/packages directory.
+func TestEncodePackages(t *testing.T) {
+	d := LoadDefinition(t, "packages.wsdl", nil)
+	enc := NewEncoder(ioutil.Discard, true, false)
+	enc.SetPackages("example.com/shop")
+	files, err := enc.EncodeFiles(d)
+	if err != nil {
+		t.Fatal(err)
+	}
+	checkGoldenFiles(t, filepath.Join("testdata", "packages"), files)
+}
+
+// checkGoldenFiles compares files, by slash separated path, to the
+// golden files of the same path in dir, or updates those.
+func checkGoldenFiles(t *testing.T, dir string, files map[string][]byte) {
+	if *update {
+		os.RemoveAll(dir)
+		for name, code := range files {
+			golden := filepath.Join(dir, filepath.FromSlash(name)+".golden")
+			err := os.MkdirAll(filepath.Dir(golden), 0755)
+			if err != nil {
+				t.Fatal(err)
+			}
+			err = ioutil.WriteFile(golden, code, 0644)
+			if err != nil {
+				t.Fatal(err)
+			}
+		}
+		return
+	}
+	var goldens []string
+	err := filepath.Walk(dir, func(path string, info os.FileInfo, err error) error {
+		if err == nil && strings.HasSuffix(path, ".golden") {
+			goldens = append(goldens, path)
+		}
+		return err
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(files) != len(goldens) {
+		t.Errorf("want %d files, have %d", len(goldens), len(files))
+	}
+	for _, golden := range goldens {
+		rel, err := filepath.Rel(dir, golden)
+		if err != nil {
+			t.Fatal(err)
+		}
+		name := filepath.ToSlash(strings.TrimSuffix(rel, ".golden"))
+		want, err := ioutil.ReadFile(golden)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !bytes.Equal(files[name], want) {
+			err := Diff("_diff", "go", want, files[name])
+			t.Errorf("%s != %s: %v\ngenerated:\n%s\n", name, golden, err, files[name])
+		}
+	}
+}
+
+// TestEncodeTests checks the round trip test generated along with the
+// code against the one of the testdata/tests directory.
+func TestEncodeTests(t *testing.T) {
+	d := LoadDefinition(t, "memcache.wsdl", nil)
+	enc := NewEncoder(ioutil.Discard, true, false)
+	enc.SetTests(true)
+	err := enc.Encode(d)
+	if err != nil {
+		t.Fatal(err)
+	}
+	checkGoldenFiles(t, filepath.Join("testdata", "tests"), map[string][]byte{
+		"types_test.go": enc.Tests(),
+	})
+}
+
+func TestEncodeSamples(t *testing.T) {
+	d := LoadDefinition(t, "samples.wsdl", nil)
+	enc := NewEncoder(ioutil.Discard, true, false)
+	files, err := enc.EncodeSamples(d)
+	if err != nil {
+		t.Fatal(err)
+	}
+	checkGoldenFiles(t, filepath.Join("testdata", "samples"), files)
+}
+
+// TestPackagesImportCycle checks that namespaces whose types refer to
+// each other can not be generated as packages.
+func TestPackagesImportCycle(t *testing.T) {
+	d := LoadDefinition(t, "packages-cycle.wsdl", nil)
+	enc := NewEncoder(ioutil.Discard, true, false)
+	enc.SetPackages("example.com/cycle")
+	_, err := enc.EncodeFiles(d)
+	if err == nil || !strings.Contains(err.Error(), "example.com/cycle -> example.com/cycle/b -> example.com/cycle") {
+		t.Fatalf("want an import cycle error, have %v", err)
+	}
+}
+
+// TestMocksConflict checks that mocks can not be generated along with
+// those of go-mock.
+func TestMocksConflict(t *testing.T) {
+	d := LoadDefinition(t, "memcache.wsdl", nil)
+	enc := NewEncoder(ioutil.Discard, true, true)
+	enc.SetMocks(true)
+	err := enc.Encode(d)
+	if err == nil || !strings.Contains(err.Error(), "go-mock") {
+		t.Fatalf("want a conflict error, have %v", err)
+	}
+}
+
+func TestRenamed(t *testing.T) {
+	d := LoadDefinition(t, "collisions.wsdl", nil)
+	enc := NewEncoder(ioutil.Discard, true, false)
+	err := enc.Encode(d)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []string{
+		"type of element Quote declared as QuoteElement, as Quote is taken",
+		"type of element Symbol declared as SymbolElement, as Symbol is taken",
+		"port type Quote declared as QuotePortType, as Quote is taken",
+		"operation Symbol declared as SymbolFunc, as Symbol is taken",
+	}
+	have := enc.Renamed()
+	if fmt.Sprint(have) != fmt.Sprint(want) {
+		t.Fatalf("want %q, have %q", want, have)
+	}
+
+	d = LoadDefinition(t, "typeclash.wsdl", nil)
+	enc = NewEncoder(ioutil.Discard, true, false)
+	if err = enc.Encode(d); err != nil {
+		t.Fatal(err)
+	}
+	want = []string{
+		"type Code of namespace http://example.com/crm declared as Code2, as Code is taken",
+		"type Person of namespace http://example.com/crm declared as Person2, as Person is taken",
+	}
+	have = enc.Renamed()
+	if fmt.Sprint(have) != fmt.Sprint(want) {
+		t.Fatalf("want %q, have %q", want, have)
+	}
+}
+
+// TestListRoundTrip runs the code generated for lists, checking that
+// lists of dateTime and base64Binary read from XML are written back as
+// they were.
+func TestListRoundTrip(t *testing.T) {
+	const doc = `<Shirt tags="a b"><sizes>1 2</sizes>` +
+		`<sold>2020-01-02T03:04:05Z 2021-06-07T08:09:10.5+02:00</sold><digests>AQI= AwQ=</digests></Shirt>`
+	if have := roundTrip(t, "list.wsdl", "Shirt", doc); have != doc {
+		t.Errorf("want %s\nhave %s", doc, have)
+	}
+}
+
+// TestDecimalRoundTrip runs the code generated for decimals, checking
+// that decimals of more digits than a float64 holds are written back
+// as they were.
+func TestDecimalRoundTrip(t *testing.T) {
+	const doc = `<Invoice tax="0.000000000000000000001"><total>12345678901234567890.12</total>` +
+		`<discount>-98765432109876543210.987654321</discount><price>100000000000000000000001</price>` +
+		`<rate>1.5</rate></Invoice>`
+	if have := roundTrip(t, "decimal.wsdl", "Invoice", doc); have != doc {
+		t.Errorf("want %s\nhave %s", doc, have)
+	}
+}
+
+// TestAnyAttributeRoundTrip runs the code generated for anyAttribute,
+// checking that attributes of another namespace are written back in it,
+// without the namespace declarations of the element.
+func TestAnyAttributeRoundTrip(t *testing.T) {
+	const doc = `<Entry xmlns="urn:x" xmlns:p="urn:p" key="k" p:color="red"><value>v</value></Entry>`
+	want := `<Entry key="k" xmlns:_="urn:p" _:color="red"><value>v</value></Entry>`
+	if have := roundTrip(t, "anyattribute.wsdl", "Entry", doc); have != want {
+		t.Errorf("want %s\nhave %s", want, have)
+	}
+}
+
+// roundTrip runs the code generated for the WSDL file, reading doc into
+// a value of the Go type typ and writing it back, and returns the XML
+// written. The test is skipped if the go command is not available.
+func roundTrip(t *testing.T, file, typ, doc string) string {
+	gobin, err := exec.LookPath("go")
+	if err != nil || testing.Short() {
+		t.Skip("go command not available")
+	}
+	d := LoadDefinition(t, file, nil)
+	var code bytes.Buffer
+	if err = NewEncoder(&code, true, false).Encode(d); err != nil {
+		t.Fatal(err)
+	}
+	dir, err := ioutil.TempDir("", "wsdl2go")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	files := map[string]string{
+		"types.go": regexp.MustCompile(`(?m)^package \w+`).ReplaceAllString(code.String(), "package main"),
+		"main.go": `package main
+
+import (
+	"encoding/xml"
+	"fmt"
+	"os"
+)
+
+func main() {
+	var v ` + typ + `
+	if err := xml.Unmarshal([]byte(os.Args[1]), &v); err != nil {
+		fmt.Println(err)
+		os.Exit(1)
+	}
+	b, err := xml.Marshal(&v)
+	if err != nil {
+		fmt.Println(err)
+		os.Exit(1)
+	}
+	fmt.Print(string(b))
+}
+`,
+	}
+	for name, src := range files {
+		if err = ioutil.WriteFile(filepath.Join(dir, name), []byte(src), 0600); err != nil {
+			t.Fatal(err)
+		}
+	}
+	cmd := exec.Command(gobin, "run", "types.go", "main.go", doc)
+	cmd.Dir = dir
+	out, err := cmd.CombinedOutput()
+	if err != nil {
+		t.Fatalf("%v: %s", err, out)
+	}
+	return string(out)
+}
+
 func Diff(prefix, ext string, a, b []byte) error {
 	diff, err := exec.LookPath("diff")
 	if err != nil {
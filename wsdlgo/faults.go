@@ -0,0 +1,117 @@
+package wsdlgo
+
+import (
+	"io"
+	"strings"
+	"text/template"
+
+	"github.com/seamuncle/wsdl2go/wsdl"
+)
+
+var faultT = template.Must(template.New("fault").Parse(`
+// {{.Name}} is a SOAP fault whose detail is the {{.Local}} element of
+// the {{.Message}} fault message of operations.
+type {{.Name}} struct {
+	*soap.Fault
+	Detail *{{.Type}}
+}
+
+// Unwrap returns the SOAP fault of f.
+func (f *{{.Name}}) Unwrap() error {
+	return f.Fault
+}
+`))
+
+// faultType is the Go type of the faults of a fault message.
+type faultType struct {
+	Name         string
+	Message      string
+	Type         string // Go type of the detail, not a pointer
+	Space, Local string // name of the element of the detail
+}
+
+// faultType returns the Go type of the fault f of the given operation
+// of port type pt, or nil if its message is not defined or has no part
+// for the detail. Faults of the same message share their type.
+func (ge *goEncoder) faultType(pt *wsdl.PortType, op *wsdl.Operation, f *wsdl.Fault) *faultType {
+	key := trimns(f.Message)
+	if ft, ok := ge.faultTypes[key]; ok {
+		return ft
+	}
+	m, ok := ge.messages[key]
+	if !ok || len(m.Parts) == 0 {
+		ge.faultTypes[key] = nil
+		return nil
+	}
+	part := m.Parts[0]
+	ft := &faultType{Message: m.Name}
+	switch {
+	case part.Element != "":
+		ft.Local = trimns(part.Element)
+		ft.Space = ge.elementNS[ft.Local]
+		if el, ok := ge.globalElement(ft.Local); ok {
+			ft.Type = ge.elementGoType(el)
+		} else {
+			ft.Type = ge.wsdl2goType(ge.elementTypeRef(part.Element))
+		}
+	default:
+		ft.Local = part.Name
+		if bf := ge.soapOp(pt, op).FaultByName(f.Name); bf != nil && bf.SOAP != nil {
+			ft.Space = bf.SOAP.Namespace
+		}
+		ft.Type = ge.wsdl2goType(part.Type)
+	}
+	ft.Type = strings.TrimPrefix(ft.Type, "*")
+	base := ge.exportedName(m.Name)
+	if !strings.HasSuffix(base, "Fault") {
+		base += "Fault"
+	}
+	taken := func(name string) bool {
+		return ge.typeExists(name) || ge.faultNames[name]
+	}
+	ft.Name = base
+	if taken(base) {
+		ft.Name = uniqueName(base+"Error", taken)
+		ge.rename("fault message %s declared as %s, as %s is taken", m.Name, ft.Name, base)
+	}
+	ge.faultTypes[key] = ft
+	ge.faultNames[ft.Name] = true
+	return ft
+}
+
+// operationFaults returns the Go types of the faults of the given
+// operation of port type pt, if bound to SOAP.
+func (ge *goEncoder) operationFaults(pt *wsdl.PortType, op *wsdl.Operation) []*faultType {
+	if ge.soapOp(pt, op) == nil || ge.httpVerbs[pt.Name] != "" {
+		return nil
+	}
+	var faults []*faultType
+	for _, f := range op.Faults {
+		if ft := ge.faultType(pt, op, f); ft != nil {
+			faults = append(faults, ft)
+		}
+	}
+	return faults
+}
+
+// writeFaults writes the Go types of the faults of the operations bound
+// to SOAP, whose detail the clients decode.
+func (ge *goEncoder) writeFaults(w io.Writer, d *wsdl.Definitions) error {
+	written := make(map[string]bool)
+	for _, pt := range d.PortTypes {
+		for _, op := range ge.funcs[pt.Name] {
+			for _, ft := range ge.operationFaults(pt, op) {
+				if written[ft.Name] {
+					continue
+				}
+				written[ft.Name] = true
+				ge.needsExtPkg["github.com/seamuncle/wsdl2go/soap"] = true
+				err := faultT.Execute(w, ft)
+				if err != nil {
+					return err
+				}
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,123 @@
+package wsdlgo
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"strings"
+
+	"github.com/seamuncle/wsdl2go/soap"
+)
+
+// FetchConfig configures the HTTP client that downloads WSDL documents
+// and the documents they import.
+type FetchConfig struct {
+	// Username and Password, if set, are sent with the requests to
+	// Hosts using HTTP Basic authentication, or Digest if Digest is
+	// set.
+	Username string
+	Password string
+	Digest   bool
+
+	// Header is added to the requests to Hosts.
+	Header http.Header
+
+	// Hosts are the hosts, as host or host:port, that the credentials
+	// and Header are sent to, such as that of the input document.
+	// They are sent to no other host, even when redirected.
+	Hosts []string
+
+	// CertFile and KeyFile are the PEM encoded client certificate
+	// and key to present to servers that require one.
+	CertFile string
+	KeyFile  string
+
+	// CAFile is a PEM encoded bundle of certificate authorities to
+	// trust in addition to those of the system.
+	CAFile string
+
+	// Insecure accepts invalid server certificates.
+	Insecure bool
+}
+
+// NewFetchClient returns an HTTP client configured by c, to be used with
+// the SetClient method of Encoder.
+func NewFetchClient(c *FetchConfig) (*http.Client, error) {
+	tc := &tls.Config{InsecureSkipVerify: c.Insecure}
+	if c.CertFile != "" || c.KeyFile != "" {
+		cert, err := tls.LoadX509KeyPair(c.CertFile, c.KeyFile)
+		if err != nil {
+			return nil, err
+		}
+		tc.Certificates = []tls.Certificate{cert}
+	}
+	if c.CAFile != "" {
+		pem, err := ioutil.ReadFile(c.CAFile)
+		if err != nil {
+			return nil, err
+		}
+		pool, err := x509.SystemCertPool()
+		if err != nil {
+			pool = x509.NewCertPool()
+		}
+		if !pool.AppendCertsFromPEM(pem) {
+			return nil, errors.New("no certificates found in " + c.CAFile)
+		}
+		tc.RootCAs = pool
+	}
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.TLSClientConfig = tc
+	var auth http.RoundTripper = transport
+	if c.Username != "" {
+		a := &soap.Auth{Username: c.Username, Password: c.Password, Digest: c.Digest}
+		auth = a.Transport(transport)
+	}
+	return &http.Client{
+		Transport: &fetchTransport{config: c, auth: auth, next: transport},
+	}, nil
+}
+
+// fetchTransport adds credentials and headers to the requests to the
+// hosts of its config.
+type fetchTransport struct {
+	config *FetchConfig
+	auth   http.RoundTripper // next with credentials
+	next   http.RoundTripper
+}
+
+func (t *fetchTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if !t.trusted(req) {
+		return t.next.RoundTrip(req)
+	}
+	if len(t.config.Header) == 0 {
+		return t.auth.RoundTrip(req)
+	}
+	// the request must not be modified, as per http.RoundTripper
+	r := new(http.Request)
+	*r = *req
+	r.Header = make(http.Header, len(req.Header))
+	for k, v := range req.Header {
+		r.Header[k] = v
+	}
+	for k, v := range t.config.Header {
+		r.Header[k] = v
+	}
+	return t.auth.RoundTrip(r)
+}
+
+// trusted reports whether req is to one of the hosts of the config of
+// t, by host, or by host and port if given.
+func (t *fetchTransport) trusted(req *http.Request) bool {
+	for _, h := range t.config.Hosts {
+		if strings.Contains(h, ":") {
+			if strings.EqualFold(h, req.URL.Host) {
+				return true
+			}
+		} else if strings.EqualFold(h, req.URL.Hostname()) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,135 @@
+package wsdlgo
+
+import (
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+)
+
+func TestFetchClient(t *testing.T) {
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		user, pass, ok := r.BasicAuth()
+		if !ok || user != "joe" || pass != "secret" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		if r.Header.Get("X-Api-Key") != "k" {
+			w.WriteHeader(http.StatusForbidden)
+			return
+		}
+		w.Write([]byte("<definitions/>"))
+	}))
+	defer s.Close()
+	other := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "" || r.Header.Get("X-Api-Key") != "" {
+			w.WriteHeader(http.StatusForbidden)
+			return
+		}
+		w.Write([]byte("<definitions/>"))
+	}))
+	defer other.Close()
+	cli, err := NewFetchClient(&FetchConfig{
+		Username: "joe",
+		Password: "secret",
+		Header:   http.Header{"X-Api-Key": {"k"}},
+		Hosts:    []string{strings.TrimPrefix(s.URL, "http://")},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	ge := NewEncoder(nil, true, false).(*goEncoder)
+	ge.SetClient(cli)
+	b, err := ge.download(s.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(b) != "<definitions/>" {
+		t.Fatalf("unexpected document: %q", b)
+	}
+	if _, err = ge.download(other.URL); err != nil {
+		t.Fatalf("credentials sent to another host: %v", err)
+	}
+	ge.SetClient(http.DefaultClient)
+	if _, err = ge.download(s.URL); err == nil {
+		t.Fatal("download without credentials succeeded")
+	}
+}
+
+func TestFetchClientDigest(t *testing.T) {
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") == "" {
+			w.Header().Set("WWW-Authenticate", `Digest realm="wsdl", nonce="n", qop="auth"`)
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		if !strings.HasPrefix(r.Header.Get("Authorization"), `Digest username="joe"`) {
+			w.WriteHeader(http.StatusForbidden)
+			return
+		}
+		w.Write([]byte("<definitions/>"))
+	}))
+	defer s.Close()
+	cli, err := NewFetchClient(&FetchConfig{Username: "joe", Password: "secret", Digest: true, Hosts: []string{"127.0.0.1"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	ge := NewEncoder(nil, true, false).(*goEncoder)
+	ge.SetClient(cli)
+	if _, err = ge.download(s.URL); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestFetchClientTLS(t *testing.T) {
+	cases := []*FetchConfig{
+		{CertFile: "testdata/missing.pem", KeyFile: "testdata/missing.key"},
+		{CAFile: "testdata/missing.pem"},
+		{CAFile: "testdata/memcache.wsdl"},
+	}
+	for i, c := range cases {
+		if _, err := NewFetchClient(c); err == nil {
+			t.Errorf("test %d: expected error for %+v", i, c)
+		}
+	}
+}
+
+func TestFetchCache(t *testing.T) {
+	var n int
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		n++
+		fmt.Fprintf(w, "<schema id=\"%d\"/>", n)
+	}))
+	defer s.Close()
+	dir, err := ioutil.TempDir("", "wsdl2go")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	cases := []struct {
+		Refresh bool
+		Want    string
+	}{
+		{false, `<schema id="1"/>`},
+		{false, `<schema id="1"/>`},
+		{true, `<schema id="2"/>`},
+		{false, `<schema id="2"/>`},
+	}
+	for i, tc := range cases {
+		ge := NewEncoder(nil, true, false).(*goEncoder)
+		ge.SetCache(dir, tc.Refresh)
+		b, err := ge.download(s.URL + "/a.xsd")
+		if err != nil {
+			t.Fatalf("test %d: %v", i, err)
+		}
+		if string(b) != tc.Want {
+			t.Errorf("test %d: want %q, have %q", i, tc.Want, b)
+		}
+	}
+	if n != 2 {
+		t.Errorf("want 2 downloads, have %d", n)
+	}
+}
@@ -0,0 +1,90 @@
+package wsdlgo
+
+import (
+	"io"
+	"strings"
+	"text/template"
+)
+
+func (ge *goEncoder) SetGetters(getters bool) {
+	ge.getters = getters
+}
+
+// structField is a field of the struct being generated.
+type structField struct {
+	Name     string
+	Type     string
+	Required bool // of a required element or attribute without fixed value
+}
+
+// fieldGetter is a pointer field of the struct being generated, read by
+// a getter.
+type fieldGetter struct {
+	Name  string
+	Type  string // type returned, the field's or the one it points to
+	Deref bool   // whether the getter returns the value pointed to
+}
+
+// addField records the field name of the struct being generated, of Go
+// type typ, which is a pointer to a value of Go type value if the field
+// is optional, and whether it is required. Getters are recorded for
+// pointer fields if enabled.
+func (ge *goEncoder) addField(name, typ, value string, required bool) {
+	ge.fieldNames[name] = true
+	ge.fields = append(ge.fields, &structField{Name: name, Type: typ, Required: required})
+	if !ge.getters || !strings.HasPrefix(typ, "*") {
+		return
+	}
+	g := &fieldGetter{Name: name, Type: typ}
+	if typ == "*"+value {
+		g.Type, g.Deref = value, true
+	}
+	ge.fieldGetters = append(ge.fieldGetters, g)
+}
+
+var gettersT = template.Must(template.New("getters").Parse(`
+{{- range .Fields}}
+{{- if .Deref}}
+// Get{{.Name}} returns the value of the {{.Name}} field of t, or the zero
+// value if t or the field is nil.
+func (t *{{$.Name}}) Get{{.Name}}() {{.Type}} {
+	if t == nil || t.{{.Name}} == nil {
+		var zero {{.Type}}
+		return zero
+	}
+	return *t.{{.Name}}
+}
+{{- else}}
+// Get{{.Name}} returns the {{.Name}} field of t, or nil if t is nil.
+func (t *{{$.Name}}) Get{{.Name}}() {{.Type}} {
+	if t == nil {
+		return nil
+	}
+	return t.{{.Name}}
+}
+{{- end}}
+{{end}}
+`))
+
+// genGetters writes the getters of the pointer fields of the struct
+// being generated, which return the zero value of their type rather
+// than panic when the field or the struct is nil, so that optional
+// values can be read through chains of calls. Getters whose name is
+// taken by a field are left out.
+func (ge *goEncoder) genGetters(w io.Writer, name string) {
+	var fields []*fieldGetter
+	for _, g := range ge.fieldGetters {
+		if !ge.fieldNames["Get"+g.Name] {
+			fields = append(fields, g)
+		}
+	}
+	ge.fieldGetters = nil
+	ge.fieldNames = make(map[string]bool)
+	if len(fields) == 0 {
+		return
+	}
+	gettersT.Execute(w, &struct {
+		Name   string
+		Fields []*fieldGetter
+	}{name, fields})
+}
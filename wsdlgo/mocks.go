@@ -0,0 +1,129 @@
+package wsdlgo
+
+import (
+	"errors"
+	"io"
+	"text/template"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/seamuncle/wsdl2go/wsdl"
+)
+
+func (ge *goEncoder) SetMocks(mocks bool) {
+	ge.mocks = mocks
+}
+
+// checkMocks returns an error if the mocks of SetMocks would conflict
+// with those of go-mock, which are named alike.
+func (ge *goEncoder) checkMocks() error {
+	if ge.mocks && ge.genMock {
+		return errors.New("mocks conflict with the go-mock mocks of the same name")
+	}
+	return nil
+}
+
+var mockT = template.Must(template.New("mock").Parse(`
+// {{.Name}} implements the {{.Interface}} interface for tests. Each
+// method records its arguments in the calls field of its operation, then
+// returns the results of the func field of its operation, or zero values
+// if that is nil. Methods may be called concurrently, but fields are to
+// be set before the calls and read after them.
+type {{.Name}} struct {
+	mu sync.Mutex
+{{range .Funcs}}
+	// {{.Name}}Func, if set, returns the results of {{.Name}}.
+	{{.Name}}Func func({{.Input}}) ({{.Output}})
+
+	// {{.Name}}Calls holds the arguments of the calls of {{.Name}}.
+	{{.Name}}Calls []struct{ {{- range .Args}}{{.Field}} {{.Type}}; {{end -}} }
+{{end}}
+}
+
+var _ {{.Interface}} = (*{{.Name}})(nil)
+{{range $f := .Funcs}}
+// {{.Name}} records its call and returns the results of {{.Name}}Func.
+func (m *{{$.Name}}) {{.Name}}({{.Input}}) ({{.Output}}) {
+	m.mu.Lock()
+	m.{{.Name}}Calls = append(m.{{.Name}}Calls, struct{ {{- range .Args}}{{.Field}} {{.Type}}; {{end -}} }{ {{- range $i, $a := .Args}}{{if $i}}, {{end}}{{.Name}}{{end -}} })
+	f := m.{{.Name}}Func
+	m.mu.Unlock()
+	if f == nil {
+		return
+	}
+	return f({{range $i, $a := .Args}}{{if $i}}, {{end}}{{.Name}}{{end}})
+}
+{{end}}
+`))
+
+// mockFunc is a method of a mock.
+type mockFunc struct {
+	Name, Input, Output string
+	Args                []*mockArg
+}
+
+// mockArg is an argument of a method of a mock, recorded in the field
+// of a call.
+type mockArg struct {
+	Name, Type, Field string
+}
+
+// writeMocks writes a mock of the interface of each port type that has
+// one, if enabled, named after it prefixed by Mock.
+func (ge *goEncoder) writeMocks(w io.Writer, d *wsdl.Definitions) error {
+	if !ge.mocks {
+		return nil
+	}
+	for _, pt := range d.PortTypes {
+		if len(ge.funcs[pt.Name]) == 0 {
+			continue
+		}
+		bound := len(ge.soapOps[pt.Name]) > 0
+		var funcs []*mockFunc
+		for _, op := range ge.funcs[pt.Name] {
+			if bound && ge.soapOp(pt, op) == nil {
+				continue
+			}
+			inParams, err := ge.inputParams(pt, op)
+			if err != nil {
+				return err
+			}
+			outParams, err := ge.outputParams(pt, op)
+			if err != nil {
+				return err
+			}
+			inParams = ge.headerParams(pt, op, inParams)
+			inParams = ge.contextParams(inParams)
+			fixParamConflicts(inParams, outParams)
+			f := &mockFunc{
+				Name:   ge.exportedName(op.Name),
+				Input:  asGoParamsString(inParams),
+				Output: asGoParamsString(outParams),
+			}
+			for _, p := range inParams {
+				r, n := utf8.DecodeRuneInString(p.Name)
+				f.Args = append(f.Args, &mockArg{
+					Name:  p.Name,
+					Type:  p.Type,
+					Field: string(unicode.ToUpper(r)) + p.Name[n:],
+				})
+			}
+			funcs = append(funcs, f)
+		}
+		iface := ge.interfaceName(pt)
+		name := uniqueName("Mock"+iface, ge.typeExists)
+		if name != "Mock"+iface {
+			ge.rename("mock of %s declared as %s, as Mock%s is taken", iface, name, iface)
+		}
+		ge.needsStdPkg["sync"] = true
+		err := mockT.Execute(w, &struct {
+			Name      string
+			Interface string
+			Funcs     []*mockFunc
+		}{name, iface, funcs})
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,161 @@
+package wsdlgo
+
+import (
+	"fmt"
+	"strings"
+	"unicode"
+)
+
+// Strategies of naming Go identifiers after schema names, which may
+// contain characters Go identifiers can not, such as dashes and dots.
+const (
+	// NamingCamelCase drops the characters that are not letters or
+	// digits, underscores included, and joins the words they
+	// separate in camel case: first_name becomes FirstName.
+	NamingCamelCase = "camel"
+
+	// NamingUnderscore separates those words with underscores
+	// instead, keeping their case: first-name becomes first_name,
+	// or First_name when exported.
+	NamingUnderscore = "underscore"
+)
+
+// DefaultAcronyms are the words written in upper case in Go
+// identifiers unless SetNaming sets others.
+var DefaultAcronyms = []string{"ID"}
+
+func (ge *goEncoder) SetNaming(strategy string, acronyms []string) {
+	ge.naming = strategy
+	if acronyms == nil {
+		acronyms = DefaultAcronyms
+	}
+	ge.acronyms = make(map[string]string)
+	for _, a := range acronyms {
+		ge.acronyms[strings.ToUpper(a)] = strings.ToUpper(a)
+	}
+}
+
+// checkNaming returns an error if the naming strategy is unknown.
+func (ge *goEncoder) checkNaming() error {
+	switch ge.naming {
+	case "", NamingCamelCase, NamingUnderscore:
+		return nil
+	}
+	return fmt.Errorf("unknown naming strategy %q, want %s or %s",
+		ge.naming, NamingCamelCase, NamingUnderscore)
+}
+
+// Cases of the first letter of identifiers.
+const (
+	keepCase = iota
+	upperCase
+	lowerCase
+)
+
+// goName returns the Go identifier of the schema name, which starts
+// with a letter of the same case as name.
+func (ge *goEncoder) goName(name string) string {
+	return ge.identifier(name, keepCase)
+}
+
+// exportedName returns the exported Go identifier of the schema name.
+func (ge *goEncoder) exportedName(name string) string {
+	return ge.identifier(name, upperCase)
+}
+
+// unexportedName returns the unexported Go identifier of the schema
+// name.
+func (ge *goEncoder) unexportedName(name string) string {
+	return ge.identifier(name, lowerCase)
+}
+
+// identifier returns the Go identifier of the schema name, named after
+// its words following the naming strategy, with those that are
+// acronyms in upper case. The case of its first letter is given by
+// first. Identifiers that would start with a digit are prefixed by
+// Var, and those that are keywords are doubled.
+func (ge *goEncoder) identifier(name string, first int) string {
+	sep := ""
+	if ge.naming == NamingUnderscore {
+		sep = "_"
+	}
+	acronyms := ge.acronyms
+	if acronyms == nil {
+		acronyms = make(map[string]string)
+		for _, a := range DefaultAcronyms {
+			acronyms[a] = a
+		}
+	}
+	var b strings.Builder
+	for i, word := range strings.FieldsFunc(name, func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	}) {
+		if i > 0 {
+			b.WriteString(sep)
+		}
+		for j, part := range splitHumps(word) {
+			a, isAcronym := acronyms[strings.ToUpper(part)]
+			switch {
+			case i == 0 && j == 0:
+				if first == keepCase {
+					first = lowerCase
+					if r := []rune(part); unicode.IsUpper(r[0]) {
+						first = upperCase
+					}
+				}
+				switch {
+				case first == upperCase && isAcronym:
+					part = a
+				case first == upperCase:
+					part = strings.Title(part)
+				case isAcronym:
+					part = strings.ToLower(a)
+				default:
+					r := []rune(part)
+					r[0] = unicode.ToLower(r[0])
+					part = string(r)
+				}
+			case isAcronym:
+				part = a
+			case sep == "" || j > 0:
+				part = strings.Title(part)
+			}
+			b.WriteString(part)
+		}
+	}
+	id := b.String()
+	if id == "" {
+		return id
+	}
+	if r := []rune(id); unicode.IsDigit(r[0]) {
+		if first == lowerCase {
+			return "var" + id
+		}
+		return "Var" + id
+	}
+	if isGoKeyword[id] {
+		return id + id
+	}
+	return id
+}
+
+// splitHumps splits word into the parts starting at each upper case
+// letter following a lower case letter or a digit, and at the last
+// upper case letter of a run followed by a lower case letter:
+// URLPath becomes URL and Path.
+func splitHumps(word string) []string {
+	r := []rune(word)
+	var parts []string
+	start := 0
+	for i := 1; i < len(r); i++ {
+		if !unicode.IsUpper(r[i]) {
+			continue
+		}
+		if unicode.IsLower(r[i-1]) || unicode.IsDigit(r[i-1]) ||
+			unicode.IsUpper(r[i-1]) && i+1 < len(r) && unicode.IsLower(r[i+1]) {
+			parts = append(parts, string(r[start:i]))
+			start = i
+		}
+	}
+	return append(parts, string(r[start:]))
+}
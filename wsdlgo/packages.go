@@ -0,0 +1,371 @@
+package wsdlgo
+
+import (
+	"encoding/xml"
+	"fmt"
+	"path"
+	"regexp"
+	"strings"
+
+	"github.com/seamuncle/wsdl2go/wsdl"
+)
+
+func (ge *goEncoder) SetPackages(importPath string) {
+	ge.packages = importPath
+}
+
+// encodePackages generates a package per namespace of the schemas of d,
+// the one of the main namespace in the directory of the import path
+// set by SetPackages and the others in directories named after their
+// namespace below it, and returns the content of their files by slash
+// separated path.
+func (ge *goEncoder) encodePackages(d *wsdl.Definitions) (map[string][]byte, error) {
+	err := ge.checkDecimal()
+	if err != nil {
+		return nil, err
+	}
+	err = ge.checkNaming()
+	if err != nil {
+		return nil, err
+	}
+	err = ge.checkTags()
+	if err != nil {
+		return nil, err
+	}
+	err = ge.checkMocks()
+	if err != nil {
+		return nil, err
+	}
+	err = ge.importParts(d)
+	if err != nil {
+		return nil, fmt.Errorf("wsdl import: %v", err)
+	}
+	all := ge.packageEncoder()
+	all.cacheTypes(packageView(d, nil, true))
+	packages := packageNamespaces(d, all.mainNamespace(d))
+
+	// the Go types of each package, as referred to by the others, and
+	// those of its simple types that have a Validate method
+	refs := make(map[string]map[string]string)
+	validRefs := make(map[string][]string)
+	for dir, namespaces := range packages {
+		e := ge.packageEncoder()
+		e.cacheTypes(packageView(d, namespaces, false))
+		refs[dir] = e.packageRefs(path.Join(ge.packages, dir))
+		if !ge.validate {
+			continue
+		}
+		e.cacheRestrictedTypes()
+		for name := range e.restrictedTypes {
+			if e.hasFacets(name) {
+				validRefs[dir] = append(validRefs[dir], packageName(path.Join(ge.packages, dir))+"."+name)
+			}
+		}
+	}
+
+	files := make(map[string][]byte)
+	imports := make(map[string][]string)
+	for _, dir := range sortedPackages(packages) {
+		namespaces := packages[dir]
+		e := ge.packageEncoder()
+		if dir != "" {
+			e.pkgName = path.Base(dir)
+		}
+		e.allDerived = true
+		e.foreignTypes = foreignTypes(d, namespaces)
+		e.typeMap = make(map[string]string)
+		e.cloneTypes = make(map[string]bool)
+		e.validTypes = make(map[string]bool)
+		for other, m := range refs {
+			if other == dir {
+				continue
+			}
+			for name, typ := range m {
+				e.typeMap[name] = typ
+				if _, ref := goTypeRef(typ); strings.HasPrefix(ref, "*") {
+					e.cloneTypes[ref[1:]] = true
+					e.validTypes[ref[1:]] = true
+				}
+			}
+			for _, ref := range validRefs[other] {
+				e.validTypes[ref] = true
+			}
+		}
+		for name, typ := range ge.typeMap {
+			e.typeMap[name] = typ
+		}
+		code, err := e.EncodeFiles(packageView(d, namespaces, dir == ""))
+		if err != nil {
+			return nil, err
+		}
+		for name, b := range code {
+			files[path.Join(dir, name)] = b
+		}
+		ge.renamed = append(ge.renamed, e.renamed...)
+		for _, pkg := range sortedKeys(e.needsExtPkg) {
+			for other := range packages {
+				if other != dir && pkg == path.Join(ge.packages, other) {
+					imports[dir] = append(imports[dir], other)
+				}
+			}
+		}
+	}
+	if cycle := importCycle(imports); cycle != nil {
+		for i, dir := range cycle {
+			cycle[i] = path.Join(ge.packages, dir)
+		}
+		return nil, fmt.Errorf("packages of namespaces whose types refer to each other import each other: %s",
+			strings.Join(cycle, " -> "))
+	}
+	return files, nil
+}
+
+// packageEncoder returns a new encoder of the settings of ge, to
+// generate one of the packages of encodePackages.
+func (ge *goEncoder) packageEncoder() *goEncoder {
+	e := NewEncoder(nil, ge.genGo, ge.genMock).(*goEncoder)
+	e.http = ge.http
+	e.location = ge.location
+	e.cacheDir, e.cacheRefresh = ge.cacheDir, ge.cacheRefresh
+	e.binding, e.service, e.port, e.transport = ge.binding, ge.service, ge.port, ge.transport
+	e.omitEmpty = ge.omitEmpty
+	e.bigIntegers = ge.bigIntegers
+	e.typeMap = ge.typeMap
+	e.decimal, e.decimalTypes = ge.decimal, ge.decimalTypes
+	e.naming, e.acronyms = ge.naming, ge.acronyms
+	e.tags, e.tagNaming = ge.tags, ge.tagNaming
+	e.getters = ge.getters
+	e.stringers = ge.stringers
+	e.clone = ge.clone
+	e.validate = ge.validate
+	e.constructors = ge.constructors
+	e.mocks = ge.mocks
+	e.servers = ge.servers
+	e.wsdl = ge.wsdl
+	e.noContext = ge.noContext
+	e.tests = ge.tests
+	return e
+}
+
+// packageRefs returns the Go types of the types cached by ge, declared
+// in the package of the given import path, by qualified name as given
+// to SetTypeMap. Types declared inline by elements are also named after
+// their element.
+func (ge *goEncoder) packageRefs(importPath string) map[string]string {
+	refs := make(map[string]string)
+	for name := range ge.stypes {
+		if ge.isCachedTypeMapped(name) {
+			continue
+		}
+		refs["{"+ge.typeNS[name]+"}"+ge.typeLocalName(name)] = importPath + "." + ge.goName(name)
+	}
+	for name, ct := range ge.ctypes {
+		if ge.isCachedTypeMapped(name) {
+			continue
+		}
+		typ := "*" + importPath + "." + ge.exportedName(name)
+		if ct.Abstract || len(ge.derived[name]) > 0 {
+			typ = importPath + "." + ge.derivedType(name)
+		}
+		refs["{"+ge.typeNS[name]+"}"+ge.typeLocalName(name)] = typ
+		if el, ok := ge.typeElements[name]; ok {
+			refs["{"+ge.typeNS[name]+"}"+el] = typ
+		}
+	}
+	return refs
+}
+
+// packageView returns a copy of d, its imports resolved, for the code of
+// the package of the given namespaces: the types of the schemas of other
+// namespaces are left out, but for the types of their elements, which are
+// referred to by qualified name instead. The messages, port types,
+// bindings and services of d are kept if service is true.
+func packageView(d *wsdl.Definitions, namespaces map[string]bool, service bool) *wsdl.Definitions {
+	v := *d
+	v.Imports = nil
+	if !service {
+		v.Messages = nil
+		v.PortTypes = nil
+		v.Bindings = nil
+		v.Services = nil
+	}
+	// schemas of the package are cached last, so that their elements
+	// take precedence over those of the same name
+	var own []*wsdl.Schema
+	v.Schemas = nil
+	for _, s := range d.Schemas {
+		c := *s
+		c.Imports = nil
+		c.Includes = nil
+		if namespaces == nil || namespaces[s.TargetNamespace] {
+			own = append(own, &c)
+			continue
+		}
+		c.SimpleTypes = nil
+		c.ComplexTypes = nil
+		c.Elements = make([]*wsdl.Element, len(s.Elements))
+		for i, el := range s.Elements {
+			if el.Type == "" && el.ComplexType != nil {
+				ref := *el
+				ref.ComplexType = nil
+				ref.Type = "{" + s.TargetNamespace + "}" + el.Name
+				el = &ref
+			}
+			c.Elements[i] = el
+		}
+		v.Schemas = append(v.Schemas, &c)
+	}
+	v.Schemas = append(v.Schemas, own...)
+	return &v
+}
+
+// foreignTypes returns the complex types of the schemas of d whose
+// namespace is not one of the given ones, by qualified name.
+func foreignTypes(d *wsdl.Definitions, namespaces map[string]bool) map[xml.Name]*inlineType {
+	types := make(map[xml.Name]*inlineType)
+	for _, s := range d.Schemas {
+		if namespaces[s.TargetNamespace] {
+			continue
+		}
+		for _, ct := range s.ComplexTypes {
+			types[xml.Name{Space: s.TargetNamespace, Local: ct.Name}] = &inlineType{ct, s}
+		}
+	}
+	return types
+}
+
+// packageNamespaces returns the namespaces of the schemas of d by the
+// directory of their package, relative to the one of the package of the
+// main namespace, which also holds the types of schemas without one.
+func packageNamespaces(d *wsdl.Definitions, main string) map[string]map[string]bool {
+	packages := map[string]map[string]bool{
+		"": {main: true, "": true},
+	}
+	dirs := make(map[string]string)
+	taken := make(map[string]bool)
+	for _, s := range d.Schemas {
+		ns := s.TargetNamespace
+		if packages[""][ns] {
+			continue
+		}
+		if _, ok := dirs[ns]; !ok {
+			dir := uniqueName(namespacePackage(ns), func(name string) bool {
+				return taken[name]
+			})
+			taken[dir] = true
+			dirs[ns] = dir
+			packages[dir] = make(map[string]bool)
+		}
+		packages[dirs[ns]][ns] = true
+	}
+	return packages
+}
+
+// sortedPackages returns the directories of packages in order.
+func sortedPackages(packages map[string]map[string]bool) []string {
+	dirs := make(map[string]bool)
+	for dir := range packages {
+		dirs[dir] = true
+	}
+	return sortedKeys(dirs)
+}
+
+// versionOrDate matches the elements of namespaces that are versions or
+// dates, such as v2, 1.0 or 2001.
+var versionOrDate = regexp.MustCompile(`^[vV]?[0-9][0-9._-]*$`)
+
+// namespacePackage returns the name of the package of the types of
+// namespace ns: the last element of its path that is not a version or a
+// date, or the first label of its host if there is none, in lower case
+// and without characters other than letters and digits.
+func namespacePackage(ns string) string {
+	host := ""
+	if i := strings.Index(ns, "://"); i >= 0 {
+		ns = ns[i+3:]
+		if j := strings.Index(ns, "/"); j >= 0 {
+			host, ns = ns[:j], ns[j:]
+		} else {
+			host, ns = ns, ""
+		}
+	}
+	elements := strings.FieldsFunc(ns, func(r rune) bool {
+		return r == '/' || r == ':' || r == '#'
+	})
+	for i := len(elements) - 1; i >= 0; i-- {
+		if versionOrDate.MatchString(elements[i]) || strings.EqualFold(elements[i], "urn") {
+			continue
+		}
+		if name := packageIdentifier(elements[i]); name != "" {
+			return name
+		}
+	}
+	for _, label := range strings.Split(host, ".") {
+		if label != "www" {
+			if name := packageIdentifier(label); name != "" {
+				return name
+			}
+		}
+	}
+	return "types"
+}
+
+// packageIdentifier returns s as a package name: its letters and digits
+// in lower case, prefixed by ns if it would start with a digit.
+func packageIdentifier(s string) string {
+	var b strings.Builder
+	for _, r := range strings.ToLower(s) {
+		if r >= 'a' && r <= 'z' || r >= '0' && r <= '9' {
+			b.WriteRune(r)
+		}
+	}
+	name := b.String()
+	if name != "" && name[0] >= '0' && name[0] <= '9' {
+		name = "ns" + name
+	}
+	return name
+}
+
+// importCycle returns the directories of the packages of a cycle of the
+// given imports, by directory, starting and ending with the same one, or
+// nil if there is none.
+func importCycle(imports map[string][]string) []string {
+	const (
+		visiting = 1
+		visited  = 2
+	)
+	state := make(map[string]int)
+	var stack []string
+	var visit func(dir string) []string
+	visit = func(dir string) []string {
+		switch state[dir] {
+		case visiting:
+			for i, d := range stack {
+				if d == dir {
+					return append(append([]string(nil), stack[i:]...), dir)
+				}
+			}
+		case visited:
+			return nil
+		}
+		state[dir] = visiting
+		stack = append(stack, dir)
+		for _, imp := range imports[dir] {
+			if cycle := visit(imp); cycle != nil {
+				return cycle
+			}
+		}
+		stack = stack[:len(stack)-1]
+		state[dir] = visited
+		return nil
+	}
+	dirs := make(map[string]bool)
+	for dir := range imports {
+		dirs[dir] = true
+	}
+	for _, dir := range sortedKeys(dirs) {
+		if cycle := visit(dir); cycle != nil {
+			return cycle
+		}
+	}
+	return nil
+}
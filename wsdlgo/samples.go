@@ -0,0 +1,773 @@
+package wsdlgo
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/xml"
+	"fmt"
+	"math/big"
+	"strconv"
+	"strings"
+
+	"github.com/seamuncle/wsdl2go/wsdl"
+)
+
+// Namespaces of the SOAP 1.1 and 1.2 envelopes, and of the attributes
+// of XML Schema instances and of XML.
+const (
+	soap11EnvelopeNamespace = "http://schemas.xmlsoap.org/soap/envelope/"
+	soap12EnvelopeNamespace = "http://www.w3.org/2003/05/soap-envelope"
+	xsiNamespace            = "http://www.w3.org/2001/XMLSchema-instance"
+	xmlNamespace            = "http://www.w3.org/XML/1998/namespace"
+)
+
+func (ge *goEncoder) EncodeSamples(d *wsdl.Definitions) (map[string][]byte, error) {
+	err := ge.importParts(d)
+	if err != nil {
+		return nil, fmt.Errorf("wsdl import: %v", err)
+	}
+	err = ge.selectBindings(d)
+	if err != nil {
+		return nil, err
+	}
+	ge.cacheTypes(d)
+	ge.cacheFuncs(d)
+	ge.cacheMessages(d)
+	ge.cacheSOAPOperations(d)
+	sw := &sampleWriter{
+		ge:              ge,
+		d:               d,
+		globals:         make(map[string]*sampleElement),
+		expanding:       make(map[*wsdl.ComplexType]bool),
+		expandingSimple: make(map[*wsdl.SimpleType]bool),
+	}
+	// as for elementNS, elements of later schemas win
+	for _, s := range d.Schemas {
+		for _, el := range s.Elements {
+			sw.globals[el.Name] = &sampleElement{el, s}
+		}
+	}
+	files := make(map[string][]byte)
+	for _, pt := range d.PortTypes {
+		var version string
+		for _, b := range ge.bindings {
+			if trimns(b.Type) == pt.Name {
+				version = b.SOAPVersion()
+			}
+		}
+		if version == "" {
+			continue
+		}
+		for _, op := range ge.funcs[pt.Name] {
+			bo := ge.soapOp(pt, op)
+			if bo == nil || op.Input == nil {
+				continue
+			}
+			b, err := sw.request(pt, op, bo, version)
+			if err != nil {
+				return nil, err
+			}
+			name := op.Name + ".xml"
+			if _, taken := files[name]; taken {
+				name = pt.Name + "." + name
+			}
+			files[name] = b
+		}
+	}
+	return files, nil
+}
+
+// sampleWriter writes sample requests of the operations of definitions,
+// whose types are cached by its encoder.
+type sampleWriter struct {
+	ge *goEncoder
+	d  *wsdl.Definitions
+
+	// global elements, by name
+	globals map[string]*sampleElement
+
+	// types being expanded, whose optional elements are left out
+	expanding       map[*wsdl.ComplexType]bool
+	expandingSimple map[*wsdl.SimpleType]bool
+}
+
+// sampleElement is a global element of schema s.
+type sampleElement struct {
+	el *wsdl.Element
+	s  *wsdl.Schema
+}
+
+// sampleNode is an element of a sample, or a comment if Comment is set.
+type sampleNode struct {
+	Comment  string
+	Name     xml.Name
+	Type     xml.Name // xsi:type, if set
+	Attrs    []xml.Attr
+	Text     string
+	Children []*sampleNode
+}
+
+// request returns the SOAP envelope of a sample request of operation op
+// of port type pt, bound by bo to the given SOAP version.
+func (sw *sampleWriter) request(pt *wsdl.PortType, op *wsdl.Operation, bo *wsdl.BindingOperation, version string) ([]byte, error) {
+	m, ok := sw.ge.messages[trimns(op.Input.Message)]
+	if !ok {
+		return nil, fmt.Errorf("operation %q wants input message %q but it's not defined", op.Name, trimns(op.Input.Message))
+	}
+	var header []*sampleNode
+	for _, h := range bo.InputHeaders {
+		nodes, err := sw.header(h)
+		if err != nil {
+			return nil, fmt.Errorf("operation %q: %v", op.Name, err)
+		}
+		header = append(header, nodes...)
+	}
+	var parts map[string]bool
+	if bo.Input != nil && bo.Input.Parts != "" {
+		parts = make(map[string]bool)
+		for _, name := range strings.Fields(bo.Input.Parts) {
+			parts[name] = true
+		}
+	}
+	var body []*sampleNode
+	for _, p := range m.Parts {
+		if parts != nil && !parts[p.Name] || parts == nil && isHeaderPart(op.Input.Message, p.Name, bo.InputHeaders) {
+			continue
+		}
+		body = append(body, sw.part(p, "")...)
+	}
+	if sw.ge.rpcStyle(pt, op) {
+		wrapper := &sampleNode{Name: xml.Name{Local: op.Name}, Children: body}
+		if bo.Input != nil {
+			wrapper.Name.Space = bo.Input.Namespace
+		}
+		body = []*sampleNode{wrapper}
+	}
+	env := soap11EnvelopeNamespace
+	if version == "1.2" {
+		env = soap12EnvelopeNamespace
+	}
+	var b bytes.Buffer
+	b.WriteString(`<soapenv:Envelope xmlns:soapenv="` + env + `">` + "\n")
+	if len(header) > 0 {
+		b.WriteString("  <soapenv:Header>\n")
+		for _, n := range header {
+			n.write(&b, "    ", "")
+		}
+		b.WriteString("  </soapenv:Header>\n")
+	}
+	b.WriteString("  <soapenv:Body>\n")
+	for _, n := range body {
+		n.write(&b, "    ", "")
+	}
+	b.WriteString("  </soapenv:Body>\n")
+	b.WriteString("</soapenv:Envelope>\n")
+	return b.Bytes(), nil
+}
+
+// header returns the nodes of the message part sent as header block h.
+func (sw *sampleWriter) header(h *wsdl.SOAPHeader) ([]*sampleNode, error) {
+	m, ok := sw.ge.messages[trimns(h.Message)]
+	if !ok {
+		return nil, fmt.Errorf("header message %q is not defined", h.Message)
+	}
+	for _, p := range m.Parts {
+		if p.Name == h.Part {
+			return sw.part(p, h.Namespace), nil
+		}
+	}
+	return nil, fmt.Errorf("header message %q has no part %q", h.Message, h.Part)
+}
+
+// part returns the nodes of message part p: the global element it
+// names, or an element named after it in namespace ns of the type it
+// names.
+func (sw *sampleWriter) part(p *wsdl.Part, ns string) []*sampleNode {
+	if p.Element != "" {
+		return sw.element(&wsdl.Element{Ref: p.Element, Min: 1}, nil)
+	}
+	return sw.typed(xml.Name{Space: ns, Local: p.Name}, &wsdl.Element{Type: p.Type}, nil)
+}
+
+// element returns the nodes of el, an element or element reference of
+// schema s, as many times as it must occur or once, preceded by a comment
+// on its occurrences. Abstract elements are replaced by the first element
+// that can substitute for them. Optional elements of the complex types
+// being expanded are left out, so that recursive types end.
+func (sw *sampleWriter) element(el *wsdl.Element, s *wsdl.Schema) []*sampleNode {
+	if el.Max == "0" {
+		return nil
+	}
+	name := xml.Name{Local: el.Name}
+	decl, ds := el, s
+	if el.Ref != "" {
+		g, ok := sw.globals[trimns(el.Ref)]
+		if !ok {
+			return []*sampleNode{{Comment: fmt.Sprintf("Element %s is not declared", el.Ref)}}
+		}
+		if g = sw.substitute(g); g == nil {
+			return []*sampleNode{{Comment: fmt.Sprintf("Element %s is abstract and can not be substituted", el.Ref)}}
+		}
+		decl, ds = g.el, g.s
+		name = xml.Name{Space: g.s.TargetNamespace, Local: g.el.Name}
+	} else if s != nil && qualified(el.Form, s.ElementFormDefault) {
+		name.Space = s.TargetNamespace
+	}
+	if ct, _ := sw.complexType(decl, ds); el.Min == 0 && ct != nil && sw.expanding[ct] {
+		return nil
+	}
+	var nodes []*sampleNode
+	switch {
+	case el.Max == "unbounded" && el.Min == 0:
+		nodes = append(nodes, &sampleNode{Comment: "Zero or more repetitions:"})
+	case el.Max == "unbounded":
+		nodes = append(nodes, &sampleNode{Comment: fmt.Sprintf("%d or more repetitions:", el.Min)})
+	case el.Max != "" && el.Max != "1":
+		nodes = append(nodes, &sampleNode{Comment: fmt.Sprintf("%d to %s repetitions:", el.Min, el.Max)})
+	case el.Min == 0:
+		nodes = append(nodes, &sampleNode{Comment: "Optional:"})
+	}
+	for i := 0; i < el.Min || i == 0; i++ {
+		nodes = append(nodes, sw.typed(name, decl, ds)...)
+	}
+	return nodes
+}
+
+// substitute returns the global element g, or the first element of its
+// substitution group that is not abstract if g is, or nil if none.
+func (sw *sampleWriter) substitute(g *sampleElement) *sampleElement {
+	if !g.el.Abstract {
+		return g
+	}
+	for _, v := range sw.ge.substitutes[g.el.Name] {
+		if sub, ok := sw.globals[v.Name]; ok && !v.Abstract {
+			return sub
+		}
+	}
+	return nil
+}
+
+// complexType returns the complex type of the element declaration decl
+// of schema s, and the schema of its elements, or nil if its type is
+// simple.
+func (sw *sampleWriter) complexType(decl *wsdl.Element, s *wsdl.Schema) (*wsdl.ComplexType, *wsdl.Schema) {
+	if decl.ComplexType != nil {
+		return decl.ComplexType, s
+	}
+	if decl.Type == "" {
+		return nil, nil
+	}
+	q := sw.resolve(s, decl.Type)
+	if q.Space == wsdl.XMLSchemaNamespace {
+		return nil, nil
+	}
+	ct, ok := sw.ge.ctypes[sw.ge.typeName(s, decl.Type)]
+	if !ok {
+		return nil, nil
+	}
+	return ct, sw.typeSchema(ct.Name, s)
+}
+
+// typed returns the node of the given name of the element declaration
+// decl of schema s, filled after its type, preceded by a comment on the
+// facets its value may not satisfy. Abstract types are replaced by the
+// first of their derived types that is not, named by xsi:type.
+func (sw *sampleWriter) typed(name xml.Name, decl *wsdl.Element, s *wsdl.Schema) []*sampleNode {
+	n := &sampleNode{Name: name}
+	var note string
+	if ct, cs := sw.complexType(decl, s); ct != nil {
+		if ct.Abstract {
+			derived := sw.ge.concreteDerivedTypes(ct.Name)
+			if len(derived) == 0 {
+				return []*sampleNode{{Comment: fmt.Sprintf("Type %s is abstract and has no derived types", ct.Name)}, n}
+			}
+			ct = sw.ge.ctypes[derived[0]]
+			cs = sw.typeSchema(ct.Name, s)
+			n.Type = xml.Name{Space: sw.ge.typeNS[ct.Name], Local: sw.ge.typeLocalName(ct.Name)}
+		}
+		sw.complexContent(n, ct, cs)
+	} else {
+		typ := decl.Type
+		if typ == "" {
+			typ = "string"
+		}
+		v := sw.simpleValue(typ, s)
+		n.Text, note = v.text, v.note
+	}
+	if len(n.Children) == 0 && (decl.Fixed != "" || decl.Default != "") {
+		n.Text, note = decl.Fixed, ""
+		if n.Text == "" {
+			n.Text = decl.Default
+		}
+	}
+	if note != "" {
+		return []*sampleNode{{Comment: note}, n}
+	}
+	return []*sampleNode{n}
+}
+
+// complexContent adds the attributes, elements or text of complex type
+// ct of schema s to n, including those of its base types.
+func (sw *sampleWriter) complexContent(n *sampleNode, ct *wsdl.ComplexType, s *wsdl.Schema) {
+	if sw.expanding[ct] {
+		return
+	}
+	sw.expanding[ct] = true
+	defer delete(sw.expanding, ct)
+	if cc := ct.ComplexContent; cc != nil && cc.Extension != nil {
+		ext := cc.Extension
+		if base, ok := sw.ge.ctypes[sw.ge.typeName(s, ext.Base)]; ok {
+			sw.complexContent(n, base, sw.typeSchema(base.Name, s))
+		}
+		sw.choice(n, ext.Choice, s)
+		sw.group(n, ext.Group, s)
+		sw.attributes(n, ext.Attributes, ext.AttributeGroups, s)
+		if seq := ext.Sequence; seq != nil {
+			for _, v := range seq.ComplexTypes {
+				sw.elements(n, v, s)
+			}
+			sw.sequence(n, seq, s)
+		}
+	}
+	if sc := ct.SimpleContent; sc != nil {
+		var base string
+		if sc.Extension != nil {
+			base = sc.Extension.Base
+		} else if sc.Restriction != nil {
+			base = sc.Restriction.Base
+		}
+		if bt, ok := sw.ge.ctypes[sw.ge.typeName(s, base)]; ok && bt != ct {
+			sw.complexContent(n, bt, sw.typeSchema(bt.Name, s))
+		} else {
+			if base == "" {
+				base = "string"
+			}
+			v := sw.simpleValue(base, s)
+			if sc.Restriction != nil {
+				v = restrictValue(v, sc.Restriction)
+			}
+			n.Text = v.text
+		}
+		if ext := sc.Extension; ext != nil {
+			sw.attributes(n, ext.Attributes, ext.AttributeGroups, s)
+		}
+	}
+	sw.elements(n, ct, s)
+	sw.attributes(n, ct.Attributes, ct.AttributeGroups, s)
+}
+
+// elements adds the elements declared by ct of schema s to n.
+func (sw *sampleWriter) elements(n *sampleNode, ct *wsdl.ComplexType, s *wsdl.Schema) {
+	for _, el := range ct.AllElements {
+		n.Children = append(n.Children, sw.element(el, s)...)
+	}
+	sw.choice(n, ct.Choice, s)
+	sw.group(n, ct.Group, s)
+	sw.sequence(n, ct.Sequence, s)
+}
+
+// sequence adds the elements of seq of schema s to n. Wildcards are
+// noted by a comment.
+func (sw *sampleWriter) sequence(n *sampleNode, seq *wsdl.Sequence, s *wsdl.Schema) {
+	if seq == nil {
+		return
+	}
+	for _, p := range seq.Particles() {
+		switch p := p.(type) {
+		case *wsdl.Element:
+			n.Children = append(n.Children, sw.element(p, s)...)
+		case *wsdl.Choice:
+			sw.choice(n, p, s)
+		case *wsdl.Group:
+			sw.group(n, p, s)
+		case *wsdl.AnyElement:
+			if p.Max != "0" {
+				n.Children = append(n.Children, &sampleNode{Comment: "Any elements may be added here"})
+			}
+		}
+	}
+}
+
+// choice adds the first alternative of ch of schema s that has nodes to
+// n, preceded by a comment listing the alternatives.
+func (sw *sampleWriter) choice(n *sampleNode, ch *wsdl.Choice, s *wsdl.Schema) {
+	if ch == nil {
+		return
+	}
+	elements := append([]*wsdl.Element{}, ch.Elements...)
+	for _, g := range ch.Groups {
+		elements = append(elements, sw.ge.groupElements(g)...)
+	}
+	names := make([]string, 0, len(elements))
+	for _, el := range elements {
+		name := el.Name
+		if name == "" {
+			name = trimns(el.Ref)
+		}
+		names = append(names, name)
+	}
+	for _, el := range elements {
+		nodes := sw.element(el, s)
+		if len(nodes) == 0 {
+			continue
+		}
+		if len(names) > 1 {
+			last := len(names) - 1
+			comment := fmt.Sprintf("Choice of %s or %s:", strings.Join(names[:last], ", "), names[last])
+			n.Children = append(n.Children, &sampleNode{Comment: comment})
+		}
+		n.Children = append(n.Children, nodes...)
+		return
+	}
+}
+
+// group adds the elements of the group referenced by ref to n.
+func (sw *sampleWriter) group(n *sampleNode, ref *wsdl.Group, s *wsdl.Schema) {
+	g := sw.ge.resolveGroup(ref)
+	if g == nil {
+		return
+	}
+	sw.ge.expanding[g.Name] = true
+	defer delete(sw.ge.expanding, g.Name)
+	for _, el := range g.AllElements {
+		n.Children = append(n.Children, sw.element(el, s)...)
+	}
+	sw.choice(n, g.Choice, s)
+	sw.sequence(n, g.Sequence, s)
+}
+
+// attributes adds the given attributes of schema s, and those of the
+// attribute groups referenced by refs, to n, optional or not.
+func (sw *sampleWriter) attributes(n *sampleNode, attrs []*wsdl.Attribute, refs []*wsdl.AttributeGroup, s *wsdl.Schema) {
+	for _, a := range attrs {
+		use := a.Use
+		var ns string
+		if a.Ref != "" {
+			ref, ok := sw.ge.attributes[trimns(a.Ref)]
+			if !ok {
+				continue
+			}
+			a = ref
+			ns = sw.ge.attributeNS[a.Name]
+		} else if s != nil && qualified(a.Form, s.AttributeFormDefault) {
+			ns = s.TargetNamespace
+		}
+		if a.Name == "" || use == "prohibited" {
+			continue
+		}
+		value := a.Fixed
+		if value == "" {
+			value = a.Default
+		}
+		if value == "" {
+			typ := a.Type
+			if typ == "" {
+				typ = "string"
+			}
+			value = sw.simpleValue(typ, s).text
+		}
+		n.Attrs = append(n.Attrs, xml.Attr{Name: xml.Name{Space: ns, Local: a.Name}, Value: value})
+	}
+	for _, ref := range refs {
+		g, ok := sw.ge.attrGroups[trimns(ref.Ref)]
+		key := "attributeGroup " + trimns(ref.Ref)
+		if !ok || sw.ge.expanding[key] {
+			continue
+		}
+		sw.ge.expanding[key] = true
+		sw.attributes(n, g.Attributes, g.AttributeGroups, s)
+		delete(sw.ge.expanding, key)
+	}
+}
+
+// sampleValue is a placeholder value of a simple type, the built-in type
+// it is derived from, and a note on the facets it may not satisfy.
+type sampleValue struct {
+	text, builtin, note string
+}
+
+// simpleValue returns a placeholder value of the simple type typ, named
+// in schema s.
+func (sw *sampleWriter) simpleValue(typ string, s *wsdl.Schema) sampleValue {
+	q := sw.resolve(s, typ)
+	if q.Space != wsdl.XMLSchemaNamespace {
+		if st, ok := sw.ge.stypes[sw.ge.typeName(s, typ)]; ok && !sw.expandingSimple[st] {
+			return sw.simpleTypeValue(st, sw.typeSchema(st.Name, s))
+		}
+	}
+	return builtinValue(q.Local)
+}
+
+// simpleTypeValue returns a placeholder value of st, of schema s: the
+// value of its base restricted by its facets, an item of its list type
+// or a value of its first member type.
+func (sw *sampleWriter) simpleTypeValue(st *wsdl.SimpleType, s *wsdl.Schema) sampleValue {
+	sw.expandingSimple[st] = true
+	defer delete(sw.expandingSimple, st)
+	switch {
+	case st.Restriction != nil:
+		base := st.Restriction.Base
+		if base == "" {
+			base = "string"
+		}
+		return restrictValue(sw.simpleValue(base, s), st.Restriction)
+	case st.List != nil && st.List.SimpleType != nil:
+		return sw.simpleTypeValue(st.List.SimpleType, s)
+	case st.List != nil:
+		return sw.simpleValue(st.List.ItemType, s)
+	case st.Union != nil:
+		if members := strings.Fields(st.Union.MemberTypes); len(members) > 0 {
+			return sw.simpleValue(members[0], s)
+		}
+		if len(st.Union.SimpleTypes) > 0 {
+			return sw.simpleTypeValue(st.Union.SimpleTypes[0], s)
+		}
+	}
+	return builtinValue("string")
+}
+
+// resolve resolves the qualified name qname of schema s, or of the
+// definitions if s is nil.
+func (sw *sampleWriter) resolve(s *wsdl.Schema, qname string) xml.Name {
+	if s != nil {
+		return s.ResolveQName(qname)
+	}
+	return sw.d.ResolveQName(qname)
+}
+
+// typeSchema returns the schema declaring the named type, or s if not
+// known.
+func (sw *sampleWriter) typeSchema(name string, s *wsdl.Schema) *wsdl.Schema {
+	if ts, ok := sw.ge.typeSchema[name]; ok {
+		return ts
+	}
+	return s
+}
+
+// builtinSamples are the placeholder values of the built-in types, by
+// name. Other types have a question mark.
+var builtinSamples = map[string]string{
+	"string":             "string",
+	"normalizedString":   "string",
+	"token":              "string",
+	"Name":               "string",
+	"NCName":             "string",
+	"NMTOKEN":            "string",
+	"NMTOKENS":           "string",
+	"ID":                 "string",
+	"IDREF":              "string",
+	"IDREFS":             "string",
+	"ENTITY":             "string",
+	"ENTITIES":           "string",
+	"QName":              "string",
+	"language":           "en",
+	"anyURI":             "http://example.com",
+	"boolean":            "false",
+	"decimal":            "0",
+	"float":              "0",
+	"double":             "0",
+	"integer":            "0",
+	"nonPositiveInteger": "0",
+	"negativeInteger":    "-1",
+	"long":               "0",
+	"int":                "0",
+	"short":              "0",
+	"byte":               "0",
+	"nonNegativeInteger": "0",
+	"positiveInteger":    "1",
+	"unsignedLong":       "0",
+	"unsignedInt":        "0",
+	"unsignedShort":      "0",
+	"unsignedByte":       "0",
+	"dateTime":           "2006-01-02T15:04:05",
+	"date":               "2006-01-02",
+	"time":               "15:04:05",
+	"duration":           "P1D",
+	"gYear":              "2006",
+	"gYearMonth":         "2006-01",
+	"gMonth":             "--01",
+	"gMonthDay":          "--01-02",
+	"gDay":               "---02",
+	"base64Binary":       "AA==",
+	"hexBinary":          "00",
+}
+
+// builtinValue returns the placeholder value of the named built-in type.
+func builtinValue(name string) sampleValue {
+	text, ok := builtinSamples[name]
+	if !ok {
+		text = "?"
+	}
+	return sampleValue{text: text, builtin: name}
+}
+
+// restrictValue returns v, of a simple type restricted by r, changed to
+// satisfy its facets: the first enumerated value, if any, or v within
+// the range of numbers and the length of strings and binary data.
+// Patterns are noted rather than satisfied.
+func restrictValue(v sampleValue, r *wsdl.Restriction) sampleValue {
+	if len(r.Enum) > 0 {
+		v.text, v.note = r.Enum[0].Value, ""
+		return v
+	}
+	if len(r.Patterns) > 0 {
+		v.note = "Pattern: " + r.Patterns[0].Value
+	}
+	switch v.builtin {
+	case "decimal", "float", "double", "integer", "nonPositiveInteger", "negativeInteger",
+		"long", "int", "short", "byte", "nonNegativeInteger", "positiveInteger",
+		"unsignedLong", "unsignedInt", "unsignedShort", "unsignedByte":
+		v.text = restrictRange(v.text, r)
+	case "hexBinary":
+		b, _ := hex.DecodeString(v.text)
+		v.text = hex.EncodeToString(make([]byte, restrictLength(len(b), r)))
+	case "base64Binary":
+		b, _ := base64.StdEncoding.DecodeString(v.text)
+		v.text = base64.StdEncoding.EncodeToString(make([]byte, restrictLength(len(b), r)))
+	case "boolean", "dateTime", "date", "time", "duration", "gYear", "gYearMonth", "gMonth", "gMonthDay", "gDay":
+	default:
+		runes := []rune(v.text)
+		n := restrictLength(len(runes), r)
+		if n < len(runes) {
+			v.text = string(runes[:n])
+		} else {
+			v.text += strings.Repeat("x", n-len(runes))
+		}
+	}
+	return v
+}
+
+// restrictLength returns the length n changed to satisfy the length
+// facets of r.
+func restrictLength(n int, r *wsdl.Restriction) int {
+	facet := func(f *wsdl.Facet) (int, bool) {
+		if f == nil {
+			return 0, false
+		}
+		v, err := strconv.Atoi(strings.TrimSpace(f.Value))
+		return v, err == nil
+	}
+	if v, ok := facet(r.Length); ok {
+		return v
+	}
+	if v, ok := facet(r.MinLength); ok && n < v {
+		n = v
+	}
+	if v, ok := facet(r.MaxLength); ok && n > v {
+		n = v
+	}
+	return n
+}
+
+// restrictRange returns the number text changed to satisfy the range
+// facets of r.
+func restrictRange(text string, r *wsdl.Restriction) string {
+	x, ok := new(big.Rat).SetString(text)
+	if !ok {
+		return text
+	}
+	bound := func(inclusive, exclusive *wsdl.Facet) (*big.Rat, bool) {
+		for _, f := range []*wsdl.Facet{inclusive, exclusive} {
+			if f == nil {
+				continue
+			}
+			if v, ok := new(big.Rat).SetString(strings.TrimSpace(f.Value)); ok {
+				return v, f == exclusive
+			}
+		}
+		return nil, false
+	}
+	one := big.NewRat(1, 1)
+	lo, loExclusive := bound(r.MinInclusive, r.MinExclusive)
+	hi, hiExclusive := bound(r.MaxInclusive, r.MaxExclusive)
+	below := func() bool {
+		return lo != nil && (x.Cmp(lo) < 0 || loExclusive && x.Cmp(lo) == 0)
+	}
+	if below() {
+		x.Set(lo)
+		if loExclusive {
+			x.Add(x, one)
+		}
+	}
+	if hi != nil && (x.Cmp(hi) > 0 || hiExclusive && x.Cmp(hi) == 0) {
+		x.Set(hi)
+		if hiExclusive {
+			x.Sub(x, one)
+		}
+	}
+	if hi != nil && below() {
+		// exclusive bounds less than 1 apart
+		x.Add(lo, hi)
+		x.Quo(x, big.NewRat(2, 1))
+	}
+	if x.IsInt() {
+		return x.RatString()
+	}
+	return strings.TrimRight(x.FloatString(10), "0")
+}
+
+// write writes n to b, indented by indent, in the default namespace ns.
+// Namespaces of attributes and xsi:type are declared on n.
+func (n *sampleNode) write(b *bytes.Buffer, indent, ns string) {
+	if n.Comment != "" {
+		c := strings.Replace(n.Comment, "--", "- -", -1)
+		if strings.HasSuffix(c, "-") {
+			c += " "
+		}
+		b.WriteString(indent + "<!--" + c + "-->\n")
+		return
+	}
+	b.WriteString(indent + "<" + n.Name.Local)
+	if n.Name.Space != ns {
+		writeSampleAttr(b, "xmlns", n.Name.Space)
+	}
+	prefixes := map[string]string{xmlNamespace: "xml"}
+	prefix := func(space string) string {
+		if p, ok := prefixes[space]; ok {
+			return p
+		}
+		p := "ns" + strconv.Itoa(len(prefixes))
+		if space == xsiNamespace {
+			p = "xsi"
+		}
+		prefixes[space] = p
+		writeSampleAttr(b, "xmlns:"+p, space)
+		return p
+	}
+	if n.Type.Local != "" {
+		typ := n.Type.Local
+		if n.Type.Space != "" && n.Type.Space != n.Name.Space {
+			typ = prefix(n.Type.Space) + ":" + typ
+		}
+		writeSampleAttr(b, prefix(xsiNamespace)+":type", typ)
+	}
+	for _, a := range n.Attrs {
+		name := a.Name.Local
+		if a.Name.Space != "" {
+			name = prefix(a.Name.Space) + ":" + name
+		}
+		writeSampleAttr(b, name, a.Value)
+	}
+	switch {
+	case len(n.Children) > 0:
+		b.WriteString(">\n")
+		for _, c := range n.Children {
+			c.write(b, indent+"  ", n.Name.Space)
+		}
+		b.WriteString(indent + "</" + n.Name.Local + ">\n")
+	case n.Text != "":
+		b.WriteString(">")
+		xml.EscapeText(b, []byte(n.Text))
+		b.WriteString("</" + n.Name.Local + ">\n")
+	default:
+		b.WriteString("/>\n")
+	}
+}
+
+// writeSampleAttr writes the attribute of the given name and value to b.
+func writeSampleAttr(b *bytes.Buffer, name, value string) {
+	b.WriteString(" " + name + `="`)
+	xml.EscapeText(b, []byte(value))
+	b.WriteString(`"`)
+}
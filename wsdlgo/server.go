@@ -0,0 +1,253 @@
+package wsdlgo
+
+import (
+	"io"
+	"strconv"
+	"strings"
+	"text/template"
+	"unicode/utf8"
+
+	"github.com/seamuncle/wsdl2go/wsdl"
+)
+
+func (ge *goEncoder) SetServers(servers bool) {
+	ge.servers = servers
+}
+
+func (ge *goEncoder) SetWSDL(doc []byte) {
+	ge.wsdl = doc
+}
+
+var wsdlT = template.Must(template.New("wsdl").Parse(`
+// {{.Name}} is the WSDL document this code was generated from, which
+// the servers serve to GET requests with a wsdl query, with the addresses
+// of its ports set to the URL of the request.
+const {{.Name}} = {{.Value}}
+`))
+
+var serverT = template.Must(template.New("server").Parse(`
+// {{.Name}} returns a SOAP server of the operations of impl, an
+// http.Handler decoding requests, calling the method of their operation
+// and encoding its results, or its error as a SOAP fault.
+func {{.Name}}(impl {{.Interface}}) *soap.Server {
+	s := &soap.Server{ {{- if .WSDL}}WSDL: []byte({{.WSDL}}){{end -}} }
+{{- range .Ops }}
+	s.Handle(&soap.Operation{
+		Action:  {{.Action}},
+		Element: xml.Name{Space: {{printf "%q" .Space}}, Local: {{printf "%q" .Local}}},
+		Serve: func(ctx context.Context, r *soap.Request) (soap.Message, error) {
+{{- if .WrapperIn }}
+			in := {{.WrapperIn.Type}}{}
+{{- else }}
+			in := struct {
+{{- range .In }}
+				{{.Name}} {{.Type}} ` + "`" + `xml:"{{.Tag}}"` + "`" + `
+{{- end }}
+			}{}
+{{- end }}
+			err := r.DecodeBody(&in)
+			if err != nil {
+				return nil, err
+			}
+{{- if .Header }}
+			header := &{{.Header}}{}
+{{- range .HeaderFields }}
+			err = r.DecodeHeader(xml.Name{Space: {{printf "%q" .Space}}, Local: {{printf "%q" .Local}}}, &header.{{.Name}})
+			if err != nil {
+				return nil, err
+			}
+{{- end }}
+{{- end }}
+{{- if .OneWay }}
+			return nil, impl.{{.Name}}({{.Args}})
+{{- else }}
+			out := struct {
+				XMLName xml.Name ` + "`" + `xml:"{{.OutTag}}"` + "`" + `
+{{- if .WrapperOut }}
+				{{.WrapperOut.Type}}
+{{- else }}
+{{- range .Out }}
+				{{.Name}} {{.Type}} ` + "`" + `xml:"{{.Tag}},omitempty"` + "`" + `
+{{- end }}
+{{- end }}
+			}{}
+			{{range .Out}}out.{{.Name}}, {{end}}err = impl.{{.Name}}({{.Args}})
+			if err != nil {
+				return nil, err
+			}
+			return &out, nil
+{{- end }}
+		},
+	})
+{{- end }}
+	return s
+}
+`))
+
+// serverOp is an operation registered by the server of a port type.
+type serverOp struct {
+	Name         string
+	Action       string // name of the constant of the SOAP action
+	Space, Local string // name of the element of the request body
+	WrapperIn    *wrapper
+	WrapperOut   *wrapper
+	In, Out      []*soapFuncField
+	Header       string
+	HeaderFields []*soapHeaderField
+	Args         string
+	OneWay       bool
+	OutTag       string
+}
+
+// writeServers writes a function returning a SOAP server of an
+// implementation of the interface of each port type bound to SOAP, if
+// enabled, named after it prefixed by New and followed by Server.
+func (ge *goEncoder) writeServers(w io.Writer, d *wsdl.Definitions) error {
+	if !ge.servers {
+		return nil
+	}
+	var ports []*wsdl.PortType
+	for _, pt := range d.PortTypes {
+		if len(ge.funcs[pt.Name]) > 0 && len(ge.soapOps[pt.Name]) > 0 && ge.httpVerbs[pt.Name] == "" {
+			ports = append(ports, pt)
+		}
+	}
+	var doc string
+	if len(ports) > 0 && ge.wsdl != nil {
+		doc = uniqueName("WSDL", ge.typeExists)
+		if doc != "WSDL" {
+			ge.rename("WSDL document declared as %s, as WSDL is taken", doc)
+		}
+		err := wsdlT.Execute(w, &struct{ Name, Value string }{doc, stringLiteral(string(ge.wsdl))})
+		if err != nil {
+			return err
+		}
+	}
+	for _, pt := range ports {
+		var ops []*serverOp
+		for _, op := range ge.funcs[pt.Name] {
+			sop, err := ge.serverOp(pt, op)
+			if err != nil {
+				return err
+			}
+			if sop != nil {
+				ops = append(ops, sop)
+			}
+		}
+		iface := ge.interfaceName(pt)
+		name := uniqueName("New"+iface+"Server", ge.typeExists)
+		if name != "New"+iface+"Server" {
+			ge.rename("server of %s declared as %s, as New%sServer is taken", iface, name, iface)
+		}
+		ge.needsStdPkg["context"] = true
+		ge.needsStdPkg["encoding/xml"] = true
+		ge.needsExtPkg["github.com/seamuncle/wsdl2go/soap"] = true
+		err := serverT.Execute(w, &struct {
+			Name      string
+			Interface string
+			WSDL      string
+			Ops       []*serverOp
+		}{name, iface, doc, ops})
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// stringLiteral returns s as a Go string literal, a raw one unless s
+// has characters that raw literals can not hold.
+func stringLiteral(s string) string {
+	if !utf8.ValidString(s) || strings.ContainsAny(s, "`\r\ufeff") {
+		return strconv.Quote(s)
+	}
+	return "`" + s + "`"
+}
+
+// serverOp returns the operation of the server of port type pt serving
+// op, or nil if op is not bound. Its messages are decoded and encoded
+// as those of the client are encoded and decoded.
+func (ge *goEncoder) serverOp(pt *wsdl.PortType, op *wsdl.Operation) (*serverOp, error) {
+	bo := ge.soapOp(pt, op)
+	if bo == nil {
+		return nil, nil
+	}
+	inParams, err := ge.inputParams(pt, op)
+	if err != nil {
+		return nil, err
+	}
+	outParams, err := ge.outputParams(pt, op)
+	if err != nil {
+		return nil, err
+	}
+	inParams = ge.headerParams(pt, op, inParams)
+	inParams = ge.contextParams(inParams)
+	fixParamConflicts(inParams, outParams)
+
+	sop := &serverOp{
+		Name:       ge.exportedName(op.Name),
+		Action:     ge.soapActionConst(pt, op),
+		Local:      trimns(op.Name),
+		WrapperIn:  ge.wrappedInput(pt, op),
+		WrapperOut: ge.wrappedOutput(pt, op),
+		OneWay:     op.Output == nil,
+	}
+	var args []string
+	if !ge.noContext {
+		args = append(args, "ctx")
+		inParams = inParams[1:]
+	}
+	if sop.Header = ge.soapHeaderType(pt, op); sop.Header != "" {
+		for _, h := range bo.InputHeaders {
+			f, err := ge.soapHeaderField(h)
+			if err != nil {
+				return nil, err
+			}
+			sop.HeaderFields = append(sop.HeaderFields, f)
+		}
+		args = append(args, "header")
+		inParams = inParams[1:]
+	}
+	rpc := ge.rpcStyle(pt, op)
+	switch {
+	case sop.WrapperIn != nil:
+		sop.Space = ge.elementNS[sop.Local]
+	case rpc && bo.Input != nil:
+		sop.Space = bo.Input.Namespace
+	}
+	encoded := ge.rpcEncoded(pt, op)
+	for i, p := range inParams {
+		f := &soapFuncField{Name: strings.Title(p.Name), Type: p.Type, Tag: p.XMLName}
+		if sop.WrapperIn != nil {
+			f.Name = sop.WrapperIn.Fields[i].Name
+		} else if encoded && p.part != nil {
+			f.Tag = p.part.Name
+		}
+		sop.In = append(sop.In, f)
+		args = append(args, "in."+f.Name)
+	}
+	sop.Args = strings.Join(args, ", ")
+	if sop.OneWay {
+		return sop, nil
+	}
+	for i, p := range outParams[:len(outParams)-1] {
+		f := &soapFuncField{Name: strings.Title(p.Name), Type: p.Type, Tag: p.XMLName}
+		if sop.WrapperOut != nil {
+			f.Name = sop.WrapperOut.Fields[i].Name
+		}
+		sop.Out = append(sop.Out, f)
+	}
+	switch {
+	case sop.WrapperOut != nil:
+		sop.OutTag = sop.WrapperOut.Tag
+	case rpc:
+		ns := ""
+		if bo.Output != nil {
+			ns = bo.Output.Namespace
+		}
+		sop.OutTag = qualify(ns, trimns(op.Name)+"Response")
+	default:
+		sop.OutTag = trimns(op.Output.Message)
+	}
+	return sop, nil
+}
@@ -0,0 +1,305 @@
+package wsdlgo
+
+import (
+	"bytes"
+	"fmt"
+	"go/ast"
+	"go/format"
+	"go/parser"
+	"go/token"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"github.com/seamuncle/wsdl2go/wsdl"
+)
+
+// Files of the code generated by EncodeFiles.
+const (
+	typesFile    = "types.go"
+	enumsFile    = "enums.go"
+	messagesFile = "messages.go"
+	clientFile   = "client.go"
+
+	// the test of SetTests
+	typesTestFile = "types_test.go"
+)
+
+func (ge *goEncoder) EncodeFiles(d *wsdl.Definitions) (map[string][]byte, error) {
+	if ge.packages != "" {
+		return ge.encodePackages(d)
+	}
+	var b bytes.Buffer
+	w := ge.w
+	ge.w = &b
+	err := ge.Encode(d)
+	ge.w = w
+	if err != nil || b.Len() == 0 {
+		return nil, err
+	}
+	files, err := ge.split(d, b.Bytes())
+	if err != nil {
+		return nil, err
+	}
+	if ge.testCode != nil {
+		files[typesTestFile] = ge.testCode
+	}
+	return files, nil
+}
+
+// split splits the code src generated from d into files.
+func (ge *goEncoder) split(d *wsdl.Definitions, src []byte) (map[string][]byte, error) {
+	fset := token.NewFileSet()
+	f, err := parser.ParseFile(fset, "", src, parser.ParseComments)
+	if err != nil {
+		return nil, err
+	}
+	files := ge.typeFiles(d)
+	typeFile := func(name string) string {
+		if file, ok := files[name]; ok {
+			return file
+		}
+		return typesFile
+	}
+	bodies := make(map[string]*bytes.Buffer)
+	decls := make(map[string][]ast.Decl)
+	for _, decl := range f.Decls {
+		var file string
+		start := decl.Pos()
+		switch decl := decl.(type) {
+		case *ast.GenDecl:
+			if decl.Tok == token.IMPORT {
+				continue
+			}
+			file = genDeclFile(decl, typeFile)
+			if decl.Doc != nil {
+				start = decl.Doc.Pos()
+			}
+		case *ast.FuncDecl:
+			file = funcDeclFile(decl, typeFile)
+			if decl.Doc != nil {
+				start = decl.Doc.Pos()
+			}
+		}
+		body, ok := bodies[file]
+		if !ok {
+			body = &bytes.Buffer{}
+			bodies[file] = body
+		}
+		body.Write(src[fset.Position(start).Offset:fset.Position(decl.End()).Offset])
+		body.WriteString("\n\n")
+		decls[file] = append(decls[file], decl)
+	}
+	out := make(map[string][]byte)
+	for file, body := range bodies {
+		var b bytes.Buffer
+		fmt.Fprintf(&b, "package %s\n\n", f.Name.Name)
+		imports := usedImports(f.Imports, decls[file])
+		if len(imports) > 0 {
+			b.WriteString("import (\n")
+			for _, imp := range imports {
+				if imp.Name != nil {
+					fmt.Fprintf(&b, "%s ", imp.Name.Name)
+				}
+				fmt.Fprintf(&b, "%s\n", imp.Path.Value)
+			}
+			b.WriteString(")\n\n")
+		}
+		b.Write(body.Bytes())
+		code, err := format.Source(b.Bytes())
+		if err != nil {
+			return nil, fmt.Errorf("%s: %v", file, err)
+		}
+		out[file] = code
+	}
+	return out, nil
+}
+
+// genDeclFile returns the file of the declarations of decl: types go in
+// the file of their name, constants in the file of their type if any,
+// and variables but Namespace and the blank ones in types.go.
+func genDeclFile(decl *ast.GenDecl, typeFile func(string) string) string {
+	for _, spec := range decl.Specs {
+		switch spec := spec.(type) {
+		case *ast.TypeSpec:
+			return typeFile(spec.Name.Name)
+		case *ast.ValueSpec:
+			if decl.Tok == token.CONST {
+				if id, ok := spec.Type.(*ast.Ident); ok {
+					return typeFile(id.Name)
+				}
+				continue
+			}
+			for _, name := range spec.Names {
+				if name.Name == "Namespace" || name.Name == "_" {
+					return clientFile
+				}
+			}
+			return typesFile
+		}
+	}
+	return clientFile
+}
+
+// funcDeclFile returns the file of decl: methods go in the file of
+// their receiver, and functions returning a single value in the file of
+// its type, but for init which goes in types.go.
+func funcDeclFile(decl *ast.FuncDecl, typeFile func(string) string) string {
+	if decl.Recv != nil && len(decl.Recv.List) == 1 {
+		t := decl.Recv.List[0].Type
+		if star, ok := t.(*ast.StarExpr); ok {
+			t = star.X
+		}
+		if id, ok := t.(*ast.Ident); ok {
+			return typeFile(id.Name)
+		}
+	}
+	if decl.Name.Name == "init" {
+		return typesFile
+	}
+	if res := decl.Type.Results; res != nil && len(res.List) == 1 && len(res.List[0].Names) <= 1 {
+		if id, ok := res.List[0].Type.(*ast.Ident); ok {
+			return typeFile(id.Name)
+		}
+	}
+	return clientFile
+}
+
+// usedImports returns the imports referred to by decls.
+func usedImports(imports []*ast.ImportSpec, decls []ast.Decl) []*ast.ImportSpec {
+	used := make(map[string]bool)
+	for _, decl := range decls {
+		ast.Inspect(decl, func(n ast.Node) bool {
+			if sel, ok := n.(*ast.SelectorExpr); ok {
+				if id, ok := sel.X.(*ast.Ident); ok {
+					used[id.Name] = true
+				}
+			}
+			return true
+		})
+	}
+	var v []*ast.ImportSpec
+	for _, imp := range imports {
+		path, _ := strconv.Unquote(imp.Path.Value)
+		name := packageName(path)
+		if imp.Name != nil {
+			name = imp.Name.Name
+		}
+		if used[name] {
+			v = append(v, imp)
+		}
+	}
+	return v
+}
+
+// typeFiles returns the files of the Go types generated from d, by
+// name.
+func (ge *goEncoder) typeFiles(d *wsdl.Definitions) map[string]string {
+	main := ge.mainNamespace(d)
+	nsFiles := make(map[string]string)
+	taken := map[string]bool{typesFile: true, enumsFile: true, messagesFile: true, clientFile: true}
+	nsFile := func(ns, file string) string {
+		if ns == main || ns == "" {
+			return file
+		}
+		if name, ok := nsFiles[ns]; ok {
+			return name
+		}
+		name := uniqueName(namespaceFile(ns), func(name string) bool {
+			return taken[name+".go"]
+		}) + ".go"
+		taken[name] = true
+		nsFiles[ns] = name
+		return name
+	}
+	files := make(map[string]string)
+	for _, name := range ge.sortedSimpleTypes() {
+		file := typesFile
+		if r := ge.stypes[name].Restriction; r != nil && len(r.Enum) > 0 {
+			file = enumsFile
+		}
+		files[ge.goName(name)] = nsFile(ge.typeNS[name], file)
+	}
+	for _, name := range ge.sortedComplexTypes() {
+		file := nsFile(ge.typeNS[name], typesFile)
+		files[ge.exportedName(name)] = file
+		files[ge.derivedType(name)] = file
+	}
+	parts := make(map[string]bool)
+	for _, m := range d.Messages {
+		for _, p := range m.Parts {
+			if p.Element != "" {
+				parts[trimns(p.Element)] = true
+			}
+		}
+	}
+	for _, t := range ge.inlineTypes {
+		el := t.ct.Name
+		if name, ok := ge.typeElements[t.ct.Name]; ok {
+			el = name
+		}
+		if parts[el] && ge.ctypes[t.ct.Name] == t.ct {
+			files[ge.exportedName(t.ct.Name)] = messagesFile
+		}
+	}
+	for _, pt := range d.PortTypes {
+		for _, op := range ge.funcs[pt.Name] {
+			if name := ge.soapHeaderType(pt, op); name != "" {
+				files[name] = messagesFile
+			}
+			for _, ft := range ge.operationFaults(pt, op) {
+				files[ft.Name] = messagesFile
+			}
+		}
+		name := ge.interfaceName(pt)
+		files[name] = clientFile
+		files["Mock"+name] = clientFile
+		files[ge.unexportedName(pt.Name)] = clientFile
+	}
+	return files
+}
+
+// mainNamespace returns the namespace whose types go in the files of
+// their kind rather than in a file of their own: the target namespace
+// of d if any types are declared in it, or the namespace in which the
+// most types are declared.
+func (ge *goEncoder) mainNamespace(d *wsdl.Definitions) string {
+	count := make(map[string]int)
+	for name := range ge.stypes {
+		count[ge.typeNS[name]]++
+	}
+	for name := range ge.ctypes {
+		count[ge.typeNS[name]]++
+	}
+	if count[d.TargetNamespace] > 0 {
+		return d.TargetNamespace
+	}
+	namespaces := make([]string, 0, len(count))
+	for ns := range count {
+		namespaces = append(namespaces, ns)
+	}
+	sort.Strings(namespaces)
+	main := d.TargetNamespace
+	for _, ns := range namespaces {
+		if count[ns] > count[main] {
+			main = ns
+		}
+	}
+	return main
+}
+
+// namespaceFile returns the name of the file of the types of namespace
+// ns, without extension: its host and path, in lower case, with
+// underscores separating their words, followed by _types so that the
+// name never ends like those of test or platform specific files.
+func namespaceFile(ns string) string {
+	if i := strings.Index(ns, "://"); i >= 0 {
+		ns = ns[i+3:]
+	}
+	ns = strings.TrimPrefix(ns, "www.")
+	words := strings.FieldsFunc(strings.ToLower(ns), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+	return strings.Join(append(words, "types"), "_")
+}
@@ -0,0 +1,84 @@
+package wsdlgo
+
+import (
+	"io"
+	"text/template"
+
+	"github.com/seamuncle/wsdl2go/wsdl"
+)
+
+func (ge *goEncoder) SetStringers(stringers bool) {
+	ge.stringers = stringers
+}
+
+var enumStringT = template.Must(template.New("enumString").Parse(`
+// String returns v as written in XML, or as a conversion to {{.Name}}
+// if it is not one of the enumerated values.
+func (v {{.Name}}) String() string {
+	if !v.Valid() {
+		return fmt.Sprintf("{{.Name}}(%#v)", {{.Basic}}(v))
+	}
+	{{- if eq .Basic "string"}}
+	return string(v)
+	{{- else}}
+	return fmt.Sprint({{.Basic}}(v))
+	{{- end}}
+}
+
+`))
+
+// genEnumString writes the String method of the enumerated simple type
+// typeName, of restriction r, if stringers are enabled.
+func (ge *goEncoder) genEnumString(w io.Writer, typeName string, r *wsdl.Restriction) {
+	basic := ge.basicType(r.Base)
+	if !ge.stringers || len(r.Enum) == 0 || basic == "" {
+		return
+	}
+	ge.needsStdPkg["fmt"] = true
+	enumStringT.Execute(w, &struct {
+		Name  string
+		Basic string
+	}{typeName, basic})
+}
+
+var messageStringT = template.Must(template.New("messageString").Parse(`
+// String returns a human readable representation of t, for logging.
+func (t {{.}}) String() string {
+	return soap.Format(t)
+}
+
+`))
+
+// genMessageString writes the String method of the struct being
+// generated, of the complex type typeName, if stringers are enabled
+// and it is the type of a part of a message. It is left out if a field
+// is named String.
+func (ge *goEncoder) genMessageString(w io.Writer, typeName, name string) {
+	if !ge.stringers || !ge.messageTypes[typeName] || ge.fieldNames["String"] {
+		return
+	}
+	ge.needsExtPkg["github.com/seamuncle/wsdl2go/soap"] = true
+	messageStringT.Execute(w, name)
+}
+
+// cacheMessageTypes records the complex types of the parts of the
+// messages of d, and of the elements they refer to.
+func (ge *goEncoder) cacheMessageTypes(d *wsdl.Definitions) {
+	ge.messageTypes = make(map[string]bool)
+	for _, m := range d.Messages {
+		for _, p := range m.Parts {
+			name := ge.typeName(nil, p.Type)
+			if p.Element != "" {
+				name = trimns(ge.elementTypeRef(p.Element))
+				if _, ok := ge.ctypes[name]; !ok {
+					if el, ok := ge.elements[name]; ok && el.Type != "" {
+						name = ge.typeName(nil, el.Type)
+					}
+				}
+			}
+			if _, ok := ge.ctypes[name]; ok {
+				ge.messageTypes[name] = true
+			}
+		}
+	}
+}
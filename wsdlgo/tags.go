@@ -0,0 +1,112 @@
+package wsdlgo
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// DefaultTags are the struct tags, other than xml, of the fields of
+// generated types unless SetTags sets others.
+var DefaultTags = []string{"json", "yaml"}
+
+// knownTags are the struct tags SetTags accepts.
+var knownTags = map[string]bool{"json": true, "yaml": true, "bson": true}
+
+// Conventions of naming the values of struct tags other than xml after
+// the names of elements and attributes.
+const (
+	// TagNamingSchema names them as in the schema.
+	TagNamingSchema = "schema"
+
+	// TagNamingCamel names them in lower camel case: first-name and
+	// FirstName become firstName.
+	TagNamingCamel = "camel"
+
+	// TagNamingSnake names them in snake case: first-name and
+	// FirstName become first_name.
+	TagNamingSnake = "snake"
+)
+
+func (ge *goEncoder) SetTags(tags []string, naming string) {
+	if tags == nil {
+		tags = DefaultTags
+	}
+	ge.tags = tags
+	ge.tagNaming = naming
+}
+
+// checkTags returns an error if a struct tag or the convention of naming
+// their values is unknown.
+func (ge *goEncoder) checkTags() error {
+	for _, tag := range ge.tags {
+		if !knownTags[tag] {
+			return fmt.Errorf("unknown struct tag %q, want json, yaml or bson", tag)
+		}
+	}
+	switch ge.tagNaming {
+	case "", TagNamingSchema, TagNamingCamel, TagNamingSnake:
+		return nil
+	}
+	return fmt.Errorf("unknown tag naming %q, want %s, %s or %s",
+		ge.tagNaming, TagNamingSchema, TagNamingCamel, TagNamingSnake)
+}
+
+// fieldTag returns the struct tag of a field, quoted in back quotes:
+// its xml tag followed by the other struct tags, whose value is name
+// named by the tag naming convention, followed by omitempty if
+// omitEmpty is true. Fields named - are left out of those encodings.
+func (ge *goEncoder) fieldTag(xmlTag, name string, omitEmpty bool) string {
+	tags := ge.tags
+	if tags == nil {
+		tags = DefaultTags
+	}
+	if name != "-" {
+		name = ge.tagName(name)
+		if omitEmpty {
+			name += ",omitempty"
+		}
+	}
+	v := []string{"xml:" + strconv.Quote(xmlTag)}
+	seen := make(map[string]bool)
+	for _, tag := range tags {
+		if !seen[tag] {
+			seen[tag] = true
+			v = append(v, tag+":"+strconv.Quote(name))
+		}
+	}
+	return "`" + strings.Join(v, " ") + "`"
+}
+
+// tagName returns the value of struct tags other than xml for the
+// element or attribute name, following the tag naming convention.
+func (ge *goEncoder) tagName(name string) string {
+	var sep string
+	switch ge.tagNaming {
+	case TagNamingCamel:
+	case TagNamingSnake:
+		sep = "_"
+	default:
+		return name
+	}
+	var b strings.Builder
+	i := 0
+	for _, word := range strings.FieldsFunc(name, func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	}) {
+		for _, part := range splitHumps(word) {
+			part = strings.ToLower(part)
+			switch {
+			case i == 0:
+			case sep != "":
+				b.WriteString(sep)
+			default:
+				part = strings.Title(part)
+			}
+			b.WriteString(part)
+			i++
+		}
+	}
+	return b.String()
+}
@@ -0,0 +1,117 @@
+package wsdlgo
+
+import (
+	"bytes"
+	"go/format"
+	"text/template"
+)
+
+func (ge *goEncoder) SetTests(tests bool) {
+	ge.tests = tests
+}
+
+func (ge *goEncoder) Tests() []byte {
+	return ge.testCode
+}
+
+var roundTripT = template.Must(template.New("roundTrip").Parse(`package {{.Package}}
+
+import (
+	"bytes"
+	"encoding/xml"
+	"reflect"
+	"testing"
+)
+
+// TestRoundTrip checks that a value of each struct, its fields set to
+// placeholder values, is read back from its XML unchanged: marshaling
+// the value read yields the same XML.
+func TestRoundTrip(t *testing.T) {
+	values := []interface{}{
+	{{- range .Types}}
+		&{{.}}{},
+	{{- end}}
+	}
+	for _, v := range values {
+		x := reflect.ValueOf(v).Elem()
+		roundTripFill(x, x.Type().PkgPath(), 0)
+		b, err := xml.Marshal(v)
+		if err != nil {
+			t.Errorf("%T: %v", v, err)
+			continue
+		}
+		r := reflect.New(x.Type()).Interface()
+		err = xml.Unmarshal(b, r)
+		if err != nil {
+			t.Errorf("%T: %v\n%s", v, err, b)
+			continue
+		}
+		c, err := xml.Marshal(r)
+		if err != nil {
+			t.Errorf("%T: %v", v, err)
+			continue
+		}
+		if !bytes.Equal(b, c) {
+			t.Errorf("%T: read back as\n%s\nwant\n%s", v, c, b)
+		}
+	}
+}
+
+// roundTripFill sets v to placeholder values: its fields, the values of
+// its pointers and a single element of its slices, recursively, down to
+// pointers and slices 3 levels deep. Only values of basic types and of
+// types declared in package pkg are set; interfaces are left nil.
+func roundTripFill(v reflect.Value, pkg string, depth int) {
+	fillable := func(t reflect.Type) bool {
+		return t.PkgPath() == "" || t.PkgPath() == pkg
+	}
+	switch v.Kind() {
+	case reflect.String:
+		v.SetString("x")
+	case reflect.Bool:
+		v.SetBool(true)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		v.SetInt(42)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		v.SetUint(42)
+	case reflect.Float32, reflect.Float64:
+		v.SetFloat(1.5)
+	case reflect.Ptr:
+		if depth < 3 && fillable(v.Type().Elem()) {
+			v.Set(reflect.New(v.Type().Elem()))
+			roundTripFill(v.Elem(), pkg, depth+1)
+		}
+	case reflect.Slice:
+		if depth < 3 && fillable(v.Type().Elem()) {
+			v.Set(reflect.MakeSlice(v.Type(), 1, 1))
+			roundTripFill(v.Index(0), pkg, depth+1)
+		}
+	case reflect.Struct:
+		for i := 0; i < v.NumField(); i++ {
+			f := v.Type().Field(i)
+			if f.PkgPath == "" && fillable(f.Type) {
+				roundTripFill(v.Field(i), pkg, depth)
+			}
+		}
+	}
+}
+`))
+
+// genTests sets the code of the test of the structs generated in
+// package pkg, if enabled, which marshals a value of each to XML and
+// back.
+func (ge *goEncoder) genTests(pkg string) error {
+	if !ge.tests || len(ge.testTypes) == 0 {
+		return nil
+	}
+	var b bytes.Buffer
+	err := roundTripT.Execute(&b, &struct {
+		Package string
+		Types   []string
+	}{pkg, sortedKeys(ge.testTypes)})
+	if err != nil {
+		return err
+	}
+	ge.testCode, err = format.Source(b.Bytes())
+	return err
+}
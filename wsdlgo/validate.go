@@ -0,0 +1,309 @@
+package wsdlgo
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"math/big"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/seamuncle/wsdl2go/wsdl"
+)
+
+func (ge *goEncoder) SetValidate(validate bool) {
+	ge.validate = validate
+}
+
+// cacheValidTypes records the types generated with a Validate method
+// returning an error, if enabled: the simple types restricted by facets
+// or restricting such types, and the structs of the complex types but
+// those having a field named Validate.
+func (ge *goEncoder) cacheValidTypes() {
+	if !ge.validate {
+		return
+	}
+	if ge.validTypes == nil {
+		ge.validTypes = make(map[string]bool)
+	}
+	for name := range ge.restrictedTypes {
+		if ge.hasFacets(name) {
+			ge.validTypes[name] = true
+		}
+	}
+	for name, fields := range ge.structFields {
+		if !fields["Validate"] {
+			ge.validTypes[name] = true
+		}
+	}
+}
+
+// hasFacets reports whether the restriction of the simple type of Go
+// type typ, or of its base, has facets.
+func (ge *goEncoder) hasFacets(typ string) bool {
+	for seen := make(map[string]bool); !seen[typ]; {
+		seen[typ] = true
+		rt, ok := ge.restrictedTypes[typ]
+		if !ok {
+			return false
+		}
+		r := rt.r
+		if len(r.Enum) > 0 || len(r.Patterns) > 0 ||
+			r.Length != nil || r.MinLength != nil || r.MaxLength != nil ||
+			r.MinInclusive != nil || r.MaxInclusive != nil ||
+			r.MinExclusive != nil || r.MaxExclusive != nil {
+			return true
+		}
+		typ = rt.base
+	}
+	return false
+}
+
+// genFacetValidate writes the Validate method of the simple type
+// typeName, which checks its values against the facets of r, its
+// restriction of base, if enabled. Values are checked against the
+// facets of base first, if it has any.
+//
+// Patterns and lengths are checked for strings, lengths for binary
+// data and ranges for numbers, big ones included. Patterns are matched
+// by package regexp, whose syntax is close to the one of XML Schema;
+// those it does not support are left out.
+func (ge *goEncoder) genFacetValidate(out io.Writer, typeName, base string, r *wsdl.Restriction) {
+	if !ge.validate || !ge.validTypes[typeName] {
+		return
+	}
+	var w bytes.Buffer
+	defer func() {
+		if bytes.Contains(w.Bytes(), []byte("fmt.")) {
+			ge.needsStdPkg["fmt"] = true
+		}
+		w.WriteTo(out)
+	}()
+	fmt.Fprintf(&w, "// Validate checks that v satisfies the facets of %s.\n", typeName)
+	fmt.Fprintf(&w, "func (v %s) Validate() error {\n", typeName)
+	if ge.validTypes[base] {
+		fmt.Fprintf(&w, "if err := %s(v).Validate(); err != nil {\nreturn err\n}\n", base)
+	}
+	basic := ge.basicType(r.Base)
+	value := "v"
+	if basic != "" {
+		value = basic + "(v)"
+	}
+	if len(r.Enum) > 0 && !embedsBase[base] {
+		verb := "%v"
+		if basic == "string" {
+			verb = "%q"
+		}
+		fmt.Fprintf(&w, "if !v.Valid() {\nreturn fmt.Errorf(\"%s: invalid value %s\", %s)\n}\n",
+			typeName, verb, value)
+	}
+	embedded := ge.embeddedType(typeName)
+	var pattern string
+	switch {
+	case basic == "string":
+		ge.genLengthChecks(&w, typeName, "utf8.RuneCountInString(string(v))", "characters", r)
+		pattern = ge.genPatternCheck(&w, typeName, r)
+	case embedded == "Base64Binary" || embedded == "HexBinary":
+		ge.genLengthChecks(&w, typeName, "len(v."+embedded+")", "bytes", r)
+	case base == "Base64Binary" || base == "HexBinary":
+		ge.genLengthChecks(&w, typeName, "len(v)", "bytes", r)
+	case numericBits[basic] > 0:
+		ge.genRangeChecks(&w, typeName, r, func(op, bound string) string {
+			if !validNumber(basic, bound) {
+				return ""
+			}
+			return fmt.Sprintf("v %s %s", op, bound)
+		}, value)
+	case embedded == "big.Float" || embedded == "big.Int" || embedded == "Decimal":
+		ge.needsStdPkg["math/big"] = true
+		x, newBound := "v.Float", "new(big.Float).SetString(%q)"
+		switch embedded {
+		case "big.Int":
+			x, newBound = "v.Int", "new(big.Int).SetString(%q, 10)"
+		case "Decimal":
+			x, newBound = "v.Decimal", "new(big.Rat).SetString(%q)"
+		}
+		ge.genRangeChecks(&w, typeName, r, func(op, bound string) string {
+			if _, ok := new(big.Rat).SetString(bound); !ok || embedded == "big.Int" && !validNumber("int64", bound) {
+				return ""
+			}
+			return fmt.Sprintf("b, _ := "+newBound+"; v.Cmp(b) %s 0", bound, op)
+		}, strings.Replace(x, "v.", "&v.", 1))
+	}
+	fmt.Fprintf(&w, "return nil\n}\n\n")
+	if pattern != "" {
+		fmt.Fprintf(&w, "%s\n", pattern)
+	}
+}
+
+// validNumber reports whether s is a literal of the Go basic numeric
+// type typ.
+func validNumber(typ, s string) bool {
+	var err error
+	switch {
+	case typ == "float64":
+		_, err = strconv.ParseFloat(s, 64)
+	case strings.HasPrefix(typ, "uint"):
+		_, err = strconv.ParseUint(s, 10, numericBits[typ])
+	default:
+		_, err = strconv.ParseInt(s, 10, numericBits[typ])
+	}
+	return err == nil
+}
+
+// genLengthChecks writes the checks of the length n of a value of
+// typeName, counted in units, against the length facets of r.
+func (ge *goEncoder) genLengthChecks(w io.Writer, typeName, n, units string, r *wsdl.Restriction) {
+	checks := []struct {
+		facet *wsdl.Facet
+		op    string
+		want  string
+	}{
+		{r.Length, "!=", ""},
+		{r.MinLength, "<", "at least "},
+		{r.MaxLength, ">", "at most "},
+	}
+	for _, c := range checks {
+		if c.facet == nil {
+			continue
+		}
+		length, err := strconv.Atoi(strings.TrimSpace(c.facet.Value))
+		if err != nil {
+			continue
+		}
+		if strings.HasPrefix(n, "utf8.") {
+			ge.needsStdPkg["unicode/utf8"] = true
+		}
+		fmt.Fprintf(w, "if n := %s; n %s %d {\n", n, c.op, length)
+		fmt.Fprintf(w, "return fmt.Errorf(\"%s: %%d %s, want %s%d\", n)\n}\n",
+			typeName, units, c.want, length)
+	}
+}
+
+// genRangeChecks writes the checks of a value of typeName, formatted
+// as the expression x, against the range facets of r. The condition of
+// each check is given by cond, which compares the value to a bound with
+// op, or returns "" if the bound is not valid for the type.
+func (ge *goEncoder) genRangeChecks(w io.Writer, typeName string, r *wsdl.Restriction, cond func(op, bound string) string, x string) {
+	checks := []struct {
+		facet *wsdl.Facet
+		op    string
+		want  string
+	}{
+		{r.MinInclusive, "<", "at least"},
+		{r.MinExclusive, "<=", "more than"},
+		{r.MaxInclusive, ">", "at most"},
+		{r.MaxExclusive, ">=", "less than"},
+	}
+	for _, c := range checks {
+		if c.facet == nil {
+			continue
+		}
+		bound := strings.TrimSpace(c.facet.Value)
+		s := cond(c.op, bound)
+		if s == "" {
+			continue
+		}
+		fmt.Fprintf(w, "if %s {\nreturn fmt.Errorf(\"%s: %%v, want %s %s\", %s)\n}\n",
+			s, typeName, c.want, bound, x)
+	}
+}
+
+// genPatternCheck writes the check of a value of typeName against the
+// patterns of r, any of which it must match, and returns the
+// declaration of the variable holding them compiled, if any.
+func (ge *goEncoder) genPatternCheck(w io.Writer, typeName string, r *wsdl.Restriction) string {
+	var patterns, values []string
+	for _, p := range r.Patterns {
+		re, ok := goPattern(p.Value)
+		if !ok {
+			fmt.Fprintf(w, "// pattern %s is not supported by package regexp\n", p.Value)
+			continue
+		}
+		patterns = append(patterns, "(?:"+re+")")
+		values = append(values, p.Value)
+	}
+	if len(patterns) == 0 {
+		return ""
+	}
+	ge.needsStdPkg["regexp"] = true
+	name := "pattern" + typeName
+	re := "^(?:" + strings.Join(patterns, "|") + ")$"
+	fmt.Fprintf(w, "if !%s.MatchString(string(v)) {\n", name)
+	fmt.Fprintf(w, "return fmt.Errorf(\"%s: %%q does not match the pattern %%s\", string(v), %s)\n}\n",
+		typeName, goLiteral(strings.Join(values, "|")))
+	return fmt.Sprintf("var %s = regexp.MustCompile(%s)\n", name, goLiteral(re))
+}
+
+// goPattern returns the regular expression of package regexp matching
+// the values that match the pattern p of XML Schema, in which ^ and $
+// are not anchors, or false if it is not supported, such as patterns
+// with character class subtractions or the escapes of XML names.
+func goPattern(p string) (string, bool) {
+	var b strings.Builder
+	inClass, escaped := false, false
+	for i, c := range p {
+		switch {
+		case escaped:
+			escaped = false
+		case c == '\\':
+			escaped = true
+		case inClass && c == '[' && strings.HasSuffix(p[:i], "-"):
+			return "", false
+		case inClass && c == ']':
+			inClass = false
+		case !inClass && c == '[':
+			inClass = true
+		case !inClass && (c == '^' || c == '$'):
+			b.WriteRune('\\')
+		}
+		b.WriteRune(c)
+	}
+	_, err := regexp.Compile(b.String())
+	return b.String(), err == nil
+}
+
+// goLiteral returns s as a Go string literal, in back quotes if
+// possible.
+func goLiteral(s string) string {
+	if strings.ContainsAny(s, "`\r") {
+		return strconv.Quote(s)
+	}
+	return "`" + s + "`"
+}
+
+// genValidateFields returns the checks of the fields of the struct
+// name, being generated, whose values have a Validate method, if
+// enabled.
+func (ge *goEncoder) genValidateFields(name string) []string {
+	if !ge.validate {
+		return nil
+	}
+	var checks []string
+	for _, f := range ge.fields {
+		typ := f.Type
+		switch {
+		case strings.HasPrefix(typ, "[]"):
+			typ = typ[2:]
+			if !ge.validTypes[strings.TrimPrefix(typ, "*")] {
+				continue
+			}
+			checks = append(checks, fmt.Sprintf(
+				"for i, v := range t.%s {\nif err := v.Validate(); err != nil {\nreturn fmt.Errorf(\"%s.%s[%%d]: %%v\", i, err)\n}\n}",
+				f.Name, name, f.Name))
+		case strings.HasPrefix(typ, "*"):
+			if !ge.validTypes[typ[1:]] {
+				continue
+			}
+			checks = append(checks, fmt.Sprintf(
+				"if t.%s != nil {\nif err := t.%s.Validate(); err != nil {\nreturn fmt.Errorf(\"%s.%s: %%v\", err)\n}\n}",
+				f.Name, f.Name, name, f.Name))
+		case ge.validTypes[typ]:
+			checks = append(checks, fmt.Sprintf(
+				"if err := t.%s.Validate(); err != nil {\nreturn fmt.Errorf(\"%s.%s: %%v\", err)\n}",
+				f.Name, name, f.Name))
+		}
+	}
+	return checks
+}